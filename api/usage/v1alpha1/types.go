@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the schema of the usage v1alpha1 API group, served by the operator
+// through the API aggregation layer at /apis/usage.environment.tf.operator.com/v1alpha1/workspaces/<name>/usage
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupVersion is the group version of the usage API
+var GroupVersion = schema.GroupVersion{Group: "usage.environment.tf.operator.com", Version: "v1alpha1"}
+
+// WorkspaceUsage is the live usage of a Workspace, read from its namespace on every request
+type WorkspaceUsage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Namespace is the namespace of the Workspace
+	Namespace string `json:"namespace"`
+
+	// Quotas is the consumption of the ResourceQuotas of the namespace
+	Quotas []QuotaUsage `json:"quotas"`
+
+	// Pods counts the pods of the namespace per phase
+	Pods PodCounts `json:"pods"`
+
+	// Cost is the spend and cost estimate of the Workspace, omitted when the operator has no pricing
+	Cost *CostEstimate `json:"cost,omitempty"`
+}
+
+// QuotaUsage is the consumption of a ResourceQuota
+type QuotaUsage struct {
+	// Name is the name of the ResourceQuota
+	Name string `json:"name"`
+
+	// Hard are the limits of the ResourceQuota
+	Hard corev1.ResourceList `json:"hard"`
+
+	// Used is the consumption of the limits
+	Used corev1.ResourceList `json:"used"`
+
+	// UsedPercent is the consumption of every limit in percent of the limit
+	UsedPercent map[corev1.ResourceName]int32 `json:"usedPercent"`
+}
+
+// PodCounts counts the pods of a namespace per phase
+type PodCounts struct {
+	Total     int32 `json:"total"`
+	Running   int32 `json:"running"`
+	Pending   int32 `json:"pending"`
+	Succeeded int32 `json:"succeeded"`
+	Failed    int32 `json:"failed"`
+}
+
+// CostEstimate is the spend of a Workspace and the monthly cost of its current requests
+type CostEstimate struct {
+	// Last7Days and Last30Days are the spend reported by OpenCost, as of LastUpdated
+	Last7Days   string       `json:"last7Days,omitempty"`
+	Last30Days  string       `json:"last30Days,omitempty"`
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+
+	// MonthlyEstimate is the monthly cost of the cpu, memory and storage currently requested in the namespace,
+	// priced with the budget unit prices of the operator
+	MonthlyEstimate string `json:"monthlyEstimate,omitempty"`
+}
@@ -17,22 +17,507 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 type WorkspaceResource struct {
+	// Memory is the requests.memory hard limit of the workspace ResourceQuota, e.g. 256Mi
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
 	Memory string `json:"memory,omitempty"`
-	CPU    string `json:"cpu,omitempty"`
-	Disk   string `json:"disk,omitempty"`
+	// CPU is the requests.cpu hard limit of the workspace ResourceQuota, e.g. 800m
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+	CPU string `json:"cpu,omitempty"`
+	// Disk is the requests.storage hard limit of the workspace ResourceQuota, e.g. 10Gi
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+	Disk string `json:"disk,omitempty"`
+	// RequestsCPU is the requests.cpu hard limit of the workspace ResourceQuota, enforced next to CPU, e.g. 2
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+	RequestsCPU string `json:"requestsCPU,omitempty"`
+	// LimitsCPU is the limits.cpu hard limit of the workspace ResourceQuota, e.g. 4
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+	LimitsCPU string `json:"limitsCPU,omitempty"`
+	// RequestsMemory is the requests.memory hard limit of the workspace ResourceQuota, enforced next to Memory, e.g. 4Gi
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+	RequestsMemory string `json:"requestsMemory,omitempty"`
+	// LimitsMemory is the limits.memory hard limit of the workspace ResourceQuota, e.g. 8Gi
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+	LimitsMemory string `json:"limitsMemory,omitempty"`
+	// Pods is the pods hard limit of the workspace ResourceQuota, the number of non-terminal pods, e.g. 50
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	Pods string `json:"pods,omitempty"`
+	// Services is the services hard limit of the workspace ResourceQuota, e.g. 10
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	Services string `json:"services,omitempty"`
+	// LoadBalancers is the services.loadbalancers hard limit of the workspace ResourceQuota, e.g. 0
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	LoadBalancers string `json:"loadBalancers,omitempty"`
+	// Secrets is the secrets hard limit of the workspace ResourceQuota, e.g. 100
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	Secrets string `json:"secrets,omitempty"`
+	// ConfigMaps is the configmaps hard limit of the workspace ResourceQuota, e.g. 100
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	ConfigMaps string `json:"configMaps,omitempty"`
+	// PersistentVolumeClaims is the persistentvolumeclaims hard limit of the workspace ResourceQuota, e.g. 20
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	PersistentVolumeClaims string `json:"persistentVolumeClaims,omitempty"`
 }
 
+// WorkspaceBudget is the monthly budget of the workspace, translated by the operator into the hard limits
+// of the workspace ResourceQuota with its unit prices of cpu, memory and storage
+type WorkspaceBudget struct {
+	// Monthly is the monthly amount, in the currency of the unit prices of the operator, e.g. 1500 or 1500.50
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	Monthly string `json:"monthly"`
+}
+
+// WorkspaceUser are the users bound to the admin, editor and viewer roles of the workspace namespace.
+// A user is an email-like identifier, e.g. jane@example.com, or a user name such as jane.
+// A role is bound to its single user and to the users of its list, e.g. admin and admins.
 type WorkspaceUser struct {
-	Admin  string `json:"admin,omitempty"`
+	// Admin is the user bound to the admin role
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([a-zA-Z0-9._%+:@-]*[a-zA-Z0-9])?$`
+	Admin string `json:"admin,omitempty"`
+	// Editor is the user bound to the editor role
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([a-zA-Z0-9._%+:@-]*[a-zA-Z0-9])?$`
 	Editor string `json:"editor,omitempty"`
+	// Viewer is the user bound to the viewer role
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([a-zA-Z0-9._%+:@-]*[a-zA-Z0-9])?$`
 	Viewer string `json:"viewer,omitempty"`
+
+	// Admins are the users bound to the admin role, next to admin
+	// +kubebuilder:validation:items:MaxLength=253
+	// +kubebuilder:validation:items:Pattern=`^[a-zA-Z0-9]([a-zA-Z0-9._%+:@-]*[a-zA-Z0-9])?$`
+	// +listType=set
+	// +optional
+	Admins []string `json:"admins,omitempty"`
+	// Editors are the users bound to the editor role, next to editor
+	// +kubebuilder:validation:items:MaxLength=253
+	// +kubebuilder:validation:items:Pattern=`^[a-zA-Z0-9]([a-zA-Z0-9._%+:@-]*[a-zA-Z0-9])?$`
+	// +listType=set
+	// +optional
+	Editors []string `json:"editors,omitempty"`
+	// Viewers are the users bound to the viewer role, next to viewer
+	// +kubebuilder:validation:items:MaxLength=253
+	// +kubebuilder:validation:items:Pattern=`^[a-zA-Z0-9]([a-zA-Z0-9._%+:@-]*[a-zA-Z0-9])?$`
+	// +listType=set
+	// +optional
+	Viewers []string `json:"viewers,omitempty"`
+}
+
+// WorkspaceAccessSchedule restricts the access of a role of the workspace to a weekly time window,
+// e.g. the editors only on weekdays from 08:00 to 18:00
+type WorkspaceAccessSchedule struct {
+	// Role whose user is only bound during the time window
+	// +kubebuilder:validation:Enum=admin;editor;viewer
+	Role string `json:"role"`
+	// Days of the week the time window starts on. Every day when empty.
+	// +optional
+	Days []WeekDay `json:"days,omitempty"`
+	// Start of the time window, e.g. 08:00
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// End of the time window, e.g. 18:00. A window ending before its start ends on the next day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+	// TimeZone of the time window, an IANA time zone such as Europe/Paris. UTC when empty.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// WorkspaceTeamProvider is the platform the members of a team are synced from
+// +kubebuilder:validation:Enum=github;gitlab
+type WorkspaceTeamProvider string
+
+const (
+	// TeamProviderGitHub syncs the members of a GitHub organization team
+	TeamProviderGitHub WorkspaceTeamProvider = "github"
+	// TeamProviderGitLab syncs the members of a GitLab group, including the members inherited from its parent groups
+	TeamProviderGitLab WorkspaceTeamProvider = "gitlab"
+)
+
+// WorkspaceTeam grants a role of the workspace to the members of a GitHub team or a GitLab group
+type WorkspaceTeam struct {
+	// Provider of the team
+	Provider WorkspaceTeamProvider `json:"provider"`
+	// Name of the team, <org>/<team-slug> for a GitHub team or the full path of a GitLab group, e.g. my-org/platform
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9._-]+(/[a-zA-Z0-9._-]+)*$`
+	Name string `json:"name"`
+	// Role bound to the members of the team
+	// +kubebuilder:validation:Enum=admin;editor;viewer
+	Role string `json:"role"`
+}
+
+// WorkspaceRBAC sets the API groups covered by the built-in rules of the admin, editor and viewer roles of the workspace
+type WorkspaceRBAC struct {
+	// APIGroups replace the API groups of the operator covered by the built-in rules, e.g. "" for the core group,
+	// apps or batch, among the ones allowed by the operator. The rules of the role templates of the operator,
+	// of the class and of the template are kept as is.
+	// +optional
+	APIGroups []string `json:"apiGroups,omitempty"`
+}
+
+// WorkspaceSubject binds a role of the workspace to a User, a Group or a ServiceAccount
+type WorkspaceSubject struct {
+	// Role bound to the subject
+	// +kubebuilder:validation:Enum=admin;editor;viewer
+	Role string `json:"role"`
+	// Kind of the subject
+	// +kubebuilder:validation:Enum=User;Group;ServiceAccount
+	Kind string `json:"kind"`
+	// Name of the subject
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+	// Namespace of a ServiceAccount subject, required for the ServiceAccounts and forbidden for the other kinds
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// WeekDay is a day of the week
+// +kubebuilder:validation:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+type WeekDay string
+
+// WorkspaceDeletionPolicy is what becomes of the namespace of a deleted Workspace
+// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+type WorkspaceDeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the namespace with all its contents
+	DeletionPolicyDelete WorkspaceDeletionPolicy = "Delete"
+	// DeletionPolicyOrphan keeps the namespace and its contents, no longer owned by the Workspace
+	DeletionPolicyOrphan WorkspaceDeletionPolicy = "Orphan"
+	// DeletionPolicyRetain keeps the namespace and its contents, with the ownership labels of the operator stripped
+	DeletionPolicyRetain WorkspaceDeletionPolicy = "Retain"
+)
+
+// WorkspaceExpiryAction is what happens to a workspace once it expired
+// +kubebuilder:validation:Enum=Delete;Suspend
+type WorkspaceExpiryAction string
+
+const (
+	// ExpiryActionDelete deletes the expired workspace, with its deletion grace period and policy
+	ExpiryActionDelete WorkspaceExpiryAction = "Delete"
+	// ExpiryActionSuspend suspends the expired workspace, until its expiry is moved later
+	ExpiryActionSuspend WorkspaceExpiryAction = "Suspend"
+)
+
+// WorkspaceSuspension are the options of the suspension of a workspace
+type WorkspaceSuspension struct {
+	// ScaleDown scales the Deployments and StatefulSets of the namespace to 0 replicas and suspends its CronJobs
+	// while the workspace is suspended, they are scaled back up when it is resumed
+	// +optional
+	ScaleDown bool `json:"scaleDown,omitempty"`
+}
+
+// WorkspaceHibernation suspends the workspace in the windows of its schedule, e.g. on nights and weekends
+type WorkspaceHibernation struct {
+	// Schedule are the windows the workspace is suspended in
+	// +kubebuilder:validation:MinItems=1
+	Schedule []WorkspaceHibernationWindow `json:"schedule"`
+	// TimeZone of the cron expressions of the windows, an IANA time zone such as Europe/Paris. UTC when empty.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// WorkspaceHibernationWindow is a window the workspace is suspended in, from one activation of its start
+// cron expression to the next activation of its stop cron expression
+type WorkspaceHibernationWindow struct {
+	// Start is the 5 field cron expression of the starts of the window, e.g. "0 20 * * 1-5" on weekdays at 20:00
+	Start string `json:"start"`
+	// Stop is the 5 field cron expression of the ends of the window, e.g. "0 8 * * 1-5" on weekdays at 08:00
+	Stop string `json:"stop"`
+}
+
+// WorkspaceQuotaMode is how the hard limits of the workspace are applied
+// +kubebuilder:validation:Enum=Enforce;Monitor
+type WorkspaceQuotaMode string
+
+const (
+	// QuotaModeEnforce enforces the hard limits of the workspace with its ResourceQuota
+	QuotaModeEnforce WorkspaceQuotaMode = "Enforce"
+	// QuotaModeMonitor only tracks the usage of the workspace namespace against its hard limits,
+	// without a ResourceQuota rejecting the pods and claims exceeding them
+	QuotaModeMonitor WorkspaceQuotaMode = "Monitor"
+)
+
+// WorkspaceQuotaAlerts configures when the Workspace reports pressure on its ResourceQuota
+type WorkspaceQuotaAlerts struct {
+	// Thresholds are the usage percentages of the ResourceQuota hard limits at which
+	// the QuotaPressure condition is raised. Defaults to 80 and 95 when empty.
+	Thresholds []int32 `json:"thresholds,omitempty"`
+}
+
+// WorkspaceSlackReceiver routes alerts to a Slack channel
+type WorkspaceSlackReceiver struct {
+	// Channel is the Slack channel the alerts are posted to
+	Channel string `json:"channel"`
+	// APIURL selects the key of a Secret in the workspace namespace that holds the Slack webhook URL
+	APIURL corev1.SecretKeySelector `json:"apiURL"`
+}
+
+// WorkspacePagerDutyReceiver routes alerts to a PagerDuty service
+type WorkspacePagerDutyReceiver struct {
+	// RoutingKey selects the key of a Secret in the workspace namespace that holds the PagerDuty integration key
+	RoutingKey corev1.SecretKeySelector `json:"routingKey"`
+}
+
+// WorkspaceAlertReceiver is the destination the alerts of the workspace are routed to
+type WorkspaceAlertReceiver struct {
+	Slack *WorkspaceSlackReceiver `json:"slack,omitempty"`
+	// Email is the address the alerts are sent to
+	// +kubebuilder:validation:Format=email
+	// +kubebuilder:validation:MaxLength=254
+	Email     string                      `json:"email,omitempty"`
+	PagerDuty *WorkspacePagerDutyReceiver `json:"pagerDuty,omitempty"`
+}
+
+// WorkspaceAlerting configures the baseline alerting provisioned for the workspace
+type WorkspaceAlerting struct {
+	// PrometheusRules creates a PrometheusRule with standard alerts (quota near limit,
+	// crashlooping pods, pending pods) in the workspace namespace.
+	// Requires the prometheus-operator CRDs to be installed in the cluster.
+	PrometheusRules bool `json:"prometheusRules,omitempty"`
+
+	// Receiver routes the alerts of the workspace namespace to the tenant through an AlertmanagerConfig.
+	// Requires the prometheus-operator CRDs to be installed in the cluster.
+	Receiver *WorkspaceAlertReceiver `json:"receiver,omitempty"`
+}
+
+// WorkspaceLogDestination is where the logs of the workspace namespace are shipped to
+type WorkspaceLogDestination struct {
+	// Type of the log destination
+	// +kubebuilder:validation:Enum=elasticsearch;loki;http
+	Type string `json:"type"`
+	// URL of the log destination, e.g. https://elasticsearch.logging:9200
+	// +kubebuilder:validation:Format=uri
+	// +kubebuilder:validation:Pattern=`^https?://[^\s]+$`
+	URL string `json:"url"`
+	// Index the logs are written to. Only used by elasticsearch destinations.
+	// +kubebuilder:validation:MaxLength=255
+	// +kubebuilder:validation:Pattern=`^[a-z0-9][a-z0-9._+-]*$`
+	// +optional
+	Index string `json:"index,omitempty"`
+}
+
+// WorkspaceBatch configures the queueing of the batch workloads of the workspace
+type WorkspaceBatch struct {
+	// Kueue creates a LocalQueue in the workspace namespace, bound to a ClusterQueue named after the namespace
+	// whose nominal quota is the cpu and memory of the workspace. Requires the Kueue CRDs to be installed in the cluster.
+	// +optional
+	Kueue *WorkspaceKueue `json:"kueue,omitempty"`
+}
+
+// WorkspaceKueue configures the Kueue ClusterQueue of the workspace
+type WorkspaceKueue struct {
+	// ResourceFlavor is the Kueue ResourceFlavor the nominal quota of the ClusterQueue is assigned to
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:default=default-flavor
+	// +optional
+	ResourceFlavor string `json:"resourceFlavor,omitempty"`
+	// Cohort of the ClusterQueue, letting the workspace borrow the unused quota of the other ClusterQueues of the cohort
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	Cohort string `json:"cohort,omitempty"`
+}
+
+// WorkspaceGPU sets the NVIDIA GPUs of the workspace and how they are shared with other workspaces
+type WorkspaceGPU struct {
+	// Count is the number of GPUs, MIG instances or time-sliced GPU replicas the workspace can request,
+	// added to the workspace ResourceQuota
+	// +kubebuilder:validation:Minimum=0
+	Count int32 `json:"count"`
+	// MIGProfile is the Multi-Instance GPU profile the workspace requests, e.g. 1g.5gb. The quota applies to the
+	// nvidia.com/mig-<profile> resource advertised by the device plugin with the mixed MIG strategy.
+	// +kubebuilder:validation:Pattern=`^[0-9]+g\.[0-9]+gb$`
+	// +optional
+	MIGProfile string `json:"migProfile,omitempty"`
+	// TimeSlicingReplicas is the number of replicas each GPU is time-sliced into. The pods of the workspace are
+	// scheduled on the nodes whose device plugin is configured with the same number of replicas.
+	// +kubebuilder:validation:Minimum=2
+	// +optional
+	TimeSlicingReplicas int32 `json:"timeSlicingReplicas,omitempty"`
+}
+
+// WorkspaceLogging configures the log pipeline of the workspace namespace
+type WorkspaceLogging struct {
+	// Format of the rendered log pipeline configuration
+	// +kubebuilder:validation:Enum=fluent-bit;vector
+	Format string `json:"format"`
+	// Destination the logs of the workspace namespace are routed to
+	Destination WorkspaceLogDestination `json:"destination"`
+}
+
+// WorkspaceNetworking sets the network peering of the workspace namespace with other workspaces
+type WorkspaceNetworking struct {
+	// AllowFrom are the names of the Workspaces whose namespaces may reach the pods of the workspace namespace
+	// +listType=set
+	AllowFrom []string `json:"allowFrom,omitempty"`
+	// Egress restricts the outbound traffic of the workspace namespace to approved destinations
+	// +optional
+	Egress *WorkspaceEgress `json:"egress,omitempty"`
+}
+
+// WorkspaceEgress are the destinations the pods of the workspace namespace are allowed to reach,
+// besides the namespace itself, the cluster DNS, the peers and the Egress grants of the workspace
+type WorkspaceEgress struct {
+	// CIDRs are the IP ranges the pods may reach, e.g. 10.20.0.0/16
+	// +listType=set
+	// +optional
+	CIDRs []string `json:"cidrs,omitempty"`
+	// FQDNs are the domain names the pods may reach, e.g. api.github.com or *.s3.amazonaws.com.
+	// They are only enforced on clusters running Cilium.
+	// +listType=set
+	// +optional
+	FQDNs []string `json:"fqdns,omitempty"`
+	// Ports the traffic to the CIDRs and FQDNs is allowed on. All the ports are allowed when empty.
+	// +optional
+	Ports []networkingv1.NetworkPolicyPort `json:"ports,omitempty"`
+}
+
+// WorkspaceNetworkingStatus shows the network peering of the workspace with other workspaces
+type WorkspaceNetworkingStatus struct {
+	// AllowedFrom are the Workspaces allowed to reach the workspace namespace
+	AllowedFrom []string `json:"allowedFrom,omitempty"`
+	// AllowedTo are the Workspaces whose namespaces the workspace is allowed to reach
+	AllowedTo []string `json:"allowedTo,omitempty"`
+}
+
+// WorkspaceGrant is an access of the workspace to a service of a shared platform namespace, e.g. ingress-nginx or Kafka
+type WorkspaceGrant struct {
+	// Name of the grant
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// Namespace is the shared namespace of the service
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Namespace string `json:"namespace"`
+	// PodSelector selects the pods of the service in the shared namespace, all its pods when unset
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// Ports the traffic is allowed on, the ports of the service for an Egress grant and the ports
+	// of the workspace pods for an Ingress grant. All the ports are allowed when empty.
+	// +optional
+	Ports []networkingv1.NetworkPolicyPort `json:"ports,omitempty"`
+	// Direction is Egress when the workspace reaches the service, e.g. Kafka,
+	// or Ingress when the service reaches the workspace, e.g. an ingress controller or Prometheus
+	// +kubebuilder:validation:Enum=Egress;Ingress
+	// +kubebuilder:default=Egress
+	// +optional
+	Direction string `json:"direction,omitempty"`
+	// ClusterRole is bound to the workspace users in the shared namespace, e.g. view,
+	// when the service is also accessed through the Kubernetes API
+	// +optional
+	ClusterRole string `json:"clusterRole,omitempty"`
+}
+
+// WorkspaceDisruptionBudgets are the PodDisruptionBudget guardrails of the workspace namespace
+type WorkspaceDisruptionBudgets struct {
+	// ForbidBlocking rejects the PodDisruptionBudgets allowing no voluntary disruption,
+	// i.e. with a maxUnavailable of 0 or a minAvailable of 100%, which block node drains
+	// +optional
+	ForbidBlocking bool `json:"forbidBlocking,omitempty"`
+	// DefaultMaxUnavailable is the maxUnavailable of the PodDisruptionBudgets generated for the Deployments
+	// labeled environment.tf.operator.com/critical=true which no PodDisruptionBudget covers yet.
+	// No PodDisruptionBudget is generated when unset.
+	// +optional
+	DefaultMaxUnavailable *intstr.IntOrString `json:"defaultMaxUnavailable,omitempty"`
+}
+
+// WorkspaceQuota is an additional ResourceQuota of the workspace namespace
+type WorkspaceQuota struct {
+	// Name of the quota, the ResourceQuota is named <namespace>-<name>
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// Hard is the set of enforced hard limits for each named resource, e.g. count/configmaps
+	Hard corev1.ResourceList `json:"hard"`
+	// Scopes filters the objects tracked by the quota, e.g. Terminating
+	Scopes []corev1.ResourceQuotaScope `json:"scopes,omitempty"`
+	// ScopeSelector filters the objects tracked by the quota by scope, e.g. by PriorityClass
+	ScopeSelector *corev1.ScopeSelector `json:"scopeSelector,omitempty"`
+}
+
+// WorkspaceLimitRange are the default resources and the bounds of a single container of the workspace namespace,
+// so that the containers omitting their requests or limits are not rejected by the ResourceQuota
+type WorkspaceLimitRange struct {
+	// DefaultRequest is the request of the containers omitting it per resource, e.g. 100m cpu.
+	// It defaults to Default, then to Min.
+	// +optional
+	DefaultRequest corev1.ResourceList `json:"defaultRequest,omitempty"`
+	// Default is the limit of the containers omitting it per resource. It defaults to Max.
+	// +optional
+	Default corev1.ResourceList `json:"default,omitempty"`
+	// Min is the minimum a container may request per resource
+	// +optional
+	Min corev1.ResourceList `json:"min,omitempty"`
+	// Max is the maximum a container may be limited to per resource, overriding spec.limits.maxPerContainer
+	// +optional
+	Max corev1.ResourceList `json:"max,omitempty"`
+}
+
+// WorkspaceLimits are the maximum resources of a single container or pod of the workspace namespace,
+// enforced with a LimitRange independently of the aggregate ResourceQuota
+type WorkspaceLimits struct {
+	// MaxPerContainer is the maximum a container may be limited to per resource, e.g. 2 cpu.
+	// The containers without a limit are given the maximum as their limit.
+	// +optional
+	MaxPerContainer corev1.ResourceList `json:"maxPerContainer,omitempty"`
+	// MaxPerPod is the maximum the limits of the containers of a pod may add up to per resource
+	// +optional
+	MaxPerPod corev1.ResourceList `json:"maxPerPod,omitempty"`
+}
+
+// WorkspaceOwner is the contact of the person or team owning the workspace
+type WorkspaceOwner struct {
+	// Name of the owning person or team
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+	// Email of the owner
+	// +kubebuilder:validation:Format=email
+	// +kubebuilder:validation:MaxLength=254
+	// +optional
+	Email string `json:"email,omitempty"`
+	// Slack handle or channel of the owner, e.g. @jane or #team-a
+	// +kubebuilder:validation:MaxLength=80
+	// +kubebuilder:validation:Pattern=`^[@#][a-zA-Z0-9._-]+$`
+	// +optional
+	Slack string `json:"slack,omitempty"`
+	// URL of the page of the owning team, e.g. its runbook or wiki page
+	// +kubebuilder:validation:Format=uri
+	// +kubebuilder:validation:MaxLength=2048
+	// +optional
+	URL string `json:"url,omitempty"`
+}
+
+// WorkspacePodSecurity sets the Pod Security Standard levels of the workspace namespace.
+// Each mode is only labeled on the namespace when its level is set.
+type WorkspacePodSecurity struct {
+	// Enforce is the level above which pods are rejected
+	// +kubebuilder:validation:Enum=privileged;baseline;restricted
+	Enforce string `json:"enforce,omitempty"`
+	// Warn is the level above which users are warned when creating pods
+	// +kubebuilder:validation:Enum=privileged;baseline;restricted
+	Warn string `json:"warn,omitempty"`
+	// Audit is the level above which pods are annotated in the audit log
+	// +kubebuilder:validation:Enum=privileged;baseline;restricted
+	Audit string `json:"audit,omitempty"`
+	// Version of the Pod Security Standards the levels refer to, e.g. v1.25. The latest version is used when empty.
+	// +kubebuilder:validation:Pattern=`^(latest|v[0-9]+\.[0-9]+)$`
+	Version string `json:"version,omitempty"`
 }
 
 // WorkspaceSpec defines the desired state of Workspace
@@ -40,23 +525,468 @@ type WorkspaceSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Foo is an example field of Workspace. Edit workspace_types.go to remove/update
-	Name        string            `json:"name,omitempty"`
-	Labels      map[string]string `json:"labels,omitempty"`
-	Annotations map[string]string `json:"annotations,omitempty"`
-	Resources   WorkspaceResource `json:"resources,omitempty"`
-	Users       WorkspaceUser     `json:"users,omitempty"`
+	// Name is the namespace managed by the Workspace, it must be a valid DNS-1123 label
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name        string               `json:"name,omitempty"`
+	Labels      map[string]string    `json:"labels,omitempty"`
+	Annotations map[string]string    `json:"annotations,omitempty"`
+	Resources   WorkspaceResource    `json:"resources,omitempty"`
+	Budget      *WorkspaceBudget     `json:"budget,omitempty"`
+	Users       WorkspaceUser        `json:"users,omitempty"`
+	QuotaAlerts WorkspaceQuotaAlerts `json:"quotaAlerts,omitempty"`
+	Alerting    WorkspaceAlerting    `json:"alerting,omitempty"`
+	Logging     *WorkspaceLogging    `json:"logging,omitempty"`
+
+	// AccessSchedules restrict the access of the users of some roles to time windows, e.g. business hours.
+	// The users of the roles without a schedule are always bound.
+	// +listType=map
+	// +listMapKey=role
+	AccessSchedules []WorkspaceAccessSchedule `json:"accessSchedules,omitempty"`
+
+	// Teams bind the members of GitHub teams or GitLab groups to the roles of the workspace, next to spec.users.
+	// The members are synced by the operator, adding someone to a team grants them its role.
+	// +optional
+	Teams []WorkspaceTeam `json:"teams,omitempty"`
+
+	// Subjects bind Users, Groups, e.g. the groups of the identity provider, and ServiceAccounts to the roles
+	// of the workspace, next to spec.users
+	// +optional
+	Subjects []WorkspaceSubject `json:"subjects,omitempty"`
+
+	// RBAC sets the API groups covered by the built-in rules of the roles of the workspace
+	// +optional
+	RBAC *WorkspaceRBAC `json:"rbac,omitempty"`
+
+	// QuotaMode is Enforce to enforce spec.resources with the ResourceQuota of the workspace, or Monitor
+	// to only track the usage of the namespace against them and report when they are exceeded,
+	// e.g. while onboarding a team whose workloads would break under hard limits
+	// +kubebuilder:default=Enforce
+	// +optional
+	QuotaMode WorkspaceQuotaMode `json:"quotaMode,omitempty"`
+
+	// Suspended pauses the workspace without deleting it: the pods hard limit of its ResourceQuota is set to 0,
+	// so that no pod is started in its namespace until it is resumed
+	// +optional
+	Suspended bool `json:"suspended,omitempty"`
+
+	// Suspension are the options of the suspension of the workspace
+	// +optional
+	Suspension *WorkspaceSuspension `json:"suspension,omitempty"`
+
+	// Hibernation suspends the workspace automatically in the windows of its schedule, as spec.suspended does
+	// +optional
+	Hibernation *WorkspaceHibernation `json:"hibernation,omitempty"`
+
+	// PodSecurity sets the Pod Security Standard levels of the workspace namespace
+	PodSecurity *WorkspacePodSecurity `json:"podSecurity,omitempty"`
+
+	// ObservabilityTenant is the Loki/Mimir tenant ID the telemetry of the workspace namespace is tagged with
+	// +kubebuilder:validation:MaxLength=150
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9!._*'()-]+$`
+	ObservabilityTenant string `json:"observabilityTenant,omitempty"`
+
+	// DeleteAt is the RFC 3339 time the Workspace is deleted at, e.g. the end of a hackathon or of a contract.
+	// The owner is warned ahead with the Expiring condition and a notification.
+	// +optional
+	DeleteAt *metav1.Time `json:"deleteAt,omitempty"`
+
+	// TTL is how long the Workspace lives after its creation, e.g. 72h for a pull request environment.
+	// The Workspace expires at the earliest of its creation time plus TTL and of spec.deleteAt.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// ExpiryAction is what happens to the Workspace once it expired: Delete, the default, deletes it,
+	// and Suspend suspends it as spec.suspended does
+	// +kubebuilder:validation:Enum=Delete;Suspend
+	// +optional
+	ExpiryAction WorkspaceExpiryAction `json:"expiryAction,omitempty"`
+
+	// DeletionGracePeriod keeps a deleted Workspace frozen, with its RBAC revoked and its workloads
+	// scaled down, for the given duration (e.g. 168h) before the namespace is deleted
+	DeletionGracePeriod *metav1.Duration `json:"deletionGracePeriod,omitempty"`
+
+	// DeletionPropagation is the propagation policy the namespace is deleted with when the Workspace is deleted.
+	// With Foreground the Workspace disappears once the namespace and all its resources are gone, with Background
+	// it disappears as soon as the deletion of the namespace is requested. Defaults to Background.
+	// Only used with the Delete deletion policy.
+	// +kubebuilder:validation:Enum=Foreground;Background
+	// +optional
+	DeletionPropagation metav1.DeletionPropagation `json:"deletionPropagation,omitempty"`
+
+	// DeletionPolicy is what becomes of the namespace when the Workspace is deleted, after its deletion grace period:
+	// Delete deletes it with all its contents, Orphan keeps it as is and Retain keeps it with the ownership labels
+	// of the operator stripped. The objects the operator generated in a kept namespace, e.g. its ResourceQuota
+	// and RBAC, are still deleted along with the Workspace.
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy WorkspaceDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// ClassName is the name of the WorkspaceClass the workspace belongs to
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	ClassName string `json:"className,omitempty"`
+
+	// TemplateRef is the name of the WorkspaceTemplate the workspace is created from. Its quota preset, roles,
+	// labels, annotations and network isolation are the defaults of the fields of the workspace.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// +optional
+	TemplateRef string `json:"templateRef,omitempty"`
+
+	// AllowedRegistries are the registries, or registry path prefixes such as ghcr.io/my-org,
+	// the images of the pods of the workspace namespace must come from. Any registry is allowed when empty.
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// DisableTokenAutomount stops mounting the token of the default ServiceAccount into the pods of the
+	// workspace namespace, and requires the pods using another ServiceAccount to opt in to its token explicitly
+	// +optional
+	DisableTokenAutomount bool `json:"disableTokenAutomount,omitempty"`
+
+	// Quotas are additional ResourceQuotas of the workspace namespace, next to the one of spec.resources,
+	// e.g. an object-count quota or a quota scoped to terminating pods
+	// +listType=map
+	// +listMapKey=name
+	Quotas []WorkspaceQuota `json:"quotas,omitempty"`
+
+	// Limits caps the resources of a single container or pod of the workspace namespace, so that one pod
+	// can not consume the whole quota of the workspace or a whole node
+	// +optional
+	Limits *WorkspaceLimits `json:"limits,omitempty"`
+
+	// LimitRange sets the default request and limit of the containers of the workspace namespace and their
+	// minimum and maximum, in the LimitRange of spec.limits
+	// +optional
+	LimitRange *WorkspaceLimitRange `json:"limitRange,omitempty"`
+
+	// DisruptionBudgets sets the PodDisruptionBudget guardrails of the workspace namespace
+	DisruptionBudgets *WorkspaceDisruptionBudgets `json:"disruptionBudgets,omitempty"`
+
+	// NetworkIsolation denies all the ingress and egress traffic of the workspace namespace by default, except the
+	// traffic within the namespace and the DNS requests to the cluster DNS.
+	// It defaults to the networkIsolation of the template of spec.templateRef.
+	// +optional
+	NetworkIsolation *bool `json:"networkIsolation,omitempty"`
+
+	// Networking sets the network peering of the workspace namespace with other workspaces
+	Networking *WorkspaceNetworking `json:"networking,omitempty"`
+
+	// Grants are the accesses of the workspace to the services of shared platform namespaces
+	// +listType=map
+	// +listMapKey=name
+	Grants []WorkspaceGrant `json:"grants,omitempty"`
+
+	// Owner is the contact of the owner of the workspace, propagated as annotations onto the namespace
+	// +optional
+	Owner *WorkspaceOwner `json:"owner,omitempty"`
+
+	// Batch configures the fair-share queueing of the batch workloads of the workspace
+	// +optional
+	Batch *WorkspaceBatch `json:"batch,omitempty"`
+
+	// GPU sets the GPU quota of the workspace and the partitioning of the GPUs it shares
+	// +optional
+	GPU *WorkspaceGPU `json:"gpu,omitempty"`
+
+	// RestoreFrom is the name of a WorkspaceSnapshot whose objects are restored in the namespace once the
+	// workspace is provisioned. It can only be set when the Workspace is created.
+	// +optional
+	RestoreFrom string `json:"restoreFrom,omitempty"`
+
+	// DependsOn are the names of the Workspaces that must be Ready before the workspace is provisioned,
+	// e.g. a shared-services workspace. The workspace waits for them with the Waiting condition.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// WorkspaceSpend is the spend of the workspace namespace reported by OpenCost
+type WorkspaceSpend struct {
+	// Last7Days is the total cost of the workspace namespace over the last 7 days
+	Last7Days string `json:"last7Days,omitempty"`
+	// Last30Days is the total cost of the workspace namespace over the last 30 days
+	Last30Days string `json:"last30Days,omitempty"`
+	// LastUpdated is the time the spend was last queried
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// WorkspaceAccess is the effective access to the workspace
+type WorkspaceAccess struct {
+	// Admin are the subjects bound to the admin Role of the workspace namespace
+	Admin []WorkspaceAccessSubject `json:"admin,omitempty"`
+	// Editor are the subjects bound to the editor Role of the workspace namespace
+	Editor []WorkspaceAccessSubject `json:"editor,omitempty"`
+	// Viewer are the subjects bound to the viewer Role of the workspace namespace
+	Viewer []WorkspaceAccessSubject `json:"viewer,omitempty"`
+	// Grants are the accesses of the workspace users to the shared namespaces of spec.grants
+	Grants []WorkspaceGrantAccess `json:"grants,omitempty"`
+}
+
+// WorkspaceAccessSubject is a subject bound by a RoleBinding of the workspace
+type WorkspaceAccessSubject struct {
+	// Kind of the subject, User, Group or ServiceAccount
+	Kind string `json:"kind"`
+	// Name of the subject
+	Name string `json:"name"`
+	// Namespace of a ServiceAccount subject
+	Namespace string `json:"namespace,omitempty"`
+	// Members are the users of a Group subject, as resolved by the group resolver of the operator
+	Members []string `json:"members,omitempty"`
+}
+
+// WorkspaceGrantAccess is the access bound in a shared namespace by a grant of the workspace
+type WorkspaceGrantAccess struct {
+	// Grant is the name of the grant in spec.grants
+	Grant string `json:"grant"`
+	// Namespace is the shared namespace of the grant
+	Namespace string `json:"namespace"`
+	// ClusterRole is the ClusterRole bound in the shared namespace
+	ClusterRole string `json:"clusterRole"`
+	// Subjects are the subjects bound to the ClusterRole
+	Subjects []WorkspaceAccessSubject `json:"subjects,omitempty"`
+}
+
+// WorkspaceRename is the migration of a workspace to a new namespace after a change of spec.name
+type WorkspaceRename struct {
+	// From is the namespace the workspace is migrated from, retired once the grace period is over
+	From string `json:"from"`
+	// To is the namespace the workspace is migrated to
+	To string `json:"to"`
+	// MigratedAt is the time the objects of the workspace were provisioned in the new namespace
+	MigratedAt metav1.Time `json:"migratedAt"`
+	// RetireAt is the time the previous namespace is deleted
+	RetireAt metav1.Time `json:"retireAt"`
+}
+
+// WorkspaceRoleChange is a change of the rules of one of the Roles of the workspace
+type WorkspaceRoleChange struct {
+	// Role is the changed role, admin, editor or viewer
+	Role string `json:"role"`
+	// Added are the rules the Role gains
+	Added []rbacv1.PolicyRule `json:"added,omitempty"`
+	// Removed are the rules the Role loses
+	Removed []rbacv1.PolicyRule `json:"removed,omitempty"`
 }
 
+// WorkspacePendingChanges are the changes of the Roles of the workspace following a change of the role
+// templates of the operator or of the class, published before they are applied
+type WorkspacePendingChanges struct {
+	// Hash identifies the changes, they are acknowledged by setting the
+	// environment.tf.operator.com/acknowledge-rbac-changes annotation to it
+	Hash string `json:"hash"`
+	// Since is the time the changes were first published
+	Since metav1.Time `json:"since"`
+	// ApplyAfter is the earliest time the changes are applied
+	ApplyAfter metav1.Time `json:"applyAfter"`
+	// AcknowledgmentRequired is true when the changes wait for the acknowledgment annotation
+	AcknowledgmentRequired bool `json:"acknowledgmentRequired,omitempty"`
+	// Roles are the changes of the rules per Role
+	Roles []WorkspaceRoleChange `json:"roles"`
+}
+
+// WorkspaceUsage is the usage history of the workspace namespace
+type WorkspaceUsage struct {
+	// Snapshots are the periodic snapshots of the usage of the workspace ResourceQuota, the most recent first
+	Snapshots []WorkspaceUsageSnapshot `json:"snapshots,omitempty"`
+	// WeekOverWeek is the change of the usage from the snapshot taken a week before the most recent one,
+	// unset until the history covers a week
+	WeekOverWeek *WorkspaceUsageTrend `json:"weekOverWeek,omitempty"`
+}
+
+// WorkspaceUsageSnapshot is the usage of the workspace ResourceQuota at a point in time
+type WorkspaceUsageSnapshot struct {
+	// Time the snapshot was taken
+	Time metav1.Time `json:"time"`
+	// CPU is the cpu requested by the pods of the namespace
+	CPU string `json:"cpu,omitempty"`
+	// Memory is the memory requested by the pods of the namespace
+	Memory string `json:"memory,omitempty"`
+	// Disk is the storage requested by the PersistentVolumeClaims of the namespace
+	Disk string `json:"disk,omitempty"`
+}
+
+// WorkspaceUsageTrend is the change of the usage of the workspace between two snapshots, in percent
+type WorkspaceUsageTrend struct {
+	// Since is the time of the snapshot the usage is compared to
+	Since metav1.Time `json:"since"`
+	// CPU is the change of the cpu usage in percent, unset when no cpu was used at the time of Since
+	CPU *int32 `json:"cpu,omitempty"`
+	// Memory is the change of the memory usage in percent, unset when no memory was used at the time of Since
+	Memory *int32 `json:"memory,omitempty"`
+	// Disk is the change of the storage usage in percent, unset when no storage was used at the time of Since
+	Disk *int32 `json:"disk,omitempty"`
+}
+
+// WorkspaceResourceStateType is the provisioning state of a resource of the workspace
+// +kubebuilder:validation:Enum=Provisioned;Missing;Terminating
+type WorkspaceResourceStateType string
+
+const (
+	// ResourceProvisioned is the state of a resource which exists
+	ResourceProvisioned WorkspaceResourceStateType = "Provisioned"
+	// ResourceMissing is the state of a resource which does not exist yet or was deleted
+	ResourceMissing WorkspaceResourceStateType = "Missing"
+	// ResourceTerminating is the state of a resource being deleted
+	ResourceTerminating WorkspaceResourceStateType = "Terminating"
+)
+
+// WorkspaceResourceState is the provisioning state of a resource of the workspace
+type WorkspaceResourceState struct {
+	// Kind of the resource, e.g. Namespace or RoleBinding
+	Kind string `json:"kind"`
+	// Name of the resource
+	Name string `json:"name"`
+	// State of the resource
+	State WorkspaceResourceStateType `json:"state"`
+}
+
+// WorkspaceActivity is a change made by the operator to the resources of the workspace
+type WorkspaceActivity struct {
+	// Time is the time of the change, the last one when it was repeated
+	Time metav1.Time `json:"time"`
+	// Action is the kind of change, e.g. Created, Updated or SubjectRemoved
+	Action string `json:"action"`
+	// Message describes the change
+	Message string `json:"message"`
+	// Count is the number of times the change was repeated in a row
+	// +optional
+	Count int32 `json:"count,omitempty"`
+}
+
+// WorkspaceRevision is a revision of the Workspace spec applied by the operator
+type WorkspaceRevision struct {
+	// Generation is the metadata.generation of the applied spec
+	Generation int64 `json:"generation"`
+	// SpecHash is the hash of the applied spec
+	SpecHash string `json:"specHash"`
+	// AppliedAt is the time the spec was applied
+	AppliedAt metav1.Time `json:"appliedAt"`
+	// Changes lists the spec fields changed from the previous revision, e.g. "spec.resources.cpu: 2 -> 4"
+	Changes []string `json:"changes,omitempty"`
+}
+
+// WorkspaceBenchmarkCheck is the result of a multi-tenancy benchmark check of the workspace
+type WorkspaceBenchmarkCheck struct {
+	// Name of the check, e.g. NetworkIsolation
+	Name string `json:"name"`
+	// Benchmark is the ID of the Kubernetes multi-tenancy benchmark the check implements, e.g. MTB-PL1-CC-TI-1
+	Benchmark string `json:"benchmark"`
+	// Result of the check
+	// +kubebuilder:validation:Enum=Pass;Fail
+	Result string `json:"result"`
+	// Message explains the result of the check
+	Message string `json:"message,omitempty"`
+}
+
+// WorkspaceBenchmark is the outcome of the multi-tenancy benchmark self-check of the workspace
+type WorkspaceBenchmark struct {
+	// LastChecked is the time the checks last ran
+	LastChecked metav1.Time `json:"lastChecked,omitempty"`
+	// Passed is the number of passed checks
+	Passed int32 `json:"passed"`
+	// Failed is the number of failed checks
+	Failed int32 `json:"failed"`
+	// Checks are the results of the individual checks
+	Checks []WorkspaceBenchmarkCheck `json:"checks,omitempty"`
+}
+
+// WorkspacePhase is the lifecycle phase of a Workspace
+// +kubebuilder:validation:Enum=Pending;Provisioning;Ready;Updating;Suspended;Terminating;Failed
+type WorkspacePhase string
+
+const (
+	// WorkspacePending is the phase of a Workspace accepted by the operator before its resources are created
+	WorkspacePending WorkspacePhase = "Pending"
+	// WorkspaceProvisioning is the phase of a Workspace whose resources are created for the first time
+	WorkspaceProvisioning WorkspacePhase = "Provisioning"
+	// WorkspaceReady is the phase of a Workspace whose resources are all in the desired state
+	WorkspaceReady WorkspacePhase = "Ready"
+	// WorkspaceUpdating is the phase of a Ready Workspace whose resources are being updated
+	WorkspaceUpdating WorkspacePhase = "Updating"
+	// WorkspaceSuspended is the phase of a Workspace whose resources are in the desired state while it is suspended
+	WorkspaceSuspended WorkspacePhase = "Suspended"
+	// WorkspaceTerminating is the phase of a deleted Workspace
+	WorkspaceTerminating WorkspacePhase = "Terminating"
+	// WorkspaceFailed is the phase of a Workspace whose last reconciliation failed with an error retrying does not fix
+	WorkspaceFailed WorkspacePhase = "Failed"
+)
+
 // WorkspaceStatus defines the observed state of Workspace
 type WorkspaceStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the generation of the spec the status was last computed for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is the lifecycle phase of the Workspace
+	Phase WorkspacePhase `json:"phase,omitempty"`
+
+	// Conditions represent the latest available observations of the Workspace state
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservabilityTenant is the observability tenant the workspace namespace is registered with
+	ObservabilityTenant string `json:"observabilityTenant,omitempty"`
+
+	// Spend is the rolling spend of the workspace namespace
+	Spend *WorkspaceSpend `json:"spend,omitempty"`
+
+	// History holds the last applied revisions of the spec, the most recent first
+	History []WorkspaceRevision `json:"history,omitempty"`
+
+	// Benchmark is the outcome of the last multi-tenancy benchmark self-check of the workspace
+	Benchmark *WorkspaceBenchmark `json:"benchmark,omitempty"`
+
+	// Networking is the network peering of the workspace with other workspaces
+	Networking *WorkspaceNetworkingStatus `json:"networking,omitempty"`
+
+	// BudgetResources are the hard limits of the workspace ResourceQuota computed from spec.budget
+	BudgetResources *WorkspaceResource `json:"budgetResources,omitempty"`
+
+	// Template is the WorkspaceTemplate of spec.templateRef merged with the fields of the workspace
+	Template *WorkspaceTemplateStatus `json:"template,omitempty"`
+
+	// Access is the effective access to the workspace, as bound by the RoleBindings the operator manages
+	Access *WorkspaceAccess `json:"access,omitempty"`
+
+	// Usage is the usage history of the workspace namespace and its trend
+	Usage *WorkspaceUsage `json:"usage,omitempty"`
+
+	// Namespace is the namespace the workspace was last provisioned in, which differs from spec.name
+	// until the workspace is migrated to the namespace of a renamed spec.name
+	Namespace string `json:"namespace,omitempty"`
+
+	// Rename is the rename of the workspace in progress, from its previous namespace to spec.name
+	Rename *WorkspaceRename `json:"rename,omitempty"`
+
+	// PendingChanges are the changes of the Roles of the workspace waiting to be applied
+	PendingChanges *WorkspacePendingChanges `json:"pendingChanges,omitempty"`
+
+	// RecentEvents are the last changes made by the operator to the resources of the workspace, the most recent first
+	RecentEvents []WorkspaceActivity `json:"recentEvents,omitempty"`
+
+	// LastResync is the value of the environment.tf.operator.com/resync annotation of the last forced
+	// resync of the workspace that completed successfully
+	LastResync string `json:"lastResync,omitempty"`
+
+	// SuspendedAt is the time the workspace was suspended, unset while it is not suspended
+	// +optional
+	SuspendedAt *metav1.Time `json:"suspendedAt,omitempty"`
+
+	// Resources is the provisioning state of the Namespace, ResourceQuota, Roles and RoleBindings of the workspace
+	// +optional
+	Resources []WorkspaceResourceState `json:"resources,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
-//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:resource:scope=Cluster,path=workspaces,singular=workspace,shortName=ws,categories=all;tenancy
+//+kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.name`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Owner",type=string,JSONPath=`.spec.owner.name`
+//+kubebuilder:printcolumn:name="Email",type=string,JSONPath=`.spec.owner.email`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Workspace is the Schema for the workspaces API
 type Workspace struct {
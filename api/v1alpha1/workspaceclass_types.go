@@ -0,0 +1,98 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkspaceSecurityPolicy is the admission policy generated in the namespace of every workspace of a class,
+// requiring hardened security contexts on its pods
+type WorkspaceSecurityPolicy struct {
+	// Engine the policy is rendered for, a Kyverno Policy or a ValidatingAdmissionPolicy
+	// +kubebuilder:validation:Enum=kyverno;vap
+	Engine string `json:"engine"`
+	// Action taken on the pods violating the policy
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +kubebuilder:default=Enforce
+	Action string `json:"action,omitempty"`
+	// RuntimeDefaultSeccomp requires the RuntimeDefault or Localhost seccomp profile
+	// +kubebuilder:default=true
+	RuntimeDefaultSeccomp bool `json:"runtimeDefaultSeccomp"`
+	// RunAsNonRoot requires the containers to run as a non-root user
+	// +kubebuilder:default=true
+	RunAsNonRoot bool `json:"runAsNonRoot"`
+	// DropAllCapabilities requires the containers to drop ALL capabilities
+	// +kubebuilder:default=true
+	DropAllCapabilities bool `json:"dropAllCapabilities"`
+}
+
+// WorkspaceRoleTemplates are the rules of the admin, editor and viewer Roles generated in the namespace of a workspace
+type WorkspaceRoleTemplates struct {
+	// Admin are the rules of the admin Role
+	// +optional
+	Admin []rbacv1.PolicyRule `json:"admin,omitempty"`
+	// Editor are the rules of the editor Role
+	// +optional
+	Editor []rbacv1.PolicyRule `json:"editor,omitempty"`
+	// Viewer are the rules of the viewer Role
+	// +optional
+	Viewer []rbacv1.PolicyRule `json:"viewer,omitempty"`
+}
+
+// WorkspaceClassSpec defines the defaults shared by the workspaces of a class
+type WorkspaceClassSpec struct {
+	// SecurityPolicy generates an admission policy in the namespace of every workspace of the class.
+	// No policy is generated when it is not set.
+	SecurityPolicy *WorkspaceSecurityPolicy `json:"securityPolicy,omitempty"`
+
+	// Resources is the typical size of the workspaces of the class. The workspaces requesting
+	// far more are warned at admission, nothing is enforced.
+	// +optional
+	Resources *WorkspaceResource `json:"resources,omitempty"`
+
+	// Roles replace the role templates of the operator for the workspaces of the class.
+	// A role without rules keeps the template of the operator.
+	// +optional
+	Roles *WorkspaceRoleTemplates `json:"roles,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,path=workspaceclasses,singular=workspaceclass,shortName=wsc,categories=tenancy
+
+// WorkspaceClass is the Schema for the workspaceclasses API.
+// Workspaces reference their class with spec.className.
+type WorkspaceClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WorkspaceClassSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// WorkspaceClassList contains a list of WorkspaceClass
+type WorkspaceClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkspaceClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkspaceClass{}, &WorkspaceClassList{})
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkspaceSnapshotKind is a kind of tenant objects captured by a snapshot
+// +kubebuilder:validation:Enum=ConfigMap;Secret;Service;Deployment;StatefulSet;DaemonSet;CronJob
+type WorkspaceSnapshotKind string
+
+const (
+	SnapshotConfigMap   WorkspaceSnapshotKind = "ConfigMap"
+	SnapshotSecret      WorkspaceSnapshotKind = "Secret"
+	SnapshotService     WorkspaceSnapshotKind = "Service"
+	SnapshotDeployment  WorkspaceSnapshotKind = "Deployment"
+	SnapshotStatefulSet WorkspaceSnapshotKind = "StatefulSet"
+	SnapshotDaemonSet   WorkspaceSnapshotKind = "DaemonSet"
+	SnapshotCronJob     WorkspaceSnapshotKind = "CronJob"
+)
+
+// WorkspaceSnapshotStorage is where the content of a snapshot is stored
+// +kubebuilder:validation:Enum=InCluster;ObjectStorage
+type WorkspaceSnapshotStorage string
+
+const (
+	// SnapshotInCluster stores the content in a ConfigMap of the snapshot namespace of the operator
+	SnapshotInCluster WorkspaceSnapshotStorage = "InCluster"
+	// SnapshotObjectStorage stores the content in the object storage configured on the operator
+	SnapshotObjectStorage WorkspaceSnapshotStorage = "ObjectStorage"
+)
+
+// WorkspaceSnapshotSpec defines the workspace and the objects captured by a snapshot
+type WorkspaceSnapshotSpec struct {
+	// Workspace is the name of the captured Workspace
+	// +kubebuilder:validation:MinLength=1
+	Workspace string `json:"workspace"`
+
+	// Include are the kinds of tenant objects captured next to the Workspace.
+	// Only the metadata, type and key names of the Secrets are captured, never their data.
+	// +kubebuilder:default={ConfigMap,Secret,Service,Deployment,StatefulSet,DaemonSet,CronJob}
+	// +optional
+	Include []WorkspaceSnapshotKind `json:"include,omitempty"`
+
+	// Storage is where the content of the snapshot is stored
+	// +kubebuilder:default=InCluster
+	// +optional
+	Storage WorkspaceSnapshotStorage `json:"storage,omitempty"`
+}
+
+// WorkspaceSnapshotPhase is the phase of a snapshot
+// +kubebuilder:validation:Enum=Pending;Completed;Failed
+type WorkspaceSnapshotPhase string
+
+const (
+	SnapshotPending   WorkspaceSnapshotPhase = "Pending"
+	SnapshotCompleted WorkspaceSnapshotPhase = "Completed"
+	SnapshotFailed    WorkspaceSnapshotPhase = "Failed"
+)
+
+// WorkspaceSnapshotStatus defines the outcome of a snapshot
+type WorkspaceSnapshotStatus struct {
+	// Phase is the phase of the snapshot, a snapshot is taken once and never updated
+	Phase WorkspaceSnapshotPhase `json:"phase,omitempty"`
+
+	// CapturedAt is the time the objects were captured
+	CapturedAt *metav1.Time `json:"capturedAt,omitempty"`
+
+	// Namespace is the namespace of the Workspace at the time of the snapshot
+	Namespace string `json:"namespace,omitempty"`
+
+	// Location is where the content is stored, the namespace/name of its ConfigMap or its object storage URL
+	Location string `json:"location,omitempty"`
+
+	// Objects is the number of tenant objects captured
+	Objects int32 `json:"objects,omitempty"`
+
+	// Message explains why the snapshot failed
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,path=workspacesnapshots,singular=workspacesnapshot,shortName=wss,categories=tenancy
+//+kubebuilder:printcolumn:name="Workspace",type=string,JSONPath=`.spec.workspace`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Objects",type=integer,JSONPath=`.status.objects`
+//+kubebuilder:printcolumn:name="Captured",type=date,JSONPath=`.status.capturedAt`
+
+// WorkspaceSnapshot is the Schema for the workspacesnapshots API.
+// It captures the spec of a Workspace and selected objects of its namespace at a point in time.
+type WorkspaceSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkspaceSnapshotSpec   `json:"spec,omitempty"`
+	Status WorkspaceSnapshotStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// WorkspaceSnapshotList contains a list of WorkspaceSnapshot
+type WorkspaceSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkspaceSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkspaceSnapshot{}, &WorkspaceSnapshotList{})
+}
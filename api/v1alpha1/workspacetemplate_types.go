@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkspaceTemplateSpec defines the profile of the workspaces created from a template, e.g. small, medium or large.
+// The fields set on a Workspace override the ones of its template.
+type WorkspaceTemplateSpec struct {
+	// Resources is the quota preset of the workspaces of the template.
+	// The cpu, memory and disk set in spec.resources of a Workspace override it.
+	// +optional
+	Resources *WorkspaceResource `json:"resources,omitempty"`
+
+	// Roles replace the role templates of the operator and of the class for the workspaces of the template.
+	// A role without rules keeps the rules it would have without the template.
+	// +optional
+	Roles *WorkspaceRoleTemplates `json:"roles,omitempty"`
+
+	// Labels are the default labels of the objects generated for the workspaces of the template,
+	// overridden by spec.labels
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are the default annotations of the objects generated for the workspaces of the template,
+	// overridden by spec.annotations
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// NetworkIsolation isolates the namespaces of the workspaces of the template by default,
+	// overridden by spec.networkIsolation
+	// +optional
+	NetworkIsolation *bool `json:"networkIsolation,omitempty"`
+}
+
+// WorkspaceTemplateStatus shows the values of the WorkspaceTemplate of a workspace merged with the fields of the workspace
+type WorkspaceTemplateStatus struct {
+	// Name of the WorkspaceTemplate
+	Name string `json:"name"`
+	// Generation of the WorkspaceTemplate the values were resolved from
+	Generation int64 `json:"generation,omitempty"`
+	// Resources are the resolved cpu, memory and disk of the workspace ResourceQuota
+	Resources WorkspaceResource `json:"resources,omitempty"`
+	// Labels are the resolved labels of the objects generated for the workspace
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are the resolved annotations of the objects generated for the workspace
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// NetworkIsolation is the resolved network isolation of the workspace namespace
+	NetworkIsolation bool `json:"networkIsolation,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,path=workspacetemplates,singular=workspacetemplate,shortName=wst,categories=tenancy
+
+// WorkspaceTemplate is the Schema for the workspacetemplates API.
+// Workspaces reference their template with spec.templateRef.
+type WorkspaceTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WorkspaceTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// WorkspaceTemplateList contains a list of WorkspaceTemplate
+type WorkspaceTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkspaceTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkspaceTemplate{}, &WorkspaceTemplateList{})
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command kubectl-workspace is a kubectl plugin for the Workspaces. Installed in the PATH it is run as
+// kubectl workspace. Its plan command previews the objects the operator would create, change or delete
+// for a Workspace manifest against the live cluster, and its resync command forces a full reconciliation
+// of one or all the Workspaces.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/controllers"
+	"github.com/dunefro/workspace-operator/internal/plan"
+)
+
+const usage = `Usage: kubectl workspace plan -f <file>
+       kubectl workspace resync [--wait] (--all | <name>...)
+
+Commands:
+  plan    Preview the objects the operator would create, change or delete for a Workspace manifest
+  resync  Force a full reconciliation of Workspaces, e.g. after a change of the role templates or a suspected drift
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "plan":
+		planMain(os.Args[2:])
+	case "resync":
+		resyncMain(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func planMain(args []string) {
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	var file, operatorUsername string
+	var deletionProtection, namespacedOnly bool
+	flags.StringVar(&file, "f", "", "YAML file with the Workspaces to plan, - for the standard input.")
+	flags.BoolVar(&deletionProtection, "deletion-protection", false, "--deletion-protection of the operator.")
+	flags.BoolVar(&namespacedOnly, "namespaced-only", false, "--namespaced-only of the operator.")
+	flags.StringVar(&operatorUsername, "operator-username", controllers.DefaultOperatorUsername, "--operator-username of the operator.")
+	_ = flags.Parse(args)
+	if file == "" {
+		fmt.Fprintln(os.Stderr, "error: -f is required")
+		os.Exit(2)
+	}
+
+	planner := &plan.Planner{
+		DeletionProtection: deletionProtection,
+		NamespacedOnly:     namespacedOnly,
+		OperatorUsername:   operatorUsername,
+	}
+	if err := run(context.Background(), planner, file, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, planner *plan.Planner, file string, out io.Writer) error {
+	in := os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	workspaces, err := plan.ReadWorkspaces(in)
+	if err != nil {
+		return err
+	}
+
+	c, scheme, err := newClient()
+	if err != nil {
+		return err
+	}
+	planner.Client = c
+	planner.Scheme = scheme
+
+	for i := range workspaces {
+		fmt.Fprintf(out, "workspace/%s:\n\n", workspaces[i].Name)
+		result, err := planner.Plan(ctx, &workspaces[i])
+		if result != nil {
+			if err := plan.Render(out, result); err != nil {
+				return err
+			}
+			if !result.Converged && err == nil {
+				fmt.Fprintln(out, "Warning: the Workspace did not reach its desired state within the plan, later changes may be missing.")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("workspace/%s: %w", workspaces[i].Name, err)
+		}
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// newClient returns a client of the cluster of the current kubeconfig context knowing the Workspaces
+func newClient() (client.Client, *runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(environmentv1alpha1.AddToScheme(scheme))
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, scheme, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/controllers"
+)
+
+func resyncMain(args []string) {
+	flags := flag.NewFlagSet("resync", flag.ExitOnError)
+	var all, waitResync bool
+	var timeout time.Duration
+	flags.BoolVar(&all, "all", false, "Resync all the Workspaces.")
+	flags.BoolVar(&waitResync, "wait", false, "Wait for the operator to complete the resyncs.")
+	flags.DurationVar(&timeout, "timeout", 5*time.Minute, "Time to wait for the resyncs with --wait.")
+	_ = flags.Parse(args)
+	if all == (flags.NArg() > 0) {
+		fmt.Fprintln(os.Stderr, "error: either Workspace names or --all is required")
+		os.Exit(2)
+	}
+
+	c, _, err := newClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	names := flags.Args()
+	if all {
+		workspaces := &environmentv1alpha1.WorkspaceList{}
+		if err := c.List(context.Background(), workspaces); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, workspace := range workspaces.Items {
+			names = append(names, workspace.Name)
+		}
+	}
+	if err := resync(context.Background(), c, names, waitResync, timeout, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// resync sets the resync annotation of the Workspaces to the current time and, with wait,
+// waits for the operator to copy it to their status.lastResync
+func resync(ctx context.Context, c client.Client, names []string, waitResync bool, timeout time.Duration, out io.Writer) error {
+	requested := time.Now().UTC().Format(time.RFC3339Nano)
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{controllers.ResyncAnnotation: requested},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		workspace := &environmentv1alpha1.Workspace{}
+		workspace.Name = name
+		if err := c.Patch(ctx, workspace, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			return fmt.Errorf("workspace/%s: %w", name, err)
+		}
+		fmt.Fprintf(out, "workspace/%s resync requested\n", name)
+	}
+	if !waitResync {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for _, name := range names {
+		err := wait.PollImmediateUntilWithContext(ctx, 2*time.Second, func(ctx context.Context) (bool, error) {
+			workspace := &environmentv1alpha1.Workspace{}
+			if err := c.Get(ctx, types.NamespacedName{Name: name}, workspace); err != nil {
+				return false, err
+			}
+			return workspace.Status.LastResync == requested, nil
+		})
+		if err != nil {
+			return fmt.Errorf("workspace/%s was not resynced: %w", name, err)
+		}
+		fmt.Fprintf(out, "workspace/%s resynced\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command workspace-migrate converts the tenants of Capsule, the Hierarchical Namespace Controller or kiosk
+// into Workspaces. The Workspaces are printed as YAML unless --apply is set.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/migrate"
+)
+
+func main() {
+	var source string
+	var apply bool
+	var adopt bool
+	flag.StringVar(&source, "from", "",
+		"Controller the tenants are migrated from, one of "+strings.Join(migrate.Sources, ", ")+".")
+	flag.BoolVar(&apply, "apply", false,
+		"Create the Workspaces in the cluster instead of printing them.")
+	flag.BoolVar(&adopt, "adopt", false,
+		"With --apply, remove the ownership of the migrated controller from the namespaces and make the Workspaces their controllers. "+
+			"Stop the migrated controller first.")
+	flag.Parse()
+
+	if err := run(context.Background(), source, apply, adopt); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, source string, apply, adopt bool) error {
+	if adopt && !apply {
+		return fmt.Errorf("--adopt requires --apply")
+	}
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(environmentv1alpha1.AddToScheme(scheme))
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	migrator := &migrate.Migrator{Client: c}
+	workspaces, err := migrator.Workspaces(ctx, source)
+	if err != nil {
+		return err
+	}
+	for _, warning := range migrator.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	if !apply {
+		for i := range workspaces {
+			out, err := yaml.Marshal(&workspaces[i])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("---\n%s", out)
+		}
+		return nil
+	}
+	if err := migrator.Apply(ctx, workspaces, adopt); err != nil {
+		return err
+	}
+	for _, workspace := range workspaces {
+		fmt.Printf("workspace/%s migrated\n", workspace.Name)
+	}
+	return nil
+}
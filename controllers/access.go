@@ -0,0 +1,141 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// GroupResolver resolves the members of the groups of the identity provider
+type GroupResolver interface {
+	Members(ctx context.Context, group string) ([]string, error)
+}
+
+// NewHTTPGroupResolver returns a GroupResolver which reads the members of a group
+// from the JSON {"members": [...]} returned by GET <endpoint>/groups/<group>
+func NewHTTPGroupResolver(endpoint string) GroupResolver {
+	return &httpGroupResolver{endpoint: strings.TrimSuffix(endpoint, "/"), client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type httpGroupResolver struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (g *httpGroupResolver) Members(ctx context.Context, group string) ([]string, error) {
+	u := fmt.Sprintf("%s/groups/%s", g.endpoint, url.PathEscape(group))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("group resolver returned %s for %s", resp.Status, u)
+	}
+	var result struct {
+		Members []string `json:"members"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	sort.Strings(result.Members)
+	return result.Members, nil
+}
+
+// reconcileAccess publishes in status.access the subjects bound by the admin, editor and viewer
+// RoleBindings of the workspace and by the RoleBindings of its grants, with the members of their groups
+func (r *WorkspaceReconciler) reconcileAccess(ctx context.Context, workspace *environmentv1alpha1.Workspace, admin, editor, viewer *rbacv1.RoleBinding) error {
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := r.List(ctx, roleBindings, client.MatchingLabels{WorkspaceLabel: workspace.Name}, client.HasLabels{GrantLabel}); err != nil {
+		return err
+	}
+
+	// the members of a group are only resolved once per reconciliation
+	members := map[string][]string{}
+	subjects := func(roleBinding *rbacv1.RoleBinding) ([]environmentv1alpha1.WorkspaceAccessSubject, error) {
+		var result []environmentv1alpha1.WorkspaceAccessSubject
+		for _, subject := range roleBinding.Subjects {
+			accessSubject := environmentv1alpha1.WorkspaceAccessSubject{Kind: subject.Kind, Name: subject.Name, Namespace: subject.Namespace}
+			if subject.Kind == rbacv1.GroupKind && r.GroupResolver != nil {
+				if _, ok := members[subject.Name]; !ok {
+					groupMembers, err := r.GroupResolver.Members(ctx, subject.Name)
+					if err != nil {
+						return nil, fmt.Errorf("failed to resolve the members of group %s: %w", subject.Name, err)
+					}
+					members[subject.Name] = groupMembers
+				}
+				accessSubject.Members = members[subject.Name]
+			}
+			result = append(result, accessSubject)
+		}
+		return result, nil
+	}
+
+	access := &environmentv1alpha1.WorkspaceAccess{}
+	var err error
+	if access.Admin, err = subjects(admin); err != nil {
+		return err
+	}
+	if access.Editor, err = subjects(editor); err != nil {
+		return err
+	}
+	if access.Viewer, err = subjects(viewer); err != nil {
+		return err
+	}
+	for i := range roleBindings.Items {
+		roleBinding := &roleBindings.Items[i]
+		grantSubjects, err := subjects(roleBinding)
+		if err != nil {
+			return err
+		}
+		access.Grants = append(access.Grants, environmentv1alpha1.WorkspaceGrantAccess{
+			Grant:       roleBinding.Labels[GrantLabel],
+			Namespace:   roleBinding.Namespace,
+			ClusterRole: roleBinding.RoleRef.Name,
+			Subjects:    grantSubjects,
+		})
+	}
+	sort.Slice(access.Grants, func(i, j int) bool {
+		if access.Grants[i].Grant != access.Grants[j].Grant {
+			return access.Grants[i].Grant < access.Grants[j].Grant
+		}
+		return access.Grants[i].Namespace < access.Grants[j].Namespace
+	})
+
+	if equality.Semantic.DeepEqual(workspace.Status.Access, access) {
+		return nil
+	}
+	workspace.Status.Access = access
+	return r.Status().Update(ctx, workspace)
+}
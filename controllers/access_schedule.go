@@ -0,0 +1,269 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// weekDays are the days of the week of the access schedules
+var weekDays = map[environmentv1alpha1.WeekDay]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// accessWindow is a parsed access schedule
+type accessWindow struct {
+	location   *time.Location
+	days       map[time.Weekday]bool
+	start, end time.Duration
+}
+
+// parseAccessSchedule parses the time zone, the days and the HH:MM start and end of an access schedule
+func parseAccessSchedule(schedule environmentv1alpha1.WorkspaceAccessSchedule) (*accessWindow, error) {
+	location, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q of the access schedule of role %s: %w", schedule.TimeZone, schedule.Role, err)
+	}
+	window := &accessWindow{location: location}
+	for _, day := range schedule.Days {
+		weekDay, ok := weekDays[day]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q of the access schedule of role %s", day, schedule.Role)
+		}
+		if window.days == nil {
+			window.days = map[time.Weekday]bool{}
+		}
+		window.days[weekDay] = true
+	}
+	for _, clock := range []struct {
+		value string
+		into  *time.Duration
+	}{{schedule.Start, &window.start}, {schedule.End, &window.end}} {
+		parsed, err := time.Parse("15:04", clock.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q of the access schedule of role %s", clock.value, schedule.Role)
+		}
+		*clock.into = time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+	}
+	return window, nil
+}
+
+// startsOn reports whether the window starts on the day of t
+func (w *accessWindow) startsOn(t time.Time) bool {
+	return w.days == nil || w.days[t.Weekday()]
+}
+
+// active reports whether the window covers t. A window ending before its start ends on the next day,
+// and a window ending at its start lasts a whole day.
+func (w *accessWindow) active(t time.Time) bool {
+	t = t.In(w.location)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.start < w.end {
+		return w.startsOn(t) && sinceMidnight >= w.start && sinceMidnight < w.end
+	}
+	return (w.startsOn(t) && sinceMidnight >= w.start) || (w.startsOn(t.AddDate(0, 0, -1)) && sinceMidnight < w.end)
+}
+
+// next returns the first time after now the window opens or closes, zero when it never changes
+func (w *accessWindow) next(now time.Time) time.Time {
+	local := now.In(w.location)
+	var candidates []time.Time
+	for day := -1; day <= 8; day++ {
+		midnight := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, 0, w.location)
+		candidates = append(candidates, midnight.Add(w.start), midnight.Add(w.end))
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	current := w.active(now)
+	for _, candidate := range candidates {
+		if candidate.After(now) && w.active(candidate) != current {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
+
+// validateAccessSchedules checks the access schedules of the workspace, one per role at most
+func validateAccessSchedules(workspace *environmentv1alpha1.Workspace) error {
+	roles := map[string]bool{}
+	for _, schedule := range workspace.Spec.AccessSchedules {
+		if roles[schedule.Role] {
+			return fmt.Errorf("role %s has more than one access schedule", schedule.Role)
+		}
+		roles[schedule.Role] = true
+		if _, err := parseAccessSchedule(schedule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roleUsers returns the users bound to a role of the workspace, the single user of spec.users followed by its list
+// and by the User subjects of spec.subjects
+func roleUsers(workspace *environmentv1alpha1.Workspace, role string) []string {
+	users := workspace.Spec.Users
+	var candidates []string
+	switch role {
+	case "admin":
+		candidates = append([]string{users.Admin}, users.Admins...)
+	case "editor":
+		candidates = append([]string{users.Editor}, users.Editors...)
+	case "viewer":
+		candidates = append([]string{users.Viewer}, users.Viewers...)
+	}
+	for _, subject := range workspace.Spec.Subjects {
+		if subject.Role == role && subject.Kind == rbacv1.UserKind {
+			candidates = append(candidates, subject.Name)
+		}
+	}
+	var result []string
+	seen := map[string]bool{}
+	for _, user := range candidates {
+		if user == "" || seen[user] {
+			continue
+		}
+		seen[user] = true
+		result = append(result, user)
+	}
+	return result
+}
+
+// roleBindingSubjects returns the subjects of the RoleBinding of a role of the workspace at now, the users of the role
+// followed by the members of its teams and by its Group and ServiceAccount subjects, or none outside of the access
+// schedule of the role or, except for the admin, once the access of the workspace expired
+func (r *WorkspaceReconciler) roleBindingSubjects(workspace *environmentv1alpha1.Workspace, role string, members []string, now time.Time) ([]rbacv1.Subject, error) {
+	if role != "admin" && r.accessExpired(workspace, now) {
+		return nil, nil
+	}
+	for _, schedule := range workspace.Spec.AccessSchedules {
+		if schedule.Role != role {
+			continue
+		}
+		window, err := parseAccessSchedule(schedule)
+		if err != nil {
+			return nil, stall(err)
+		}
+		if !window.active(now) {
+			return nil, nil
+		}
+	}
+	// The users deactivated in the identity provider are left out until they are reactivated
+	deactivated := deactivatedUsers(workspace.Annotations)
+	var subjects []rbacv1.Subject
+	bound := map[string]bool{}
+	for _, user := range append(roleUsers(workspace, role), members...) {
+		if bound[user] || deactivated[user] {
+			continue
+		}
+		bound[user] = true
+		subjects = append(subjects, rbacv1.Subject{Kind: "User", Name: user, APIGroup: "rbac.authorization.k8s.io"})
+	}
+	for _, workspaceSubject := range workspace.Spec.Subjects {
+		if workspaceSubject.Role != role || workspaceSubject.Kind == rbacv1.UserKind {
+			continue
+		}
+		subject := rbacv1.Subject{Kind: workspaceSubject.Kind, Name: workspaceSubject.Name, APIGroup: "rbac.authorization.k8s.io"}
+		if workspaceSubject.Kind == rbacv1.ServiceAccountKind {
+			subject.APIGroup = ""
+			subject.Namespace = workspaceSubject.Namespace
+		}
+		if !containsSubject(subjects, subject) {
+			subjects = append(subjects, subject)
+		}
+	}
+	return subjects, nil
+}
+
+// validateSubjects checks that the ServiceAccount subjects of the workspace, and only them, have a namespace
+func validateSubjects(workspace *environmentv1alpha1.Workspace) error {
+	for i, subject := range workspace.Spec.Subjects {
+		switch {
+		case subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == "":
+			return fmt.Errorf("spec.subjects[%d].namespace: the namespace of ServiceAccount %s is required", i, subject.Name)
+		case subject.Kind != rbacv1.ServiceAccountKind && subject.Namespace != "":
+			return fmt.Errorf("spec.subjects[%d].namespace: only ServiceAccount subjects have a namespace", i)
+		}
+	}
+	return nil
+}
+
+// diffSubjects returns the subjects of previous missing from subjects, and the subjects missing from previous
+func diffSubjects(previous, subjects []rbacv1.Subject) (removed, added []rbacv1.Subject) {
+	for _, subject := range previous {
+		if !containsSubject(subjects, subject) {
+			removed = append(removed, subject)
+		}
+	}
+	for _, subject := range subjects {
+		if !containsSubject(previous, subject) {
+			added = append(added, subject)
+		}
+	}
+	return removed, added
+}
+
+func containsSubject(subjects []rbacv1.Subject, subject rbacv1.Subject) bool {
+	for _, s := range subjects {
+		if s.Kind == subject.Kind && s.Name == subject.Name && s.Namespace == subject.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// nextAccessChange returns the first time after now an access schedule of the workspace opens or closes,
+// zero when the access of the workspace never changes
+func nextAccessChange(workspace *environmentv1alpha1.Workspace, now time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range workspace.Spec.AccessSchedules {
+		window, err := parseAccessSchedule(schedule)
+		if err != nil {
+			continue
+		}
+		if change := window.next(now); !change.IsZero() && (next.IsZero() || change.Before(next)) {
+			next = change
+		}
+	}
+	return next
+}
+
+// recordAccessTransition records an event for each user of a role bound or unbound by its access schedule
+// or by the recertification of the workspace
+func (r *WorkspaceReconciler) recordAccessTransition(workspace *environmentv1alpha1.Workspace, role string, users []string, wasBound, bound bool) {
+	if wasBound == bound || r.Recorder == nil {
+		return
+	}
+	for _, user := range users {
+		if bound {
+			r.Recorder.Event(workspace, corev1.EventTypeNormal, "AccessGranted", fmt.Sprintf("User %s is bound to the %s role", user, role))
+			continue
+		}
+		r.Recorder.Event(workspace, corev1.EventTypeNormal, "AccessRevoked", fmt.Sprintf("User %s is unbound from the %s role", user, role))
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestDiffSubjects(t *testing.T) {
+	alice := rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"}
+	bob := rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "bob"}
+	devs := rbacv1.Subject{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "alice"}
+	deployer := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "deployer", Namespace: "ci"}
+	otherDeployer := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "deployer", Namespace: "cd"}
+
+	tests := []struct {
+		name     string
+		previous []rbacv1.Subject
+		subjects []rbacv1.Subject
+		removed  []rbacv1.Subject
+		added    []rbacv1.Subject
+	}{
+		{
+			name: "no subjects",
+		},
+		{
+			name:     "unchanged subjects in another order",
+			previous: []rbacv1.Subject{alice, bob},
+			subjects: []rbacv1.Subject{bob, alice},
+		},
+		{
+			name:     "added subject",
+			previous: []rbacv1.Subject{alice},
+			subjects: []rbacv1.Subject{alice, bob},
+			added:    []rbacv1.Subject{bob},
+		},
+		{
+			name:     "removed subject",
+			previous: []rbacv1.Subject{alice, bob},
+			subjects: []rbacv1.Subject{bob},
+			removed:  []rbacv1.Subject{alice},
+		},
+		{
+			name:     "replaced subjects",
+			previous: []rbacv1.Subject{alice},
+			subjects: []rbacv1.Subject{bob, deployer},
+			removed:  []rbacv1.Subject{alice},
+			added:    []rbacv1.Subject{bob, deployer},
+		},
+		{
+			name:     "user and group of the same name",
+			previous: []rbacv1.Subject{alice},
+			subjects: []rbacv1.Subject{devs},
+			removed:  []rbacv1.Subject{alice},
+			added:    []rbacv1.Subject{devs},
+		},
+		{
+			name:     "service account moved to another namespace",
+			previous: []rbacv1.Subject{deployer},
+			subjects: []rbacv1.Subject{otherDeployer},
+			removed:  []rbacv1.Subject{deployer},
+			added:    []rbacv1.Subject{otherDeployer},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed, added := diffSubjects(tt.previous, tt.subjects)
+			if !reflect.DeepEqual(removed, tt.removed) {
+				t.Errorf("removed = %v, want %v", removed, tt.removed)
+			}
+			if !reflect.DeepEqual(added, tt.added) {
+				t.Errorf("added = %v, want %v", added, tt.added)
+			}
+		})
+	}
+}
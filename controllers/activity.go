@@ -0,0 +1,58 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// RecentEventsLimit is the number of changes of the operator kept in status.recentEvents
+const RecentEventsLimit = 10
+
+// Actions of the changes recorded in status.recentEvents, next to the audit actions of the RBAC changes
+const (
+	ActivityCreated = "Created"
+	ActivityUpdated = "Updated"
+)
+
+// recordActivity adds a change made by the operator to status.recentEvents of the workspace. A change repeating
+// the most recent one, e.g. the same drift corrected again, is counted instead of pushing the older ones out.
+// The activity is written along with the status of the workspace at the end of the reconciliation.
+func (r *WorkspaceReconciler) recordActivity(workspace *environmentv1alpha1.Workspace, action, message string) {
+	// The children of a workspace are created and patched concurrently
+	r.activityMu.Lock()
+	defer r.activityMu.Unlock()
+	now := metav1.NewTime(time.Now().UTC().Truncate(time.Second))
+	events := workspace.Status.RecentEvents
+	if len(events) > 0 && events[0].Action == action && events[0].Message == message {
+		events[0].Time = now
+		if events[0].Count == 0 {
+			events[0].Count = 1
+		}
+		events[0].Count++
+		return
+	}
+	events = append([]environmentv1alpha1.WorkspaceActivity{{Time: now, Action: action, Message: message}}, events...)
+	if len(events) > RecentEventsLimit {
+		events = events[:RecentEventsLimit]
+	}
+	workspace.Status.RecentEvents = events
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=environment.tf.operator.com,resources=workspaceclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kyverno.io,resources=policies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingadmissionpolicies;validatingadmissionpolicybindings,verbs=get;list;watch;create;update;patch;delete
+
+// The admission policies are handled as unstructured so that the operator depends neither on Kyverno
+// nor on a Kubernetes version serving ValidatingAdmissionPolicies.
+var (
+	kyvernoPolicyGVK    = schema.GroupVersionKind{Group: "kyverno.io", Version: "v1", Kind: "Policy"}
+	admissionPolicyGVK  = schema.GroupVersionKind{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "ValidatingAdmissionPolicy"}
+	admissionBindingGVK = schema.GroupVersionKind{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "ValidatingAdmissionPolicyBinding"}
+)
+
+// AdmissionPolicyHashAnnotation records the hash of the rendered spec of an admission policy object.
+// The live spec can not be compared as it is defaulted by the API server and the policy engine.
+const AdmissionPolicyHashAnnotation = "environment.tf.operator.com/admission-policy-hash"
+
+// admissionPolicyContainers is the CEL expression of all the containers of a pod,
+// available to the validations as variables.containers
+const admissionPolicyContainers = "object.spec.containers + " +
+	"(has(object.spec.initContainers) ? object.spec.initContainers : []) + " +
+	"(has(object.spec.ephemeralContainers) ? object.spec.ephemeralContainers : [])"
+
+// admissionPolicyTarget is the kind of objects validated by an admission policy
+type admissionPolicyTarget struct {
+	group    string
+	version  string
+	resource string
+	kind     string
+	// scope restricts the objects validated to Cluster or Namespaced ones, all of them when empty
+	scope string
+}
+
+// podTarget is the target of the admission policies validating pods,
+// the only target whose validations can use variables.containers
+var podTarget = admissionPolicyTarget{version: "v1", resource: "pods", kind: "Pod"}
+
+// admissionPolicy is a set of CEL validations of the objects of a workspace namespace
+type admissionPolicy struct {
+	// engine is kyverno or vap
+	engine string
+	// action is Enforce or Audit
+	action string
+	// target is the kind of objects validated, pods when nil
+	target *admissionPolicyTarget
+	// operations are the operations validated, CREATE and UPDATE when nil
+	operations []interface{}
+	// validations are the CEL expressions and messages of the policy
+	validations []interface{}
+}
+
+// policyTarget returns the kind of objects validated by the policy
+func (p *admissionPolicy) policyTarget() admissionPolicyTarget {
+	if p.target == nil {
+		return podTarget
+	}
+	return *p.target
+}
+
+// policyOperations returns the operations validated by the policy
+func (p *admissionPolicy) policyOperations() []interface{} {
+	if p.operations == nil {
+		return []interface{}{"CREATE", "UPDATE"}
+	}
+	return p.operations
+}
+
+// variables returns the CEL variables available to the validations of the policy
+func (p *admissionPolicy) variables() []interface{} {
+	if p.policyTarget() != podTarget {
+		return nil
+	}
+	return []interface{}{
+		map[string]interface{}{"name": "containers", "expression": admissionPolicyContainers},
+	}
+}
+
+// celValidation returns a CEL validation of an admission policy
+func celValidation(expression, message string) map[string]interface{} {
+	return map[string]interface{}{"expression": expression, "message": message}
+}
+
+// workspaceClass returns the WorkspaceClass of the workspace, or nil when the workspace has no class
+func (r *WorkspaceReconciler) workspaceClass(ctx context.Context, workspace *environmentv1alpha1.Workspace) (*environmentv1alpha1.WorkspaceClass, error) {
+	if workspace.Spec.ClassName == "" {
+		return nil, nil
+	}
+	class := &environmentv1alpha1.WorkspaceClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: workspace.Spec.ClassName}, class); err != nil {
+		return nil, fmt.Errorf("failed to get WorkspaceClass %s: %w", workspace.Spec.ClassName, err)
+	}
+	return class, nil
+}
+
+// reconcileAdmissionPolicy renders the policy with its engine under the given name and removes the objects
+// of the other engine. All the objects are removed when policy is nil.
+// Clusters without the CRDs or APIs of an engine are skipped.
+func (r *WorkspaceReconciler) reconcileAdmissionPolicy(ctx context.Context, workspace *environmentv1alpha1.Workspace, name string, policy *admissionPolicy) error {
+	var desired []*unstructured.Unstructured
+	if policy != nil && len(policy.validations) > 0 {
+		var err error
+		if policy.engine == "kyverno" {
+			desired, err = r.kyvernoPolicyForWorkspace(workspace, name, policy)
+		} else {
+			desired, err = r.validatingAdmissionPolicyForWorkspace(workspace, name, policy)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, gvk := range []schema.GroupVersionKind{kyvernoPolicyGVK, admissionPolicyGVK, admissionBindingGVK} {
+		var want *unstructured.Unstructured
+		for _, object := range desired {
+			if object.GroupVersionKind() == gvk {
+				want = object
+			}
+		}
+		if err := r.applyAdmissionPolicyObject(ctx, workspace, gvk, name, want); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyAdmissionPolicyObject creates or updates the desired admission policy object of the given kind,
+// or deletes the existing one when desired is nil
+func (r *WorkspaceReconciler) applyAdmissionPolicyObject(ctx context.Context, workspace *environmentv1alpha1.Workspace, gvk schema.GroupVersionKind, name string, desired *unstructured.Unstructured) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	key := types.NamespacedName{Name: name}
+	if gvk == kyvernoPolicyGVK {
+		key.Namespace = workspace.Spec.Name
+	}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(gvk)
+	err := r.Get(ctx, key, existing)
+	if meta.IsNoMatchError(err) {
+		if desired != nil {
+			reconcilerLog.Info(fmt.Sprintf("%s is not served by the cluster. Skipping admission policy %s for Workspace", gvk.Kind, name))
+		}
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if desired == nil {
+		if exists {
+			reconcilerLog.Info(fmt.Sprintf("Deleting %s %s", gvk.Kind, key.Name))
+			if err := r.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	spec, err := json.Marshal(desired.Object["spec"])
+	if err != nil {
+		return err
+	}
+	hash := specHash(spec)
+	if !exists {
+		reconcilerLog.Info(fmt.Sprintf("Creating a new %s %s", gvk.Kind, desired.GetName()))
+		annotations := desired.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[AdmissionPolicyHashAnnotation] = hash
+		desired.SetAnnotations(annotations)
+		return r.Create(ctx, desired)
+	}
+
+	// check if the rendered policy changed
+	annotations := existing.GetAnnotations()
+	if annotations[AdmissionPolicyHashAnnotation] != hash {
+		reconcilerLog.Info(fmt.Sprintf("Admission policy not same for %s %s", gvk.Kind, key.Name))
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[AdmissionPolicyHashAnnotation] = hash
+		existing.SetAnnotations(annotations)
+		existing.Object["spec"] = desired.Object["spec"]
+		return r.update(ctx, existing)
+	}
+	return nil
+}
+
+// Kyverno Policy for Workspace
+func (r *WorkspaceReconciler) kyvernoPolicyForWorkspace(workspace *environmentv1alpha1.Workspace, name string, policy *admissionPolicy) ([]*unstructured.Unstructured, error) {
+	kyvernoPolicy := &unstructured.Unstructured{}
+	kyvernoPolicy.SetGroupVersionKind(kyvernoPolicyGVK)
+	kyvernoPolicy.SetName(name)
+	kyvernoPolicy.SetNamespace(workspace.Spec.Name)
+	kyvernoPolicy.SetLabels(labelsForWorkspace(workspace, nil))
+	kyvernoPolicy.SetAnnotations(annotationsForWorkspace(workspace))
+	cel := map[string]interface{}{"expressions": policy.validations}
+	if variables := policy.variables(); variables != nil {
+		cel["variables"] = variables
+	}
+	kyvernoPolicy.Object["spec"] = map[string]interface{}{
+		"validationFailureAction": policy.action,
+		"background":              true,
+		"rules": []interface{}{
+			map[string]interface{}{
+				"name": name,
+				"match": map[string]interface{}{
+					"any": []interface{}{
+						map[string]interface{}{"resources": map[string]interface{}{"kinds": []interface{}{policy.policyTarget().kind}}},
+					},
+				},
+				"validate": map[string]interface{}{
+					"cel": cel,
+				},
+			},
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, kyvernoPolicy, r.Scheme); err != nil {
+		return nil, err
+	}
+	return []*unstructured.Unstructured{kyvernoPolicy}, nil
+}
+
+// ValidatingAdmissionPolicy and ValidatingAdmissionPolicyBinding for Workspace.
+// Both are cluster scoped, the binding selects the workspace namespace.
+func (r *WorkspaceReconciler) validatingAdmissionPolicyForWorkspace(workspace *environmentv1alpha1.Workspace, name string, policy *admissionPolicy) ([]*unstructured.Unstructured, error) {
+	validatingPolicy := &unstructured.Unstructured{}
+	validatingPolicy.SetGroupVersionKind(admissionPolicyGVK)
+	validatingPolicy.SetName(name)
+	validatingPolicy.SetLabels(labelsForWorkspace(workspace, nil))
+	validatingPolicy.SetAnnotations(annotationsForWorkspace(workspace))
+	target := policy.policyTarget()
+	resourceRule := map[string]interface{}{
+		"apiGroups":   []interface{}{target.group},
+		"apiVersions": []interface{}{target.version},
+		"operations":  policy.policyOperations(),
+		"resources":   []interface{}{target.resource},
+	}
+	if target.scope != "" {
+		resourceRule["scope"] = target.scope
+	}
+	spec := map[string]interface{}{
+		"failurePolicy": "Fail",
+		"matchConstraints": map[string]interface{}{
+			"resourceRules": []interface{}{resourceRule},
+		},
+		"validations": policy.validations,
+	}
+	if variables := policy.variables(); variables != nil {
+		spec["variables"] = variables
+	}
+	validatingPolicy.Object["spec"] = spec
+
+	validationActions := []interface{}{"Deny"}
+	if policy.action == "Audit" {
+		validationActions = []interface{}{"Warn", "Audit"}
+	}
+	binding := &unstructured.Unstructured{}
+	binding.SetGroupVersionKind(admissionBindingGVK)
+	binding.SetName(name)
+	binding.SetLabels(labelsForWorkspace(workspace, nil))
+	binding.SetAnnotations(annotationsForWorkspace(workspace))
+	binding.Object["spec"] = map[string]interface{}{
+		"policyName":        name,
+		"validationActions": validationActions,
+		"matchResources": map[string]interface{}{
+			"namespaceSelector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"kubernetes.io/metadata.name": workspace.Spec.Name},
+			},
+		},
+	}
+
+	for _, object := range []*unstructured.Unstructured{validatingPolicy, binding} {
+		if err := ctrl.SetControllerReference(workspace, object, r.Scheme); err != nil {
+			return nil, err
+		}
+	}
+	return []*unstructured.Unstructured{validatingPolicy, binding}, nil
+}
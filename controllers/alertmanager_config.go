@@ -0,0 +1,148 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=alertmanagerconfigs,verbs=get;list;watch;create;update;patch;delete
+
+// alertmanagerConfigGVK is the prometheus-operator AlertmanagerConfig kind.
+// It is handled as unstructured so that the operator does not depend on prometheus-operator.
+var alertmanagerConfigGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1alpha1", Kind: "AlertmanagerConfig"}
+
+// reconcileAlertmanagerConfig keeps the AlertmanagerConfig of the workspace in sync with spec.alerting.receiver.
+// Clusters without the prometheus-operator CRDs are skipped.
+func (r *WorkspaceReconciler) reconcileAlertmanagerConfig(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	alertmanagerConfig := &unstructured.Unstructured{}
+	alertmanagerConfig.SetGroupVersionKind(alertmanagerConfigGVK)
+	err := r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-alerting", workspace.Spec.Name)}, alertmanagerConfig)
+	if meta.IsNoMatchError(err) {
+		if workspace.Spec.Alerting.Receiver != nil {
+			reconcilerLog.Info("AlertmanagerConfig CRD is not installed. Skipping alert routing for Workspace")
+		}
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if workspace.Spec.Alerting.Receiver == nil {
+		if exists {
+			reconcilerLog.Info(fmt.Sprintf("Deleting AlertmanagerConfig AlertmanagerConfig.Name %s", alertmanagerConfig.GetName()))
+			if err := r.Delete(ctx, alertmanagerConfig); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	amc, err := r.alertmanagerConfigForWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		reconcilerLog.Info(fmt.Sprintf("Creating a new AlertmanagerConfig AlertmanagerConfig.Name %s", amc.GetName()))
+		return r.apply(ctx, amc)
+	}
+
+	// check if the receiver of the workspace changed
+	if !semanticEqualJSON(alertmanagerConfig.Object["spec"], amc.Object["spec"]) {
+		reconcilerLog.Info(fmt.Sprintf("Receiver not same for AlertmanagerConfig %s in Namespace.Name %s", amc.GetName(), workspace.Spec.Name))
+		alertmanagerConfig.Object["spec"] = amc.Object["spec"]
+		if err := r.update(ctx, alertmanagerConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// secretKeySelector renders a SecretKeySelector the way AlertmanagerConfig expects it
+func secretKeySelector(selector corev1.SecretKeySelector) map[string]interface{} {
+	return map[string]interface{}{
+		"name": selector.Name,
+		"key":  selector.Key,
+	}
+}
+
+// AlertmanagerConfig for Workspace
+func (r *WorkspaceReconciler) alertmanagerConfigForWorkspace(workspace *environmentv1alpha1.Workspace) (*unstructured.Unstructured, error) {
+	receiver := workspace.Spec.Alerting.Receiver
+	receiverConfig := map[string]interface{}{
+		"name": "workspace",
+	}
+	if receiver.Slack != nil {
+		receiverConfig["slackConfigs"] = []interface{}{
+			map[string]interface{}{
+				"channel":      receiver.Slack.Channel,
+				"apiURL":       secretKeySelector(receiver.Slack.APIURL),
+				"sendResolved": true,
+			},
+		}
+	}
+	if receiver.Email != "" {
+		receiverConfig["emailConfigs"] = []interface{}{
+			map[string]interface{}{
+				"to":           receiver.Email,
+				"sendResolved": true,
+			},
+		}
+	}
+	if receiver.PagerDuty != nil {
+		receiverConfig["pagerdutyConfigs"] = []interface{}{
+			map[string]interface{}{
+				"routingKey":   secretKeySelector(receiver.PagerDuty.RoutingKey),
+				"sendResolved": true,
+			},
+		}
+	}
+
+	alertmanagerConfig := &unstructured.Unstructured{}
+	alertmanagerConfig.SetGroupVersionKind(alertmanagerConfigGVK)
+	alertmanagerConfig.SetName(fmt.Sprintf("%s-alerting", workspace.Spec.Name))
+	alertmanagerConfig.SetNamespace(workspace.Spec.Name)
+	alertmanagerConfig.SetLabels(labelsForWorkspace(workspace, nil))
+	alertmanagerConfig.SetAnnotations(annotationsForWorkspace(workspace))
+	alertmanagerConfig.Object["spec"] = map[string]interface{}{
+		// prometheus-operator scopes the route to alerts of the namespace the AlertmanagerConfig lives in
+		"route": map[string]interface{}{
+			"receiver": "workspace",
+			"groupBy":  []interface{}{"alertname"},
+		},
+		"receivers": []interface{}{receiverConfig},
+	}
+	if err := ctrl.SetControllerReference(workspace, alertmanagerConfig, r.Scheme); err != nil {
+		return nil, err
+	}
+	return alertmanagerConfig, nil
+}
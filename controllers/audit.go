@@ -0,0 +1,235 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// Audit actions recorded for RBAC changes performed by the operator
+const (
+	AuditActionRoleCreated        = "RoleCreated"
+	AuditActionRoleUpdated        = "RoleUpdated"
+	AuditActionRoleBindingCreated = "RoleBindingCreated"
+	AuditActionSubjectAdded       = "SubjectAdded"
+	AuditActionSubjectRemoved     = "SubjectRemoved"
+)
+
+// AuditRecord is a structured record of a single RBAC change made by the operator.
+// Records are serialized as JSON so that they can be ingested by a SIEM as-is.
+type AuditRecord struct {
+	Timestamp time.Time           `json:"timestamp"`
+	Action    string              `json:"action"`
+	Workspace string              `json:"workspace"`
+	Namespace string              `json:"namespace"`
+	Kind      string              `json:"kind"`
+	Name      string              `json:"name"`
+	Subject   *rbacv1.Subject     `json:"subject,omitempty"`
+	Rules     []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
+
+// AuditSink receives audit records and ships them to an external system
+type AuditSink interface {
+	Send(ctx context.Context, record AuditRecord) error
+}
+
+// NewAuditSink builds an AuditSink from an endpoint URL.
+// Supported schemes are http and https (records are POSTed as JSON) and
+// syslog+udp, syslog+tcp (records are written as JSON syslog messages).
+func NewAuditSink(endpoint string) (AuditSink, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit endpoint %q: %w", endpoint, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &httpAuditSink{endpoint: endpoint, client: &http.Client{Timeout: 5 * time.Second}}, nil
+	case "syslog+udp", "syslog+tcp":
+		w, err := syslog.Dial(strings.TrimPrefix(u.Scheme, "syslog+"), u.Host, syslog.LOG_NOTICE|syslog.LOG_AUTH, "workspace-operator")
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to syslog endpoint %q: %w", endpoint, err)
+		}
+		return &syslogAuditSink{writer: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported audit endpoint scheme %q", u.Scheme)
+	}
+}
+
+// httpAuditSink POSTs every record as a JSON document
+type httpAuditSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (s *httpAuditSink) Send(ctx context.Context, record AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// DefaultAuditBufferSize is the number of audit records buffered by an AuditQueue when its size is not set
+const DefaultAuditBufferSize = 1000
+
+// auditDrainTimeout bounds the shipping of the records left in the buffer of an AuditQueue on shutdown
+const auditDrainTimeout = 10 * time.Second
+
+// AuditQueue buffers the audit records and ships them to Sink in the background, so that a slow or unreachable
+// audit endpoint never delays the reconciliations. A record sent while the buffer is full is dropped and logged.
+// It is a Runnable of the manager, the records left in the buffer are shipped on shutdown.
+type AuditQueue struct {
+	// Sink ships the records
+	Sink AuditSink
+
+	records chan AuditRecord
+}
+
+// NewAuditQueue returns an AuditQueue buffering up to size records, DefaultAuditBufferSize when size is not positive
+func NewAuditQueue(sink AuditSink, size int) *AuditQueue {
+	if size <= 0 {
+		size = DefaultAuditBufferSize
+	}
+	return &AuditQueue{Sink: sink, records: make(chan AuditRecord, size)}
+}
+
+// Send buffers the record without blocking, it fails when the buffer is full
+func (q *AuditQueue) Send(_ context.Context, record AuditRecord) error {
+	select {
+	case q.records <- record:
+		return nil
+	default:
+		return fmt.Errorf("audit buffer of %d records is full", cap(q.records))
+	}
+}
+
+// Start ships the buffered records until the context is cancelled
+func (q *AuditQueue) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			q.drain()
+			return nil
+		case record := <-q.records:
+			q.ship(ctx, record)
+		}
+	}
+}
+
+// NeedLeaderElection makes every replica ship the records it buffered, including a leader stepping down
+func (q *AuditQueue) NeedLeaderElection() bool {
+	return false
+}
+
+// drain ships the records left in the buffer within auditDrainTimeout
+func (q *AuditQueue) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), auditDrainTimeout)
+	defer cancel()
+	for {
+		select {
+		case record := <-q.records:
+			if ctx.Err() != nil {
+				ctrl.Log.WithName("audit").Error(ctx.Err(), fmt.Sprintf("Dropped audit record %s for %s %s on shutdown", record.Action, record.Kind, record.Name))
+				continue
+			}
+			q.ship(ctx, record)
+		default:
+			return
+		}
+	}
+}
+
+func (q *AuditQueue) ship(ctx context.Context, record AuditRecord) {
+	if err := q.Sink.Send(ctx, record); err != nil {
+		ctrl.Log.WithName("audit").Error(err, fmt.Sprintf("Failed to send audit record %s for %s %s", record.Action, record.Kind, record.Name))
+	}
+}
+
+// syslogAuditSink writes every record as a JSON syslog message
+type syslogAuditSink struct {
+	writer *syslog.Writer
+}
+
+func (s *syslogAuditSink) Send(_ context.Context, record AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.writer.Notice(string(body))
+}
+
+// audit sends a record to the configured sink, an AuditQueue shipping it in the background.
+// Failing to deliver an audit record is logged but never blocks the reconciliation.
+func (r *WorkspaceReconciler) audit(ctx context.Context, workspace *environmentv1alpha1.Workspace, action, kind, name string, subject *rbacv1.Subject, rules []rbacv1.PolicyRule) {
+	// The creations are already recorded in status.recentEvents and in the Events along with the other children of the workspace
+	message := ""
+	switch action {
+	case AuditActionSubjectAdded:
+		message = fmt.Sprintf("Granted %s %s through %s %s", subject.Kind, subject.Name, kind, name)
+	case AuditActionSubjectRemoved:
+		message = fmt.Sprintf("Revoked %s %s from %s %s", subject.Kind, subject.Name, kind, name)
+	case AuditActionRoleUpdated:
+		message = fmt.Sprintf("Updated the rules of %s %s", kind, name)
+	}
+	if message != "" {
+		r.recordActivity(workspace, action, message)
+		r.event(workspace, corev1.EventTypeNormal, EventRBACSynced, message)
+	}
+	if r.Audit == nil {
+		return
+	}
+	record := AuditRecord{
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Workspace: workspace.Name,
+		Namespace: workspace.Spec.Name,
+		Kind:      kind,
+		Name:      name,
+		Subject:   subject,
+		Rules:     rules,
+	}
+	if err := r.Audit.Send(ctx, record); err != nil {
+		ctrl.Log.WithName("audit").Error(err, fmt.Sprintf("Failed to queue audit record %s for %s %s", action, kind, name))
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// AutoWorkspaceAnnotation set to "true" on a namespace creates a Workspace managing it
+	AutoWorkspaceAnnotation = "environment.tf.operator.com/auto-workspace"
+
+	// AutoWorkspaceCPUAnnotation, AutoWorkspaceMemoryAnnotation and AutoWorkspaceDiskAnnotation
+	// set spec.resources of the Workspace created for an annotated namespace
+	AutoWorkspaceCPUAnnotation    = "environment.tf.operator.com/cpu"
+	AutoWorkspaceMemoryAnnotation = "environment.tf.operator.com/memory"
+	AutoWorkspaceDiskAnnotation   = "environment.tf.operator.com/disk"
+
+	// AutoWorkspaceAdminAnnotation, AutoWorkspaceEditorAnnotation and AutoWorkspaceViewerAnnotation
+	// set spec.users of the Workspace created for an annotated namespace
+	AutoWorkspaceAdminAnnotation  = "environment.tf.operator.com/admin"
+	AutoWorkspaceEditorAnnotation = "environment.tf.operator.com/editor"
+	AutoWorkspaceViewerAnnotation = "environment.tf.operator.com/viewer"
+
+	// AutoWorkspaceClassAnnotation sets spec.className of the Workspace created for an annotated namespace
+	AutoWorkspaceClassAnnotation = "environment.tf.operator.com/class"
+
+	// AutoCreatedFromAnnotation records the namespace a Workspace was automatically created for
+	AutoCreatedFromAnnotation = "environment.tf.operator.com/auto-created-from"
+)
+
+// autoWorkspaceRequested reports whether a Workspace must be created for the namespace
+func autoWorkspaceRequested(obj client.Object) bool {
+	auto, _ := strconv.ParseBool(obj.GetAnnotations()[AutoWorkspaceAnnotation])
+	return auto
+}
+
+// NamespaceReconciler creates the Workspaces of the namespaces annotated with AutoWorkspaceAnnotation,
+// so that teams already owning namespaces can adopt workspaces bottom-up. The Workspaces are only created,
+// they are the source of truth afterwards.
+type NamespaceReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// Reconcile creates the Workspace of an annotated namespace unless a Workspace already manages it
+func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	reconcilerLog := ctrl.Log.WithName("auto-workspace").WithValues(logging.WorkspaceKey, req.Name)
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, req.NamespacedName, namespace); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !autoWorkspaceRequested(namespace) || !namespace.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// Skip the namespaces already managed by a Workspace
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := r.List(ctx, workspaces); err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range workspaces.Items {
+		if workspaces.Items[i].Spec.Name == namespace.Name {
+			return ctrl.Result{}, nil
+		}
+	}
+	existing := &environmentv1alpha1.Workspace{}
+	err := r.Get(ctx, types.NamespacedName{Name: namespace.Name}, existing)
+	if err == nil {
+		r.warn(namespace, fmt.Sprintf("Workspace %s already exists for Namespace %s", existing.Name, existing.Spec.Name))
+		return ctrl.Result{}, nil
+	} else if !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	workspace, missing := workspaceForAnnotatedNamespace(namespace)
+	if missing != "" {
+		r.warn(namespace, fmt.Sprintf("Namespace %s is missing the %s annotation to create its Workspace", namespace.Name, missing))
+		return ctrl.Result{}, nil
+	}
+	reconcilerLog.Info(fmt.Sprintf("Creating a new Workspace for Namespace.Name %s", namespace.Name))
+	if err := r.Create(ctx, workspace); err != nil {
+		if apierrors.IsInvalid(err) {
+			r.warn(namespace, fmt.Sprintf("Workspace of Namespace %s is invalid: %s", namespace.Name, err))
+			return ctrl.Result{}, nil
+		}
+		reconcilerLog.Error(err, fmt.Sprintf("Error creating a new Workspace for Namespace.Name %s", namespace.Name))
+		return ctrl.Result{}, err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(namespace, "Normal", "WorkspaceCreated", fmt.Sprintf("Created Workspace %s", workspace.Name))
+	}
+	return ctrl.Result{}, nil
+}
+
+// warn reports why the Workspace of the namespace is not created with a Warning event on the namespace
+func (r *NamespaceReconciler) warn(namespace *corev1.Namespace, message string) {
+	ctrl.Log.WithName("auto-workspace").WithValues(logging.WorkspaceKey, namespace.Name).Info(message)
+	if r.Recorder != nil {
+		r.Recorder.Event(namespace, "Warning", "AutoWorkspaceSkipped", message)
+	}
+}
+
+// workspaceForAnnotatedNamespace builds the Workspace of the namespace from its annotations.
+// It returns the first missing required annotation instead when the Workspace can not be built.
+func workspaceForAnnotatedNamespace(namespace *corev1.Namespace) (*environmentv1alpha1.Workspace, string) {
+	annotations := namespace.Annotations
+	for _, required := range []string{AutoWorkspaceCPUAnnotation, AutoWorkspaceMemoryAnnotation, AutoWorkspaceDiskAnnotation, AutoWorkspaceAdminAnnotation} {
+		if annotations[required] == "" {
+			return nil, required
+		}
+	}
+	return &environmentv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        namespace.Name,
+			Annotations: map[string]string{AutoCreatedFromAnnotation: namespace.Name},
+		},
+		Spec: environmentv1alpha1.WorkspaceSpec{
+			Name:      namespace.Name,
+			ClassName: annotations[AutoWorkspaceClassAnnotation],
+			Resources: environmentv1alpha1.WorkspaceResource{
+				CPU:    annotations[AutoWorkspaceCPUAnnotation],
+				Memory: annotations[AutoWorkspaceMemoryAnnotation],
+				Disk:   annotations[AutoWorkspaceDiskAnnotation],
+			},
+			Users: environmentv1alpha1.WorkspaceUser{
+				Admin:  annotations[AutoWorkspaceAdminAnnotation],
+				Editor: annotations[AutoWorkspaceEditorAnnotation],
+				Viewer: annotations[AutoWorkspaceViewerAnnotation],
+			},
+		},
+	}, ""
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("auto-workspace").
+		For(&corev1.Namespace{}, builder.WithPredicates(predicate.NewPredicateFuncs(autoWorkspaceRequested))).
+		Complete(r)
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch
+
+const (
+	// BenchmarkPass is the result of a passed benchmark check
+	BenchmarkPass = "Pass"
+	// BenchmarkFail is the result of a failed benchmark check
+	BenchmarkFail = "Fail"
+)
+
+// benchmarkCheck evaluates a workspace against one of the Kubernetes multi-tenancy benchmarks
+// (https://github.com/kubernetes-sigs/multi-tenancy/tree/master/benchmarks).
+// run returns whether the check passed and a message explaining the result.
+type benchmarkCheck struct {
+	name      string
+	benchmark string
+	run       func(ctx context.Context, r *WorkspaceReconciler, workspace *environmentv1alpha1.Workspace) (bool, string, error)
+}
+
+// benchmarkChecks are the checks of the multi-tenancy benchmark self-check, in the order they are reported
+var benchmarkChecks = []benchmarkCheck{
+	{name: "ClusterResources", benchmark: "MTB-PL1-CC-CPI-1", run: checkClusterResources},
+	{name: "OtherTenants", benchmark: "MTB-PL1-CC-TI-1", run: checkOtherTenants},
+	{name: "NetworkIsolation", benchmark: "MTB-PL1-CC-TI-2", run: checkNetworkIsolation},
+	{name: "ResourceQuota", benchmark: "MTB-PL1-CC-FNS-1", run: checkResourceQuota},
+	{name: "PrivilegedContainers", benchmark: "MTB-PL1-BC-CPI-5", run: checkPodSecurity},
+}
+
+// reconcileBenchmark runs the multi-tenancy benchmark checks of the workspace and reports them in status.benchmark
+// once the previous report is older than BenchmarkInterval
+func (r *WorkspaceReconciler) reconcileBenchmark(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	if r.BenchmarkInterval == 0 {
+		return nil
+	}
+	previous := workspace.Status.Benchmark
+	if previous != nil && time.Since(previous.LastChecked.Time) < r.BenchmarkInterval {
+		return nil
+	}
+
+	benchmark := &environmentv1alpha1.WorkspaceBenchmark{LastChecked: metav1.Now()}
+	for _, check := range benchmarkChecks {
+		passed, message, err := check.run(ctx, r, workspace)
+		if err != nil {
+			return fmt.Errorf("benchmark check %s: %w", check.name, err)
+		}
+		result := BenchmarkPass
+		if passed {
+			benchmark.Passed++
+		} else {
+			result = BenchmarkFail
+			benchmark.Failed++
+		}
+		benchmark.Checks = append(benchmark.Checks, environmentv1alpha1.WorkspaceBenchmarkCheck{
+			Name:      check.name,
+			Benchmark: check.benchmark,
+			Result:    result,
+			Message:   message,
+		})
+	}
+
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Updating benchmark for Workspace %s: %d passed, %d failed", workspace.Name, benchmark.Passed, benchmark.Failed))
+	workspace.Status.Benchmark = benchmark
+	return r.Status().Update(ctx, workspace)
+}
+
+// workspaceUsers returns the users bound to the roles of the workspace
+func workspaceUsers(workspace *environmentv1alpha1.Workspace) []string {
+	var users []string
+	seen := map[string]bool{}
+	for _, role := range workspaceRoles {
+		for _, user := range roleUsers(workspace, role) {
+			if !seen[user] {
+				seen[user] = true
+				users = append(users, user)
+			}
+		}
+	}
+	return users
+}
+
+// deniedToUsers checks with SubjectAccessReviews that none of the workspace users is allowed the actions
+func (r *WorkspaceReconciler) deniedToUsers(ctx context.Context, workspace *environmentv1alpha1.Workspace, actions []authorizationv1.ResourceAttributes) (bool, string, error) {
+	var allowed []string
+	for _, user := range workspaceUsers(workspace) {
+		for i := range actions {
+			review := &authorizationv1.SubjectAccessReview{
+				Spec: authorizationv1.SubjectAccessReviewSpec{
+					User:               user,
+					ResourceAttributes: &actions[i],
+				},
+			}
+			if err := r.Create(ctx, review); err != nil {
+				return false, "", err
+			}
+			if review.Status.Allowed {
+				action := fmt.Sprintf("%s %s", actions[i].Verb, actions[i].Resource)
+				if actions[i].Namespace != "" {
+					action += " in " + actions[i].Namespace
+				}
+				allowed = append(allowed, fmt.Sprintf("%s can %s", user, action))
+			}
+		}
+	}
+	if len(allowed) > 0 {
+		return false, strings.Join(allowed, ", "), nil
+	}
+	return true, "Workspace users are denied", nil
+}
+
+// checkClusterResources verifies that the workspace users can not access cluster-wide resources
+func checkClusterResources(ctx context.Context, r *WorkspaceReconciler, workspace *environmentv1alpha1.Workspace) (bool, string, error) {
+	return r.deniedToUsers(ctx, workspace, []authorizationv1.ResourceAttributes{
+		{Verb: "list", Resource: "nodes"},
+		{Verb: "create", Resource: "namespaces"},
+		{Verb: "list", Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"},
+		{Verb: "list", Group: "environment.tf.operator.com", Resource: "workspaces"},
+	})
+}
+
+// checkOtherTenants verifies that the workspace users can not access the resources of other namespaces
+func checkOtherTenants(ctx context.Context, r *WorkspaceReconciler, workspace *environmentv1alpha1.Workspace) (bool, string, error) {
+	return r.deniedToUsers(ctx, workspace, []authorizationv1.ResourceAttributes{
+		{Verb: "list", Resource: "pods"},
+		{Verb: "list", Resource: "secrets"},
+		{Verb: "list", Resource: "secrets", Namespace: "kube-system"},
+	})
+}
+
+// checkNetworkIsolation verifies that a NetworkPolicy restricts the ingress traffic of all the pods of the namespace
+// to the namespace itself
+func checkNetworkIsolation(ctx context.Context, r *WorkspaceReconciler, workspace *environmentv1alpha1.Workspace) (bool, string, error) {
+	policies := &networkingv1.NetworkPolicyList{}
+	if err := r.List(ctx, policies, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return false, "", err
+	}
+	for _, policy := range policies.Items {
+		if len(policy.Spec.PodSelector.MatchLabels) > 0 || len(policy.Spec.PodSelector.MatchExpressions) > 0 {
+			continue
+		}
+		ingress := len(policy.Spec.PolicyTypes) == 0
+		for _, policyType := range policy.Spec.PolicyTypes {
+			if policyType == networkingv1.PolicyTypeIngress {
+				ingress = true
+			}
+		}
+		if !ingress {
+			continue
+		}
+		isolated := true
+		for _, rule := range policy.Spec.Ingress {
+			if len(rule.From) == 0 {
+				isolated = false
+			}
+			for _, peer := range rule.From {
+				if peer.NamespaceSelector != nil || peer.IPBlock != nil {
+					isolated = false
+				}
+			}
+		}
+		if isolated {
+			return true, fmt.Sprintf("NetworkPolicy %s isolates the ingress traffic of the namespace", policy.Name), nil
+		}
+	}
+	return false, fmt.Sprintf("No NetworkPolicy restricts the ingress traffic of all the pods of Namespace %s to the namespace", workspace.Spec.Name), nil
+}
+
+// checkResourceQuota verifies that the namespace has a ResourceQuota limiting its cpu, memory and storage
+func checkResourceQuota(ctx context.Context, r *WorkspaceReconciler, workspace *environmentv1alpha1.Workspace) (bool, string, error) {
+	quotas := &corev1.ResourceQuotaList{}
+	if err := r.List(ctx, quotas, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return false, "", err
+	}
+	missing := map[corev1.ResourceName]bool{corev1.ResourceCPU: true, corev1.ResourceMemory: true, corev1.ResourceRequestsStorage: true}
+	for _, quota := range quotas.Items {
+		for name := range quota.Spec.Hard {
+			delete(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		var names []string
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceRequestsStorage} {
+			if missing[name] {
+				names = append(names, string(name))
+			}
+		}
+		return false, fmt.Sprintf("No ResourceQuota limits %s in Namespace %s", strings.Join(names, ", "), workspace.Spec.Name), nil
+	}
+	return true, "ResourceQuota limits cpu, memory and requests.storage", nil
+}
+
+// checkPodSecurity verifies that the Pod Security Admission enforces at least the baseline level in the namespace,
+// which blocks privileged containers
+func checkPodSecurity(ctx context.Context, r *WorkspaceReconciler, workspace *environmentv1alpha1.Workspace) (bool, string, error) {
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: workspace.Spec.Name}, namespace); err != nil {
+		return false, "", err
+	}
+	level := namespace.Labels[PodSecurityLabelPrefix+"enforce"]
+	if level == "baseline" || level == "restricted" {
+		return true, fmt.Sprintf("Pod Security Admission enforces the %s level", level), nil
+	}
+	if level == "" {
+		level = "privileged"
+	}
+	return false, fmt.Sprintf("Pod Security Admission enforces the %s level, set spec.podSecurity.enforce of Workspace %s to baseline or restricted", level, workspace.Name), nil
+}
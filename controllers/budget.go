@@ -0,0 +1,163 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// DefaultBudgetSplit is the share, in percent, of the budget of a workspace spent on each resource
+const DefaultBudgetSplit = "cpu=50,memory=40,storage=10"
+
+// budgetResources are the resources a budget is spent on
+var budgetResources = []string{"cpu", "memory", "storage"}
+
+// BudgetPricing translates the monthly budget of the workspaces into ResourceQuota hard limits
+type BudgetPricing struct {
+	// Prices are the monthly unit prices of a cpu core, of a GiB of memory and of a GiB of storage
+	Prices map[string]float64
+
+	// Split is the share, in percent, of the budget spent on each resource
+	Split map[string]float64
+}
+
+// NewBudgetPricing parses comma separated resource=value unit prices and budget split, e.g. cpu=20,memory=2.5,storage=0.1.
+// The split must add up to 100, and every resource the budget is spent on must have a price.
+func NewBudgetPricing(prices, split string) (*BudgetPricing, error) {
+	parse := func(values string) (map[string]float64, error) {
+		parsed := map[string]float64{}
+		for _, value := range strings.Split(values, ",") {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			name, amount, found := strings.Cut(value, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid value %q, expected resource=amount", value)
+			}
+			known := false
+			for _, resource := range budgetResources {
+				known = known || resource == name
+			}
+			if !known {
+				return nil, fmt.Errorf("unknown resource %q, expected one of %s", name, strings.Join(budgetResources, ", "))
+			}
+			parsedAmount, err := strconv.ParseFloat(amount, 64)
+			if err != nil || parsedAmount < 0 {
+				return nil, fmt.Errorf("invalid amount %q of %s", amount, name)
+			}
+			parsed[name] = parsedAmount
+		}
+		return parsed, nil
+	}
+	pricing := &BudgetPricing{}
+	var err error
+	if pricing.Prices, err = parse(prices); err != nil {
+		return nil, fmt.Errorf("invalid unit prices: %w", err)
+	}
+	if pricing.Split, err = parse(split); err != nil {
+		return nil, fmt.Errorf("invalid budget split: %w", err)
+	}
+	total := 0.0
+	for _, resource := range budgetResources {
+		total += pricing.Split[resource]
+		if pricing.Split[resource] > 0 && pricing.Prices[resource] == 0 {
+			return nil, fmt.Errorf("no unit price for %s, which gets %g%% of the budget", resource, pricing.Split[resource])
+		}
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("the budget split adds up to %g%% instead of 100%%", total)
+	}
+	return pricing, nil
+}
+
+// Resources returns the largest cpu, memory and storage hard limits the monthly budget pays for.
+// The cpu is rounded down to the millicore, and the memory and storage to the MiB.
+func (p *BudgetPricing) Resources(budget *environmentv1alpha1.WorkspaceBudget) (environmentv1alpha1.WorkspaceResource, error) {
+	amount, err := strconv.ParseFloat(budget.Monthly, 64)
+	if err != nil {
+		return environmentv1alpha1.WorkspaceResource{}, fmt.Errorf("invalid spec.budget.monthly %q: %w", budget.Monthly, err)
+	}
+	units := func(resource string) float64 {
+		if p.Split[resource] == 0 {
+			return 0
+		}
+		return amount * p.Split[resource] / 100 / p.Prices[resource]
+	}
+	mebibytes := func(resource string) string {
+		return quotaResource.NewQuantity(int64(math.Floor(units(resource)*1024))<<20, quotaResource.BinarySI).String()
+	}
+	return environmentv1alpha1.WorkspaceResource{
+		CPU:    quotaResource.NewMilliQuantity(int64(math.Floor(units("cpu")*1000)), quotaResource.DecimalSI).String(),
+		Memory: mebibytes("memory"),
+		Disk:   mebibytes("storage"),
+	}, nil
+}
+
+// MonthlyCost returns the monthly price of the cpu cores and of the GiB of memory and storage of resources
+func (p *BudgetPricing) MonthlyCost(resources corev1.ResourceList) float64 {
+	gibibytes := func(name corev1.ResourceName) float64 {
+		quantity := resources[name]
+		return quantity.AsApproximateFloat64() / (1 << 30)
+	}
+	cpu := resources[corev1.ResourceCPU]
+	return cpu.AsApproximateFloat64()*p.Prices["cpu"] +
+		gibibytes(corev1.ResourceMemory)*p.Prices["memory"] +
+		gibibytes(corev1.ResourceStorage)*p.Prices["storage"]
+}
+
+// reconcileBudget returns the hard limits of the workspace ResourceQuota. They are spec.resources merged with
+// the quota preset of the template of the workspace, or the resources spec.budget pays for, recorded in status.budgetResources, when the workspace has a budget.
+func (r *WorkspaceReconciler) reconcileBudget(ctx context.Context, workspace *environmentv1alpha1.Workspace) (environmentv1alpha1.WorkspaceResource, error) {
+	var computed *environmentv1alpha1.WorkspaceResource
+	if workspace.Spec.Budget != nil {
+		if r.BudgetPricing == nil {
+			return environmentv1alpha1.WorkspaceResource{}, stall(fmt.Errorf("spec.budget is set but no unit prices are configured to translate it into a ResourceQuota"))
+		}
+		resources, err := r.BudgetPricing.Resources(workspace.Spec.Budget)
+		if err != nil {
+			return environmentv1alpha1.WorkspaceResource{}, stall(err)
+		}
+		computed = &resources
+	}
+
+	if !equality.Semantic.DeepEqual(workspace.Status.BudgetResources, computed) {
+		if computed != nil {
+			ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Budget %s of Workspace %s pays for cpu %s, memory %s and storage %s",
+				workspace.Spec.Budget.Monthly, workspace.Name, computed.CPU, computed.Memory, computed.Disk))
+		}
+		workspace.Status.BudgetResources = computed
+		if err := r.Status().Update(ctx, workspace); err != nil {
+			return environmentv1alpha1.WorkspaceResource{}, err
+		}
+	}
+	if computed == nil {
+		return workspaceResources(workspace), nil
+	}
+	return *computed, nil
+}
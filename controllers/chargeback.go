@@ -0,0 +1,255 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/cron"
+)
+
+// ChargebackReportLabel marks the ConfigMaps holding generated chargeback reports
+const ChargebackReportLabel = "environment.tf.operator.com/chargeback-report"
+
+// DefaultChargebackRetention is the number of most recent chargeback report ConfigMaps kept by default
+const DefaultChargebackRetention = 12
+
+// ReportMailer emails generated chargeback reports through an SMTP server
+type ReportMailer struct {
+	// Server is the host:port of the SMTP server
+	Server string
+	From   string
+	To     []string
+}
+
+// Send emails the report as a CSV attachment
+func (m *ReportMailer) Send(subject, filename string, report []byte) error {
+	boundary := fmt.Sprintf("chargeback-%d", time.Now().UnixNano())
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\n", m.From, strings.Join(m.To, ", "), subject)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n", boundary)
+	fmt.Fprintf(&msg, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", boundary, subject)
+	fmt.Fprintf(&msg, "--%s\r\nContent-Type: text/csv; charset=utf-8\r\nContent-Disposition: attachment; filename=%q\r\n\r\n", boundary, filename)
+	msg.Write(report)
+	fmt.Fprintf(&msg, "\r\n--%s--\r\n", boundary)
+	return smtp.SendMail(m.Server, nil, m.From, m.To, msg.Bytes())
+}
+
+// ChargebackReporter generates a chargeback report of all workspaces on a cron schedule.
+// Every report covers the window between the previous and the current activation of the schedule.
+type ChargebackReporter struct {
+	client.Client
+
+	// Schedule is the cron schedule reports are generated on
+	Schedule *cron.Schedule
+
+	// Namespace is the namespace the report ConfigMaps are created in
+	Namespace string
+
+	// CostProvider reports the spend of the workspace namespaces.
+	// Reports only contain quota usage when it is nil.
+	CostProvider CostProvider
+
+	// Mailer emails every generated report. Reports are not emailed when it is nil.
+	Mailer *ReportMailer
+
+	// Filter scopes the reports to a subset of the Workspaces.
+	// Reports cover all the Workspaces when it is nil.
+	Filter *WorkspaceFilter
+
+	// Retention is the number of most recent report ConfigMaps kept, the older ones are deleted
+	// once a report is generated. All the reports are kept when it is 0.
+	Retention int
+}
+
+// ValidateChargebackSchedule returns an error when the schedule never activates, e.g. on February 30th,
+// so that it is rejected at startup instead of stopping the reporter
+func ValidateChargebackSchedule(schedule *cron.Schedule, now time.Time) error {
+	if schedule.Next(now).IsZero() {
+		return fmt.Errorf("chargeback schedule never activates")
+	}
+	return nil
+}
+
+// Start runs the reporter until the context is cancelled. A schedule which stops activating only stops
+// the reporter, not the manager.
+func (c *ChargebackReporter) Start(ctx context.Context) error {
+	reporterLog := ctrl.Log.WithName("chargeback")
+	for {
+		next := c.Schedule.Next(time.Now())
+		if next.IsZero() {
+			reporterLog.Error(nil, "Stopping chargeback reports, the schedule no longer activates")
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+		start := c.Schedule.Prev(next.Add(-time.Minute))
+		if err := c.report(ctx, start, next); err != nil {
+			reporterLog.Error(err, fmt.Sprintf("Failed to generate chargeback report for %s", next.Format(time.RFC3339)))
+		}
+		if err := c.prune(ctx); err != nil {
+			reporterLog.Error(err, "Failed to delete expired chargeback reports")
+		}
+	}
+}
+
+// prune deletes the report ConfigMaps older than the Retention most recent ones
+func (c *ChargebackReporter) prune(ctx context.Context) error {
+	if c.Retention <= 0 {
+		return nil
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps, client.InNamespace(c.Namespace), client.MatchingLabels{ChargebackReportLabel: "true"}); err != nil {
+		return err
+	}
+	for _, configMap := range expiredReports(configMaps.Items, c.Retention) {
+		ctrl.Log.WithName("chargeback").Info(fmt.Sprintf("Deleting expired chargeback report ConfigMap.Name %s", configMap.Name))
+		if err := c.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// expiredReports returns the report ConfigMaps older than the retention most recent ones.
+// The names of the reports end with the end of their window, so that they sort by age.
+func expiredReports(configMaps []corev1.ConfigMap, retention int) []*corev1.ConfigMap {
+	if len(configMaps) <= retention {
+		return nil
+	}
+	reports := make([]*corev1.ConfigMap, 0, len(configMaps))
+	for i := range configMaps {
+		reports = append(reports, &configMaps[i])
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Name < reports[j].Name
+	})
+	return reports[:len(reports)-retention]
+}
+
+// NeedLeaderElection makes sure that only the leader generates reports
+func (c *ChargebackReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// report aggregates the usage and cost of every workspace between start and end into a ConfigMap
+func (c *ChargebackReporter) report(ctx context.Context, start, end time.Time) error {
+	reporterLog := ctrl.Log.WithName("chargeback")
+
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := c.List(ctx, workspaces); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"workspace", "namespace", "owner", "cpu_hard", "cpu_used", "memory_hard", "memory_used", "storage_hard", "storage_used", "cost"}); err != nil {
+		return err
+	}
+	window := fmt.Sprintf("%s,%s", start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+	for i := range workspaces.Items {
+		workspace := &workspaces.Items[i]
+		if !c.Filter.Matches(workspace) {
+			continue
+		}
+		resourceQuota := &corev1.ResourceQuota{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-quota", workspace.Spec.Name)}, resourceQuota)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		quantity := func(list corev1.ResourceList, name corev1.ResourceName) string {
+			if q, ok := list[name]; ok {
+				return q.String()
+			}
+			return ""
+		}
+		cost := ""
+		if c.CostProvider != nil {
+			value, err := c.CostProvider.NamespaceCost(ctx, workspace.Spec.Name, window)
+			if err != nil {
+				reporterLog.Error(err, fmt.Sprintf("Failed to get cost of Workspace %s", workspace.Name))
+			} else {
+				cost = strconv.FormatFloat(value, 'f', 2, 64)
+			}
+		}
+		if err := w.Write([]string{
+			workspace.Name,
+			workspace.Spec.Name,
+			strings.Join(roleUsers(workspace, "admin"), " "),
+			quantity(resourceQuota.Status.Hard, corev1.ResourceCPU),
+			quantity(resourceQuota.Status.Used, corev1.ResourceCPU),
+			quantity(resourceQuota.Status.Hard, corev1.ResourceMemory),
+			quantity(resourceQuota.Status.Used, corev1.ResourceMemory),
+			quantity(resourceQuota.Status.Hard, corev1.ResourceRequestsStorage),
+			quantity(resourceQuota.Status.Used, corev1.ResourceRequestsStorage),
+			cost,
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("chargeback-%s", end.UTC().Format("20060102-1504"))
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: c.Namespace,
+			Labels:    map[string]string{ChargebackReportLabel: "true"},
+			Annotations: map[string]string{
+				"environment.tf.operator.com/report-start": start.UTC().Format(time.RFC3339),
+				"environment.tf.operator.com/report-end":   end.UTC().Format(time.RFC3339),
+			},
+		},
+		Data: map[string]string{
+			"report.csv": buf.String(),
+		},
+	}
+	reporterLog.Info(fmt.Sprintf("Creating chargeback report ConfigMap.Name %s", name))
+	if err := c.Create(ctx, configMap); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	if c.Mailer != nil {
+		subject := fmt.Sprintf("Workspace chargeback report %s - %s", start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+		if err := c.Mailer.Send(subject, name+".csv", buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to email chargeback report: %w", err)
+		}
+	}
+	return nil
+}
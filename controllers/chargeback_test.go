@@ -0,0 +1,81 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/dunefro/workspace-operator/internal/cron"
+)
+
+func TestExpiredReports(t *testing.T) {
+	reports := func(names ...string) []corev1.ConfigMap {
+		configMaps := make([]corev1.ConfigMap, 0, len(names))
+		for _, name := range names {
+			configMaps = append(configMaps, corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+		return configMaps
+	}
+	tests := []struct {
+		name      string
+		reports   []corev1.ConfigMap
+		retention int
+		want      []string
+	}{
+		{name: "no report", retention: 2},
+		{name: "within the retention", reports: reports("chargeback-20230501-0000", "chargeback-20230601-0000"), retention: 2},
+		{
+			name:      "older reports",
+			reports:   reports("chargeback-20230601-0000", "chargeback-20230301-0000", "chargeback-20230501-0000", "chargeback-20230401-0000"),
+			retention: 2,
+			want:      []string{"chargeback-20230301-0000", "chargeback-20230401-0000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, configMap := range expiredReports(tt.reports, tt.retention) {
+				got = append(got, configMap.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expiredReports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateChargebackSchedule(t *testing.T) {
+	now := time.Date(2023, time.June, 7, 12, 0, 0, 0, time.UTC)
+	for spec, valid := range map[string]bool{
+		"0 0 1 * *":  true,
+		"0 0 29 2 *": true,
+		"0 0 30 2 *": false,
+	} {
+		schedule, err := cron.Parse(spec)
+		if err != nil {
+			t.Fatalf("cron.Parse(%q): %v", spec, err)
+		}
+		if err := ValidateChargebackSchedule(schedule, now); (err == nil) != valid {
+			t.Errorf("ValidateChargebackSchedule(%q) = %v, want valid %t", spec, err, valid)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+const (
+	// DefaultReconcileTimeout bounds a single reconciliation of a workspace
+	DefaultReconcileTimeout = 2 * time.Minute
+
+	// DefaultCircuitBreakerThreshold is the number of consecutive failed reconciliations of a workspace
+	// after which its circuit opens
+	DefaultCircuitBreakerThreshold = 5
+
+	// DefaultCircuitBreakerMaxBackoff caps the time a workspace whose circuit is open is left alone
+	DefaultCircuitBreakerMaxBackoff = time.Hour
+
+	// circuitBreakerBaseBackoff is the time a workspace is left alone when its circuit opens,
+	// doubled on every further failure
+	circuitBreakerBaseBackoff = time.Minute
+)
+
+// circuitOpenError is the error of the reconciliations of a workspace whose circuit is open,
+// reported with the CircuitOpen reason of the Stalled condition
+type circuitOpenError struct {
+	failures int
+	backoff  time.Duration
+	err      error
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("Reconciliation failed %d consecutive times, retrying in %s: %s", e.failures, e.backoff, e.err)
+}
+
+func (e *circuitOpenError) Unwrap() error {
+	return e.err
+}
+
+// workspaceFailures are the consecutive failed reconciliations of a spec of a workspace
+type workspaceFailures struct {
+	count      int
+	generation int64
+	openUntil  time.Time
+}
+
+// circuitBreaker counts the consecutive failed reconciliations of the workspaces, so that a broken workspace
+// is backed off instead of being retried by the rate limiter of the work queue along with the healthy ones
+type circuitBreaker struct {
+	mu       sync.Mutex
+	failures map[string]*workspaceFailures
+}
+
+// open returns the time left before the next reconciliation of the workspace when its circuit is open.
+// A change of the spec of the workspace closes its circuit, as the new spec may fix the failure.
+func (c *circuitBreaker) open(workspace *environmentv1alpha1.Workspace, now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	failures, ok := c.failures[workspace.Name]
+	if !ok || failures.generation != workspace.Generation || !now.Before(failures.openUntil) {
+		return 0
+	}
+	return failures.openUntil.Sub(now)
+}
+
+// record records the outcome of a reconciliation of the workspace. It returns the error to report,
+// a circuitOpenError once the workspace failed threshold consecutive times.
+func (c *circuitBreaker) record(workspace *environmentv1alpha1.Workspace, err error, threshold int, maxBackoff time.Duration, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.failures, workspace.Name)
+		return nil
+	}
+	if threshold <= 0 {
+		return err
+	}
+	if c.failures == nil {
+		c.failures = map[string]*workspaceFailures{}
+	}
+	failures, ok := c.failures[workspace.Name]
+	if !ok || failures.generation != workspace.Generation {
+		failures = &workspaceFailures{generation: workspace.Generation}
+		c.failures[workspace.Name] = failures
+	}
+	failures.count++
+	if failures.count < threshold {
+		return err
+	}
+	backoff := circuitBreakerBaseBackoff
+	for i := threshold; i < failures.count && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	failures.openUntil = now.Add(backoff)
+	return &circuitOpenError{failures: failures.count, backoff: backoff, err: err}
+}
+
+// forget drops the failures of a deleted workspace
+func (c *circuitBreaker) forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.failures, name)
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// ConditionConflicted is raised when the target namespace of the workspace is managed by another controller
+// or claimed by an older Workspace
+const ConditionConflicted = "Conflicted"
+
+// WorkspaceNamespaceIndex indexes the Workspaces by the namespaces they claim
+const WorkspaceNamespaceIndex = "workspace.namespaces"
+
+const (
+	// hncSubnamespaceAnnotation marks the subnamespaces created by the Hierarchical Namespace Controller
+	hncSubnamespaceAnnotation = "hnc.x-k8s.io/subnamespace-of"
+
+	// hncDepthLabelSuffix is the suffix of the labels HNC sets on a namespace for itself and each of its ancestors
+	hncDepthLabelSuffix = ".tree.hnc.x-k8s.io/depth"
+
+	// capsuleTenantLabel is set by Capsule on the namespaces of a Tenant
+	capsuleTenantLabel = "capsule.clastix.io/tenant"
+
+	// kioskAccountLabel is set by kiosk on the namespaces of an Account
+	kioskAccountLabel = "kiosk.sh/account"
+
+	// helmReleaseAnnotation is set by Helm on the resources of a release
+	helmReleaseAnnotation = "meta.helm.sh/release-name"
+)
+
+// namespaceConflict returns why the namespace is managed by another controller than the workspace,
+// or an empty string when the workspace can manage it
+func namespaceConflict(workspace *environmentv1alpha1.Workspace, namespace *corev1.Namespace) string {
+	if owner := metav1.GetControllerOf(namespace); owner != nil && owner.UID != workspace.UID {
+		return fmt.Sprintf("Namespace %s is controlled by %s %s", namespace.Name, owner.Kind, owner.Name)
+	}
+	if parent, ok := namespace.Annotations[hncSubnamespaceAnnotation]; ok {
+		return fmt.Sprintf("Namespace %s is a subnamespace of %s managed by the Hierarchical Namespace Controller", namespace.Name, parent)
+	}
+	for label := range namespace.Labels {
+		if ancestor := strings.TrimSuffix(label, hncDepthLabelSuffix); ancestor != label && ancestor != namespace.Name {
+			return fmt.Sprintf("Namespace %s is a descendant of %s in a Hierarchical Namespace Controller hierarchy", namespace.Name, ancestor)
+		}
+	}
+	if tenant, ok := namespace.Labels[capsuleTenantLabel]; ok {
+		return fmt.Sprintf("Namespace %s belongs to the Capsule tenant %s", namespace.Name, tenant)
+	}
+	if account, ok := namespace.Labels[kioskAccountLabel]; ok {
+		return fmt.Sprintf("Namespace %s belongs to the kiosk account %s", namespace.Name, account)
+	}
+	if release, ok := namespace.Annotations[helmReleaseAnnotation]; ok {
+		return fmt.Sprintf("Namespace %s is managed by the Helm release %s", namespace.Name, release)
+	}
+	return ""
+}
+
+// checkNamespaceConflict sets the Conflicted condition of the workspace and returns an error
+// when its namespace is managed by another controller, so that the operator never co-manages it.
+// The condition is written with the rest of the status by reconcileStatus.
+func checkNamespaceConflict(workspace *environmentv1alpha1.Workspace, namespace *corev1.Namespace) error {
+	conflict := namespaceConflict(workspace, namespace)
+	if conflict == "" {
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionConflicted)
+		return nil
+	}
+	meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+		Type:               ConditionConflicted,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             "NamespaceManagedElsewhere",
+		Message:            conflict,
+	})
+	return stall(fmt.Errorf("%s", conflict))
+}
+
+// WorkspaceNamespaces returns the namespaces claimed by a Workspace, spec.name and the namespace it is renamed from
+func WorkspaceNamespaces(obj client.Object) []string {
+	workspace, ok := obj.(*environmentv1alpha1.Workspace)
+	if !ok || workspace.Spec.Name == "" {
+		return nil
+	}
+	namespaces := []string{workspace.Spec.Name}
+	if rename := workspace.Status.Rename; rename != nil && rename.From != workspace.Spec.Name {
+		namespaces = append(namespaces, rename.From)
+	}
+	return namespaces
+}
+
+// IndexWorkspaceNamespaces registers WorkspaceNamespaceIndex in the cache of the manager
+func IndexWorkspaceNamespaces(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &environmentv1alpha1.Workspace{}, WorkspaceNamespaceIndex, WorkspaceNamespaces)
+}
+
+// claimingWorkspaces returns the other Workspaces claiming the target namespace of the workspace
+func claimingWorkspaces(ctx context.Context, c client.Reader, workspace *environmentv1alpha1.Workspace) ([]environmentv1alpha1.Workspace, error) {
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := c.List(ctx, workspaces, client.MatchingFields{WorkspaceNamespaceIndex: workspace.Spec.Name}); err != nil {
+		return nil, err
+	}
+	var claiming []environmentv1alpha1.Workspace
+	for _, other := range workspaces.Items {
+		if other.Name == workspace.Name {
+			continue
+		}
+		for _, namespace := range WorkspaceNamespaces(&other) {
+			if namespace == workspace.Spec.Name {
+				claiming = append(claiming, other)
+				break
+			}
+		}
+	}
+	return claiming, nil
+}
+
+// checkDuplicateWorkspace sets the Conflicted condition of the workspace and returns an error when its target
+// namespace is claimed by an older Workspace, so that two Workspaces never co-manage a namespace.
+// The workspace controlling the namespace keeps it, whatever its age.
+// The condition is written with the rest of the status by reconcileStatus.
+func (r *WorkspaceReconciler) checkDuplicateWorkspace(ctx context.Context, workspace *environmentv1alpha1.Workspace, namespace *corev1.Namespace) error {
+	if namespace != nil && metav1.IsControlledBy(namespace, workspace) {
+		return nil
+	}
+	claiming, err := claimingWorkspaces(ctx, r, workspace)
+	if err != nil {
+		return err
+	}
+	for _, other := range claiming {
+		older := other.CreationTimestamp.Before(&workspace.CreationTimestamp) ||
+			(other.CreationTimestamp.Equal(&workspace.CreationTimestamp) && other.Name < workspace.Name)
+		if !older {
+			continue
+		}
+		conflict := fmt.Sprintf("Namespace %s is already claimed by Workspace %s", workspace.Spec.Name, other.Name)
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionConflicted,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "DuplicateNamespace",
+			Message:            conflict,
+		})
+		return stall(fmt.Errorf("%s", conflict))
+	}
+	return nil
+}
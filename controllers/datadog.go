@@ -0,0 +1,292 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+const (
+	// DefaultDatadogSite is the Datadog site the metrics and events are sent to when Site is not set
+	DefaultDatadogSite = "datadoghq.com"
+
+	// DefaultDatadogInterval is the time between two submissions of the workspace metrics when Interval is not set
+	DefaultDatadogInterval = time.Minute
+
+	// datadogGauge is the type of the gauge series of the Datadog metrics API
+	datadogGauge = 3
+
+	// datadogEventTimeout bounds the submission of an event, sent in the background of the reconciliation
+	datadogEventTimeout = 10 * time.Second
+)
+
+// DatadogPublisher sends the quota utilization of the workspaces as Datadog metrics on every Interval,
+// and the Kubernetes events of the workspaces as Datadog events when wrapped around the event recorder
+// with NewDatadogRecorder. The metrics and events are tagged with the workspace, its namespace, its class and its team.
+type DatadogPublisher struct {
+	// Client reads the workspaces and their ResourceQuotas
+	Client client.Reader
+
+	// APIKey is the Datadog API key
+	APIKey string
+
+	// Site is the Datadog site, e.g. datadoghq.eu, DefaultDatadogSite when empty
+	Site string
+
+	// Interval is the time between two submissions of the metrics, DefaultDatadogInterval when 0
+	Interval time.Duration
+
+	// Tags are added to all the metrics and events, e.g. env:prod
+	Tags []string
+
+	// TagLabels are the labels of the workspaces added as tags to their metrics and events, e.g. cost-center
+	TagLabels []string
+
+	// Filter scopes the metrics to the workspaces of the operator instance
+	Filter *WorkspaceFilter
+
+	client *http.Client
+}
+
+// datadogSeries is a series of the Datadog v2 metrics API
+type datadogSeries struct {
+	Metric string         `json:"metric"`
+	Type   int            `json:"type"`
+	Points []datadogPoint `json:"points"`
+	Tags   []string       `json:"tags,omitempty"`
+}
+
+type datadogPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// datadogEvent is an event of the Datadog v1 events API
+type datadogEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	AlertType      string   `json:"alert_type"`
+	AggregationKey string   `json:"aggregation_key,omitempty"`
+	SourceTypeName string   `json:"source_type_name"`
+	Tags           []string `json:"tags,omitempty"`
+}
+
+// Start sends the metrics of the workspaces until the context is cancelled
+func (d *DatadogPublisher) Start(ctx context.Context) error {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultDatadogInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := d.publishMetrics(ctx); err != nil {
+			ctrl.Log.WithName("datadog").Error(err, "Failed to send workspace metrics to Datadog")
+		}
+	}
+}
+
+// NeedLeaderElection makes sure that only the leader sends the metrics
+func (d *DatadogPublisher) NeedLeaderElection() bool {
+	return true
+}
+
+// publishMetrics sends the used and hard quantities of the ResourceQuota of every workspace and their utilization in percent
+func (d *DatadogPublisher) publishMetrics(ctx context.Context) error {
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := d.Client.List(ctx, workspaces); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	var series []datadogSeries
+	gauge := func(metric string, value float64, tags []string) {
+		series = append(series, datadogSeries{Metric: metric, Type: datadogGauge, Points: []datadogPoint{{Timestamp: now, Value: value}}, Tags: tags})
+	}
+	for i := range workspaces.Items {
+		workspace := &workspaces.Items[i]
+		if !d.Filter.Matches(workspace) || !workspace.DeletionTimestamp.IsZero() {
+			continue
+		}
+		tags := d.workspaceTags(workspace)
+		gauge("workspace.ready", boolValue(workspace.Status.Phase == environmentv1alpha1.WorkspaceReady), tags)
+
+		// Workspaces in Monitor quota mode have no ResourceQuota
+		resourceQuota := &corev1.ResourceQuota{}
+		key := types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-quota", workspace.Spec.Name)}
+		if err := d.Client.Get(ctx, key, resourceQuota); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		for name, hard := range resourceQuota.Status.Hard {
+			used, ok := resourceQuota.Status.Used[name]
+			if !ok {
+				continue
+			}
+			resourceTags := append(append([]string{}, tags...), "resource:"+string(name))
+			gauge("workspace.quota.used", used.AsApproximateFloat64(), resourceTags)
+			gauge("workspace.quota.hard", hard.AsApproximateFloat64(), resourceTags)
+			if !hard.IsZero() {
+				gauge("workspace.quota.utilization", used.AsApproximateFloat64()*100/hard.AsApproximateFloat64(), resourceTags)
+			}
+		}
+	}
+	if len(series) == 0 {
+		return nil
+	}
+	return d.post(ctx, "/api/v2/series", map[string]interface{}{"series": series})
+}
+
+// Event sends an event of the workspace to Datadog
+func (d *DatadogPublisher) Event(ctx context.Context, workspace *environmentv1alpha1.Workspace, eventType, reason, message string) error {
+	alertType := "info"
+	if eventType == corev1.EventTypeWarning {
+		alertType = "warning"
+	}
+	return d.post(ctx, "/api/v1/events", datadogEvent{
+		Title:          fmt.Sprintf("Workspace %s: %s", workspace.Name, reason),
+		Text:           message,
+		AlertType:      alertType,
+		AggregationKey: "workspace:" + workspace.Name,
+		SourceTypeName: "kubernetes",
+		Tags:           append(d.workspaceTags(workspace), "reason:"+reason),
+	})
+}
+
+// workspaceTags returns the tags of the metrics and events of the workspace
+func (d *DatadogPublisher) workspaceTags(workspace *environmentv1alpha1.Workspace) []string {
+	tags := append([]string{}, d.Tags...)
+	tags = append(tags, "workspace:"+workspace.Name, "kube_namespace:"+workspace.Spec.Name)
+	if workspace.Spec.ClassName != "" {
+		tags = append(tags, "workspace_class:"+workspace.Spec.ClassName)
+	}
+	if workspace.Spec.Owner != nil {
+		tags = append(tags, "team:"+datadogTagValue(workspace.Spec.Owner.Name))
+	}
+	for _, label := range d.TagLabels {
+		if value, ok := workspace.Labels[label]; ok {
+			tags = append(tags, datadogTagValue(label)+":"+datadogTagValue(value))
+		}
+	}
+	return tags
+}
+
+// datadogTagValue normalizes a value into a Datadog tag, lower case without spaces
+func datadogTagValue(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), "_"))
+}
+
+// boolValue returns 1 for true and 0 for false
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// post sends the JSON body to the path of the API of the Datadog site
+func (d *DatadogPublisher) post(ctx context.Context, path string, body interface{}) error {
+	site := d.Site
+	if site == "" {
+		site = DefaultDatadogSite
+	}
+	if d.client == nil {
+		d.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api."+site+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", d.APIKey)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Datadog returned %s for %s", resp.Status, path)
+	}
+	return nil
+}
+
+// NewDatadogRecorder returns an event recorder sending the events of the workspaces to Datadog
+// in addition to recording them with recorder
+func NewDatadogRecorder(recorder record.EventRecorder, publisher *DatadogPublisher) record.EventRecorder {
+	return &datadogRecorder{EventRecorder: recorder, publisher: publisher}
+}
+
+type datadogRecorder struct {
+	record.EventRecorder
+	publisher *DatadogPublisher
+}
+
+func (r *datadogRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	r.EventRecorder.Event(object, eventType, reason, message)
+	r.send(object, eventType, reason, message)
+}
+
+func (r *datadogRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.Eventf(object, eventType, reason, messageFmt, args...)
+	r.send(object, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *datadogRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, annotations, eventType, reason, messageFmt, args...)
+	r.send(object, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// send sends the event of a workspace to Datadog in the background, so that the reconciliation is never slowed down
+func (r *datadogRecorder) send(object runtime.Object, eventType, reason, message string) {
+	workspace, ok := object.(*environmentv1alpha1.Workspace)
+	if !ok {
+		return
+	}
+	workspace = workspace.DeepCopy()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), datadogEventTimeout)
+		defer cancel()
+		if err := r.publisher.Event(ctx, workspace, eventType, reason, message); err != nil {
+			ctrl.Log.WithName("datadog").Error(err, fmt.Sprintf("Failed to send %s event for Workspace %s to Datadog", reason, workspace.Name))
+		}
+	}()
+}
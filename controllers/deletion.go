@@ -0,0 +1,344 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;update;patch
+
+const (
+	// WorkspaceFinalizer holds the deletion of a Workspace until its deletion grace period is over and its
+	// namespace is deleted, orphaned or retained with its deletion policy
+	WorkspaceFinalizer = "environment.tf.operator.com/finalizer"
+
+	// ConditionTerminating is True while a deleted Workspace is frozen during its deletion grace period
+	ConditionTerminating = "Terminating"
+
+	// ReplicasBeforeFreezeAnnotation records the replicas of a workload before it was scaled down
+	ReplicasBeforeFreezeAnnotation = "environment.tf.operator.com/replicas-before-freeze"
+
+	// SuspendedBeforeFreezeAnnotation records that a CronJob was suspended by the operator
+	SuspendedBeforeFreezeAnnotation = "environment.tf.operator.com/suspended-by-freeze"
+)
+
+// reconcileFinalizer adds the finalizer to the workspaces, so that their namespace is handled with their deletion
+// policy instead of being left to the garbage collector
+func (r *WorkspaceReconciler) reconcileFinalizer(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	if controllerutil.ContainsFinalizer(workspace, WorkspaceFinalizer) {
+		return nil
+	}
+	controllerutil.AddFinalizer(workspace, WorkspaceFinalizer)
+	return r.Update(ctx, workspace)
+}
+
+// deletionPolicy returns the deletion policy of the workspace, Delete when it is not set
+func deletionPolicy(workspace *environmentv1alpha1.Workspace) environmentv1alpha1.WorkspaceDeletionPolicy {
+	if workspace.Spec.DeletionPolicy == "" {
+		return environmentv1alpha1.DeletionPolicyDelete
+	}
+	return workspace.Spec.DeletionPolicy
+}
+
+// validateDeletionPolicy checks that a deletion propagation is only set along with the Delete deletion policy
+func validateDeletionPolicy(workspace *environmentv1alpha1.Workspace) error {
+	if workspace.Spec.DeletionPropagation != "" && deletionPolicy(workspace) != environmentv1alpha1.DeletionPolicyDelete {
+		return fmt.Errorf("spec.deletionPropagation can not be set with the %s deletion policy, the namespace is not deleted", workspace.Spec.DeletionPolicy)
+	}
+	return nil
+}
+
+// reconcileDelete freezes a deleted workspace for its deletion grace period. Once the grace period is over,
+// the namespace is deleted with the deletion propagation of the workspace, or released with the Orphan and Retain
+// deletion policies, and the finalizer is released, which lets the garbage collector delete the other owned resources.
+func (r *WorkspaceReconciler) reconcileDelete(ctx context.Context, workspace *environmentv1alpha1.Workspace) (ctrl.Result, error) {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+	if !controllerutil.ContainsFinalizer(workspace, WorkspaceFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// A conflicted workspace never managed its namespace, so there is nothing to freeze
+	conflicted := meta.IsStatusConditionTrue(workspace.Status.Conditions, ConditionConflicted)
+	gracePeriod := time.Duration(0)
+	if workspace.Spec.DeletionGracePeriod != nil && !conflicted {
+		gracePeriod = workspace.Spec.DeletionGracePeriod.Duration
+	}
+	deleteAt := workspace.DeletionTimestamp.Add(gracePeriod)
+	if remaining := time.Until(deleteAt); remaining > 0 && restoreRequested(workspace) {
+		if err := r.restoreWorkspace(ctx, workspace); err != nil {
+			reconcilerLog.Error(err, "Failed to restore Workspace")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	if remaining := time.Until(deleteAt); remaining > 0 {
+		if !meta.IsStatusConditionTrue(workspace.Status.Conditions, ConditionTerminating) {
+			reconcilerLog.Info(fmt.Sprintf("Freezing Workspace %s until %s", workspace.Name, deleteAt.UTC().Format(time.RFC3339)))
+			if err := r.freezeWorkspace(ctx, workspace); err != nil {
+				reconcilerLog.Error(err, "Failed to freeze Workspace")
+				return ctrl.Result{}, err
+			}
+			meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+				Type:               ConditionTerminating,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: workspace.Generation,
+				Reason:             "DeletionGracePeriod",
+				Message:            fmt.Sprintf("Workspace is frozen and Namespace %s will be deleted at %s", workspace.Spec.Name, deleteAt.UTC().Format(time.RFC3339)),
+			})
+			setPhase(workspace, environmentv1alpha1.WorkspaceTerminating)
+			if err := r.Status().Update(ctx, workspace); err != nil {
+				reconcilerLog.Error(err, "Failed to update Terminating condition for Workspace")
+				return ctrl.Result{}, err
+			}
+			if r.Recorder != nil {
+				r.Recorder.Event(workspace, "Warning", "DeletionGracePeriod",
+					fmt.Sprintf("Workspace is frozen and will be deleted at %s", deleteAt.UTC().Format(time.RFC3339)))
+			}
+		}
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	// Keep the namespace without its ownership with the Orphan and Retain deletion policies
+	if deletionPolicy(workspace) != environmentv1alpha1.DeletionPolicyDelete && !conflicted && !r.NamespacedOnly {
+		if err := r.releaseNamespace(ctx, workspace); err != nil {
+			reconcilerLog.Error(err, "Failed to release Namespace of Workspace")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Tear the namespace down with the deletion propagation of the workspace
+	if deletionPolicy(workspace) == environmentv1alpha1.DeletionPolicyDelete && !conflicted && !r.NamespacedOnly {
+		namespace, deleted, err := r.deleteNamespace(ctx, workspace)
+		if err != nil {
+			reconcilerLog.Error(err, "Failed to delete Namespace of Workspace")
+			return ctrl.Result{}, err
+		}
+		if !deleted {
+			// Report a namespace stuck terminating instead of waiting for it silently
+			if namespace != nil && r.checkNamespaceTermination(workspace, namespace) {
+				if err := r.Status().Update(ctx, workspace); err != nil {
+					reconcilerLog.Error(err, "Failed to update TerminationBlocked condition for Workspace")
+					return ctrl.Result{}, err
+				}
+			}
+			// Strip the known-safe finalizers blocking the namespace when a force cleanup was requested
+			if namespace != nil && forceCleanupRequested(workspace) &&
+				meta.IsStatusConditionTrue(workspace.Status.Conditions, ConditionTerminationBlocked) {
+				if err := r.forceCleanup(ctx, workspace, namespace); err != nil {
+					reconcilerLog.Error(err, "Failed to force the cleanup of Namespace of Workspace")
+					return ctrl.Result{}, err
+				}
+			}
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		}
+	}
+
+	// The namespace name may be reused by another workspace, which must not inherit the observability tenant
+	if err := r.unregisterObservabilityTenant(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to unregister Namespace of Workspace from its observability tenant")
+		return ctrl.Result{}, err
+	}
+
+	reconcilerLog.Info(fmt.Sprintf("Deletion grace period of Workspace %s is over, releasing the finalizer", workspace.Name))
+	controllerutil.RemoveFinalizer(workspace, WorkspaceFinalizer)
+	if err := r.Update(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to remove finalizer from Workspace")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// deleteNamespace deletes the namespace of the workspace with its deletion propagation, Background by default,
+// and returns it while it exists. It reports whether the finalizer can be released: with Foreground once
+// the namespace is gone, with Background as soon as its deletion is requested.
+func (r *WorkspaceReconciler) deleteNamespace(ctx context.Context, workspace *environmentv1alpha1.Workspace) (*corev1.Namespace, bool, error) {
+	namespace := &corev1.Namespace{}
+	err := r.Get(ctx, types.NamespacedName{Name: workspace.Spec.Name}, namespace)
+	if apierrors.IsNotFound(err) {
+		return nil, true, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	// Never delete a namespace the workspace does not control
+	if !metav1.IsControlledBy(namespace, workspace) {
+		return nil, true, nil
+	}
+	propagation := workspace.Spec.DeletionPropagation
+	if propagation == "" {
+		propagation = metav1.DeletePropagationBackground
+	}
+	if namespace.DeletionTimestamp.IsZero() {
+		ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Deleting Namespace Namespace.Name %s with %s propagation", namespace.Name, propagation))
+		if err := r.Delete(ctx, namespace, client.PropagationPolicy(propagation)); err != nil && !apierrors.IsNotFound(err) {
+			return nil, false, err
+		}
+	}
+	return namespace, propagation == metav1.DeletePropagationBackground, nil
+}
+
+// releaseNamespace removes the owner reference of the workspace from its namespace, so that the garbage collector
+// keeps the namespace when the Workspace is removed. With the Retain deletion policy the ownership labels
+// of the operator are stripped as well.
+func (r *WorkspaceReconciler) releaseNamespace(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	namespace := &corev1.Namespace{}
+	err := r.Get(ctx, types.NamespacedName{Name: workspace.Spec.Name}, namespace)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// Never release a namespace the workspace does not control
+	if !metav1.IsControlledBy(namespace, workspace) {
+		return nil
+	}
+	original := namespace.DeepCopy()
+	var ownerReferences []metav1.OwnerReference
+	for _, ownerReference := range namespace.OwnerReferences {
+		if ownerReference.UID != workspace.UID {
+			ownerReferences = append(ownerReferences, ownerReference)
+		}
+	}
+	namespace.OwnerReferences = ownerReferences
+	if deletionPolicy(workspace) == environmentv1alpha1.DeletionPolicyRetain {
+		delete(namespace.Labels, ManagedByLabel)
+		delete(namespace.Labels, WorkspaceLabel)
+	}
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Releasing Namespace Namespace.Name %s with the %s deletion policy", namespace.Name, deletionPolicy(workspace)))
+	return r.Patch(ctx, namespace, client.MergeFrom(original))
+}
+
+// freezeWorkspace revokes the RBAC of the workspace and scales its workloads down
+func (r *WorkspaceReconciler) freezeWorkspace(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	// 1. revoke the access of the workspace users
+	for _, role := range []string{"admin", "editor", "viewer"} {
+		roleBinding := &rbacv1.RoleBinding{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-%s-rb", workspace.Spec.Name, role)}, roleBinding)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := r.Delete(ctx, roleBinding); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		for i := range roleBinding.Subjects {
+			r.audit(ctx, workspace, AuditActionSubjectRemoved, "RoleBinding", roleBinding.Name, &roleBinding.Subjects[i], nil)
+		}
+	}
+
+	// and to the shared namespaces of its grants
+	grantBindings := &rbacv1.RoleBindingList{}
+	if err := r.List(ctx, grantBindings, client.MatchingLabels{WorkspaceLabel: workspace.Name}, client.HasLabels{GrantLabel}); err != nil {
+		return err
+	}
+	for i := range grantBindings.Items {
+		if !metav1.IsControlledBy(&grantBindings.Items[i], workspace) {
+			continue
+		}
+		if err := r.revokeGrantRoleBinding(ctx, workspace, &grantBindings.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	// 2. scale the workloads down
+	return r.scaleDownWorkloads(ctx, workspace)
+}
+
+// scaleDownWorkloads scales the deployments and statefulsets of the workspace down, remembering their replicas,
+// and suspends its cronjobs. thawWorkspace scales them back up.
+func (r *WorkspaceReconciler) scaleDownWorkloads(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	// 1. scale the deployments and statefulsets down, remembering their replicas
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return err
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas == 0 {
+			continue
+		}
+		setAnnotation(&deployment.ObjectMeta, ReplicasBeforeFreezeAnnotation, strconv.Itoa(int(*deployment.Spec.Replicas)))
+		zero := int32(0)
+		deployment.Spec.Replicas = &zero
+		if err := r.Update(ctx, deployment); err != nil {
+			return err
+		}
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return err
+	}
+	for i := range statefulSets.Items {
+		statefulSet := &statefulSets.Items[i]
+		if statefulSet.Spec.Replicas == nil || *statefulSet.Spec.Replicas == 0 {
+			continue
+		}
+		setAnnotation(&statefulSet.ObjectMeta, ReplicasBeforeFreezeAnnotation, strconv.Itoa(int(*statefulSet.Spec.Replicas)))
+		zero := int32(0)
+		statefulSet.Spec.Replicas = &zero
+		if err := r.Update(ctx, statefulSet); err != nil {
+			return err
+		}
+	}
+
+	// 2. suspend the cronjobs so that no new jobs are started
+	cronJobs := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronJobs, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return err
+	}
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+			continue
+		}
+		setAnnotation(&cronJob.ObjectMeta, SuspendedBeforeFreezeAnnotation, "true")
+		suspend := true
+		cronJob.Spec.Suspend = &suspend
+		if err := r.Update(ctx, cronJob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAnnotation sets an annotation on the object, initializing the annotations if needed
+func setAnnotation(objectMeta *metav1.ObjectMeta, key, value string) {
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	objectMeta.Annotations[key] = value
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// DefaultOperatorUsername is the username of the service account of the operator deployed with config/default
+const DefaultOperatorUsername = "system:serviceaccount:workspace-operator-system:workspace-operator-controller-manager"
+
+// deletionProtectionExemptUsers are the Kubernetes controllers which delete the objects of a namespace on their own,
+// the namespace controller when the namespace terminates and the garbage collector when the Workspace is deleted
+var deletionProtectionExemptUsers = []string{
+	"system:serviceaccount:kube-system:namespace-controller",
+	"system:serviceaccount:kube-system:generic-garbage-collector",
+}
+
+// namespaceTarget is the target of the admission policy protecting the workspace namespace
+var namespaceTarget = admissionPolicyTarget{version: "v1", resource: "namespaces", kind: "Namespace"}
+
+// ownedObjectTarget is the target of the admission policy protecting the objects owned by the workspace,
+// all the namespaced resources of the workspace namespace
+var ownedObjectTarget = admissionPolicyTarget{group: "*", version: "*", resource: "*", scope: "Namespaced"}
+
+// deletionProtectionExempt returns the CEL expression of the requests exempted from the deletion protection,
+// the ones of the operator, of the controllers cleaning up namespaces and of the cluster administrators
+func (r *WorkspaceReconciler) deletionProtectionExempt() string {
+	var users []string
+	for _, user := range append([]string{r.OperatorUsername}, deletionProtectionExemptUsers...) {
+		users = append(users, fmt.Sprintf("%q", user))
+	}
+	return fmt.Sprintf("request.userInfo.username in [%s] || 'system:masters' in request.userInfo.groups", strings.Join(users, ", "))
+}
+
+// reconcileDeletionProtection generates the admission policies preventing the users of the workspace from deleting
+// its namespace and the objects the operator owns in it, so that they are only deleted through the Workspace
+func (r *WorkspaceReconciler) reconcileDeletionProtection(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	var namespacePolicy, objectPolicy *admissionPolicy
+	if r.DeletionProtection {
+		exempt := r.deletionProtectionExempt()
+		namespacePolicy = &admissionPolicy{
+			engine:     "vap",
+			action:     "Enforce",
+			target:     &namespaceTarget,
+			operations: []interface{}{"DELETE"},
+			validations: []interface{}{celValidation(exempt,
+				fmt.Sprintf("Namespace %s is managed by Workspace %s, delete the Workspace instead", workspace.Spec.Name, workspace.Name))},
+		}
+		objectPolicy = &admissionPolicy{
+			engine:     "vap",
+			action:     "Enforce",
+			target:     &ownedObjectTarget,
+			operations: []interface{}{"DELETE"},
+			validations: []interface{}{celValidation(
+				"!has(oldObject.metadata.ownerReferences) || "+
+					"!oldObject.metadata.ownerReferences.exists(o, o.kind == 'Workspace' && o.name == '"+workspace.Name+"' && o.apiVersion.startsWith('"+environmentv1alpha1.GroupVersion.Group+"/')) || "+
+					exempt,
+				fmt.Sprintf("The object is managed by Workspace %s, change the Workspace instead", workspace.Name))},
+		}
+	}
+	if err := r.reconcileAdmissionPolicy(ctx, workspace, fmt.Sprintf("%s-namespace-protection", workspace.Spec.Name), namespacePolicy); err != nil {
+		return err
+	}
+	return r.reconcileAdmissionPolicy(ctx, workspace, fmt.Sprintf("%s-object-protection", workspace.Spec.Name), objectPolicy)
+}
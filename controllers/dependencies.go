@@ -0,0 +1,147 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+const (
+	// ConditionWaiting is True while the workspace waits for the Workspaces of spec.dependsOn to be Ready
+	// before it is provisioned. It is an "abnormal-true" condition removed once they are.
+	ConditionWaiting = "Waiting"
+
+	// WorkspaceDependsOnIndex indexes the Workspaces by the Workspaces of their spec.dependsOn
+	WorkspaceDependsOnIndex = "spec.dependsOn"
+
+	// dependencyRetryInterval is the time after which a waiting workspace checks its dependencies again,
+	// in case the Ready condition of a dependency reconciled by another operator instance is missed
+	dependencyRetryInterval = 30 * time.Second
+)
+
+// WorkspaceDependencies returns the Workspaces of the spec.dependsOn of a Workspace
+func WorkspaceDependencies(obj client.Object) []string {
+	workspace, ok := obj.(*environmentv1alpha1.Workspace)
+	if !ok {
+		return nil
+	}
+	return workspace.Spec.DependsOn
+}
+
+// IndexWorkspaceDependencies registers WorkspaceDependsOnIndex in the cache of the manager
+func IndexWorkspaceDependencies(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &environmentv1alpha1.Workspace{}, WorkspaceDependsOnIndex, WorkspaceDependencies)
+}
+
+// reconcileDependencies reports whether the workspace waits for the Workspaces of its spec.dependsOn to be Ready,
+// in the Waiting condition. Only the provisioning of the workspace waits, a provisioned workspace whose namespace
+// was deleted out-of-band is recreated right away.
+func (r *WorkspaceReconciler) reconcileDependencies(ctx context.Context, workspace *environmentv1alpha1.Workspace) (bool, error) {
+	var blocking []string
+	if !provisioned(workspace.Status.Phase) {
+		for _, name := range workspace.Spec.DependsOn {
+			dependency := &environmentv1alpha1.Workspace{}
+			if err := r.Get(ctx, types.NamespacedName{Name: name}, dependency); apierrors.IsNotFound(err) {
+				blocking = append(blocking, fmt.Sprintf("%s (not found)", name))
+				continue
+			} else if err != nil {
+				return false, err
+			}
+			if !meta.IsStatusConditionTrue(dependency.Status.Conditions, ConditionReady) {
+				phase := string(dependency.Status.Phase)
+				if phase == "" {
+					phase = string(environmentv1alpha1.WorkspacePending)
+				}
+				blocking = append(blocking, fmt.Sprintf("%s (%s)", name, phase))
+			}
+		}
+	}
+
+	previous := meta.FindStatusCondition(workspace.Status.Conditions, ConditionWaiting)
+	if len(blocking) == 0 {
+		if previous == nil {
+			return false, nil
+		}
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionWaiting)
+		if r.Recorder != nil {
+			r.Recorder.Event(workspace, corev1.EventTypeNormal, "DependenciesReady", "The Workspaces the workspace depends on are Ready, provisioning it")
+		}
+		return false, r.Status().Update(ctx, workspace)
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionWaiting,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             "DependenciesNotReady",
+		Message:            fmt.Sprintf("Waiting for Workspaces %s to be Ready", strings.Join(blocking, ", ")),
+	}
+	if previous != nil && previous.Message == condition.Message && previous.ObservedGeneration == condition.ObservedGeneration {
+		return true, nil
+	}
+	meta.SetStatusCondition(&workspace.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return false, err
+	}
+	if r.Recorder != nil && previous == nil {
+		r.Recorder.Event(workspace, corev1.EventTypeNormal, condition.Reason, condition.Message)
+	}
+	return true, nil
+}
+
+// dependentWorkspaces returns the requests of the Workspaces depending on a Workspace, so that they are
+// provisioned as soon as it is Ready
+func (r *WorkspaceReconciler) dependentWorkspaces(obj client.Object) []ctrl.Request {
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := r.List(context.Background(), workspaces, client.MatchingFields{WorkspaceDependsOnIndex: obj.GetName()}); err != nil {
+		ctrl.Log.WithName("reconciler").Error(err, fmt.Sprintf("Failed to list the Workspaces depending on Workspace %s", obj.GetName()))
+		return nil
+	}
+	requests := make([]ctrl.Request, 0, len(workspaces.Items))
+	for _, workspace := range workspaces.Items {
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: workspace.Name}})
+	}
+	return requests
+}
+
+// validateDependsOn checks that a workspace depends neither on itself nor twice on the same Workspace
+func validateDependsOn(workspace *environmentv1alpha1.Workspace) error {
+	seen := map[string]bool{}
+	for _, name := range workspace.Spec.DependsOn {
+		switch {
+		case name == workspace.Name:
+			return fmt.Errorf("spec.dependsOn: Workspace %s can not depend on itself", name)
+		case seen[name]:
+			return fmt.Errorf("spec.dependsOn: Workspace %s is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DesiredStateHashAnnotation holds the hash of the desired state the operator last applied to a child
+// resource of a workspace, computed from the spec of the workspace and the templates of the operator
+const DesiredStateHashAnnotation = "environment.tf.operator.com/desired-state-hash"
+
+// desiredStateHash hashes the inputs the desired state of a child resource is built from
+func desiredStateHash(inputs ...interface{}) (string, error) {
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return "", err
+	}
+	return specHash(data), nil
+}
+
+// appliedStates remembers, per workspace, the resourceVersion of the child resources once they were brought
+// to their desired state. A child resource changed since, e.g. edited out-of-band, has another resourceVersion.
+type appliedStates struct {
+	mu       sync.Mutex
+	versions map[string]map[string]string
+}
+
+func appliedStateKey(object client.Object) string {
+	return fmt.Sprintf("%T/%s/%s", object, object.GetNamespace(), object.GetName())
+}
+
+// upToDate reports whether the child resource is known to be in the desired state of hash: it carries the hash
+// and was not changed since the operator last brought it to that state. Its desired state is then neither
+// rebuilt nor compared. The resourceVersions are not persisted, every child resource is compared once
+// after a restart of the operator.
+func (a *appliedStates) upToDate(workspace string, object client.Object, hash string) bool {
+	if object.GetAnnotations()[DesiredStateHashAnnotation] != hash {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	version, ok := a.versions[workspace][appliedStateKey(object)]
+	return ok && version == object.GetResourceVersion()
+}
+
+// applied records the child resource as being in its desired state, once it was compared or patched
+func (a *appliedStates) applied(workspace string, object client.Object) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.versions == nil {
+		a.versions = map[string]map[string]string{}
+	}
+	if a.versions[workspace] == nil {
+		a.versions[workspace] = map[string]string{}
+	}
+	a.versions[workspace][appliedStateKey(object)] = object.GetResourceVersion()
+}
+
+// forget drops the child resources of a workspace, so that they are all compared again
+func (a *appliedStates) forget(workspace string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.versions, workspace)
+}
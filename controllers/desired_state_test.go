@@ -0,0 +1,181 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDesiredStateHash(t *testing.T) {
+	labels := map[string]string{"team": "data"}
+	tests := []struct {
+		name  string
+		a, b  []interface{}
+		equal bool
+	}{
+		{
+			name:  "same inputs",
+			a:     []interface{}{labels, "10Gi"},
+			b:     []interface{}{map[string]string{"team": "data"}, "10Gi"},
+			equal: true,
+		},
+		{
+			name:  "map keys in another order",
+			a:     []interface{}{map[string]string{"a": "1", "b": "2"}},
+			b:     []interface{}{map[string]string{"b": "2", "a": "1"}},
+			equal: true,
+		},
+		{
+			name: "changed input",
+			a:    []interface{}{labels, "10Gi"},
+			b:    []interface{}{labels, "20Gi"},
+		},
+		{
+			name: "inputs in another order",
+			a:    []interface{}{"a", "b"},
+			b:    []interface{}{"b", "a"},
+		},
+		{
+			name: "added input",
+			a:    []interface{}{labels},
+			b:    []interface{}{labels, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := desiredStateHash(tt.a...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := desiredStateHash(tt.b...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (a == b) != tt.equal {
+				t.Errorf("desiredStateHash(%v) = %s, desiredStateHash(%v) = %s, want equal %t", tt.a, a, tt.b, b, tt.equal)
+			}
+		})
+	}
+
+	if _, err := desiredStateHash(make(chan int)); err == nil {
+		t.Error("desiredStateHash of an unmarshalable input succeeded")
+	}
+}
+
+func TestAppliedStates(t *testing.T) {
+	const hash = "0123456789abcdef"
+	object := func(kind client.Object, name, version, objectHash string) client.Object {
+		kind.SetName(name)
+		kind.SetNamespace("team-a")
+		kind.SetResourceVersion(version)
+		if objectHash != "" {
+			kind.SetAnnotations(map[string]string{DesiredStateHashAnnotation: objectHash})
+		}
+		return kind
+	}
+
+	tests := []struct {
+		name      string
+		applied   []client.Object
+		forget    bool
+		workspace string
+		object    client.Object
+		want      bool
+	}{
+		{
+			name:      "never applied",
+			workspace: "team-a",
+			object:    object(&corev1.ResourceQuota{}, "quota", "1", hash),
+		},
+		{
+			name:      "applied and unchanged",
+			applied:   []client.Object{object(&corev1.ResourceQuota{}, "quota", "1", hash)},
+			workspace: "team-a",
+			object:    object(&corev1.ResourceQuota{}, "quota", "1", hash),
+			want:      true,
+		},
+		{
+			name:      "changed out-of-band",
+			applied:   []client.Object{object(&corev1.ResourceQuota{}, "quota", "1", hash)},
+			workspace: "team-a",
+			object:    object(&corev1.ResourceQuota{}, "quota", "2", hash),
+		},
+		{
+			name:      "desired state changed",
+			applied:   []client.Object{object(&corev1.ResourceQuota{}, "quota", "1", hash)},
+			workspace: "team-a",
+			object:    object(&corev1.ResourceQuota{}, "quota", "1", "fedcba9876543210"),
+		},
+		{
+			name:      "hash annotation removed",
+			applied:   []client.Object{object(&corev1.ResourceQuota{}, "quota", "1", hash)},
+			workspace: "team-a",
+			object:    object(&corev1.ResourceQuota{}, "quota", "1", ""),
+		},
+		{
+			name:      "another kind of the same name",
+			applied:   []client.Object{object(&corev1.ResourceQuota{}, "edit", "1", hash)},
+			workspace: "team-a",
+			object:    object(&rbacv1.RoleBinding{}, "edit", "1", hash),
+		},
+		{
+			name:      "applied for another workspace",
+			applied:   []client.Object{object(&corev1.ResourceQuota{}, "quota", "1", hash)},
+			workspace: "team-b",
+			object:    object(&corev1.ResourceQuota{}, "quota", "1", hash),
+		},
+		{
+			name:      "forgotten workspace",
+			applied:   []client.Object{object(&corev1.ResourceQuota{}, "quota", "1", hash)},
+			forget:    true,
+			workspace: "team-a",
+			object:    object(&corev1.ResourceQuota{}, "quota", "1", hash),
+		},
+		{
+			name:      "applied again after a change",
+			applied:   []client.Object{object(&corev1.ResourceQuota{}, "quota", "1", hash), object(&corev1.ResourceQuota{}, "quota", "2", hash)},
+			workspace: "team-a",
+			object:    object(&corev1.ResourceQuota{}, "quota", "2", hash),
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var states appliedStates
+			for _, applied := range tt.applied {
+				states.applied("team-a", applied)
+			}
+			if tt.forget {
+				states.forget("team-a")
+			}
+			if got := states.upToDate(tt.workspace, tt.object, hash); got != tt.want {
+				t.Errorf("upToDate() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	var empty appliedStates
+	empty.forget("team-a")
+	if empty.upToDate("team-a", &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}, "") {
+		t.Error("upToDate() of an empty appliedStates = true, want false")
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	// CriticalWorkloadLabel marks the Deployments of a workspace which get a default PodDisruptionBudget
+	CriticalWorkloadLabel = "environment.tf.operator.com/critical"
+
+	// DefaultDisruptionBudgetLabel marks the generated default PodDisruptionBudgets with the name of their Deployment
+	DefaultDisruptionBudgetLabel = "environment.tf.operator.com/default-pdb"
+)
+
+// disruptionBudgetTarget is the target of the admission policy of the PodDisruptionBudget guardrails
+var disruptionBudgetTarget = admissionPolicyTarget{group: "policy", version: "v1", resource: "poddisruptionbudgets", kind: "PodDisruptionBudget"}
+
+// blockingDisruptionBudgetValidation returns the CEL validation rejecting the PodDisruptionBudgets which allow
+// no voluntary disruption
+func blockingDisruptionBudgetValidation() map[string]interface{} {
+	return celValidation(
+		"!(has(object.spec.maxUnavailable) && string(object.spec.maxUnavailable) in ['0', '0%']) && "+
+			"!(has(object.spec.minAvailable) && string(object.spec.minAvailable) == '100%')",
+		"PodDisruptionBudgets must allow at least one voluntary disruption, maxUnavailable 0 and minAvailable 100% block node drains")
+}
+
+// reconcileDisruptionBudgets keeps the PodDisruptionBudget guardrails of the workspace in sync with
+// spec.disruptionBudgets: the admission policy rejecting blocking budgets and the default budgets
+// of the critical Deployments
+func (r *WorkspaceReconciler) reconcileDisruptionBudgets(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	disruptionBudgets := workspace.Spec.DisruptionBudgets
+	var policy *admissionPolicy
+	if disruptionBudgets != nil && disruptionBudgets.ForbidBlocking {
+		class, err := r.workspaceClass(ctx, workspace)
+		if err != nil {
+			return err
+		}
+		policy = &admissionPolicy{
+			engine:      "vap",
+			action:      "Enforce",
+			target:      &disruptionBudgetTarget,
+			validations: []interface{}{blockingDisruptionBudgetValidation()},
+		}
+		if class != nil && class.Spec.SecurityPolicy != nil {
+			policy.engine = class.Spec.SecurityPolicy.Engine
+		}
+	}
+	if err := r.reconcileAdmissionPolicy(ctx, workspace, fmt.Sprintf("%s-disruption-budgets", workspace.Spec.Name), policy); err != nil {
+		return err
+	}
+	return r.reconcileDefaultDisruptionBudgets(ctx, workspace)
+}
+
+// reconcileDefaultDisruptionBudgets creates a PodDisruptionBudget for each critical Deployment of the workspace
+// namespace no other PodDisruptionBudget covers, and deletes the ones no longer needed
+func (r *WorkspaceReconciler) reconcileDefaultDisruptionBudgets(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	budgets := &policyv1.PodDisruptionBudgetList{}
+	if err := r.List(ctx, budgets, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return err
+	}
+	generated := map[string]*policyv1.PodDisruptionBudget{}
+	var tenantBudgets []policyv1.PodDisruptionBudget
+	for i := range budgets.Items {
+		if deployment, ok := budgets.Items[i].Labels[DefaultDisruptionBudgetLabel]; ok && metav1.IsControlledBy(&budgets.Items[i], workspace) {
+			generated[deployment] = &budgets.Items[i]
+		} else {
+			tenantBudgets = append(tenantBudgets, budgets.Items[i])
+		}
+	}
+
+	if workspace.Spec.DisruptionBudgets != nil && workspace.Spec.DisruptionBudgets.DefaultMaxUnavailable != nil {
+		deployments := &appsv1.DeploymentList{}
+		if err := r.List(ctx, deployments, client.InNamespace(workspace.Spec.Name), client.MatchingLabels{CriticalWorkloadLabel: "true"}); err != nil {
+			return err
+		}
+		for i := range deployments.Items {
+			deployment := &deployments.Items[i]
+			covered, err := coveredByDisruptionBudget(deployment, tenantBudgets)
+			if err != nil {
+				return err
+			}
+			if covered {
+				continue
+			}
+			budget, err := r.defaultDisruptionBudgetForDeployment(workspace, deployment)
+			if err != nil {
+				return err
+			}
+			existing, ok := generated[deployment.Name]
+			delete(generated, deployment.Name)
+			if !ok {
+				reconcilerLog.Info(fmt.Sprintf("Creating a new PodDisruptionBudget PodDisruptionBudget.Name %s", budget.Name))
+				if err := r.Create(ctx, budget); err != nil && !apierrors.IsAlreadyExists(err) {
+					return err
+				}
+				continue
+			}
+			// check if the selector of the Deployment or the default maxUnavailable changed
+			if !equality.Semantic.DeepEqual(existing.Spec, budget.Spec) {
+				reconcilerLog.Info(fmt.Sprintf("Spec not same for PodDisruptionBudget.Name %s in Namespace.Name %s", existing.Name, existing.Namespace))
+				existing.Spec = budget.Spec
+				if err := r.update(ctx, existing); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Delete the default PodDisruptionBudgets of the Deployments no longer critical, removed or covered by the tenant
+	for _, budget := range generated {
+		reconcilerLog.Info(fmt.Sprintf("Deleting PodDisruptionBudget PodDisruptionBudget.Name %s", budget.Name))
+		if err := r.Delete(ctx, budget); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// coveredByDisruptionBudget returns whether one of the budgets selects the pods of the Deployment
+func coveredByDisruptionBudget(deployment *appsv1.Deployment, budgets []policyv1.PodDisruptionBudget) (bool, error) {
+	for _, budget := range budgets {
+		// A nil selector selects no pod, an empty one all the pods of the namespace
+		if budget.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil {
+			return false, fmt.Errorf("invalid selector of PodDisruptionBudget %s: %w", budget.Name, err)
+		}
+		if selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Default PodDisruptionBudget for a critical Deployment of the Workspace
+func (r *WorkspaceReconciler) defaultDisruptionBudgetForDeployment(workspace *environmentv1alpha1.Workspace, deployment *appsv1.Deployment) (*policyv1.PodDisruptionBudget, error) {
+	budgetLabels := labelsForWorkspace(workspace, map[string]string{DefaultDisruptionBudgetLabel: deployment.Name})
+	maxUnavailable := *workspace.Spec.DisruptionBudgets.DefaultMaxUnavailable
+	budget := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-default", deployment.Name),
+			Namespace:   workspace.Spec.Name,
+			Labels:      budgetLabels,
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector:       deployment.Spec.Selector,
+			MaxUnavailable: &maxUnavailable,
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, budget, r.Scheme); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=cilium.io,resources=ciliumnetworkpolicies,verbs=get;list;watch;create;update;patch;delete
+
+// ciliumNetworkPolicyGVK is the Cilium policy kind the FQDNs of the egress of the workspaces are enforced with.
+// It is handled as unstructured so that the operator does not depend on Cilium.
+var ciliumNetworkPolicyGVK = schema.GroupVersionKind{Group: "cilium.io", Version: "v2", Kind: "CiliumNetworkPolicy"}
+
+// clusterDNSLabels select the pods of the cluster DNS in the kube-system namespace, for both CoreDNS and kube-dns
+var clusterDNSLabels = map[string]string{"k8s-app": "kube-dns"}
+
+// validateEgress checks that the CIDRs of spec.networking.egress are valid
+func validateEgress(networking *environmentv1alpha1.WorkspaceNetworking) error {
+	if networking == nil || networking.Egress == nil {
+		return nil
+	}
+	for _, cidr := range networking.Egress.CIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("spec.networking.egress.cidrs: %s is not a valid CIDR", cidr)
+		}
+	}
+	return nil
+}
+
+// reconcileEgress keeps the egress NetworkPolicy of the workspace namespace, and the CiliumNetworkPolicy
+// of its FQDNs, in sync with spec.networking.egress. It is run after reconcileNetworking, whose peers
+// in status.networking.allowedTo stay reachable.
+func (r *WorkspaceReconciler) reconcileEgress(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+	var egress *environmentv1alpha1.WorkspaceEgress
+	if workspace.Spec.Networking != nil {
+		egress = workspace.Spec.Networking.Egress
+	}
+
+	networkPolicy := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-egress", workspace.Spec.Name)}, networkPolicy)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if egress == nil {
+		if exists {
+			reconcilerLog.Info(fmt.Sprintf("Deleting NetworkPolicy NetworkPolicy.Name %s", networkPolicy.Name))
+			if err := r.Delete(ctx, networkPolicy); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	} else {
+		np, err := r.egressNetworkPolicyForWorkspace(ctx, workspace, egress)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			reconcilerLog.Info(fmt.Sprintf("Creating a new NetworkPolicy NetworkPolicy.Name %s", np.Name))
+			if err := r.Create(ctx, np); err != nil {
+				return err
+			}
+		} else if !equality.Semantic.DeepEqual(networkPolicy.Spec, np.Spec) {
+			// check if the destinations changed
+			reconcilerLog.Info(fmt.Sprintf("Destinations not same for NetworkPolicy.Name %s in Namespace.Name %s", np.Name, np.Namespace))
+			networkPolicy.Spec = np.Spec
+			if err := r.update(ctx, networkPolicy); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.reconcileEgressFQDNs(ctx, workspace, egress)
+}
+
+// reconcileEgressFQDNs keeps the CiliumNetworkPolicy allowing the FQDNs of the egress of the workspace in sync.
+// Clusters without the Cilium CRDs are skipped.
+func (r *WorkspaceReconciler) reconcileEgressFQDNs(ctx context.Context, workspace *environmentv1alpha1.Workspace, egress *environmentv1alpha1.WorkspaceEgress) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+	enabled := egress != nil && len(egress.FQDNs) > 0
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(ciliumNetworkPolicyGVK)
+	err := r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-egress-fqdns", workspace.Spec.Name)}, existing)
+	if meta.IsNoMatchError(err) {
+		if enabled {
+			reconcilerLog.Info("Cilium CRDs are not installed. Skipping the FQDNs of the egress of Workspace")
+		}
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if !enabled {
+		if exists {
+			reconcilerLog.Info(fmt.Sprintf("Deleting CiliumNetworkPolicy CiliumNetworkPolicy.Name %s", existing.GetName()))
+			if err := r.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	policy, err := r.egressFQDNPolicyForWorkspace(workspace, egress)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		reconcilerLog.Info(fmt.Sprintf("Creating a new CiliumNetworkPolicy CiliumNetworkPolicy.Name %s", policy.GetName()))
+		return r.Create(ctx, policy)
+	}
+	// check if the FQDNs or their ports changed
+	if !semanticEqualJSON(existing.Object["spec"], policy.Object["spec"]) {
+		reconcilerLog.Info(fmt.Sprintf("FQDNs not same for CiliumNetworkPolicy.Name %s in Namespace.Name %s", policy.GetName(), policy.GetNamespace()))
+		existing.Object["spec"] = policy.Object["spec"]
+		return r.update(ctx, existing)
+	}
+	return nil
+}
+
+// Egress NetworkPolicy for Workspace, named <namespace>-egress.
+// It allows the egress traffic of the pods of the namespace to the namespace itself, to the cluster DNS,
+// to the namespaces of the peers allowing the workspace, to the services of its allowed Egress grants and to the
+// CIDRs of spec.networking.egress, which denies any other destination.
+func (r *WorkspaceReconciler) egressNetworkPolicyForWorkspace(ctx context.Context, workspace *environmentv1alpha1.Workspace, egress *environmentv1alpha1.WorkspaceEgress) (*networkingv1.NetworkPolicy, error) {
+	rules := []networkingv1.NetworkPolicyEgressRule{
+		{To: []networkingv1.NetworkPolicyPeer{namespacePeer(workspace.Spec.Name)}},
+		clusterDNSEgressRule(),
+	}
+
+	// Peers which were deleted since the status was computed are skipped
+	if workspace.Status.Networking != nil {
+		var peers []networkingv1.NetworkPolicyPeer
+		for _, name := range workspace.Status.Networking.AllowedTo {
+			peer := &environmentv1alpha1.Workspace{}
+			err := r.Get(ctx, types.NamespacedName{Name: name}, peer)
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			peers = append(peers, namespacePeer(peer.Spec.Name))
+		}
+		if len(peers) > 0 {
+			rules = append(rules, networkingv1.NetworkPolicyEgressRule{To: peers})
+		}
+	}
+
+	for _, grant := range workspace.Spec.Grants {
+		if grant.Direction == "Ingress" || r.GrantPolicy.CheckGrant(grant) != nil {
+			continue
+		}
+		service := namespacePeer(grant.Namespace)
+		service.PodSelector = &metav1.LabelSelector{}
+		if grant.PodSelector != nil {
+			service.PodSelector = grant.PodSelector.DeepCopy()
+		}
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To:    []networkingv1.NetworkPolicyPeer{service},
+			Ports: defaultedPorts(grant.Ports),
+		})
+	}
+
+	if len(egress.CIDRs) > 0 {
+		var blocks []networkingv1.NetworkPolicyPeer
+		for _, cidr := range egress.CIDRs {
+			blocks = append(blocks, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+		}
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{To: blocks, Ports: defaultedPorts(egress.Ports)})
+	}
+
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-egress", workspace.Spec.Name),
+			Namespace:   workspace.Spec.Name,
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      rules,
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, np, r.Scheme); err != nil {
+		return nil, err
+	}
+	return np, nil
+}
+
+// CiliumNetworkPolicy of the FQDNs of the egress of the Workspace, named <namespace>-egress-fqdns.
+// The DNS requests of the pods are proxied by Cilium, which learns the IPs of the allowed FQDNs from them.
+// Cilium combines it with the egress NetworkPolicy, the traffic allowed by either of them being allowed.
+func (r *WorkspaceReconciler) egressFQDNPolicyForWorkspace(workspace *environmentv1alpha1.Workspace, egress *environmentv1alpha1.WorkspaceEgress) (*unstructured.Unstructured, error) {
+	var fqdns []interface{}
+	for _, fqdn := range egress.FQDNs {
+		selector := map[string]interface{}{"matchName": fqdn}
+		if strings.Contains(fqdn, "*") {
+			selector = map[string]interface{}{"matchPattern": fqdn}
+		}
+		fqdns = append(fqdns, selector)
+	}
+	fqdnRule := map[string]interface{}{"toFQDNs": fqdns}
+	if len(egress.Ports) > 0 {
+		var ports []interface{}
+		for _, port := range defaultedPorts(egress.Ports) {
+			ciliumPort := map[string]interface{}{"protocol": string(*port.Protocol)}
+			if port.Port != nil {
+				ciliumPort["port"] = port.Port.String()
+			}
+			if port.EndPort != nil {
+				ciliumPort["endPort"] = int64(*port.EndPort)
+			}
+			ports = append(ports, ciliumPort)
+		}
+		fqdnRule["toPorts"] = []interface{}{map[string]interface{}{"ports": ports}}
+	}
+
+	policy := &unstructured.Unstructured{}
+	policy.SetGroupVersionKind(ciliumNetworkPolicyGVK)
+	policy.SetName(fmt.Sprintf("%s-egress-fqdns", workspace.Spec.Name))
+	policy.SetNamespace(workspace.Spec.Name)
+	policy.SetLabels(labelsForWorkspace(workspace, nil))
+	policy.SetAnnotations(annotationsForWorkspace(workspace))
+	policy.Object["spec"] = map[string]interface{}{
+		"endpointSelector": map[string]interface{}{},
+		"egress": []interface{}{
+			map[string]interface{}{
+				"toEndpoints": []interface{}{map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						"k8s:io.kubernetes.pod.namespace": metav1.NamespaceSystem,
+						"k8s:k8s-app":                     clusterDNSLabels["k8s-app"],
+					},
+				}},
+				"toPorts": []interface{}{map[string]interface{}{
+					"ports": []interface{}{map[string]interface{}{"port": "53", "protocol": "ANY"}},
+					"rules": map[string]interface{}{"dns": []interface{}{map[string]interface{}{"matchPattern": "*"}}},
+				}},
+			},
+			fqdnRule,
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, policy, r.Scheme); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// defaultedPorts returns a copy of the NetworkPolicy ports with the protocol defaulted to TCP,
+// as done by the API server, so that the generated policies compare equal to the stored ones
+func defaultedPorts(ports []networkingv1.NetworkPolicyPort) []networkingv1.NetworkPolicyPort {
+	var defaulted []networkingv1.NetworkPolicyPort
+	for _, port := range ports {
+		port := *port.DeepCopy()
+		if port.Protocol == nil {
+			protocol := corev1.ProtocolTCP
+			port.Protocol = &protocol
+		}
+		defaulted = append(defaulted, port)
+	}
+	return defaulted
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// ConditionExpiring is raised when the workspace is about to be deleted at its expiry time
+	ConditionExpiring = "Expiring"
+
+	// DefaultExpiryWarningPeriod is the time before its expiry the owner of a workspace is warned
+	// when ExpiryWarningPeriod is not set
+	DefaultExpiryWarningPeriod = 72 * time.Hour
+)
+
+// Reasons of the Expiring condition
+const (
+	expiryScheduled = "Scheduled"
+	expirySoon      = "ExpiresSoon"
+	expiryExpired   = "Expired"
+)
+
+// expiresAt returns the time the workspace expires at, the earliest of spec.deleteAt and of its creation time
+// plus spec.ttl, zero when it never expires
+func expiresAt(workspace *environmentv1alpha1.Workspace) time.Time {
+	var expiry time.Time
+	if workspace.Spec.DeleteAt != nil {
+		expiry = workspace.Spec.DeleteAt.Time
+	}
+	if ttl := workspace.Spec.TTL; ttl != nil && !workspace.CreationTimestamp.IsZero() {
+		if end := workspace.CreationTimestamp.Add(ttl.Duration); expiry.IsZero() || end.Before(expiry) {
+			expiry = end
+		}
+	}
+	return expiry
+}
+
+// expiryField returns the field of the spec the expiry of the workspace comes from, to be moved to keep it longer
+func expiryField(workspace *environmentv1alpha1.Workspace) string {
+	if workspace.Spec.DeleteAt != nil && workspace.Spec.DeleteAt.Time.Equal(expiresAt(workspace)) {
+		return "spec.deleteAt"
+	}
+	return "spec.ttl"
+}
+
+// expirySuspends reports whether the workspace is suspended rather than deleted once it expired
+func expirySuspends(workspace *environmentv1alpha1.Workspace) bool {
+	return workspace.Spec.ExpiryAction == environmentv1alpha1.ExpiryActionSuspend
+}
+
+// expiredSuspended reports whether the workspace expired at now and is suspended for it
+func expiredSuspended(workspace *environmentv1alpha1.Workspace, now time.Time) bool {
+	expiry := expiresAt(workspace)
+	return expirySuspends(workspace) && !expiry.IsZero() && !now.Before(expiry)
+}
+
+// expiryWarningPeriod returns the time before its expiry the owner of a workspace is warned
+func (r *WorkspaceReconciler) expiryWarningPeriod() time.Duration {
+	if r.ExpiryWarningPeriod > 0 {
+		return r.ExpiryWarningPeriod
+	}
+	return DefaultExpiryWarningPeriod
+}
+
+// expiryState returns the expiry state of the workspace at now, empty when it never expires
+func (r *WorkspaceReconciler) expiryState(workspace *environmentv1alpha1.Workspace, now time.Time) string {
+	expiry := expiresAt(workspace)
+	switch {
+	case expiry.IsZero():
+		return ""
+	case now.Before(expiry.Add(-r.expiryWarningPeriod())):
+		return expiryScheduled
+	case now.Before(expiry):
+		return expirySoon
+	default:
+		return expiryExpired
+	}
+}
+
+// nextExpiryChange returns the first time after now the expiry state of the workspace changes,
+// zero when it never expires
+func (r *WorkspaceReconciler) nextExpiryChange(workspace *environmentv1alpha1.Workspace, now time.Time) time.Time {
+	switch r.expiryState(workspace, now) {
+	case expiryScheduled:
+		return expiresAt(workspace).Add(-r.expiryWarningPeriod())
+	case expirySoon:
+		return expiresAt(workspace)
+	default:
+		return time.Time{}
+	}
+}
+
+// reconcileExpiry updates the Expiring condition of the workspace and deletes it once it expired, or leaves it
+// to be suspended with the Suspend expiry action. The owner is notified when the warning period starts and when
+// the workspace expires. It reports whether the workspace was deleted at now.
+func (r *WorkspaceReconciler) reconcileExpiry(ctx context.Context, workspace *environmentv1alpha1.Workspace, now time.Time) (bool, error) {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	previous := meta.FindStatusCondition(workspace.Status.Conditions, ConditionExpiring)
+	state := r.expiryState(workspace, now)
+	if state == "" {
+		if previous == nil {
+			return false, nil
+		}
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionExpiring)
+		return false, r.Status().Update(ctx, workspace)
+	}
+
+	expiry := expiresAt(workspace).UTC().Format(time.RFC3339)
+	action := "deleted"
+	if expirySuspends(workspace) {
+		action = "suspended"
+	}
+	condition := metav1.Condition{
+		Type:               ConditionExpiring,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             state,
+	}
+	switch state {
+	case expiryScheduled:
+		condition.Status = metav1.ConditionFalse
+		condition.Message = fmt.Sprintf("Workspace is %s at %s", action, expiry)
+	case expirySoon:
+		condition.Message = fmt.Sprintf("Workspace is %s at %s, move %s to keep it longer", action, expiry, expiryField(workspace))
+	default:
+		condition.Message = fmt.Sprintf("Workspace expired at %s and is %s", expiry, action)
+	}
+	if previous == nil || previous.Reason != condition.Reason || previous.Message != condition.Message ||
+		previous.ObservedGeneration != condition.ObservedGeneration {
+		meta.SetStatusCondition(&workspace.Status.Conditions, condition)
+		if err := r.Status().Update(ctx, workspace); err != nil {
+			return false, err
+		}
+		if state != expiryScheduled && (previous == nil || previous.Reason != condition.Reason) {
+			if r.Recorder != nil {
+				r.Recorder.Event(workspace, corev1.EventTypeWarning, condition.Reason, condition.Message)
+			}
+			r.notify(ctx, workspace, condition.Reason, condition.Message)
+		}
+	}
+	if state != expiryExpired || expirySuspends(workspace) {
+		return false, nil
+	}
+
+	// The deletion grace period and propagation of the workspace apply as for any deletion
+	reconcilerLog.Info(fmt.Sprintf("Deleting expired Workspace Workspace.Name %s", workspace.Name))
+	if err := r.Delete(ctx, workspace); err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// validateDeleteAt refuses a new spec.deleteAt in the past, which would delete the workspace right away
+func validateDeleteAt(previous, deleteAt *metav1.Time, now time.Time) error {
+	if deleteAt == nil || !deleteAt.Time.Before(now) || (previous != nil && previous.Equal(deleteAt)) {
+		return nil
+	}
+	return fmt.Errorf("spec.deleteAt %s is in the past", deleteAt.UTC().Format(time.RFC3339))
+}
+
+// validateTTL refuses a new spec.ttl the workspace already outlived, which would expire it right away
+func validateTTL(previous, ttl *metav1.Duration, created metav1.Time, now time.Time) error {
+	if ttl == nil {
+		return nil
+	}
+	if ttl.Duration <= 0 {
+		return fmt.Errorf("spec.ttl %s must be positive", ttl.Duration)
+	}
+	if created.IsZero() || (previous != nil && previous.Duration == ttl.Duration) || created.Add(ttl.Duration).After(now) {
+		return nil
+	}
+	return fmt.Errorf("spec.ttl %s is already over, the Workspace was created at %s", ttl.Duration, created.UTC().Format(time.RFC3339))
+}
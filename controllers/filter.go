@@ -0,0 +1,65 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// WorkspaceFilter scopes an operator instance to a subset of the Workspaces,
+// e.g. to run one operator per business unit on a shared cluster
+type WorkspaceFilter struct {
+	// Namespaces are the target namespaces (spec.name) of the managed Workspaces.
+	// Workspaces are not filtered by namespace when it is empty.
+	Namespaces []string
+
+	// Selector selects the managed Workspaces by label.
+	// Workspaces are not filtered by label when it is nil.
+	Selector labels.Selector
+}
+
+// Matches reports whether the workspace is managed by the operator instance.
+// A nil filter matches all the Workspaces.
+func (f *WorkspaceFilter) Matches(workspace *environmentv1alpha1.Workspace) bool {
+	if f == nil {
+		return true
+	}
+	if f.Selector != nil && !f.Selector.Matches(labels.Set(workspace.Labels)) {
+		return false
+	}
+	if len(f.Namespaces) == 0 {
+		return true
+	}
+	for _, namespace := range f.Namespaces {
+		if namespace == workspace.Spec.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// predicate filters the Workspace events handled by the controller
+func (f *WorkspaceFilter) predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		workspace, ok := obj.(*environmentv1alpha1.Workspace)
+		return !ok || f.Matches(workspace)
+	})
+}
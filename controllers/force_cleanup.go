@@ -0,0 +1,124 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// ForceCleanupAnnotation set to "true" on a Workspace lets the operator strip the known-safe finalizers
+	// of the resources blocking the termination of its namespace. It can only be set by the users
+	// allowed the force-cleanup verb on the workspace.
+	ForceCleanupAnnotation = "environment.tf.operator.com/force-cleanup"
+
+	// ForceCleanupVerb is the RBAC verb on workspaces required to set the ForceCleanupAnnotation
+	ForceCleanupVerb = "force-cleanup"
+)
+
+// forceCleanupRequested reports whether the force cleanup of a workspace was requested
+func forceCleanupRequested(workspace *environmentv1alpha1.Workspace) bool {
+	force, _ := strconv.ParseBool(workspace.Annotations[ForceCleanupAnnotation])
+	return force
+}
+
+// forceCleanup removes the known-safe finalizers from the resources being deleted in the terminating namespace
+// of the workspace. Every namespaced resource the operator can list and patch is inspected, the resources
+// the operator is not allowed to list are left alone. Every removal is logged and evented.
+func (r *WorkspaceReconciler) forceCleanup(ctx context.Context, workspace *environmentv1alpha1.Workspace, namespace *corev1.Namespace) error {
+	if len(r.ForceCleanupFinalizers) == 0 || r.Discovery == nil {
+		return nil
+	}
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	resourceLists, err := discovery.ServerPreferredNamespacedResources(r.Discovery)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return err
+	}
+	resourceLists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "patch"}}, resourceLists)
+	for _, resourceList := range resourceLists {
+		groupVersion, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			return err
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			objects := &unstructured.UnstructuredList{}
+			objects.SetGroupVersionKind(groupVersion.WithKind(resource.Kind + "List"))
+			err := r.List(ctx, objects, client.InNamespace(namespace.Name))
+			if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			for i := range objects.Items {
+				object := &objects.Items[i]
+				if object.GetDeletionTimestamp().IsZero() {
+					continue
+				}
+				var kept, forced []string
+				for _, finalizer := range object.GetFinalizers() {
+					if r.safeFinalizer(finalizer) {
+						forced = append(forced, finalizer)
+					} else {
+						kept = append(kept, finalizer)
+					}
+				}
+				if len(forced) == 0 {
+					continue
+				}
+				original := object.DeepCopy()
+				object.SetFinalizers(kept)
+				if err := r.Patch(ctx, object, client.MergeFrom(original)); err != nil && !apierrors.IsNotFound(err) {
+					return err
+				}
+				message := fmt.Sprintf("Forced the removal of finalizers %s from %s %s/%s", strings.Join(forced, ", "), resource.Kind, object.GetNamespace(), object.GetName())
+				reconcilerLog.Info(message)
+				if r.Recorder != nil {
+					r.Recorder.Event(workspace, "Warning", "FinalizerForced", message)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// safeFinalizer reports whether the finalizer is one of the finalizers the force cleanup may remove
+func (r *WorkspaceReconciler) safeFinalizer(finalizer string) bool {
+	for _, safe := range r.ForceCleanupFinalizers {
+		if finalizer == safe {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// NodeSelectorAnnotation is the namespace annotation of the PodNodeSelector admission plugin,
+	// merging its node selector into the pods of the namespace
+	NodeSelectorAnnotation = "scheduler.alpha.kubernetes.io/node-selector"
+
+	// DevicePluginConfigLabel selects the configuration of the NVIDIA device plugin of a node,
+	// one of the keys of the device plugin ConfigMap
+	DevicePluginConfigLabel = "nvidia.com/device-plugin.config"
+
+	// gpuQuotaPrefix is the prefix of the ResourceQuota names of the NVIDIA GPU resources
+	gpuQuotaPrefix = "requests.nvidia.com/"
+
+	// timeSlicingConfigPrefix is the prefix of the device plugin configurations generated for spec.gpu.timeSlicingReplicas
+	timeSlicingConfigPrefix = "time-slicing-"
+)
+
+// validateGPU checks that a GPU is either partitioned with MIG or time-sliced
+func validateGPU(gpu *environmentv1alpha1.WorkspaceGPU) error {
+	if gpu != nil && gpu.MIGProfile != "" && gpu.TimeSlicingReplicas > 0 {
+		return fmt.Errorf("spec.gpu.migProfile and spec.gpu.timeSlicingReplicas are mutually exclusive")
+	}
+	return nil
+}
+
+// setGPUQuota sets the hard limit of the GPU resource of spec.gpu in the hard limits of the workspace
+// ResourceQuota, and removes the limits of the other GPU resources
+func setGPUQuota(hard corev1.ResourceList, workspace *environmentv1alpha1.Workspace) {
+	desired := corev1.ResourceName("")
+	if gpu := workspace.Spec.GPU; gpu != nil {
+		desired = corev1.ResourceName(gpuQuotaPrefix + "gpu")
+		if gpu.MIGProfile != "" {
+			desired = corev1.ResourceName(gpuQuotaPrefix + "mig-" + gpu.MIGProfile)
+		}
+		hard[desired] = *quotaResource.NewQuantity(int64(gpu.Count), quotaResource.DecimalSI)
+	}
+	for name := range hard {
+		if strings.HasPrefix(string(name), gpuQuotaPrefix) && name != desired {
+			delete(hard, name)
+		}
+	}
+}
+
+// timeSlicingConfig is the name of the device plugin configuration time-slicing the GPUs of a node into replicas
+func timeSlicingConfig(replicas int32) string {
+	return fmt.Sprintf("%s%d", timeSlicingConfigPrefix, replicas)
+}
+
+// gpuAnnotationsForWorkspace returns the node selector annotation scheduling the pods of the workspace
+// on the nodes time-slicing their GPUs into spec.gpu.timeSlicingReplicas
+func gpuAnnotationsForWorkspace(workspace *environmentv1alpha1.Workspace) map[string]string {
+	annotations := map[string]string{}
+	if gpu := workspace.Spec.GPU; gpu != nil && gpu.TimeSlicingReplicas > 0 {
+		annotations[NodeSelectorAnnotation] = fmt.Sprintf("%s=%s", DevicePluginConfigLabel, timeSlicingConfig(gpu.TimeSlicingReplicas))
+	}
+	return annotations
+}
+
+// pruneGPUAnnotations removes the node selector annotation of a time slicing no longer set in spec.gpu.
+// Node selectors not generated by the operator are left untouched.
+func pruneGPUAnnotations(objectMeta *metav1.ObjectMeta, workspace *environmentv1alpha1.Workspace) {
+	if _, ok := gpuAnnotationsForWorkspace(workspace)[NodeSelectorAnnotation]; ok {
+		return
+	}
+	if strings.HasPrefix(objectMeta.Annotations[NodeSelectorAnnotation], DevicePluginConfigLabel+"="+timeSlicingConfigPrefix) {
+		delete(objectMeta.Annotations, NodeSelectorAnnotation)
+	}
+}
+
+// timeSlicingDevicePluginConfig renders the NVIDIA device plugin configuration time-slicing every GPU into replicas
+func timeSlicingDevicePluginConfig(replicas int32) string {
+	return fmt.Sprintf(`version: v1
+sharing:
+  timeSlicing:
+    resources:
+    - name: nvidia.com/gpu
+      replicas: %d
+`, replicas)
+}
+
+// reconcileGPUSharing keeps the device plugin ConfigMap in sync with the time slicing of all the workspaces.
+// It holds one configuration per number of replicas in use, that the nodes select with the DevicePluginConfigLabel,
+// and the configurations no workspace uses anymore are removed. The other keys of the ConfigMap are left untouched.
+func (r *WorkspaceReconciler) reconcileGPUSharing(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	if err := validateGPU(workspace.Spec.GPU); err != nil {
+		return stall(err)
+	}
+	if r.DevicePluginConfig == nil {
+		return nil
+	}
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := r.List(ctx, workspaces); err != nil {
+		return err
+	}
+	desired := map[string]string{}
+	for _, item := range workspaces.Items {
+		if gpu := item.Spec.GPU; gpu != nil && gpu.TimeSlicingReplicas > 0 && item.DeletionTimestamp.IsZero() {
+			desired[timeSlicingConfig(gpu.TimeSlicingReplicas)] = timeSlicingDevicePluginConfig(gpu.TimeSlicingReplicas)
+		}
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, *r.DevicePluginConfig, configMap)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if apierrors.IsNotFound(err) {
+		if len(desired) == 0 {
+			return nil
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: r.DevicePluginConfig.Name, Namespace: r.DevicePluginConfig.Namespace},
+			Data:       desired,
+		}
+		reconcilerLog.Info(fmt.Sprintf("Creating a new device plugin ConfigMap ConfigMap.Name %s", configMap.Name))
+		return r.Create(ctx, configMap)
+	}
+
+	changed := false
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	for key, value := range desired {
+		if configMap.Data[key] != value {
+			configMap.Data[key] = value
+			changed = true
+		}
+	}
+	for key := range configMap.Data {
+		if _, ok := desired[key]; !ok && strings.HasPrefix(key, timeSlicingConfigPrefix) {
+			delete(configMap.Data, key)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	reconcilerLog.Info(fmt.Sprintf("Time slicing not same for device plugin ConfigMap %s in Namespace.Name %s", configMap.Name, configMap.Namespace))
+	return r.Update(ctx, configMap)
+}
+
+// ParseDevicePluginConfig parses the namespace/name reference of the NVIDIA device plugin ConfigMap
+func ParseDevicePluginConfig(reference string) (*types.NamespacedName, error) {
+	namespace, name, found := strings.Cut(reference, "/")
+	if !found || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid device plugin ConfigMap %q, expected namespace/name", reference)
+	}
+	return &types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
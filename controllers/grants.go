@@ -0,0 +1,293 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// GrantLabel marks the NetworkPolicies and RoleBindings generated from spec.grants with the name of their grant
+	GrantLabel = "environment.tf.operator.com/grant"
+
+	// EventGrantDenied is emitted when a grant of the workspace is not allowed by the grant policy of the operator
+	EventGrantDenied = "GrantDenied"
+)
+
+// GrantPolicy restricts the shared namespaces and the ClusterRoles the grants of the workspaces can target,
+// so that a workspace can not open the traffic to or bind a role in any namespace of the cluster.
+// The zero policy allows no grant.
+type GrantPolicy struct {
+	// Namespaces are the shared namespaces the grants can target
+	Namespaces []string
+
+	// ClusterRoles are the ClusterRoles the grants can bind in the shared namespaces
+	ClusterRoles []string
+}
+
+// NewGrantPolicy parses comma separated shared namespaces and ClusterRoles
+func NewGrantPolicy(namespaces, clusterRoles string) GrantPolicy {
+	split := func(values string) []string {
+		var items []string
+		for _, value := range strings.Split(values, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				items = append(items, value)
+			}
+		}
+		return items
+	}
+	return GrantPolicy{Namespaces: split(namespaces), ClusterRoles: split(clusterRoles)}
+}
+
+// CheckGrant returns an error when the shared namespace or the ClusterRole of the grant is not allowed
+func (p GrantPolicy) CheckGrant(grant environmentv1alpha1.WorkspaceGrant) error {
+	if !containsString(p.Namespaces, grant.Namespace) {
+		return fmt.Errorf("grant %s: namespace %s is not a shared namespace allowed by the operator", grant.Name, grant.Namespace)
+	}
+	if grant.ClusterRole != "" && !containsString(p.ClusterRoles, grant.ClusterRole) {
+		return fmt.Errorf("grant %s: ClusterRole %s can not be granted in shared namespaces", grant.Name, grant.ClusterRole)
+	}
+	return nil
+}
+
+// Check returns an error for the first grant of the workspace which is not allowed
+func (p GrantPolicy) Check(workspace *environmentv1alpha1.Workspace) error {
+	for i, grant := range workspace.Spec.Grants {
+		if err := p.CheckGrant(grant); err != nil {
+			return fmt.Errorf("spec.grants[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// reconcileGrants keeps the NetworkPolicies and RoleBindings of the grants of the workspace in sync with spec.grants.
+// The grants not allowed by the grant policy are skipped, so that their objects are removed, e.g. when the policy
+// was restricted or the webhook bypassed.
+func (r *WorkspaceReconciler) reconcileGrants(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	selector := client.MatchingLabels{WorkspaceLabel: workspace.Name}
+	networkPolicies := &networkingv1.NetworkPolicyList{}
+	if err := r.List(ctx, networkPolicies, selector, client.HasLabels{GrantLabel}); err != nil {
+		return err
+	}
+	currentPolicies := map[types.NamespacedName]*networkingv1.NetworkPolicy{}
+	for i := range networkPolicies.Items {
+		if metav1.IsControlledBy(&networkPolicies.Items[i], workspace) {
+			currentPolicies[client.ObjectKeyFromObject(&networkPolicies.Items[i])] = &networkPolicies.Items[i]
+		}
+	}
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := r.List(ctx, roleBindings, selector); err != nil {
+		return err
+	}
+	currentBindings := map[types.NamespacedName]*rbacv1.RoleBinding{}
+	for i := range roleBindings.Items {
+		if metav1.IsControlledBy(&roleBindings.Items[i], workspace) {
+			currentBindings[client.ObjectKeyFromObject(&roleBindings.Items[i])] = &roleBindings.Items[i]
+		}
+	}
+
+	for _, grant := range workspace.Spec.Grants {
+		if err := r.GrantPolicy.CheckGrant(grant); err != nil {
+			reconcilerLog.Info("Skipping grant of Workspace", "reason", err.Error())
+			r.event(workspace, corev1.EventTypeWarning, EventGrantDenied, err.Error())
+			continue
+		}
+		np, err := r.grantNetworkPolicyForWorkspace(workspace, grant)
+		if err != nil {
+			return err
+		}
+		networkPolicy, ok := currentPolicies[client.ObjectKeyFromObject(np)]
+		delete(currentPolicies, client.ObjectKeyFromObject(np))
+		if !ok {
+			reconcilerLog.Info(fmt.Sprintf("Creating a new NetworkPolicy NetworkPolicy.Name %s in Namespace.Name %s", np.Name, np.Namespace))
+			if err := r.Create(ctx, np); err != nil {
+				return err
+			}
+		} else if !equality.Semantic.DeepEqual(networkPolicy.Spec, np.Spec) {
+			reconcilerLog.Info(fmt.Sprintf("Spec not same for NetworkPolicy.Name %s in Namespace.Name %s", np.Name, np.Namespace))
+			networkPolicy.Spec = np.Spec
+			if err := r.update(ctx, networkPolicy); err != nil {
+				return err
+			}
+		}
+
+		if grant.ClusterRole == "" {
+			continue
+		}
+		rb, err := r.grantRoleBindingForWorkspace(workspace, grant)
+		if err != nil {
+			return err
+		}
+		roleBinding, ok := currentBindings[client.ObjectKeyFromObject(rb)]
+		delete(currentBindings, client.ObjectKeyFromObject(rb))
+		if ok && roleBinding.RoleRef == rb.RoleRef {
+			if !equality.Semantic.DeepEqual(roleBinding.Subjects, rb.Subjects) {
+				reconcilerLog.Info(fmt.Sprintf("Subjects not same for RoleBinding.Name %s in Namespace.Name %s", rb.Name, rb.Namespace))
+				roleBinding.Subjects = rb.Subjects
+				if err := r.update(ctx, roleBinding); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		// The role of a RoleBinding can not be changed, the RoleBinding is recreated
+		if ok {
+			if err := r.Delete(ctx, roleBinding); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		reconcilerLog.Info(fmt.Sprintf("Creating a new RoleBinding RoleBinding.Name %s in Namespace.Name %s", rb.Name, rb.Namespace))
+		if err := r.Create(ctx, rb); err != nil {
+			return err
+		}
+		for i := range rb.Subjects {
+			r.audit(ctx, workspace, AuditActionSubjectAdded, "RoleBinding", rb.Name, &rb.Subjects[i], nil)
+		}
+	}
+
+	// Delete the NetworkPolicies and RoleBindings of the removed grants
+	for _, networkPolicy := range currentPolicies {
+		reconcilerLog.Info(fmt.Sprintf("Deleting NetworkPolicy NetworkPolicy.Name %s in Namespace.Name %s", networkPolicy.Name, networkPolicy.Namespace))
+		if err := r.Delete(ctx, networkPolicy); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	for _, roleBinding := range currentBindings {
+		if err := r.revokeGrantRoleBinding(ctx, workspace, roleBinding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeGrantRoleBinding deletes a RoleBinding of a grant of the workspace
+func (r *WorkspaceReconciler) revokeGrantRoleBinding(ctx context.Context, workspace *environmentv1alpha1.Workspace, roleBinding *rbacv1.RoleBinding) error {
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Deleting RoleBinding RoleBinding.Name %s in Namespace.Name %s", roleBinding.Name, roleBinding.Namespace))
+	if err := r.Delete(ctx, roleBinding); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	for i := range roleBinding.Subjects {
+		r.audit(ctx, workspace, AuditActionSubjectRemoved, "RoleBinding", roleBinding.Name, &roleBinding.Subjects[i], nil)
+	}
+	return nil
+}
+
+// grantLabels returns the labels of the objects generated for a grant of the workspace
+func grantLabels(workspace *environmentv1alpha1.Workspace, grant environmentv1alpha1.WorkspaceGrant) map[string]string {
+	return labelsForWorkspace(workspace, map[string]string{GrantLabel: grant.Name})
+}
+
+// NetworkPolicy of a grant of the Workspace, named <namespace>-<grant>.
+// For an Egress grant it is created in the shared namespace and allows the traffic from the workspace namespace
+// to the pods of the service, for an Ingress grant it is created in the workspace namespace and allows the traffic
+// from the pods of the service.
+func (r *WorkspaceReconciler) grantNetworkPolicyForWorkspace(workspace *environmentv1alpha1.Workspace, grant environmentv1alpha1.WorkspaceGrant) (*networkingv1.NetworkPolicy, error) {
+	servicePods := metav1.LabelSelector{}
+	if grant.PodSelector != nil {
+		servicePods = *grant.PodSelector
+	}
+	ports := defaultedPorts(grant.Ports)
+
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s", workspace.Spec.Name, grant.Name),
+			Labels:      grantLabels(workspace, grant),
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+	if grant.Direction == "Ingress" {
+		np.Namespace = workspace.Spec.Name
+		service := namespacePeer(grant.Namespace)
+		service.PodSelector = &servicePods
+		np.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{
+			From:  []networkingv1.NetworkPolicyPeer{service},
+			Ports: ports,
+		}}
+	} else {
+		np.Namespace = grant.Namespace
+		np.Spec.PodSelector = servicePods
+		np.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{
+			From:  []networkingv1.NetworkPolicyPeer{namespacePeer(workspace.Spec.Name)},
+			Ports: ports,
+		}}
+	}
+	if err := ctrl.SetControllerReference(workspace, np, r.Scheme); err != nil {
+		return nil, err
+	}
+	return np, nil
+}
+
+// RoleBinding of a grant of the Workspace, binding the workspace users to the ClusterRole of the grant
+// in the shared namespace
+func (r *WorkspaceReconciler) grantRoleBindingForWorkspace(workspace *environmentv1alpha1.Workspace, grant environmentv1alpha1.WorkspaceGrant) (*rbacv1.RoleBinding, error) {
+	var subjects []rbacv1.Subject
+	for _, user := range workspaceUsers(workspace) {
+		subjects = append(subjects, rbacv1.Subject{
+			Kind:     rbacv1.UserKind,
+			Name:     user,
+			APIGroup: "rbac.authorization.k8s.io",
+		})
+	}
+	rb := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s", workspace.Spec.Name, grant.Name),
+			Namespace:   grant.Namespace,
+			Labels:      grantLabels(workspace, grant),
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Subjects: subjects,
+		RoleRef: rbacv1.RoleRef{
+			Kind:     "ClusterRole",
+			Name:     grant.ClusterRole,
+			APIGroup: "rbac.authorization.k8s.io",
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, rb, r.Scheme); err != nil {
+		return nil, err
+	}
+	return rb, nil
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
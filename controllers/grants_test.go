@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+func TestGrantPolicyCheckGrant(t *testing.T) {
+	policy := NewGrantPolicy("kafka, monitoring", "view")
+	tests := []struct {
+		name    string
+		policy  GrantPolicy
+		grant   environmentv1alpha1.WorkspaceGrant
+		allowed bool
+	}{
+		{name: "empty policy", grant: environmentv1alpha1.WorkspaceGrant{Name: "kafka", Namespace: "kafka"}},
+		{name: "allowed namespace", policy: policy, grant: environmentv1alpha1.WorkspaceGrant{Name: "kafka", Namespace: "kafka"}, allowed: true},
+		{name: "other namespace", policy: policy, grant: environmentv1alpha1.WorkspaceGrant{Name: "system", Namespace: "kube-system"}},
+		{name: "allowed ClusterRole", policy: policy, grant: environmentv1alpha1.WorkspaceGrant{Name: "dashboards", Namespace: "monitoring", ClusterRole: "view"}, allowed: true},
+		{name: "other ClusterRole", policy: policy, grant: environmentv1alpha1.WorkspaceGrant{Name: "dashboards", Namespace: "monitoring", ClusterRole: "admin"}},
+		{name: "allowed ClusterRole in another namespace", policy: policy, grant: environmentv1alpha1.WorkspaceGrant{Name: "system", Namespace: "kube-system", ClusterRole: "view"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.CheckGrant(tt.grant); (err == nil) != tt.allowed {
+				t.Errorf("CheckGrant() = %v, want allowed %t", err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestNewGrantBindings(t *testing.T) {
+	kafka := environmentv1alpha1.WorkspaceGrant{Name: "kafka", Namespace: "kafka"}
+	dashboards := environmentv1alpha1.WorkspaceGrant{Name: "dashboards", Namespace: "monitoring", ClusterRole: "view"}
+	renamed := environmentv1alpha1.WorkspaceGrant{Name: "grafana", Namespace: "monitoring", ClusterRole: "view"}
+	edit := environmentv1alpha1.WorkspaceGrant{Name: "dashboards", Namespace: "monitoring", ClusterRole: "edit"}
+	workspace := func(grants ...environmentv1alpha1.WorkspaceGrant) *environmentv1alpha1.Workspace {
+		return &environmentv1alpha1.Workspace{Spec: environmentv1alpha1.WorkspaceSpec{Grants: grants}}
+	}
+
+	tests := []struct {
+		name      string
+		previous  *environmentv1alpha1.Workspace
+		workspace *environmentv1alpha1.Workspace
+		want      []string
+	}{
+		{name: "created without ClusterRole", workspace: workspace(kafka)},
+		{name: "created with a ClusterRole", workspace: workspace(kafka, dashboards), want: []string{"dashboards"}},
+		{name: "unchanged", previous: workspace(dashboards), workspace: workspace(dashboards)},
+		{name: "renamed grant", previous: workspace(dashboards), workspace: workspace(renamed)},
+		{name: "changed ClusterRole", previous: workspace(dashboards), workspace: workspace(edit), want: []string{"dashboards"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, grant := range newGrantBindings(tt.previous, tt.workspace) {
+				got = append(got, grant.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newGrantBindings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
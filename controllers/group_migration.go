@@ -0,0 +1,244 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// ConditionMigrated reports whether the Workspace is mirrored to the target API group of the migration
+	ConditionMigrated = "Migrated"
+
+	// MirroredFromAnnotation marks the Workspaces of the target API group mirrored from a Workspace of the current group,
+	// only these mirrors are updated and deleted by the migration
+	MirroredFromAnnotation = "environment.tf.operator.com/mirrored-from"
+)
+
+// Reasons of the Migrated condition
+const (
+	migrationSynced   = "Synced"
+	migrationConflict = "Conflict"
+	migrationFrozen   = "WindowEnded"
+)
+
+// migrationStates are the reasons of the Migrated condition exported by the migration metrics
+var migrationStates = []string{migrationSynced, migrationConflict, migrationFrozen}
+
+// workspaceMigrationDesc counts the Workspaces per state of their migration, pending ones have no Migrated condition yet
+var workspaceMigrationDesc = prometheus.NewDesc("workspace_group_migration_workspaces",
+	"Number of workspaces per state of their migration to the target API group", []string{"group", "state"}, nil)
+
+// GroupMigrationReconciler mirrors the Workspaces of the environment.tf.operator.com group to the Workspaces of
+// TargetGroup, so that the clients and GitOps repositories can move to the new group during a transition window.
+// The spec, labels and annotations of the mirrors are kept in sync with the current Workspaces until WindowEnd,
+// and the progress is reported in the Migrated condition of every Workspace and in the migration metrics.
+type GroupMigrationReconciler struct {
+	client.Client
+
+	// TargetGroup is the API group the Workspaces are mirrored to, its CRD must be installed
+	TargetGroup string
+
+	// WindowEnd is the end of the transition window, after which the mirrors are left as they are
+	// and become the source of truth. The mirrors are kept in sync forever when it is zero.
+	WindowEnd time.Time
+
+	// Filter scopes the migration to the workspaces of the operator instance
+	Filter *WorkspaceFilter
+}
+
+// targetGVK is the group version kind of the mirrors
+func (r *GroupMigrationReconciler) targetGVK() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: r.TargetGroup, Version: environmentv1alpha1.GroupVersion.Version, Kind: "Workspace"}
+}
+
+// Reconcile mirrors a Workspace to the target group and records the outcome in its Migrated condition
+func (r *GroupMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	reconcilerLog := ctrl.Log.WithName("group-migration").WithValues(logging.WorkspaceKey, req.Name)
+	windowOpen := r.WindowEnd.IsZero() || time.Now().Before(r.WindowEnd)
+
+	mirror := &unstructured.Unstructured{}
+	mirror.SetGroupVersionKind(r.targetGVK())
+	err := r.Get(ctx, req.NamespacedName, mirror)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	exists := err == nil
+	mirrored := exists && mirror.GetAnnotations()[MirroredFromAnnotation] != ""
+
+	workspace := &environmentv1alpha1.Workspace{}
+	if err := r.Get(ctx, req.NamespacedName, workspace); apierrors.IsNotFound(err) {
+		// Workspaces deleted during the transition window are deleted in the target group as well
+		if mirrored && windowOpen {
+			reconcilerLog.Info(fmt.Sprintf("Deleting mirror Workspace.Name %s in group %s", req.Name, r.TargetGroup))
+			return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, mirror))
+		}
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !r.Filter.Matches(workspace) || !workspace.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionMigrated,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             migrationSynced,
+		Message:            fmt.Sprintf("Workspace is mirrored to the %s group", r.TargetGroup),
+	}
+	switch {
+	case !windowOpen:
+		condition.Reason = migrationFrozen
+		condition.Message = fmt.Sprintf("Transition window ended at %s, the Workspace of the %s group is the source of truth",
+			r.WindowEnd.UTC().Format(time.RFC3339), r.TargetGroup)
+	case exists && !mirrored:
+		// A Workspace created directly in the target group is never overwritten
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = migrationConflict
+		condition.Message = fmt.Sprintf("Workspace %s already exists in the %s group and is not a mirror", workspace.Name, r.TargetGroup)
+	default:
+		desired, err := r.mirrorForWorkspace(workspace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !mirrored {
+			reconcilerLog.Info(fmt.Sprintf("Creating mirror Workspace.Name %s in group %s", workspace.Name, r.TargetGroup))
+			if err := r.Create(ctx, desired); err != nil {
+				return ctrl.Result{}, err
+			}
+		} else if !equality.Semantic.DeepEqual(mirror.Object["spec"], desired.Object["spec"]) ||
+			!equality.Semantic.DeepEqual(mirror.GetLabels(), desired.GetLabels()) ||
+			!equality.Semantic.DeepEqual(mirror.GetAnnotations(), desired.GetAnnotations()) {
+			reconcilerLog.Info(fmt.Sprintf("Updating mirror Workspace.Name %s in group %s", workspace.Name, r.TargetGroup))
+			mirror.Object["spec"] = desired.Object["spec"]
+			mirror.SetLabels(desired.GetLabels())
+			mirror.SetAnnotations(desired.GetAnnotations())
+			if err := r.Update(ctx, mirror); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	previous := meta.FindStatusCondition(workspace.Status.Conditions, ConditionMigrated)
+	if previous != nil && previous.Reason == condition.Reason && previous.Message == condition.Message &&
+		previous.ObservedGeneration == condition.ObservedGeneration {
+		return r.requeueAtWindowEnd(windowOpen), nil
+	}
+	meta.SetStatusCondition(&workspace.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return ctrl.Result{}, err
+	}
+	return r.requeueAtWindowEnd(windowOpen), nil
+}
+
+// requeueAtWindowEnd requeues the Workspace at the end of the transition window to freeze its mirror
+func (r *GroupMigrationReconciler) requeueAtWindowEnd(windowOpen bool) ctrl.Result {
+	if !windowOpen || r.WindowEnd.IsZero() {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: time.Until(r.WindowEnd)}
+}
+
+// mirrorForWorkspace returns the Workspace of the target group mirroring the workspace.
+// The status is left to the operator serving the target group.
+func (r *GroupMigrationReconciler) mirrorForWorkspace(workspace *environmentv1alpha1.Workspace) (*unstructured.Unstructured, error) {
+	spec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&workspace.Spec)
+	if err != nil {
+		return nil, err
+	}
+	annotations := map[string]string{}
+	for k, v := range workspace.Annotations {
+		annotations[k] = v
+	}
+	delete(annotations, LastAppliedSpecAnnotation)
+	delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	annotations[MirroredFromAnnotation] = fmt.Sprintf("%s/%s", environmentv1alpha1.GroupVersion.Group, workspace.Name)
+
+	mirror := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	mirror.SetGroupVersionKind(r.targetGVK())
+	mirror.SetName(workspace.Name)
+	mirror.SetLabels(workspace.Labels)
+	mirror.SetAnnotations(annotations)
+	return mirror, nil
+}
+
+// Describe sends the descriptor of the migration metrics
+func (r *GroupMigrationReconciler) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workspaceMigrationDesc
+}
+
+// Collect counts the Workspaces per state of their migration from their Migrated condition
+func (r *GroupMigrationReconciler) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := r.List(ctx, workspaces); err != nil {
+		ctrl.Log.WithName("metrics").Error(err, "Failed to list Workspaces")
+		return
+	}
+	counts := map[string]float64{}
+	for i := range workspaces.Items {
+		workspace := &workspaces.Items[i]
+		if !r.Filter.Matches(workspace) {
+			continue
+		}
+		condition := meta.FindStatusCondition(workspace.Status.Conditions, ConditionMigrated)
+		if condition == nil || condition.ObservedGeneration != workspace.Generation {
+			counts["Pending"]++
+			continue
+		}
+		counts[condition.Reason]++
+	}
+	for _, state := range append([]string{"Pending"}, migrationStates...) {
+		ch <- prometheus.MustNewConstMetric(workspaceMigrationDesc, prometheus.GaugeValue, counts[state], r.TargetGroup, state)
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// The changes made directly to the mirrors are reverted during the transition window.
+func (r *GroupMigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	mirror := &unstructured.Unstructured{}
+	mirror.SetGroupVersionKind(r.targetGVK())
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("group-migration").
+		For(&environmentv1alpha1.Workspace{}).
+		Watches(&source.Kind{Type: mirror}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []ctrl.Request {
+			return []ctrl.Request{{NamespacedName: types.NamespacedName{Name: obj.GetName()}}}
+		})).
+		Complete(r)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/cron"
+)
+
+// hibernationWindow is a parsed window of the hibernation schedule of a workspace
+type hibernationWindow struct {
+	start, stop *cron.Schedule
+}
+
+// parseHibernation parses the time zone and the start and stop cron expressions of the hibernation schedule
+func parseHibernation(hibernation *environmentv1alpha1.WorkspaceHibernation) (*time.Location, []hibernationWindow, error) {
+	location, err := time.LoadLocation(hibernation.TimeZone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid time zone %q of spec.hibernation: %w", hibernation.TimeZone, err)
+	}
+	windows := make([]hibernationWindow, 0, len(hibernation.Schedule))
+	for i, window := range hibernation.Schedule {
+		start, err := cron.Parse(window.Start)
+		if err != nil {
+			return nil, nil, fmt.Errorf("spec.hibernation.schedule[%d].start: %w", i, err)
+		}
+		stop, err := cron.Parse(window.Stop)
+		if err != nil {
+			return nil, nil, fmt.Errorf("spec.hibernation.schedule[%d].stop: %w", i, err)
+		}
+		windows = append(windows, hibernationWindow{start: start, stop: stop})
+	}
+	return location, windows, nil
+}
+
+// active reports whether the window covers t, i.e. it started more recently than it stopped
+func (w hibernationWindow) active(t time.Time) bool {
+	start := w.start.Prev(t)
+	return !start.IsZero() && start.After(w.stop.Prev(t))
+}
+
+// validateHibernation checks the time zone and the cron expressions of the hibernation schedule of the workspace
+func validateHibernation(workspace *environmentv1alpha1.Workspace) error {
+	if workspace.Spec.Hibernation == nil {
+		return nil
+	}
+	if len(workspace.Spec.Hibernation.Schedule) == 0 {
+		return fmt.Errorf("spec.hibernation.schedule must have at least one window")
+	}
+	_, _, err := parseHibernation(workspace.Spec.Hibernation)
+	return err
+}
+
+// hibernating reports whether a window of the hibernation schedule of the workspace covers now
+func hibernating(workspace *environmentv1alpha1.Workspace, now time.Time) bool {
+	if workspace.Spec.Hibernation == nil {
+		return false
+	}
+	location, windows, err := parseHibernation(workspace.Spec.Hibernation)
+	if err != nil {
+		return false
+	}
+	for _, window := range windows {
+		if window.active(now.In(location)) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextHibernationChange returns the first time after now a window of the hibernation schedule of the workspace
+// starts or stops, zero when the workspace has no hibernation schedule
+func nextHibernationChange(workspace *environmentv1alpha1.Workspace, now time.Time) time.Time {
+	if workspace.Spec.Hibernation == nil {
+		return time.Time{}
+	}
+	location, windows, err := parseHibernation(workspace.Spec.Hibernation)
+	if err != nil {
+		return time.Time{}
+	}
+	var next time.Time
+	for _, window := range windows {
+		for _, schedule := range []*cron.Schedule{window.start, window.stop} {
+			if change := schedule.Next(now.In(location)); !change.IsZero() && (next.IsZero() || change.Before(next)) {
+				next = change
+			}
+		}
+	}
+	return next
+}
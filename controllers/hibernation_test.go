@@ -0,0 +1,176 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/cron"
+)
+
+func mustParseCron(t *testing.T, spec string) *cron.Schedule {
+	t.Helper()
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		t.Fatalf("cron.Parse(%q): %v", spec, err)
+	}
+	return schedule
+}
+
+func TestHibernationWindowActive(t *testing.T) {
+	// 2023-06-07 is a Wednesday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, time.June, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name        string
+		start, stop string
+		t           time.Time
+		want        bool
+	}{
+		{name: "nightly before the start", start: "0 20 * * *", stop: "0 8 * * *", t: at(7, 19, 59)},
+		{name: "nightly at the start", start: "0 20 * * *", stop: "0 8 * * *", t: at(7, 20, 0), want: true},
+		{name: "nightly after midnight", start: "0 20 * * *", stop: "0 8 * * *", t: at(8, 3, 0), want: true},
+		{name: "nightly at the stop", start: "0 20 * * *", stop: "0 8 * * *", t: at(8, 8, 0)},
+		{name: "nightly during the day", start: "0 20 * * *", stop: "0 8 * * *", t: at(8, 12, 0)},
+		{name: "weekend on a weekday", start: "0 0 * * 6", stop: "0 0 * * 1", t: at(7, 12, 0)},
+		{name: "weekend on a Saturday", start: "0 0 * * 6", stop: "0 0 * * 1", t: at(10, 12, 0), want: true},
+		{name: "weekend on a Sunday night", start: "0 0 * * 6", stop: "0 0 * * 1", t: at(11, 23, 59), want: true},
+		{name: "weekend on the Monday", start: "0 0 * * 6", stop: "0 0 * * 1", t: at(12, 0, 0)},
+		{name: "start never reached", start: "0 0 30 2 *", stop: "0 8 * * *", t: at(7, 12, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := hibernationWindow{start: mustParseCron(t, tt.start), stop: mustParseCron(t, tt.stop)}
+			if got := window.active(tt.t); got != tt.want {
+				t.Errorf("active(%s) = %t, want %t", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextHibernationChange(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	stJohns, err := time.LoadLocation("America/St_Johns")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	now := time.Date(2023, time.June, 7, 12, 0, 0, 0, time.UTC)
+	nightly := environmentv1alpha1.WorkspaceHibernationWindow{Start: "0 20 * * *", Stop: "0 8 * * *"}
+	lunch := environmentv1alpha1.WorkspaceHibernationWindow{Start: "30 12 * * *", Stop: "30 13 * * *"}
+
+	tests := []struct {
+		name        string
+		hibernation *environmentv1alpha1.WorkspaceHibernation
+		want        time.Time
+	}{
+		{
+			name: "no hibernation schedule",
+		},
+		{
+			name:        "next start",
+			hibernation: &environmentv1alpha1.WorkspaceHibernation{Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{nightly}},
+			want:        time.Date(2023, time.June, 7, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "earliest change of all the windows",
+			hibernation: &environmentv1alpha1.WorkspaceHibernation{Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{nightly, lunch}},
+			want:        time.Date(2023, time.June, 7, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "in the time zone of the schedule",
+			hibernation: &environmentv1alpha1.WorkspaceHibernation{
+				Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{nightly},
+				TimeZone: "Europe/Berlin",
+			},
+			want: time.Date(2023, time.June, 7, 20, 0, 0, 0, berlin),
+		},
+		{
+			name: "in a half hour offset time zone",
+			hibernation: &environmentv1alpha1.WorkspaceHibernation{
+				Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{nightly},
+				TimeZone: "Asia/Kolkata",
+			},
+			want: time.Date(2023, time.June, 7, 20, 0, 0, 0, kolkata),
+		},
+		{
+			name: "in a half hour offset time zone with daylight saving time",
+			hibernation: &environmentv1alpha1.WorkspaceHibernation{
+				Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{nightly},
+				TimeZone: "America/St_Johns",
+			},
+			want: time.Date(2023, time.June, 7, 20, 0, 0, 0, stJohns),
+		},
+		{
+			name:        "invalid schedule",
+			hibernation: &environmentv1alpha1.WorkspaceHibernation{Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{{Start: "0 25 * * *", Stop: "0 8 * * *"}}},
+		},
+		{
+			name: "invalid time zone",
+			hibernation: &environmentv1alpha1.WorkspaceHibernation{
+				Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{nightly},
+				TimeZone: "Mars/Olympus_Mons",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := &environmentv1alpha1.Workspace{}
+			workspace.Spec.Hibernation = tt.hibernation
+			if got := nextHibernationChange(workspace, now); !got.Equal(tt.want) {
+				t.Errorf("nextHibernationChange() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHibernating(t *testing.T) {
+	for _, zone := range []string{"UTC", "Asia/Kolkata", "Asia/Kathmandu", "America/St_Johns"} {
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			t.Skipf("time zone database unavailable: %v", err)
+		}
+		workspace := &environmentv1alpha1.Workspace{}
+		workspace.Spec.Hibernation = &environmentv1alpha1.WorkspaceHibernation{
+			Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{{Start: "0 20 * * *", Stop: "0 8 * * *"}},
+			TimeZone: zone,
+		}
+		for _, tt := range []struct {
+			hour, minute int
+			want         bool
+		}{
+			{hour: 19, minute: 59},
+			{hour: 20, minute: 0, want: true},
+			{hour: 23, minute: 30, want: true},
+			{hour: 12, minute: 0},
+		} {
+			now := time.Date(2023, time.June, 7, tt.hour, tt.minute, 0, 0, location)
+			if got := hibernating(workspace, now); got != tt.want {
+				t.Errorf("hibernating(%s) in %s = %t, want %t", now, zone, got, tt.want)
+			}
+		}
+	}
+}
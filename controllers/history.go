@@ -0,0 +1,137 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// LastAppliedSpecAnnotation holds the last spec applied by the operator, it is diffed against the next applied spec
+const LastAppliedSpecAnnotation = "environment.tf.operator.com/last-applied-spec"
+
+// maxChangeValueLength truncates the values shown in the changes of a revision
+const maxChangeValueLength = 64
+
+// specHash returns a short hash identifying the spec
+func specHash(spec []byte) string {
+	sum := sha256.Sum256(spec)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// flattenSpec flattens the JSON spec into a map of field paths such as spec.resources.cpu to their values.
+// Lists are compared as a whole.
+func flattenSpec(prefix string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			flattenSpec(prefix+"."+k, child, fields)
+		}
+	case string:
+		fields[prefix] = v
+	default:
+		data, _ := json.Marshal(v)
+		fields[prefix] = string(data)
+	}
+}
+
+// specChanges returns the field level changes between two JSON specs
+func specChanges(previous, current []byte) ([]string, error) {
+	var previousSpec, currentSpec interface{}
+	if err := json.Unmarshal(previous, &previousSpec); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(current, &currentSpec); err != nil {
+		return nil, err
+	}
+	previousFields, currentFields := map[string]string{}, map[string]string{}
+	flattenSpec("spec", previousSpec, previousFields)
+	flattenSpec("spec", currentSpec, currentFields)
+
+	short := func(value string, ok bool) string {
+		if !ok {
+			return "<none>"
+		}
+		if len(value) > maxChangeValueLength {
+			return value[:maxChangeValueLength] + "..."
+		}
+		return value
+	}
+	var changes []string
+	for field, value := range currentFields {
+		if previousValue, ok := previousFields[field]; !ok || previousValue != value {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", field, short(previousValue, ok), short(value, true)))
+		}
+	}
+	for field, previousValue := range previousFields {
+		if _, ok := currentFields[field]; !ok {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", field, short(previousValue, true), short("", false)))
+		}
+	}
+	sort.Strings(changes)
+	return changes, nil
+}
+
+// reconcileHistory records the spec of the workspace in status.history once it is applied,
+// keeping the last HistoryLimit revisions
+func (r *WorkspaceReconciler) reconcileHistory(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	if r.HistoryLimit <= 0 {
+		return nil
+	}
+	if len(workspace.Status.History) > 0 && workspace.Status.History[0].Generation == workspace.Generation {
+		return nil
+	}
+
+	spec, err := json.Marshal(workspace.Spec)
+	if err != nil {
+		return err
+	}
+	revision := environmentv1alpha1.WorkspaceRevision{
+		Generation: workspace.Generation,
+		SpecHash:   specHash(spec),
+		AppliedAt:  metav1.Now(),
+	}
+	if previous, ok := workspace.Annotations[LastAppliedSpecAnnotation]; ok {
+		revision.Changes, err = specChanges([]byte(previous), spec)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Recording revision %d of Workspace %s", workspace.Generation, workspace.Name))
+	history := append([]environmentv1alpha1.WorkspaceRevision{revision}, workspace.Status.History...)
+	if len(history) > r.HistoryLimit {
+		history = history[:r.HistoryLimit]
+	}
+	workspace.Status.History = history
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return err
+	}
+
+	setAnnotation(&workspace.ObjectMeta, LastAppliedSpecAnnotation, string(spec))
+	return r.Update(ctx, workspace)
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+const (
+	// IdentityEventsPath is the path the events of the identity provider or HR system are served on
+	IdentityEventsPath = "/identity-events"
+
+	// DeactivatedUsersAnnotation lists the users of the workspace deactivated in the identity provider,
+	// comma separated. They are left out of the RoleBindings of the workspace until they are reactivated.
+	DeactivatedUsersAnnotation = "environment.tf.operator.com/deactivated-users"
+
+	// IdentityChangedAtAnnotation is the time the members of one of the groups of the workspace last changed,
+	// the members of its teams fetched before are fetched again
+	IdentityChangedAtAnnotation = "environment.tf.operator.com/identity-changed-at"
+)
+
+// Types of the identity events
+const (
+	IdentityEventUserDeactivated = "UserDeactivated"
+	IdentityEventUserReactivated = "UserReactivated"
+	IdentityEventGroupChanged    = "GroupChanged"
+)
+
+// IdentityEvent is an event POSTed by the identity provider or HR system
+type IdentityEvent struct {
+	// Type is one of UserDeactivated, UserReactivated and GroupChanged
+	Type string `json:"type"`
+	// User is the username of the user, as authenticated by the cluster, of the UserDeactivated and UserReactivated events
+	User string `json:"user,omitempty"`
+	// Group is the group or team whose members changed of the GroupChanged events
+	Group string `json:"group,omitempty"`
+}
+
+// IdentityEvents receives the deactivations of users and the membership changes of groups from an identity provider
+// or an HR system, and marks the affected workspaces so that their RoleBindings are updated right away instead of
+// on their next resync or team sync. The marks are annotations, so that the leader picks them up whichever replica
+// received the event.
+type IdentityEvents struct {
+	client.Client
+
+	// Secret is the secret the events are signed with in the X-Signature-256 header, as sha256=<hex HMAC of the body>.
+	// The events are rejected when it is empty.
+	Secret string
+
+	// Filter scopes the events to the workspaces of the operator instance
+	Filter *WorkspaceFilter
+}
+
+// ServeHTTP verifies the signature of an identity event and marks the workspaces it affects
+func (i *IdentityEvents) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mac := hmac.New(sha256.New, []byte(i.Secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if i.Secret == "" || !hmac.Equal([]byte(signature), []byte(req.Header.Get("X-Signature-256"))) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var identityEvent IdentityEvent
+	if err := json.Unmarshal(body, &identityEvent); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch {
+	case (identityEvent.Type == IdentityEventUserDeactivated || identityEvent.Type == IdentityEventUserReactivated) && identityEvent.User == "":
+		http.Error(w, fmt.Sprintf("%s event without user", identityEvent.Type), http.StatusBadRequest)
+		return
+	case identityEvent.Type == IdentityEventGroupChanged && identityEvent.Group == "":
+		http.Error(w, fmt.Sprintf("%s event without group", identityEvent.Type), http.StatusBadRequest)
+		return
+	case identityEvent.Type != IdentityEventUserDeactivated && identityEvent.Type != IdentityEventUserReactivated &&
+		identityEvent.Type != IdentityEventGroupChanged:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := i.handle(req.Context(), identityEvent); err != nil {
+		ctrl.Log.WithName("identity-events").Error(err, fmt.Sprintf("Failed to handle %s event", identityEvent.Type))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handle annotates the workspaces affected by the identity event
+func (i *IdentityEvents) handle(ctx context.Context, identityEvent IdentityEvent) error {
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := i.List(ctx, workspaces); err != nil {
+		return err
+	}
+	bound, err := i.boundWorkspaces(ctx, identityEvent)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for n := range workspaces.Items {
+		workspace := &workspaces.Items[n]
+		if !i.Filter.Matches(workspace) || !workspace.DeletionTimestamp.IsZero() {
+			continue
+		}
+		var annotate func(annotations map[string]string)
+		switch identityEvent.Type {
+		case IdentityEventUserDeactivated:
+			named := false
+			for _, user := range workspaceUsers(workspace) {
+				named = named || user == identityEvent.User
+			}
+			if !bound[workspace.Name] && !named {
+				continue
+			}
+			annotate = func(annotations map[string]string) {
+				deactivated := deactivatedUsers(annotations)
+				deactivated[identityEvent.User] = true
+				annotations[DeactivatedUsersAnnotation] = joinUsers(deactivated)
+			}
+		case IdentityEventUserReactivated:
+			if !deactivatedUsers(workspace.Annotations)[identityEvent.User] {
+				continue
+			}
+			annotate = func(annotations map[string]string) {
+				deactivated := deactivatedUsers(annotations)
+				delete(deactivated, identityEvent.User)
+				if len(deactivated) == 0 {
+					delete(annotations, DeactivatedUsersAnnotation)
+					return
+				}
+				annotations[DeactivatedUsersAnnotation] = joinUsers(deactivated)
+			}
+		case IdentityEventGroupChanged:
+			if !bound[workspace.Name] && !hasTeam(workspace, identityEvent.Group) {
+				continue
+			}
+			annotate = func(annotations map[string]string) {
+				annotations[IdentityChangedAtAnnotation] = now
+			}
+		}
+		ctrl.Log.WithName("identity-events").Info(fmt.Sprintf("Marking Workspace.Name %s after %s event", workspace.Name, identityEvent.Type))
+		if err := i.annotate(ctx, workspace.Name, annotate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boundWorkspaces returns the names of the workspaces whose RoleBindings bind the user or the group of the event
+func (i *IdentityEvents) boundWorkspaces(ctx context.Context, identityEvent IdentityEvent) (map[string]bool, error) {
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := i.List(ctx, roleBindings, client.MatchingLabels{ManagedByLabel: ManagedByValue}); err != nil {
+		return nil, err
+	}
+	kind, name := rbacv1.UserKind, identityEvent.User
+	if identityEvent.Type == IdentityEventGroupChanged {
+		kind, name = rbacv1.GroupKind, identityEvent.Group
+	}
+	bound := map[string]bool{}
+	for _, roleBinding := range roleBindings.Items {
+		for _, subject := range roleBinding.Subjects {
+			if subject.Kind == kind && subject.Name == name {
+				bound[roleBinding.Labels[WorkspaceLabel]] = true
+			}
+		}
+	}
+	return bound, nil
+}
+
+// annotate updates the annotations of the workspace, retrying on conflicts
+func (i *IdentityEvents) annotate(ctx context.Context, name string, annotate func(annotations map[string]string)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		workspace := &environmentv1alpha1.Workspace{}
+		if err := i.Get(ctx, types.NamespacedName{Name: name}, workspace); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if workspace.Annotations == nil {
+			workspace.Annotations = map[string]string{}
+		}
+		annotate(workspace.Annotations)
+		return i.Update(ctx, workspace)
+	})
+}
+
+// hasTeam reports whether the group is one of the teams of the workspace
+func hasTeam(workspace *environmentv1alpha1.Workspace, group string) bool {
+	for _, team := range workspace.Spec.Teams {
+		if strings.EqualFold(team.Name, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// deactivatedUsers returns the users of the DeactivatedUsersAnnotation
+func deactivatedUsers(annotations map[string]string) map[string]bool {
+	users := map[string]bool{}
+	for _, user := range strings.Split(annotations[DeactivatedUsersAnnotation], ",") {
+		if user = strings.TrimSpace(user); user != "" {
+			users[user] = true
+		}
+	}
+	return users
+}
+
+// joinUsers returns the value of the DeactivatedUsersAnnotation listing the users
+func joinUsers(users map[string]bool) string {
+	names := make([]string, 0, len(users))
+	for user := range users {
+		names = append(names, user)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// identityChangedAt returns the time the members of a group of the workspace last changed, zero when they never did
+func identityChangedAt(workspace *environmentv1alpha1.Workspace) time.Time {
+	changedAt, err := time.Parse(time.RFC3339Nano, workspace.Annotations[IdentityChangedAtAnnotation])
+	if err != nil {
+		return time.Time{}
+	}
+	return changedAt
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=clusterqueues;localqueues,verbs=get;list;watch;create;update;patch;delete
+
+// clusterQueueGVK and localQueueGVK are the Kueue queue kinds.
+// They are handled as unstructured so that the operator does not depend on Kueue.
+var (
+	clusterQueueGVK = schema.GroupVersionKind{Group: "kueue.x-k8s.io", Version: "v1beta1", Kind: "ClusterQueue"}
+	localQueueGVK   = schema.GroupVersionKind{Group: "kueue.x-k8s.io", Version: "v1beta1", Kind: "LocalQueue"}
+)
+
+// reconcileKueue keeps the ClusterQueue and the LocalQueue of the workspace in sync with spec.batch.kueue
+// and with the resources of the workspace, and removes them when Kueue is disabled.
+// Clusters without the Kueue CRDs are skipped.
+func (r *WorkspaceReconciler) reconcileKueue(ctx context.Context, workspace *environmentv1alpha1.Workspace, resources environmentv1alpha1.WorkspaceResource) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+	enabled := workspace.Spec.Batch != nil && workspace.Spec.Batch.Kueue != nil
+
+	var clusterQueue, localQueue *unstructured.Unstructured
+	if enabled {
+		var err error
+		if clusterQueue, err = r.clusterQueueForWorkspace(workspace, resources); err != nil {
+			return err
+		}
+		if localQueue, err = r.localQueueForWorkspace(workspace); err != nil {
+			return err
+		}
+	}
+
+	for _, queue := range []struct {
+		gvk     schema.GroupVersionKind
+		key     types.NamespacedName
+		desired *unstructured.Unstructured
+		fields  []string
+	}{
+		{clusterQueueGVK, types.NamespacedName{Name: workspace.Spec.Name}, clusterQueue, []string{"namespaceSelector", "resourceGroups", "cohort"}},
+		{localQueueGVK, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-queue", workspace.Spec.Name)}, localQueue, []string{"clusterQueue"}},
+	} {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(queue.gvk)
+		err := r.Get(ctx, queue.key, existing)
+		if meta.IsNoMatchError(err) {
+			if enabled {
+				reconcilerLog.Info("Kueue CRDs are not installed. Skipping batch queueing for Workspace")
+			}
+			return nil
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		exists := err == nil
+
+		if queue.desired == nil {
+			if exists {
+				reconcilerLog.Info(fmt.Sprintf("Deleting %s %s.Name %s", queue.gvk.Kind, queue.gvk.Kind, queue.key.Name))
+				if err := r.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+					return err
+				}
+			}
+			continue
+		}
+		if !exists {
+			reconcilerLog.Info(fmt.Sprintf("Creating a new %s %s.Name %s", queue.gvk.Kind, queue.gvk.Kind, queue.key.Name))
+			if err := r.apply(ctx, queue.desired); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// check if the managed fields of the spec changed, the other ones being defaulted by Kueue
+		spec, _, _ := unstructured.NestedMap(existing.Object, "spec")
+		if spec == nil {
+			spec = map[string]interface{}{}
+		}
+		desiredSpec := queue.desired.Object["spec"].(map[string]interface{})
+		changed := false
+		for _, field := range queue.fields {
+			value, ok := desiredSpec[field]
+			if equality.Semantic.DeepEqual(spec[field], value) {
+				continue
+			}
+			if ok {
+				spec[field] = value
+			} else {
+				delete(spec, field)
+			}
+			changed = true
+		}
+		if changed {
+			reconcilerLog.Info(fmt.Sprintf("Spec not same for %s %s.Name %s", queue.gvk.Kind, queue.gvk.Kind, queue.key.Name))
+			existing.Object["spec"] = spec
+			if err := r.update(ctx, existing); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ClusterQueue for Workspace, admitting the workloads of the workspace namespace up to the cpu and memory of the workspace
+func (r *WorkspaceReconciler) clusterQueueForWorkspace(workspace *environmentv1alpha1.Workspace, resources environmentv1alpha1.WorkspaceResource) (*unstructured.Unstructured, error) {
+	kueue := workspace.Spec.Batch.Kueue
+	flavor := kueue.ResourceFlavor
+	if flavor == "" {
+		flavor = "default-flavor"
+	}
+
+	var coveredResources, quotas []interface{}
+	for _, quota := range []struct {
+		name  corev1.ResourceName
+		value string
+	}{
+		{corev1.ResourceCPU, resources.CPU},
+		{corev1.ResourceMemory, resources.Memory},
+	} {
+		quantity, err := quotaResource.ParseQuantity(quota.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s quota %q: %w", quota.name, quota.value, err)
+		}
+		coveredResources = append(coveredResources, string(quota.name))
+		quotas = append(quotas, map[string]interface{}{"name": string(quota.name), "nominalQuota": quantity.String()})
+	}
+
+	spec := map[string]interface{}{
+		"namespaceSelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{corev1.LabelMetadataName: workspace.Spec.Name},
+		},
+		"resourceGroups": []interface{}{
+			map[string]interface{}{
+				"coveredResources": coveredResources,
+				"flavors": []interface{}{
+					map[string]interface{}{"name": flavor, "resources": quotas},
+				},
+			},
+		},
+	}
+	if kueue.Cohort != "" {
+		spec["cohort"] = kueue.Cohort
+	}
+
+	clusterQueue := &unstructured.Unstructured{}
+	clusterQueue.SetGroupVersionKind(clusterQueueGVK)
+	clusterQueue.SetName(workspace.Spec.Name)
+	clusterQueue.SetLabels(labelsForWorkspace(workspace, nil))
+	clusterQueue.SetAnnotations(annotationsForWorkspace(workspace))
+	clusterQueue.Object["spec"] = spec
+	if err := ctrl.SetControllerReference(workspace, clusterQueue, r.Scheme); err != nil {
+		return nil, err
+	}
+	return clusterQueue, nil
+}
+
+// LocalQueue for Workspace, the queue the batch workloads of the workspace namespace are submitted to
+func (r *WorkspaceReconciler) localQueueForWorkspace(workspace *environmentv1alpha1.Workspace) (*unstructured.Unstructured, error) {
+	localQueue := &unstructured.Unstructured{}
+	localQueue.SetGroupVersionKind(localQueueGVK)
+	localQueue.SetName(fmt.Sprintf("%s-queue", workspace.Spec.Name))
+	localQueue.SetNamespace(workspace.Spec.Name)
+	localQueue.SetLabels(labelsForWorkspace(workspace, nil))
+	localQueue.SetAnnotations(annotationsForWorkspace(workspace))
+	localQueue.Object["spec"] = map[string]interface{}{
+		"clusterQueue": workspace.Spec.Name,
+	}
+	if err := ctrl.SetControllerReference(workspace, localQueue, r.Scheme); err != nil {
+		return nil, err
+	}
+	return localQueue, nil
+}
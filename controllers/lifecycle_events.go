@@ -0,0 +1,63 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// Reasons of the Events emitted on the Workspace along its provisioning, so that `kubectl describe workspace`
+// shows its provisioning history
+const (
+	// EventNamespaceCreated is emitted when the namespace of the workspace is created
+	EventNamespaceCreated = "NamespaceCreated"
+	// EventQuotaApplied is emitted when the ResourceQuota of the workspace is created or updated
+	EventQuotaApplied = "QuotaApplied"
+	// EventRBACSynced is emitted when a Role or a RoleBinding of the workspace is created, or its rules or subjects change
+	EventRBACSynced = "RBACSynced"
+	// EventDriftCorrected is emitted when a resource of the workspace changed out-of-band is brought back to its desired state
+	EventDriftCorrected = "DriftCorrected"
+	// EventSuspended is emitted when the workspace is suspended
+	EventSuspended = "Suspended"
+	// EventResumed is emitted when the suspended workspace is resumed
+	EventResumed = "Resumed"
+	// EventReconcileFailed is emitted when a reconciliation of the workspace fails
+	EventReconcileFailed = "ReconcileFailed"
+)
+
+// event emits an Event on the workspace, nothing is emitted when the reconciler has no recorder
+func (r *WorkspaceReconciler) event(workspace *environmentv1alpha1.Workspace, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(workspace, eventType, reason, message)
+}
+
+// patchEventReason returns the reason of the Event of a resource of the given kind patched back to its desired state
+func patchEventReason(kind string) string {
+	if kind == "ResourceQuota" {
+		return EventQuotaApplied
+	}
+	return EventDriftCorrected
+}
+
+// reconcileFailedEvent emits the Warning Event of a failed reconciliation of the workspace
+func (r *WorkspaceReconciler) reconcileFailedEvent(workspace *environmentv1alpha1.Workspace, err error) {
+	r.event(workspace, corev1.EventTypeWarning, EventReconcileFailed, err.Error())
+}
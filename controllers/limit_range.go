@@ -0,0 +1,171 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// reconcileLimitRange keeps the LimitRange of the workspace namespace in sync with spec.limits and spec.limitRange
+func (r *WorkspaceReconciler) reconcileLimitRange(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	lr, err := r.limitRangeForWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	limitRange := &corev1.LimitRange{}
+	err = r.Get(ctx, types.NamespacedName{Name: lr.Name, Namespace: lr.Namespace}, limitRange)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if len(lr.Spec.Limits) == 0 {
+		// Delete the LimitRange when spec.limits is removed
+		if exists && metav1.IsControlledBy(limitRange, workspace) {
+			reconcilerLog.Info(fmt.Sprintf("Deleting LimitRange LimitRange.Name %s", limitRange.Name))
+			if err := r.Delete(ctx, limitRange); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	if !exists {
+		reconcilerLog.Info(fmt.Sprintf("Creating a new LimitRange LimitRange.Name %s", lr.Name))
+		if err := r.apply(ctx, lr); err != nil {
+			return err
+		}
+		return nil
+	}
+	// check if the maximums of spec.limits changed
+	if !equality.Semantic.DeepEqual(limitRange.Spec, lr.Spec) {
+		reconcilerLog.Info(fmt.Sprintf("Spec not same for LimitRange.Name %s in Namespace.Name %s", lr.Name, lr.Namespace))
+		limitRange.Spec = lr.Spec
+		if err := r.update(ctx, limitRange); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LimitRange of spec.limits and spec.limitRange, named <namespace>-limits. It has no limits when neither is set.
+func (r *WorkspaceReconciler) limitRangeForWorkspace(workspace *environmentv1alpha1.Workspace) (*corev1.LimitRange, error) {
+	lr := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-limits", workspace.Spec.Name),
+			Namespace:   workspace.Spec.Name,
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
+		},
+	}
+	if container := containerLimits(workspace); container != nil {
+		lr.Spec.Limits = append(lr.Spec.Limits, *container)
+	}
+	if limits := workspace.Spec.Limits; limits != nil && len(limits.MaxPerPod) > 0 {
+		lr.Spec.Limits = append(lr.Spec.Limits, corev1.LimitRangeItem{
+			Type: corev1.LimitTypePod,
+			Max:  limits.MaxPerPod,
+		})
+	}
+	if err := ctrl.SetControllerReference(workspace, lr, r.Scheme); err != nil {
+		return nil, err
+	}
+	return lr, nil
+}
+
+// containerLimits returns the limits of a single container of spec.limits and spec.limitRange, or nil when
+// neither sets any. The maximum of spec.limitRange overrides spec.limits.maxPerContainer per resource.
+// The defaults are filled in as the API server would default them, so that the LimitRange read back
+// is equal to the desired one: the default limit to the maximum, the default request to the default limit
+// then to the minimum.
+func containerLimits(workspace *environmentv1alpha1.Workspace) *corev1.LimitRangeItem {
+	container := corev1.LimitRangeItem{
+		Type:           corev1.LimitTypeContainer,
+		Max:            corev1.ResourceList{},
+		Min:            corev1.ResourceList{},
+		Default:        corev1.ResourceList{},
+		DefaultRequest: corev1.ResourceList{},
+	}
+	if limits := workspace.Spec.Limits; limits != nil {
+		mergeResources(container.Max, limits.MaxPerContainer)
+	}
+	if limitRange := workspace.Spec.LimitRange; limitRange != nil {
+		mergeResources(container.Max, limitRange.Max)
+		mergeResources(container.Min, limitRange.Min)
+		mergeResources(container.Default, limitRange.Default)
+		mergeResources(container.DefaultRequest, limitRange.DefaultRequest)
+	}
+	for _, defaults := range []struct{ from, to corev1.ResourceList }{
+		{container.Max, container.Default},
+		{container.Default, container.DefaultRequest},
+		{container.Min, container.DefaultRequest},
+	} {
+		for name, quantity := range defaults.from {
+			if _, ok := defaults.to[name]; !ok {
+				defaults.to[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	if len(container.Max) == 0 && len(container.Min) == 0 && len(container.Default) == 0 {
+		return nil
+	}
+	return &container
+}
+
+// mergeResources sets the quantities of from in to, overriding the ones already set
+func mergeResources(to, from corev1.ResourceList) {
+	for name, quantity := range from {
+		to[name] = quantity.DeepCopy()
+	}
+}
+
+// validateLimitRange checks that the limits of a single container of the workspace are consistent per resource,
+// i.e. min <= default request <= default limit <= max, as the API server would reject the LimitRange otherwise
+func validateLimitRange(workspace *environmentv1alpha1.Workspace) error {
+	container := containerLimits(workspace)
+	if container == nil {
+		return nil
+	}
+	for _, bound := range []struct {
+		lowerName, upperName string
+		lower, upper         corev1.ResourceList
+	}{
+		{"min", "defaultRequest", container.Min, container.DefaultRequest},
+		{"defaultRequest", "default", container.DefaultRequest, container.Default},
+		{"default", "max", container.Default, container.Max},
+		{"min", "max", container.Min, container.Max},
+	} {
+		for name, lower := range bound.lower {
+			if upper, ok := bound.upper[name]; ok && lower.Cmp(upper) > 0 {
+				return fmt.Errorf("spec.limitRange: %s %s of %s is greater than its %s %s", bound.lowerName, lower.String(), name, bound.upperName, upper.String())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// LogPipelineLabel marks the ConfigMaps holding rendered log pipeline snippets,
+// so that the log agents can discover them with a label selector
+const LogPipelineLabel = "environment.tf.operator.com/log-pipeline"
+
+// logIndexPattern matches the Elasticsearch index names of spec.logging.destination.index
+var logIndexPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._+-]*$`)
+
+// validateLogging checks the log destination of the workspace
+func validateLogging(workspace *environmentv1alpha1.Workspace) error {
+	if workspace.Spec.Logging == nil {
+		return nil
+	}
+	return validateLogDestination(workspace.Spec.Logging.Destination)
+}
+
+// validateLogDestination checks that the url and the index of the log destination can be rendered into the
+// pipeline configuration as-is: a line break or a space would let them inject directives into the configuration
+func validateLogDestination(destination environmentv1alpha1.WorkspaceLogDestination) error {
+	if strings.IndexFunc(destination.URL, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("spec.logging.destination.url %q must not contain whitespace", destination.URL)
+	}
+	u, err := url.Parse(destination.URL)
+	if err != nil {
+		return fmt.Errorf("invalid log destination url %q: %w", destination.URL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("spec.logging.destination.url %q must be an http or https url with a host", destination.URL)
+	}
+	if destination.Index != "" && !logIndexPattern.MatchString(destination.Index) {
+		return fmt.Errorf("spec.logging.destination.index %q must be a lowercase Elasticsearch index name matching %s", destination.Index, logIndexPattern)
+	}
+	return nil
+}
+
+// logPipelineNamespace returns the namespace the log pipeline ConfigMap of the workspace is created in
+func (r *WorkspaceReconciler) logPipelineNamespace(workspace *environmentv1alpha1.Workspace) string {
+	if r.LogPipelineNamespace != "" {
+		return r.LogPipelineNamespace
+	}
+	return workspace.Spec.Name
+}
+
+// reconcileLogPipeline keeps the log pipeline ConfigMap of the workspace in sync with spec.logging
+func (r *WorkspaceReconciler) reconcileLogPipeline(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: r.logPipelineNamespace(workspace), Name: fmt.Sprintf("%s-log-pipeline", workspace.Spec.Name)}, configMap)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if workspace.Spec.Logging == nil {
+		if exists {
+			reconcilerLog.Info(fmt.Sprintf("Deleting log pipeline ConfigMap ConfigMap.Name %s", configMap.Name))
+			if err := r.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	cm, err := r.logPipelineConfigMapForWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		reconcilerLog.Info(fmt.Sprintf("Creating a new log pipeline ConfigMap ConfigMap.Name %s", cm.Name))
+		return r.apply(ctx, cm)
+	}
+
+	// check if the rendered pipeline changed
+	if configMap.Data[logPipelineKey(workspace)] != cm.Data[logPipelineKey(workspace)] || len(configMap.Data) != len(cm.Data) {
+		reconcilerLog.Info(fmt.Sprintf("Log pipeline not same for ConfigMap %s in Namespace.Name %s", cm.Name, cm.Namespace))
+		configMap.Data = cm.Data
+		if err := r.update(ctx, configMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logPipelineKey is the ConfigMap key holding the rendered pipeline snippet
+func logPipelineKey(workspace *environmentv1alpha1.Workspace) string {
+	if workspace.Spec.Logging.Format == "vector" {
+		return "vector.toml"
+	}
+	return "fluent-bit.conf"
+}
+
+// Log pipeline ConfigMap for Workspace
+func (r *WorkspaceReconciler) logPipelineConfigMapForWorkspace(workspace *environmentv1alpha1.Workspace) (*corev1.ConfigMap, error) {
+	// the workspaces created before the validation of the destination are never rendered
+	if err := validateLogDestination(workspace.Spec.Logging.Destination); err != nil {
+		return nil, stall(err)
+	}
+	var snippet string
+	var err error
+	if workspace.Spec.Logging.Format == "vector" {
+		snippet = vectorPipeline(workspace.Spec.Name, workspace.Spec.Logging.Destination)
+	} else {
+		snippet, err = fluentBitPipeline(workspace.Spec.Name, workspace.Spec.Logging.Destination)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	labels := labelsForWorkspace(workspace, map[string]string{LogPipelineLabel: "true"})
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-log-pipeline", workspace.Spec.Name),
+			Namespace:   r.logPipelineNamespace(workspace),
+			Labels:      labels,
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Data: map[string]string{
+			logPipelineKey(workspace): snippet,
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, configMap, r.Scheme); err != nil {
+		return nil, err
+	}
+	return configMap, nil
+}
+
+// fluentBitPipeline renders a fluent-bit [OUTPUT] section matching the container logs of the namespace.
+// It assumes the default kubernetes tail input tagged kube.*
+func fluentBitPipeline(namespace string, destination environmentv1alpha1.WorkspaceLogDestination) (string, error) {
+	u, err := url.Parse(destination.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid log destination url %q: %w", destination.URL, err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	tls := "Off"
+	if u.Scheme == "https" {
+		tls = "On"
+	}
+
+	var b strings.Builder
+	b.WriteString("[OUTPUT]\n")
+	switch destination.Type {
+	case "elasticsearch":
+		b.WriteString("    Name  es\n")
+	case "loki":
+		b.WriteString("    Name  loki\n")
+	default:
+		b.WriteString("    Name  http\n")
+	}
+	fmt.Fprintf(&b, "    Match kube.var.log.containers.*_%s_*\n", namespace)
+	fmt.Fprintf(&b, "    Host  %s\n", u.Hostname())
+	fmt.Fprintf(&b, "    Port  %s\n", port)
+	fmt.Fprintf(&b, "    tls   %s\n", tls)
+	switch destination.Type {
+	case "elasticsearch":
+		if destination.Index != "" {
+			fmt.Fprintf(&b, "    Index %s\n", destination.Index)
+		}
+	case "loki":
+		fmt.Fprintf(&b, "    Labels namespace=%s\n", namespace)
+	default:
+		if u.Path != "" {
+			fmt.Fprintf(&b, "    URI   %s\n", u.EscapedPath())
+		}
+		b.WriteString("    Format json\n")
+	}
+	return b.String(), nil
+}
+
+// vectorPipeline renders a vector filter transform and sink for the logs of the namespace.
+// It assumes a kubernetes_logs source named kubernetes_logs.
+func vectorPipeline(namespace string, destination environmentv1alpha1.WorkspaceLogDestination) string {
+	id := fmt.Sprintf("workspace_%s", strings.ReplaceAll(namespace, "-", "_"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[transforms.%s]\n", id)
+	b.WriteString("type = \"filter\"\n")
+	b.WriteString("inputs = [\"kubernetes_logs\"]\n")
+	fmt.Fprintf(&b, "condition = '.kubernetes.pod_namespace == \"%s\"'\n\n", namespace)
+	fmt.Fprintf(&b, "[sinks.%s]\n", id)
+	fmt.Fprintf(&b, "type = %q\n", destination.Type)
+	fmt.Fprintf(&b, "inputs = [%q]\n", id)
+	switch destination.Type {
+	case "elasticsearch":
+		fmt.Fprintf(&b, "endpoints = [%q]\n", destination.URL)
+		if destination.Index != "" {
+			fmt.Fprintf(&b, "bulk.index = %q\n", destination.Index)
+		}
+	case "loki":
+		fmt.Fprintf(&b, "endpoint = %q\n", destination.URL)
+		b.WriteString("encoding.codec = \"json\"\n")
+		fmt.Fprintf(&b, "labels.namespace = %q\n", namespace)
+	default:
+		fmt.Fprintf(&b, "uri = %q\n", destination.URL)
+		b.WriteString("encoding.codec = \"json\"\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+func TestValidateLogDestination(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination environmentv1alpha1.WorkspaceLogDestination
+		valid       bool
+	}{
+		{name: "elasticsearch with an index", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "elasticsearch", URL: "https://elasticsearch.logging:9200", Index: "team-a"}, valid: true},
+		{name: "http with a path", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "http", URL: "http://collector.logging/v1/logs"}, valid: true},
+		{name: "index with a line break", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "elasticsearch", URL: "https://elasticsearch.logging:9200", Index: "team-a\n    Match *"}},
+		{name: "index with a space", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "elasticsearch", URL: "https://elasticsearch.logging:9200", Index: "team a"}},
+		{name: "uppercase index", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "elasticsearch", URL: "https://elasticsearch.logging:9200", Index: "Team-A"}},
+		{name: "url with a line break", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "http", URL: "http://collector.logging/logs\n    Match *"}},
+		{name: "url with a tab", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "http", URL: "http://collector.logging/\tlogs"}},
+		{name: "url without a scheme", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "loki", URL: "loki.logging:3100"}},
+		{name: "url of another scheme", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "loki", URL: "ftp://loki.logging"}},
+		{name: "url without a host", destination: environmentv1alpha1.WorkspaceLogDestination{Type: "http", URL: "http:///logs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLogDestination(tt.destination); (err == nil) != tt.valid {
+				t.Errorf("validateLogDestination() = %v, want valid %t", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestFluentBitPipelineEscapesPath(t *testing.T) {
+	snippet, err := fluentBitPipeline("team-a", environmentv1alpha1.WorkspaceLogDestination{Type: "http", URL: "http://collector.logging/logs%0A%20%20%20%20Match%20*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[OUTPUT]\n    Name  http\n    Match kube.var.log.containers.*_team-a_*\n    Host  collector.logging\n    Port  80\n    tls   Off\n" +
+		"    URI   /logs%0A%20%20%20%20Match%20*\n    Format json\n"
+	if snippet != want {
+		t.Errorf("fluentBitPipeline() = %q, want %q", snippet, want)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// ManagedByLabel marks every object generated for a Workspace as managed by the operator
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+
+	// ManagedByValue is the value of ManagedByLabel on the objects generated by the operator
+	ManagedByValue = "workspace-operator"
+
+	// FieldManager owns the fields of the objects applied by the operator with server-side apply
+	FieldManager = "workspace-operator"
+
+	// WorkspaceLabel marks every object generated for a Workspace with the name of the Workspace,
+	// so that they can be selected or cleaned up even in the shared namespaces
+	WorkspaceLabel = "environment.tf.operator.com/workspace"
+)
+
+// labelsForWorkspace returns the labels of an object generated for the workspace: the extra labels of the object,
+// overridden by spec.labels merged with the labels of its template, and the ownership labels of the operator
+// which can not be overridden by the users
+func labelsForWorkspace(workspace *environmentv1alpha1.Workspace, extra map[string]string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range extra {
+		labels[k] = v
+	}
+	workspaceLabels := workspace.Spec.Labels
+	if resolved := resolvedTemplate(workspace); resolved != nil {
+		workspaceLabels = resolved.Labels
+	}
+	for k, v := range workspaceLabels {
+		labels[k] = v
+	}
+	labels[ManagedByLabel] = ManagedByValue
+	labels[WorkspaceLabel] = workspace.Name
+	return labels
+}
+
+// setMetadata sets the desired labels and annotations on the object, keeping the ones set by others.
+// The corrections of an object are then sent in a single patch instead of one update per mismatched key.
+func setMetadata(objectMeta *metav1.ObjectMeta, labels, annotations map[string]string) {
+	for k, v := range labels {
+		if objectMeta.Labels == nil {
+			objectMeta.Labels = map[string]string{}
+		}
+		objectMeta.Labels[k] = v
+	}
+	for k, v := range annotations {
+		setAnnotation(objectMeta, k, v)
+	}
+}
+
+// patchIfChanged sends the corrections made to the object since original in a single merge patch.
+// Nothing is written when the object is semantically equal to original, i.e. quantities are compared
+// by value and maps regardless of their ordering. It reports whether the object was patched.
+func (r *WorkspaceReconciler) patchIfChanged(ctx context.Context, workspace *environmentv1alpha1.Workspace, kind string, original, object client.Object) (bool, error) {
+	if equality.Semantic.DeepEqual(original, object) {
+		return false, nil
+	}
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("%s not same for %s.Name %s in Namespace.Name %s", kind, kind, object.GetName(), object.GetNamespace()))
+	if err := r.Patch(ctx, object, client.MergeFrom(original)); err != nil {
+		return true, err
+	}
+	r.Metrics.countChild(kind, childActionUpdate)
+	message := fmt.Sprintf("Brought %s %s back to its desired state", kind, object.GetName())
+	r.recordActivity(workspace, ActivityUpdated, message)
+	r.event(workspace, corev1.EventTypeNormal, patchEventReason(kind), message)
+	return true, nil
+}
+
+// apply creates or updates the object by applying its desired state server-side, owned by FieldManager.
+// The object is then the one returned by the API server, so that the rest of the reconciliation can patch it
+// in the same pass instead of waiting for the cache to see it.
+func (r *WorkspaceReconciler) apply(ctx context.Context, object client.Object) error {
+	gvk, err := apiutil.GVKForObject(object, r.Scheme)
+	if err != nil {
+		return err
+	}
+	object.GetObjectKind().SetGroupVersionKind(gvk)
+	object.SetManagedFields(nil)
+	if err := r.Patch(ctx, object, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return err
+	}
+	// The missing objects are applied, the drifted ones are updated or patched
+	r.Metrics.countChild(gvk.Kind, childActionCreate)
+	return nil
+}
+
+// update writes the desired state of a child resource of a workspace and counts it in the provisioning metrics
+func (r *WorkspaceReconciler) update(ctx context.Context, object client.Object) error {
+	if err := r.Update(ctx, object); err != nil {
+		return err
+	}
+	if gvk, err := apiutil.GVKForObject(object, r.Scheme); err == nil {
+		r.Metrics.countChild(gvk.Kind, childActionUpdate)
+	}
+	return nil
+}
+
+// semanticEqualJSON compares two unstructured values by their JSON form, so that the numbers rendered by
+// the operator as int compare equal to the int64 or float64 decoded from the API server
+func semanticEqualJSON(a, b interface{}) bool {
+	normalized := make([]interface{}, 2)
+	for i, value := range []interface{}{a, b} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return false
+		}
+		if err := json.Unmarshal(data, &normalized[i]); err != nil {
+			return false
+		}
+	}
+	return equality.Semantic.DeepEqual(normalized[0], normalized[1])
+}
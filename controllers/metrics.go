@@ -0,0 +1,368 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// MetricsLevel is the aggregation level of the workspace metrics
+type MetricsLevel string
+
+const (
+	// MetricsLevelGlobal aggregates the workspace metrics over all the workspaces
+	MetricsLevelGlobal MetricsLevel = "global"
+	// MetricsLevelClass aggregates the workspace metrics per workspace class
+	MetricsLevelClass MetricsLevel = "class"
+	// MetricsLevelWorkspace exports the workspace metrics per workspace as well
+	MetricsLevelWorkspace MetricsLevel = "workspace"
+)
+
+// ParseMetricsLevel parses the aggregation level of the workspace metrics
+func ParseMetricsLevel(level string) (MetricsLevel, error) {
+	switch MetricsLevel(level) {
+	case MetricsLevelGlobal, MetricsLevelClass, MetricsLevelWorkspace:
+		return MetricsLevel(level), nil
+	}
+	return "", fmt.Errorf("unknown metrics level %q, expected one of global, class or workspace", level)
+}
+
+// collectTimeout bounds the listing of the workspaces on a scrape
+const collectTimeout = 10 * time.Second
+
+// workspacePhases are all the lifecycle phases exported by the phase metrics
+var workspacePhases = []environmentv1alpha1.WorkspacePhase{
+	environmentv1alpha1.WorkspacePending,
+	environmentv1alpha1.WorkspaceProvisioning,
+	environmentv1alpha1.WorkspaceReady,
+	environmentv1alpha1.WorkspaceUpdating,
+	environmentv1alpha1.WorkspaceSuspended,
+	environmentv1alpha1.WorkspaceTerminating,
+	environmentv1alpha1.WorkspaceFailed,
+}
+
+// spendWindows are the windows of status.spend exported by the spend metrics
+var spendWindows = []string{"7d", "30d"}
+
+// summaryResources are the resources of the workspace ResourceQuotas summed in the tenancy summary
+var summaryResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceRequestsStorage}
+
+// tenancyKey partitions the tenancy summary, by class and team owning the workspaces
+type tenancyKey struct {
+	class string
+	team  string
+}
+
+// tenancySummary sums the quotas and counts the expiring and failing workspaces of a partition of the tenancy summary
+type tenancySummary struct {
+	committed map[corev1.ResourceName]float64
+	used      map[corev1.ResourceName]float64
+	expiring  float64
+	failing   float64
+}
+
+// WorkspaceCollector exports the phase, the spend, the quota utilization and the usage trend of the workspaces from their status
+// and their ResourceQuotas on every scrape.
+// The series labeled per workspace explode the cardinality on large fleets, so the metrics are aggregated
+// at Level and the per-workspace series are only exported at the workspace level or for the Detailed workspaces.
+type WorkspaceCollector struct {
+	// Client lists the workspaces
+	Client client.Reader
+
+	// Level is the aggregation level of the metrics, MetricsLevelWorkspace when empty
+	Level MetricsLevel
+
+	// Detailed are the names of the workspaces whose per-workspace series are exported at any level
+	Detailed []string
+
+	// Filter scopes the metrics to the workspaces of the operator instance
+	Filter *WorkspaceFilter
+}
+
+var (
+	// workspacePhaseDesc is 1 for the current lifecycle phase of the workspace and 0 for the other phases
+	workspacePhaseDesc = prometheus.NewDesc("workspace_phase",
+		"Lifecycle phase of the workspace", []string{"workspace", "phase"}, nil)
+
+	// workspaceSpendDesc is the rolling spend of the workspace namespace reported by the CostProvider
+	workspaceSpendDesc = prometheus.NewDesc("workspace_spend",
+		"Cost of the workspace namespace over the rolling window (7d or 30d), in the currency of the cost provider", []string{"workspace", "namespace", "window"}, nil)
+
+	// workspaceQuotaUtilizationDesc is the usage of the ResourceQuota of the workspace relative to its hard limit
+	workspaceQuotaUtilizationDesc = prometheus.NewDesc("workspace_quota_utilization_percent",
+		"Usage of the ResourceQuota of the workspace namespace relative to its hard limit in percent", []string{"workspace", "namespace", "resource"}, nil)
+
+	// workspaceUsageChangeDesc is the week-over-week change of the usage of the workspace from status.usage
+	workspaceUsageChangeDesc = prometheus.NewDesc("workspace_usage_week_over_week_percent",
+		"Change of the usage of the workspace namespace over the last week in percent", []string{"workspace", "namespace", "resource"}, nil)
+)
+
+// aggregateLabels are the labels the aggregated series are partitioned by at the level
+func (c *WorkspaceCollector) aggregateLabels() []string {
+	if c.Level == MetricsLevelGlobal {
+		return nil
+	}
+	return []string{"class"}
+}
+
+// workspacesDesc counts the workspaces per lifecycle phase
+func (c *WorkspaceCollector) workspacesDesc() *prometheus.Desc {
+	return prometheus.NewDesc("workspaces",
+		"Number of workspaces per lifecycle phase", append(c.aggregateLabels(), "phase"), nil)
+}
+
+// workspacesSpendDesc sums the spend of the workspaces
+func (c *WorkspaceCollector) workspacesSpendDesc() *prometheus.Desc {
+	return prometheus.NewDesc("workspaces_spend",
+		"Cost of the workspace namespaces over the rolling window (7d or 30d), in the currency of the cost provider", append(c.aggregateLabels(), "window"), nil)
+}
+
+// summaryLabels are the labels the series of the tenancy summary are partitioned by at the level
+func (c *WorkspaceCollector) summaryLabels() []string {
+	if c.Level == MetricsLevelGlobal {
+		return nil
+	}
+	return []string{"class", "team"}
+}
+
+// workspacesQuotaCommittedDesc sums the hard limits of the ResourceQuotas of the workspaces
+func (c *WorkspaceCollector) workspacesQuotaCommittedDesc() *prometheus.Desc {
+	return prometheus.NewDesc("workspaces_quota_committed",
+		"Sum of the hard limits of the ResourceQuotas of the workspaces, in cores or bytes", append(c.summaryLabels(), "resource"), nil)
+}
+
+// workspacesQuotaUsedDesc sums the usage of the ResourceQuotas of the workspaces
+func (c *WorkspaceCollector) workspacesQuotaUsedDesc() *prometheus.Desc {
+	return prometheus.NewDesc("workspaces_quota_used",
+		"Sum of the usage of the ResourceQuotas of the workspaces, in cores or bytes", append(c.summaryLabels(), "resource"), nil)
+}
+
+// workspacesExpiringDesc counts the workspaces about to expire
+func (c *WorkspaceCollector) workspacesExpiringDesc() *prometheus.Desc {
+	return prometheus.NewDesc("workspaces_expiring",
+		"Number of workspaces with the Expiring condition", c.summaryLabels(), nil)
+}
+
+// workspacesFailingDesc counts the workspaces whose last reconciliation failed
+func (c *WorkspaceCollector) workspacesFailingDesc() *prometheus.Desc {
+	return prometheus.NewDesc("workspaces_failing",
+		"Number of workspaces with the Stalled condition", c.summaryLabels(), nil)
+}
+
+// Describe sends the descriptors of the workspace metrics
+func (c *WorkspaceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workspacePhaseDesc
+	ch <- workspaceSpendDesc
+	ch <- workspaceQuotaUtilizationDesc
+	ch <- workspaceUsageChangeDesc
+	ch <- c.workspacesDesc()
+	ch <- c.workspacesSpendDesc()
+	ch <- c.workspacesQuotaCommittedDesc()
+	ch <- c.workspacesQuotaUsedDesc()
+	ch <- c.workspacesExpiringDesc()
+	ch <- c.workspacesFailingDesc()
+}
+
+// Collect sends the workspace metrics computed from the status of the workspaces
+func (c *WorkspaceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := c.Client.List(ctx, workspaces); err != nil {
+		ctrl.Log.WithName("metrics").Error(err, "Failed to list Workspaces")
+		return
+	}
+	resourceQuotas := &corev1.ResourceQuotaList{}
+	if err := c.Client.List(ctx, resourceQuotas, client.HasLabels{WorkspaceLabel}); err != nil {
+		ctrl.Log.WithName("metrics").Error(err, "Failed to list ResourceQuotas")
+		return
+	}
+	quotas := map[types.NamespacedName]*corev1.ResourceQuota{}
+	for i := range resourceQuotas.Items {
+		quotas[client.ObjectKeyFromObject(&resourceQuotas.Items[i])] = &resourceQuotas.Items[i]
+	}
+
+	detailed := map[string]bool{}
+	for _, name := range c.Detailed {
+		detailed[name] = true
+	}
+	phases := map[string]map[environmentv1alpha1.WorkspacePhase]float64{}
+	spend := map[string]map[string]float64{}
+	summaries := map[tenancyKey]*tenancySummary{}
+	for i := range workspaces.Items {
+		workspace := &workspaces.Items[i]
+		if !c.Filter.Matches(workspace) {
+			continue
+		}
+		costs := workspaceCosts(workspace)
+		// Only the main ResourceQuota of the workspace is summed, not its additional quotas
+		quota := quotas[types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-quota", workspace.Spec.Name)}]
+
+		// Per-workspace series
+		if c.Level == "" || c.Level == MetricsLevelWorkspace || detailed[workspace.Name] {
+			for _, phase := range workspacePhases {
+				value := 0.0
+				if phase == workspace.Status.Phase {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(workspacePhaseDesc, prometheus.GaugeValue, value, workspace.Name, string(phase))
+			}
+			for window, cost := range costs {
+				ch <- prometheus.MustNewConstMetric(workspaceSpendDesc, prometheus.GaugeValue, cost, workspace.Name, workspace.Spec.Name, window)
+			}
+			for resource, utilization := range quotaUtilization(quota) {
+				ch <- prometheus.MustNewConstMetric(workspaceQuotaUtilizationDesc, prometheus.GaugeValue, utilization, workspace.Name, workspace.Spec.Name, string(resource))
+			}
+			for resource, change := range workspaceUsageChanges(workspace) {
+				ch <- prometheus.MustNewConstMetric(workspaceUsageChangeDesc, prometheus.GaugeValue, change, workspace.Name, workspace.Spec.Name, resource)
+			}
+		}
+
+		// Aggregated series
+		key := ""
+		if c.Level != MetricsLevelGlobal {
+			key = workspace.Spec.ClassName
+		}
+		if phases[key] == nil {
+			phases[key] = map[environmentv1alpha1.WorkspacePhase]float64{}
+			spend[key] = map[string]float64{}
+		}
+		if workspace.Status.Phase != "" {
+			phases[key][workspace.Status.Phase]++
+		}
+		for window, cost := range costs {
+			spend[key][window] += cost
+		}
+
+		// Tenancy summary
+		summaryKey := tenancyKey{}
+		if c.Level != MetricsLevelGlobal {
+			summaryKey.class = workspace.Spec.ClassName
+			if workspace.Spec.Owner != nil {
+				summaryKey.team = workspace.Spec.Owner.Name
+			}
+		}
+		summary, ok := summaries[summaryKey]
+		if !ok {
+			summary = &tenancySummary{committed: map[corev1.ResourceName]float64{}, used: map[corev1.ResourceName]float64{}}
+			summaries[summaryKey] = summary
+		}
+		if quota != nil {
+			for _, resource := range summaryResources {
+				if hard, ok := quota.Status.Hard[resource]; ok {
+					summary.committed[resource] += hard.AsApproximateFloat64()
+				}
+				if used, ok := quota.Status.Used[resource]; ok {
+					summary.used[resource] += used.AsApproximateFloat64()
+				}
+			}
+		}
+		if meta.IsStatusConditionTrue(workspace.Status.Conditions, ConditionExpiring) {
+			summary.expiring++
+		}
+		if meta.IsStatusConditionTrue(workspace.Status.Conditions, ConditionStalled) {
+			summary.failing++
+		}
+	}
+
+	workspacesDesc, workspacesSpendDesc := c.workspacesDesc(), c.workspacesSpendDesc()
+	for key := range phases {
+		var labels []string
+		if c.Level != MetricsLevelGlobal {
+			labels = []string{key}
+		}
+		for _, phase := range workspacePhases {
+			ch <- prometheus.MustNewConstMetric(workspacesDesc, prometheus.GaugeValue, phases[key][phase], append(labels, string(phase))...)
+		}
+		for window, cost := range spend[key] {
+			ch <- prometheus.MustNewConstMetric(workspacesSpendDesc, prometheus.GaugeValue, cost, append(labels, window)...)
+		}
+	}
+
+	committedDesc, usedDesc := c.workspacesQuotaCommittedDesc(), c.workspacesQuotaUsedDesc()
+	expiringDesc, failingDesc := c.workspacesExpiringDesc(), c.workspacesFailingDesc()
+	for key, summary := range summaries {
+		var labels []string
+		if c.Level != MetricsLevelGlobal {
+			labels = []string{key.class, key.team}
+		}
+		for _, resource := range summaryResources {
+			ch <- prometheus.MustNewConstMetric(committedDesc, prometheus.GaugeValue, summary.committed[resource], append(labels, string(resource))...)
+			ch <- prometheus.MustNewConstMetric(usedDesc, prometheus.GaugeValue, summary.used[resource], append(labels, string(resource))...)
+		}
+		ch <- prometheus.MustNewConstMetric(expiringDesc, prometheus.GaugeValue, summary.expiring, labels...)
+		ch <- prometheus.MustNewConstMetric(failingDesc, prometheus.GaugeValue, summary.failing, labels...)
+	}
+}
+
+// workspaceCosts returns the spend of the workspace per window from its status
+func workspaceCosts(workspace *environmentv1alpha1.Workspace) map[string]float64 {
+	costs := map[string]float64{}
+	if workspace.Status.Spend == nil {
+		return costs
+	}
+	for i, value := range []string{workspace.Status.Spend.Last7Days, workspace.Status.Spend.Last30Days} {
+		if cost, err := strconv.ParseFloat(value, 64); err == nil {
+			costs[spendWindows[i]] = cost
+		}
+	}
+	return costs
+}
+
+// quotaUtilization returns the usage of the summary resources of the ResourceQuota relative to their hard limit in percent
+func quotaUtilization(quota *corev1.ResourceQuota) map[corev1.ResourceName]float64 {
+	utilization := map[corev1.ResourceName]float64{}
+	if quota == nil {
+		return utilization
+	}
+	for _, resource := range summaryResources {
+		hard, ok := quota.Status.Hard[resource]
+		if !ok || hard.IsZero() {
+			continue
+		}
+		used := quota.Status.Used[resource]
+		utilization[resource] = used.AsApproximateFloat64() / hard.AsApproximateFloat64() * 100
+	}
+	return utilization
+}
+
+// workspaceUsageChanges returns the week-over-week change of the usage of the workspace per resource from its status
+func workspaceUsageChanges(workspace *environmentv1alpha1.Workspace) map[string]float64 {
+	changes := map[string]float64{}
+	if workspace.Status.Usage == nil || workspace.Status.Usage.WeekOverWeek == nil {
+		return changes
+	}
+	trend := workspace.Status.Usage.WeekOverWeek
+	for resource, change := range map[string]*int32{"cpu": trend.CPU, "memory": trend.Memory, "disk": trend.Disk} {
+		if change != nil {
+			changes[resource] = float64(*change)
+		}
+	}
+	return changes
+}
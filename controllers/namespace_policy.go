@@ -0,0 +1,123 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// ApprovedNamespaceAnnotation set to the target namespace of a Workspace approves a namespace matching a deny pattern.
+// Only the NamespacePolicy approvers can set it.
+const ApprovedNamespaceAnnotation = "environment.tf.operator.com/approved-namespace"
+
+// NamespacePolicy restricts the names of the namespaces workspaces can target
+type NamespacePolicy struct {
+	// Allow patterns of which the target namespace must match at least one. Any name is allowed when empty.
+	Allow []*regexp.Regexp
+
+	// Deny patterns the target namespace must not match, unless the namespace was approved
+	Deny []*regexp.Regexp
+
+	// Approvers are the users and groups allowed to approve a denied namespace.
+	// Denied namespaces can not be approved when it is empty.
+	Approvers []string
+}
+
+// NewNamespacePolicy compiles comma separated allow and deny patterns. Patterns must match the whole name.
+func NewNamespacePolicy(allow, deny, approvers string) (*NamespacePolicy, error) {
+	policy := &NamespacePolicy{}
+	compile := func(patterns string) ([]*regexp.Regexp, error) {
+		var compiled []*regexp.Regexp
+		for _, pattern := range strings.Split(patterns, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+			}
+			compiled = append(compiled, re)
+		}
+		return compiled, nil
+	}
+	var err error
+	if policy.Allow, err = compile(allow); err != nil {
+		return nil, err
+	}
+	if policy.Deny, err = compile(deny); err != nil {
+		return nil, err
+	}
+	for _, approver := range strings.Split(approvers, ",") {
+		if approver = strings.TrimSpace(approver); approver != "" {
+			policy.Approvers = append(policy.Approvers, approver)
+		}
+	}
+	return policy, nil
+}
+
+// Check returns an error when the target namespace of the workspace is not allowed.
+// A nil policy allows all the namespaces.
+func (p *NamespacePolicy) Check(workspace *environmentv1alpha1.Workspace) error {
+	if p == nil {
+		return nil
+	}
+	name := workspace.Spec.Name
+	if len(p.Allow) > 0 {
+		allowed := false
+		for _, re := range p.Allow {
+			if re.MatchString(name) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("namespace %s does not match any of the allowed namespace patterns", name)
+		}
+	}
+	for _, re := range p.Deny {
+		if !re.MatchString(name) {
+			continue
+		}
+		if len(p.Approvers) > 0 && workspace.Annotations[ApprovedNamespaceAnnotation] == name {
+			return nil
+		}
+		return fmt.Errorf("namespace %s matches the denied namespace pattern %s", name, strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$"))
+	}
+	return nil
+}
+
+// IsApprover reports whether the user or one of its groups can approve denied namespaces
+func (p *NamespacePolicy) IsApprover(username string, groups []string) bool {
+	if p == nil {
+		return false
+	}
+	for _, approver := range p.Approvers {
+		if approver == username {
+			return true
+		}
+		for _, group := range groups {
+			if approver == group {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// namespaceDeletionPredicate only lets through the events of the deletion of a namespace, when its termination
+// starts and when it is gone, so that a namespace deleted out-of-band is recreated as soon as possible
+var namespaceDeletionPredicate = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetDeletionTimestamp().IsZero() && !e.ObjectNew.GetDeletionTimestamp().IsZero()
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return true },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// reportNamespaceDeleted raises a Warning event and a notification when the namespace of a workspace which
+// was already provisioned is missing, i.e. it was deleted out-of-band rather than through the Workspace
+func (r *WorkspaceReconciler) reportNamespaceDeleted(ctx context.Context, workspace *environmentv1alpha1.Workspace) {
+	if !provisioned(workspace.Status.Phase) {
+		return
+	}
+	message := fmt.Sprintf("Namespace %s of Workspace %s was deleted out-of-band, recreating it", workspace.Spec.Name, workspace.Name)
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, "Warning", "NamespaceDeleted", message)
+	}
+	r.notify(ctx, workspace, "NamespaceDeleted", message)
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// AdoptedByAnnotation is set by the cluster administrators on a pre-created namespace to the name of the Workspace
+// allowed to manage it, when the operator runs without the rights to create and update namespaces
+const AdoptedByAnnotation = "environment.tf.operator.com/adopted-by"
+
+// checkNamespaceAdopted returns an error unless the namespace was adopted by the workspace,
+// either with the AdoptedByAnnotation or by being controlled by the workspace
+func checkNamespaceAdopted(workspace *environmentv1alpha1.Workspace, namespace *corev1.Namespace) error {
+	if namespace.Annotations[AdoptedByAnnotation] == workspace.Name || metav1.IsControlledBy(namespace, workspace) {
+		return nil
+	}
+	return stall(fmt.Errorf("Namespace %s is not adopted by Workspace %s, annotate it with %s=%s", namespace.Name, workspace.Name, AdoptedByAnnotation, workspace.Name))
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// reconcileNetworkIsolation keeps the default-deny NetworkPolicy of the workspace namespace, and the one allowing
+// the traffic within the namespace and to the cluster DNS, in sync with spec.networkIsolation or the one of its template.
+// The other NetworkPolicies of the workspace, e.g. the peering, the egress and the grants, add to them.
+func (r *WorkspaceReconciler) reconcileNetworkIsolation(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	for _, np := range []*networkingv1.NetworkPolicy{
+		r.defaultDenyNetworkPolicyForWorkspace(workspace),
+		r.intraNamespaceNetworkPolicyForWorkspace(workspace),
+	} {
+		networkPolicy := &networkingv1.NetworkPolicy{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: np.Namespace, Name: np.Name}, networkPolicy)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		exists := err == nil
+
+		if !networkIsolated(workspace) {
+			if exists && metav1.IsControlledBy(networkPolicy, workspace) {
+				reconcilerLog.Info(fmt.Sprintf("Deleting NetworkPolicy NetworkPolicy.Name %s", networkPolicy.Name))
+				if err := r.Delete(ctx, networkPolicy); err != nil && !apierrors.IsNotFound(err) {
+					return err
+				}
+			}
+			continue
+		}
+		if err := ctrl.SetControllerReference(workspace, np, r.Scheme); err != nil {
+			return err
+		}
+		if !exists {
+			reconcilerLog.Info(fmt.Sprintf("Creating a new NetworkPolicy NetworkPolicy.Name %s", np.Name))
+			if err := r.apply(ctx, np); err != nil {
+				return err
+			}
+			continue
+		}
+		// check if the isolation rules were edited
+		if !equality.Semantic.DeepEqual(networkPolicy.Spec, np.Spec) {
+			reconcilerLog.Info(fmt.Sprintf("Rules not same for NetworkPolicy.Name %s in Namespace.Name %s", np.Name, np.Namespace))
+			networkPolicy.Spec = np.Spec
+			if err := r.update(ctx, networkPolicy); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Default-deny NetworkPolicy for Workspace, named <namespace>-default-deny.
+// It selects every pod of the namespace for both ingress and egress without allowing anything.
+func (r *WorkspaceReconciler) defaultDenyNetworkPolicyForWorkspace(workspace *environmentv1alpha1.Workspace) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-default-deny", workspace.Spec.Name),
+			Namespace:   workspace.Spec.Name,
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
+
+// Intra-namespace NetworkPolicy for Workspace, named <namespace>-allow-intra-namespace.
+// It allows the traffic between the pods of the namespace and their DNS requests to the cluster DNS.
+func (r *WorkspaceReconciler) intraNamespaceNetworkPolicyForWorkspace(workspace *environmentv1alpha1.Workspace) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-allow-intra-namespace", workspace.Spec.Name),
+			Namespace:   workspace.Spec.Name,
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{From: []networkingv1.NetworkPolicyPeer{namespacePeer(workspace.Spec.Name)}},
+			},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{To: []networkingv1.NetworkPolicyPeer{namespacePeer(workspace.Spec.Name)}},
+				clusterDNSEgressRule(),
+			},
+		},
+	}
+}
+
+// clusterDNSEgressRule allows the DNS requests to the cluster DNS, over both UDP and TCP
+func clusterDNSEgressRule() networkingv1.NetworkPolicyEgressRule {
+	udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+	dnsPort := intstr.FromInt(53)
+	clusterDNS := namespacePeer(metav1.NamespaceSystem)
+	clusterDNS.PodSelector = &metav1.LabelSelector{MatchLabels: clusterDNSLabels}
+	return networkingv1.NetworkPolicyEgressRule{
+		To:    []networkingv1.NetworkPolicyPeer{clusterDNS},
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dnsPort}, {Protocol: &tcp, Port: &dnsPort}},
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+
+// namespaceNameLabel is set by Kubernetes on every namespace with its name
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// reconcileNetworking keeps the peering NetworkPolicy of the workspace namespace in sync with
+// spec.networking.allowFrom and reports the peering of the workspace in status.networking.
+// The status is written with the rest of the status by reconcileStatus.
+func (r *WorkspaceReconciler) reconcileNetworking(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	// Namespaces of the peers allowed to reach the workspace namespace, peers which do not exist are skipped
+	// until they are created
+	var allowedFrom []string
+	peerNamespaces := map[string]string{}
+	if workspace.Spec.Networking != nil {
+		for _, name := range workspace.Spec.Networking.AllowFrom {
+			peer := &environmentv1alpha1.Workspace{}
+			err := r.Get(ctx, types.NamespacedName{Name: name}, peer)
+			if apierrors.IsNotFound(err) {
+				reconcilerLog.Info(fmt.Sprintf("Workspace %s allowed by Workspace %s not found. Skipping peering", name, workspace.Name))
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			allowedFrom = append(allowedFrom, name)
+			peerNamespaces[name] = peer.Spec.Name
+		}
+	}
+
+	// Peers allowing the workspace to reach their namespace
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := r.List(ctx, workspaces); err != nil {
+		return err
+	}
+	var allowedTo []string
+	for _, peer := range workspaces.Items {
+		if peer.Spec.Networking == nil || peer.Name == workspace.Name {
+			continue
+		}
+		for _, name := range peer.Spec.Networking.AllowFrom {
+			if name == workspace.Name {
+				allowedTo = append(allowedTo, peer.Name)
+			}
+		}
+	}
+	sort.Strings(allowedFrom)
+	sort.Strings(allowedTo)
+	workspace.Status.Networking = nil
+	if len(allowedFrom) > 0 || len(allowedTo) > 0 {
+		workspace.Status.Networking = &environmentv1alpha1.WorkspaceNetworkingStatus{AllowedFrom: allowedFrom, AllowedTo: allowedTo}
+	}
+
+	networkPolicy := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-peering", workspace.Spec.Name)}, networkPolicy)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if workspace.Spec.Networking == nil {
+		if exists {
+			reconcilerLog.Info(fmt.Sprintf("Deleting NetworkPolicy NetworkPolicy.Name %s", networkPolicy.Name))
+			if err := r.Delete(ctx, networkPolicy); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	np, err := r.peeringNetworkPolicyForWorkspace(workspace, allowedFrom, peerNamespaces)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		reconcilerLog.Info(fmt.Sprintf("Creating a new NetworkPolicy NetworkPolicy.Name %s", np.Name))
+		return r.Create(ctx, np)
+	}
+	// check if the peers changed
+	if !equality.Semantic.DeepEqual(networkPolicy.Spec, np.Spec) {
+		reconcilerLog.Info(fmt.Sprintf("Peers not same for NetworkPolicy.Name %s in Namespace.Name %s", np.Name, np.Namespace))
+		networkPolicy.Spec = np.Spec
+		return r.update(ctx, networkPolicy)
+	}
+	return nil
+}
+
+// Peering NetworkPolicy for Workspace.
+// It allows the ingress traffic of the pods of the namespace from the namespace itself and from the namespaces
+// of the peers, which isolates the namespace from any other namespace.
+func (r *WorkspaceReconciler) peeringNetworkPolicyForWorkspace(workspace *environmentv1alpha1.Workspace, allowedFrom []string, peerNamespaces map[string]string) (*networkingv1.NetworkPolicy, error) {
+	from := []networkingv1.NetworkPolicyPeer{namespacePeer(workspace.Spec.Name)}
+	for _, name := range allowedFrom {
+		from = append(from, namespacePeer(peerNamespaces[name]))
+	}
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-peering", workspace.Spec.Name),
+			Namespace:   workspace.Spec.Name,
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: from}},
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, np, r.Scheme); err != nil {
+		return nil, err
+	}
+	return np, nil
+}
+
+// namespacePeer returns the NetworkPolicy peer selecting all the pods of a namespace
+func namespacePeer(namespace string) networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{namespaceNameLabel: namespace},
+		},
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// Notification is a message about a Workspace that is sent to the workspace owners
+type Notification struct {
+	Timestamp time.Time `json:"timestamp"`
+	Workspace string    `json:"workspace"`
+	Namespace string    `json:"namespace"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+
+	// Owner is the contact of the owner of the workspace, when set in spec.owner
+	Owner *environmentv1alpha1.WorkspaceOwner `json:"owner,omitempty"`
+}
+
+// Notifier delivers notifications to an external system
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// NewWebhookNotifier returns a Notifier which POSTs every notification as JSON to url
+func NewWebhookNotifier(url string) Notifier {
+	return &webhookNotifier{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// notify sends a notification for the workspace if a Notifier is configured.
+// Delivery failures are logged and never fail the reconciliation.
+func (r *WorkspaceReconciler) notify(ctx context.Context, workspace *environmentv1alpha1.Workspace, reason, message string) {
+	if r.Notifier == nil {
+		return
+	}
+	notification := Notification{
+		Timestamp: time.Now().UTC(),
+		Workspace: workspace.Name,
+		Namespace: workspace.Spec.Name,
+		Reason:    reason,
+		Message:   message,
+		Owner:     workspace.Spec.Owner,
+	}
+	if err := r.Notifier.Notify(ctx, notification); err != nil {
+		ctrl.Log.WithName("notifier").Error(err, fmt.Sprintf("Failed to send %s notification for Workspace %s", reason, workspace.Name))
+	}
+}
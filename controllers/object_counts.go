@@ -0,0 +1,76 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// objectCounts returns the object counts of resources by the name of their hard limit in the workspace ResourceQuota
+func objectCounts(resources environmentv1alpha1.WorkspaceResource) map[corev1.ResourceName]string {
+	return map[corev1.ResourceName]string{
+		corev1.ResourcePods:                   resources.Pods,
+		corev1.ResourceServices:               resources.Services,
+		corev1.ResourceServicesLoadBalancers:  resources.LoadBalancers,
+		corev1.ResourceSecrets:                resources.Secrets,
+		corev1.ResourceConfigMaps:             resources.ConfigMaps,
+		corev1.ResourcePersistentVolumeClaims: resources.PersistentVolumeClaims,
+	}
+}
+
+// setOptionalQuota sets the hard limits of the object counts and of the separate requests and limits of resources
+// in the hard limits of the workspace ResourceQuota, and removes the limits of the ones that are not set
+func setOptionalQuota(hard corev1.ResourceList, resources environmentv1alpha1.WorkspaceResource) error {
+	for _, limits := range []map[corev1.ResourceName]string{objectCounts(resources), requestsAndLimits(resources)} {
+		for name, value := range limits {
+			if value == "" {
+				delete(hard, name)
+				continue
+			}
+			quantity, err := quotaResource.ParseQuantity(value)
+			if err != nil {
+				return fmt.Errorf("failed to parse the %s hard limit %q: %w", name, value, err)
+			}
+			hard[name] = quantity
+		}
+	}
+	return nil
+}
+
+// mergeObjectCounts sets the object counts set in resources on merged
+func mergeObjectCounts(merged *environmentv1alpha1.WorkspaceResource, resources environmentv1alpha1.WorkspaceResource) {
+	for _, count := range []struct {
+		merged *string
+		value  string
+	}{
+		{&merged.Pods, resources.Pods},
+		{&merged.Services, resources.Services},
+		{&merged.LoadBalancers, resources.LoadBalancers},
+		{&merged.Secrets, resources.Secrets},
+		{&merged.ConfigMaps, resources.ConfigMaps},
+		{&merged.PersistentVolumeClaims, resources.PersistentVolumeClaims},
+	} {
+		if count.value != "" {
+			*count.merged = count.value
+		}
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// ObservabilityTenantAnnotation is set on the workspace namespace so that telemetry agents
+// can tag the logs, metrics and traces of the namespace with the tenant ID
+const ObservabilityTenantAnnotation = "environment.tf.operator.com/observability-tenant"
+
+// TenantRegistry maps namespaces to observability tenants in the observability backend
+type TenantRegistry interface {
+	Register(ctx context.Context, tenant, namespace string) error
+	Unregister(ctx context.Context, tenant, namespace string) error
+}
+
+// NewHTTPTenantRegistry returns a TenantRegistry which manages the mappings through
+// PUT and DELETE requests on <endpoint>/tenants/<tenant>/namespaces/<namespace>
+func NewHTTPTenantRegistry(endpoint string) TenantRegistry {
+	return &httpTenantRegistry{endpoint: strings.TrimSuffix(endpoint, "/"), client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type httpTenantRegistry struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (t *httpTenantRegistry) Register(ctx context.Context, tenant, namespace string) error {
+	body, err := json.Marshal(map[string]string{"tenant": tenant, "namespace": namespace})
+	if err != nil {
+		return err
+	}
+	return t.do(ctx, http.MethodPut, tenant, namespace, body)
+}
+
+func (t *httpTenantRegistry) Unregister(ctx context.Context, tenant, namespace string) error {
+	return t.do(ctx, http.MethodDelete, tenant, namespace, nil)
+}
+
+func (t *httpTenantRegistry) do(ctx context.Context, method, tenant, namespace string, body []byte) error {
+	u := fmt.Sprintf("%s/tenants/%s/namespaces/%s", t.endpoint, url.PathEscape(tenant), url.PathEscape(namespace))
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// deleting a mapping which does not exist is not an error
+	if resp.StatusCode >= 300 && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("tenant registry returned %s for %s %s", resp.Status, method, u)
+	}
+	return nil
+}
+
+// namespaceAnnotationsForWorkspace returns the annotations of the workspace namespace
+func namespaceAnnotationsForWorkspace(workspace *environmentv1alpha1.Workspace) map[string]string {
+	annotations := ownerAnnotationsForWorkspace(workspace)
+	for k, v := range gpuAnnotationsForWorkspace(workspace) {
+		annotations[k] = v
+	}
+	if workspace.Spec.ObservabilityTenant == "" && len(annotations) == 0 {
+		return annotationsForWorkspace(workspace)
+	}
+	if workspace.Spec.ObservabilityTenant != "" {
+		annotations[ObservabilityTenantAnnotation] = workspace.Spec.ObservabilityTenant
+	}
+	for k, v := range annotationsForWorkspace(workspace) {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// reconcileObservabilityTenant registers the workspace namespace with spec.observabilityTenant
+// and records the registered tenant in the status
+func (r *WorkspaceReconciler) reconcileObservabilityTenant(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	desired := workspace.Spec.ObservabilityTenant
+	registered := workspace.Status.ObservabilityTenant
+	if desired == registered {
+		return nil
+	}
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	if r.TenantRegistry != nil {
+		if registered != "" {
+			reconcilerLog.Info(fmt.Sprintf("Unregistering Namespace.Name %s from observability tenant %s", workspace.Spec.Name, registered))
+			if err := r.TenantRegistry.Unregister(ctx, registered, workspace.Spec.Name); err != nil {
+				return err
+			}
+		}
+		if desired != "" {
+			reconcilerLog.Info(fmt.Sprintf("Registering Namespace.Name %s with observability tenant %s", workspace.Spec.Name, desired))
+			if err := r.TenantRegistry.Register(ctx, desired, workspace.Spec.Name); err != nil {
+				return err
+			}
+		}
+	}
+	workspace.Status.ObservabilityTenant = desired
+	return r.Status().Update(ctx, workspace)
+}
+
+// unregisterObservabilityTenant removes the mapping of the namespace of a deleted workspace from its registered
+// observability tenant, so that a later workspace reusing the namespace name does not send its telemetry to it
+func (r *WorkspaceReconciler) unregisterObservabilityTenant(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	registered := workspace.Status.ObservabilityTenant
+	if r.TenantRegistry == nil || registered == "" {
+		return nil
+	}
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Unregistering Namespace.Name %s from observability tenant %s", workspace.Spec.Name, registered))
+	return r.TenantRegistry.Unregister(ctx, registered, workspace.Spec.Name)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// recordingTenantRegistry records the mappings removed from it
+type recordingTenantRegistry struct {
+	unregistered []string
+}
+
+func (t *recordingTenantRegistry) Register(ctx context.Context, tenant, namespace string) error {
+	return nil
+}
+
+func (t *recordingTenantRegistry) Unregister(ctx context.Context, tenant, namespace string) error {
+	t.unregistered = append(t.unregistered, tenant+"/"+namespace)
+	return nil
+}
+
+func TestUnregisterObservabilityTenant(t *testing.T) {
+	tests := []struct {
+		name       string
+		registered string
+		want       []string
+	}{
+		{name: "never registered"},
+		{name: "registered", registered: "team-a", want: []string{"team-a/payments"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &recordingTenantRegistry{}
+			r := &WorkspaceReconciler{TenantRegistry: registry}
+			workspace := &environmentv1alpha1.Workspace{}
+			workspace.Spec.Name = "payments"
+			workspace.Spec.ObservabilityTenant = "team-b"
+			workspace.Status.ObservabilityTenant = tt.registered
+			if err := r.unregisterObservabilityTenant(context.Background(), workspace); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(registry.unregistered, tt.want) {
+				t.Errorf("unregistered = %v, want %v", registry.unregistered, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+const (
+	// OwnerNameAnnotation is set on the workspace namespace to the name of the owner of the workspace
+	OwnerNameAnnotation = "environment.tf.operator.com/owner-name"
+
+	// OwnerEmailAnnotation is set on the workspace namespace to the email of the owner of the workspace
+	OwnerEmailAnnotation = "environment.tf.operator.com/owner-email"
+
+	// OwnerSlackAnnotation is set on the workspace namespace to the Slack handle or channel of the owner of the workspace
+	OwnerSlackAnnotation = "environment.tf.operator.com/owner-slack"
+
+	// OwnerURLAnnotation is set on the workspace namespace to the URL of the team page of the owner of the workspace
+	OwnerURLAnnotation = "environment.tf.operator.com/owner-url"
+)
+
+// ownerAnnotationsForWorkspace returns the annotations describing spec.owner, one per set field
+func ownerAnnotationsForWorkspace(workspace *environmentv1alpha1.Workspace) map[string]string {
+	annotations := map[string]string{}
+	owner := workspace.Spec.Owner
+	if owner == nil {
+		return annotations
+	}
+	for key, value := range map[string]string{
+		OwnerNameAnnotation:  owner.Name,
+		OwnerEmailAnnotation: owner.Email,
+		OwnerSlackAnnotation: owner.Slack,
+		OwnerURLAnnotation:   owner.URL,
+	} {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
+
+// pruneOwnerAnnotations removes the owner annotations of the fields no longer set in spec.owner
+func pruneOwnerAnnotations(objectMeta *metav1.ObjectMeta, workspace *environmentv1alpha1.Workspace) {
+	desired := ownerAnnotationsForWorkspace(workspace)
+	for _, key := range []string{OwnerNameAnnotation, OwnerEmailAnnotation, OwnerSlackAnnotation, OwnerURLAnnotation} {
+		if _, ok := desired[key]; !ok {
+			delete(objectMeta.Annotations, key)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// DefaultCreateParallelism is the number of child objects created or patched concurrently
+// when CreateParallelism is not set
+const DefaultCreateParallelism = 4
+
+// childObject is an object of the workspace namespace applied by createChildren when it is not found
+type childObject struct {
+	// kind names the object in the logs, e.g. Admin Role
+	kind string
+	// name of the object in the workspace namespace
+	name string
+	// existing receives the object when it is found, or once it is applied
+	existing client.Object
+	// define returns the object to apply
+	define func() (client.Object, error)
+	// created is called once the object is created, e.g. to audit it
+	created func(client.Object)
+	// reason of the Event emitted on the workspace once the object is created, none when empty
+	reason string
+}
+
+// createChildren gets the child objects and applies the missing ones concurrently with server-side apply, so that
+// a new workspace is provisioned in a single reconciliation. The applied objects are returned in existing, for the
+// rest of the reconciliation to bring them to their desired state in the same pass.
+func (r *WorkspaceReconciler) createChildren(ctx context.Context, workspace *environmentv1alpha1.Workspace, children []childObject) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+	tasks := make([]func() error, 0, len(children))
+	for i := range children {
+		child := children[i]
+		tasks = append(tasks, func() error {
+			err := r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: child.name}, child.existing)
+			if err == nil {
+				return nil
+			} else if !apierrors.IsNotFound(err) {
+				reconcilerLog.Error(err, fmt.Sprintf("Failed to get %s", child.kind))
+				return err
+			}
+			object, err := child.define()
+			if err != nil {
+				reconcilerLog.Error(err, fmt.Sprintf("Failed to define new %s resource for Workspace", child.kind))
+				return err
+			}
+			reconcilerLog.Info(fmt.Sprintf("Creating a new %s %s", child.kind, object.GetName()))
+			if err := r.apply(ctx, object); err != nil {
+				reconcilerLog.Error(err, fmt.Sprintf("Error creating a new %s %s", child.kind, object.GetName()))
+				return err
+			}
+			reflect.ValueOf(child.existing).Elem().Set(reflect.ValueOf(object).Elem())
+			message := fmt.Sprintf("Created %s %s", child.kind, object.GetName())
+			r.recordActivity(workspace, ActivityCreated, message)
+			if child.reason != "" {
+				r.event(workspace, corev1.EventTypeNormal, child.reason, message)
+			}
+			if child.created != nil {
+				child.created(object)
+			}
+			return nil
+		})
+	}
+	return r.parallel(tasks...)
+}
+
+// parallel runs the tasks concurrently, at most CreateParallelism at a time, and returns their errors
+func (r *WorkspaceReconciler) parallel(tasks ...func() error) error {
+	limit := r.CreateParallelism
+	if limit <= 0 {
+		limit = DefaultCreateParallelism
+	}
+	semaphore := make(chan struct{}, limit)
+	errs := make([]error, len(tasks))
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(i int, task func() error) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			errs[i] = task()
+		}(i, task)
+	}
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
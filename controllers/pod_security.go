@@ -0,0 +1,44 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// PodSecurityLabelPrefix prefixes the Pod Security Admission labels of a namespace,
+// e.g. pod-security.kubernetes.io/enforce and pod-security.kubernetes.io/enforce-version
+const PodSecurityLabelPrefix = "pod-security.kubernetes.io/"
+
+// namespaceLabelsForWorkspace returns the labels of the workspace namespace
+func namespaceLabelsForWorkspace(workspace *environmentv1alpha1.Workspace) map[string]string {
+	podSecurity := workspace.Spec.PodSecurity
+	if podSecurity == nil {
+		return labelsForWorkspace(workspace, nil)
+	}
+	labels := map[string]string{}
+	for mode, level := range map[string]string{"enforce": podSecurity.Enforce, "warn": podSecurity.Warn, "audit": podSecurity.Audit} {
+		if level == "" {
+			continue
+		}
+		labels[PodSecurityLabelPrefix+mode] = level
+		if podSecurity.Version != "" {
+			labels[PodSecurityLabelPrefix+mode+"-version"] = podSecurity.Version
+		}
+	}
+	return labelsForWorkspace(workspace, labels)
+}
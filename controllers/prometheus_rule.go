@@ -0,0 +1,141 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
+
+// prometheusRuleGVK is the prometheus-operator PrometheusRule kind.
+// It is handled as unstructured so that the operator does not depend on prometheus-operator.
+var prometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+
+// reconcilePrometheusRule keeps the PrometheusRule of the workspace in sync when alerting is enabled
+// and removes it when alerting is disabled.
+// Clusters without the prometheus-operator CRDs are skipped.
+func (r *WorkspaceReconciler) reconcilePrometheusRule(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	prometheusRule := &unstructured.Unstructured{}
+	prometheusRule.SetGroupVersionKind(prometheusRuleGVK)
+	err := r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-alerts", workspace.Spec.Name)}, prometheusRule)
+	if meta.IsNoMatchError(err) {
+		if workspace.Spec.Alerting.PrometheusRules {
+			reconcilerLog.Info("PrometheusRule CRD is not installed. Skipping alerting for Workspace")
+		}
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if !workspace.Spec.Alerting.PrometheusRules {
+		if exists {
+			reconcilerLog.Info(fmt.Sprintf("Deleting PrometheusRule PrometheusRule.Name %s", prometheusRule.GetName()))
+			if err := r.Delete(ctx, prometheusRule); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	pr, err := r.prometheusRuleForWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		reconcilerLog.Info(fmt.Sprintf("Creating a new PrometheusRule PrometheusRule.Name %s", pr.GetName()))
+		return r.apply(ctx, pr)
+	}
+
+	// check if the alerts of the workspace changed, e.g. edited out-of-band or updated by a new operator version
+	if !semanticEqualJSON(prometheusRule.Object["spec"], pr.Object["spec"]) {
+		reconcilerLog.Info(fmt.Sprintf("Alerts not same for PrometheusRule %s in Namespace.Name %s", pr.GetName(), workspace.Spec.Name))
+		prometheusRule.Object["spec"] = pr.Object["spec"]
+		if err := r.update(ctx, prometheusRule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrometheusRule for Workspace
+func (r *WorkspaceReconciler) prometheusRuleForWorkspace(workspace *environmentv1alpha1.Workspace) (*unstructured.Unstructured, error) {
+	namespace := workspace.Spec.Name
+	alert := func(name, expr, duration, severity, summary string) map[string]interface{} {
+		return map[string]interface{}{
+			"alert":  name,
+			"expr":   expr,
+			"for":    duration,
+			"labels": map[string]interface{}{"severity": severity, "workspace": workspace.Name, "namespace": namespace},
+			"annotations": map[string]interface{}{
+				"summary": summary,
+			},
+		}
+	}
+
+	prometheusRule := &unstructured.Unstructured{}
+	prometheusRule.SetGroupVersionKind(prometheusRuleGVK)
+	prometheusRule.SetName(fmt.Sprintf("%s-alerts", namespace))
+	prometheusRule.SetNamespace(namespace)
+	prometheusRule.SetLabels(labelsForWorkspace(workspace, nil))
+	prometheusRule.SetAnnotations(annotationsForWorkspace(workspace))
+	prometheusRule.Object["spec"] = map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{
+				"name": fmt.Sprintf("workspace-%s", workspace.Name),
+				"rules": []interface{}{
+					alert("WorkspaceQuotaNearLimit",
+						fmt.Sprintf(`kube_resourcequota{namespace="%s",type="used"} / ignoring(type) kube_resourcequota{namespace="%s",type="hard"} > 0.9`, namespace, namespace),
+						"15m", "warning",
+						fmt.Sprintf("Workspace %s is using more than 90%% of its {{ $labels.resource }} quota", workspace.Name)),
+					alert("WorkspacePodCrashLooping",
+						fmt.Sprintf(`max by (pod, container) (kube_pod_container_status_waiting_reason{namespace="%s",reason="CrashLoopBackOff"}) > 0`, namespace),
+						"15m", "warning",
+						fmt.Sprintf("Container {{ $labels.container }} of pod {{ $labels.pod }} in workspace %s is crashlooping", workspace.Name)),
+					// the pods rejected by the ResourceQuota are never created, so they are never pending
+					alert("WorkspaceQuotaExhausted",
+						fmt.Sprintf(`kube_resourcequota{namespace="%s",type="used"} / ignoring(type) kube_resourcequota{namespace="%s",type="hard"} >= 1`, namespace, namespace),
+						"5m", "warning",
+						fmt.Sprintf("Workspace %s exhausted its {{ $labels.resource }} quota, new pods requesting it are rejected", workspace.Name)),
+					alert("WorkspacePodsFailedCreate",
+						fmt.Sprintf(`max by (deployment) (kube_deployment_status_condition{namespace="%s",condition="ReplicaFailure",status="true"}) > 0`, namespace),
+						"15m", "warning",
+						fmt.Sprintf("Deployment {{ $labels.deployment }} in workspace %s fails to create pods, the workspace may have run out of quota", workspace.Name)),
+				},
+			},
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, prometheusRule, r.Scheme); err != nil {
+		return nil, err
+	}
+	return prometheusRule, nil
+}
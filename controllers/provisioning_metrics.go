@@ -0,0 +1,151 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// Actions of the changes of the child resources counted by the provisioning metrics
+const (
+	childActionCreate = "create"
+	childActionUpdate = "update"
+)
+
+// ProvisioningMetrics instruments the reconciliations of the workspaces: their duration, their errors and the
+// child resources they create and update. Like the WorkspaceCollector, the series are aggregated at Level and
+// the per-workspace series are only recorded at the workspace level or for the Detailed workspaces.
+// A nil ProvisioningMetrics records nothing.
+type ProvisioningMetrics struct {
+	level    MetricsLevel
+	detailed map[string]bool
+
+	workspaceDuration  *prometheus.HistogramVec
+	workspaceErrors    *prometheus.CounterVec
+	workspacesDuration *prometheus.HistogramVec
+	workspacesErrors   *prometheus.CounterVec
+	childChanges       *prometheus.CounterVec
+}
+
+// NewProvisioningMetrics returns the provisioning metrics aggregated at level, MetricsLevelWorkspace when empty,
+// with the per-workspace series of the detailed workspaces
+func NewProvisioningMetrics(level MetricsLevel, detailed []string) *ProvisioningMetrics {
+	aggregateLabels := []string{"class"}
+	if level == MetricsLevelGlobal {
+		aggregateLabels = nil
+	}
+	m := &ProvisioningMetrics{
+		level:    level,
+		detailed: map[string]bool{},
+		workspaceDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "workspace_reconcile_duration_seconds",
+			Help:    "Duration of the reconciliations of the workspace",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"workspace"}),
+		workspaceErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "workspace_reconcile_errors_total",
+			Help: "Number of failed reconciliations of the workspace",
+		}, []string{"workspace"}),
+		workspacesDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "workspaces_reconcile_duration_seconds",
+			Help:    "Duration of the reconciliations of the workspaces",
+			Buckets: prometheus.DefBuckets,
+		}, aggregateLabels),
+		workspacesErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "workspaces_reconcile_errors_total",
+			Help: "Number of failed reconciliations of the workspaces",
+		}, aggregateLabels),
+		childChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "workspace_child_resources_total",
+			Help: "Number of child resources of the workspaces created and updated by the operator",
+		}, []string{"kind", "action"}),
+	}
+	for _, name := range detailed {
+		m.detailed[name] = true
+	}
+	return m
+}
+
+// Describe sends the descriptors of the provisioning metrics
+func (m *ProvisioningMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.workspaceDuration.Describe(ch)
+	m.workspaceErrors.Describe(ch)
+	m.workspacesDuration.Describe(ch)
+	m.workspacesErrors.Describe(ch)
+	m.childChanges.Describe(ch)
+}
+
+// Collect sends the provisioning metrics recorded since the start of the operator
+func (m *ProvisioningMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.workspaceDuration.Collect(ch)
+	m.workspaceErrors.Collect(ch)
+	m.workspacesDuration.Collect(ch)
+	m.workspacesErrors.Collect(ch)
+	m.childChanges.Collect(ch)
+}
+
+// aggregateLabels returns the label values of the aggregated series of the workspace at the level
+func (m *ProvisioningMetrics) aggregateLabels(workspace *environmentv1alpha1.Workspace) []string {
+	if m.level == MetricsLevelGlobal {
+		return nil
+	}
+	return []string{workspace.Spec.ClassName}
+}
+
+// perWorkspace reports whether the per-workspace series of the workspace are recorded
+func (m *ProvisioningMetrics) perWorkspace(workspace *environmentv1alpha1.Workspace) bool {
+	return m.level == "" || m.level == MetricsLevelWorkspace || m.detailed[workspace.Name]
+}
+
+// observeReconcile records the duration of a reconciliation of the workspace and whether it failed
+func (m *ProvisioningMetrics) observeReconcile(workspace *environmentv1alpha1.Workspace, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.workspacesDuration.WithLabelValues(m.aggregateLabels(workspace)...).Observe(duration.Seconds())
+	if err != nil {
+		m.workspacesErrors.WithLabelValues(m.aggregateLabels(workspace)...).Inc()
+	}
+	if !m.perWorkspace(workspace) {
+		return
+	}
+	m.workspaceDuration.WithLabelValues(workspace.Name).Observe(duration.Seconds())
+	if err != nil {
+		m.workspaceErrors.WithLabelValues(workspace.Name).Inc()
+	}
+}
+
+// countChild counts a child resource of the given kind created or updated by the operator
+func (m *ProvisioningMetrics) countChild(kind, action string) {
+	if m == nil {
+		return
+	}
+	m.childChanges.WithLabelValues(kind, action).Inc()
+}
+
+// forget drops the per-workspace series of a deleted workspace
+func (m *ProvisioningMetrics) forget(name string) {
+	if m == nil {
+		return
+	}
+	m.workspaceDuration.DeleteLabelValues(name)
+	m.workspaceErrors.DeleteLabelValues(name)
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// ConditionQuotaExceeded is raised when the usage of a workspace in Monitor quota mode exceeds its hard limits
+const ConditionQuotaExceeded = "QuotaExceeded"
+
+// podResources returns the requests, or the limits, of a pod as accounted by the quota controller:
+// the largest of the sum of its containers and of each of its init containers, plus the pod overhead
+func podResources(pod *corev1.Pod, limits bool) corev1.ResourceList {
+	resources := func(container corev1.Container) corev1.ResourceList {
+		if limits {
+			return container.Resources.Limits
+		}
+		return container.Resources.Requests
+	}
+	total := corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range resources(container) {
+			sum := total[name]
+			sum.Add(quantity)
+			total[name] = sum
+		}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range resources(container) {
+			if current, ok := total[name]; !ok || quantity.Cmp(current) > 0 {
+				total[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	for name, quantity := range pod.Spec.Overhead {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+	return total
+}
+
+// namespaceUsage returns the usage of the namespace of the workspace for the resources of the hard limits,
+// computed from its running pods and its PersistentVolumeClaims as the quota controller would.
+// The counts of the other objects, e.g. services or secrets, are not tracked.
+func (r *WorkspaceReconciler) namespaceUsage(ctx context.Context, workspace *environmentv1alpha1.Workspace, hard corev1.ResourceList) (corev1.ResourceList, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return nil, err
+	}
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := r.List(ctx, claims, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return nil, err
+	}
+
+	used := corev1.ResourceList{}
+	add := func(name corev1.ResourceName, quantity quotaResource.Quantity) {
+		sum := used[name]
+		sum.Add(quantity)
+		used[name] = sum
+	}
+	for name := range hard {
+		switch {
+		case name == corev1.ResourcePods:
+			count := int64(0)
+			for i := range pods.Items {
+				if phase := pods.Items[i].Status.Phase; phase != corev1.PodSucceeded && phase != corev1.PodFailed {
+					count++
+				}
+			}
+			used[name] = *quotaResource.NewQuantity(count, quotaResource.DecimalSI)
+		case name == corev1.ResourcePersistentVolumeClaims:
+			used[name] = *quotaResource.NewQuantity(int64(len(claims.Items)), quotaResource.DecimalSI)
+		case name == corev1.ResourceRequestsStorage:
+			used[name] = *quotaResource.NewQuantity(0, quotaResource.BinarySI)
+			for i := range claims.Items {
+				add(name, claims.Items[i].Spec.Resources.Requests[corev1.ResourceStorage])
+			}
+		case name == corev1.ResourceCPU || name == corev1.ResourceMemory ||
+			strings.HasPrefix(string(name), "requests.") || strings.HasPrefix(string(name), "limits."):
+			limits := strings.HasPrefix(string(name), "limits.")
+			resource := corev1.ResourceName(strings.TrimPrefix(strings.TrimPrefix(string(name), "requests."), "limits."))
+			used[name] = *quotaResource.NewQuantity(0, quotaResource.DecimalSI)
+			for i := range pods.Items {
+				pod := &pods.Items[i]
+				// terminated pods release their resources
+				if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+					continue
+				}
+				if quantity, ok := podResources(pod, limits)[resource]; ok {
+					add(name, quantity)
+				}
+			}
+		}
+	}
+	return used, nil
+}
+
+// reconcileMonitoredQuota removes the ResourceQuota of a workspace in Monitor quota mode and returns a ResourceQuota
+// holding its hard limits and the usage of its namespace in its status, as the quota controller would report them
+func (r *WorkspaceReconciler) reconcileMonitoredQuota(ctx context.Context, workspace *environmentv1alpha1.Workspace, resources environmentv1alpha1.WorkspaceResource, now time.Time) (*corev1.ResourceQuota, error) {
+	desired, err := r.resourceQuotaForWorkspace(workspace, resources, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ResourceQuota created while the workspace was in Enforce mode
+	existing := &corev1.ResourceQuota{}
+	err = r.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil && metav1.IsControlledBy(existing, workspace) {
+		ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Deleting ResourceQuota %s in Monitor quota mode", existing.Name))
+		if err := r.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	used, err := r.namespaceUsage(ctx, workspace, desired.Spec.Hard)
+	if err != nil {
+		return nil, err
+	}
+	desired.Status = corev1.ResourceQuotaStatus{Hard: desired.Spec.Hard, Used: used}
+	return desired, nil
+}
+
+// quotaExceededCondition computes the QuotaExceeded condition from the usage of the monitored ResourceQuota
+func quotaExceededCondition(workspace *environmentv1alpha1.Workspace, resourceQuota *corev1.ResourceQuota) metav1.Condition {
+	var details []string
+	for name, hard := range resourceQuota.Status.Hard {
+		if used, ok := resourceQuota.Status.Used[name]; ok && used.Cmp(hard) > 0 {
+			details = append(details, fmt.Sprintf("%s at %s of %s", name, used.String(), hard.String()))
+		}
+	}
+	if len(details) == 0 {
+		return metav1.Condition{
+			Type:               ConditionQuotaExceeded,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "UsageWithinLimits",
+			Message:            "The usage of the namespace is within the limits of spec.resources",
+		}
+	}
+	sort.Strings(details)
+	return metav1.Condition{
+		Type:               ConditionQuotaExceeded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             "UsageAboveLimits",
+		Message:            strings.Join(details, ", "),
+	}
+}
+
+// reconcileQuotaExceeded updates the QuotaExceeded condition of a workspace in Monitor quota mode and removes it
+// in Enforce mode. An event and a notification are fired whenever the usage goes above or back within the limits.
+func (r *WorkspaceReconciler) reconcileQuotaExceeded(ctx context.Context, workspace *environmentv1alpha1.Workspace, resourceQuota *corev1.ResourceQuota) error {
+	previous := meta.FindStatusCondition(workspace.Status.Conditions, ConditionQuotaExceeded)
+	if workspace.Spec.QuotaMode != environmentv1alpha1.QuotaModeMonitor {
+		if previous == nil {
+			return nil
+		}
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionQuotaExceeded)
+		return r.Status().Update(ctx, workspace)
+	}
+
+	condition := quotaExceededCondition(workspace, resourceQuota)
+	if previous != nil && previous.Reason == condition.Reason && previous.Message == condition.Message &&
+		previous.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	transitioned := previous == nil || previous.Reason != condition.Reason
+	meta.SetStatusCondition(&workspace.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return err
+	}
+	if !transitioned || (previous == nil && condition.Status == metav1.ConditionFalse) {
+		return nil
+	}
+	eventType := corev1.EventTypeNormal
+	if condition.Status == metav1.ConditionTrue {
+		eventType = corev1.EventTypeWarning
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, eventType, condition.Reason, condition.Message)
+	}
+	r.notify(ctx, workspace, condition.Reason, condition.Message)
+	return nil
+}
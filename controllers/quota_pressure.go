@@ -0,0 +1,116 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// ConditionQuotaPressure is raised when the usage of the workspace ResourceQuota crosses a threshold
+const ConditionQuotaPressure = "QuotaPressure"
+
+// defaultQuotaThresholds are used when spec.quotaAlerts.thresholds is empty
+var defaultQuotaThresholds = []int32{80, 95}
+
+// quotaThresholds returns the configured thresholds sorted in ascending order
+func quotaThresholds(workspace *environmentv1alpha1.Workspace) []int32 {
+	thresholds := workspace.Spec.QuotaAlerts.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = defaultQuotaThresholds
+	}
+	sorted := append([]int32{}, thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// quotaPressureCondition computes the QuotaPressure condition from the usage reported in the ResourceQuota status
+func quotaPressureCondition(workspace *environmentv1alpha1.Workspace, resourceQuota *corev1.ResourceQuota) metav1.Condition {
+	thresholds := quotaThresholds(workspace)
+	crossed := int32(0)
+	var details []string
+	for name, hard := range resourceQuota.Status.Hard {
+		used, ok := resourceQuota.Status.Used[name]
+		if !ok || hard.IsZero() {
+			continue
+		}
+		percent := int32(used.AsApproximateFloat64() * 100 / hard.AsApproximateFloat64())
+		// the highest threshold crossed by the resource
+		for i := len(thresholds) - 1; i >= 0; i-- {
+			if threshold := thresholds[i]; percent >= threshold {
+				if threshold > crossed {
+					crossed = threshold
+				}
+				details = append(details, fmt.Sprintf("%s at %d%% of %s", name, percent, hard.String()))
+				break
+			}
+		}
+	}
+	if crossed == 0 {
+		return metav1.Condition{
+			Type:               ConditionQuotaPressure,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "UsageBelowThreshold",
+			Message:            fmt.Sprintf("ResourceQuota usage is below %d%%", thresholds[0]),
+		}
+	}
+	sort.Strings(details)
+	return metav1.Condition{
+		Type:               ConditionQuotaPressure,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             fmt.Sprintf("UsageAbove%dPercent", crossed),
+		Message:            strings.Join(details, ", "),
+	}
+}
+
+// reconcileQuotaPressure updates the QuotaPressure condition of the workspace.
+// An event and a notification are fired whenever the crossed threshold changes.
+func (r *WorkspaceReconciler) reconcileQuotaPressure(ctx context.Context, workspace *environmentv1alpha1.Workspace, resourceQuota *corev1.ResourceQuota) error {
+	condition := quotaPressureCondition(workspace, resourceQuota)
+	previous := meta.FindStatusCondition(workspace.Status.Conditions, ConditionQuotaPressure)
+	if previous != nil && previous.Reason == condition.Reason && previous.Message == condition.Message &&
+		previous.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	transitioned := previous == nil || previous.Reason != condition.Reason
+	meta.SetStatusCondition(&workspace.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return err
+	}
+	if !transitioned || (previous == nil && condition.Status == metav1.ConditionFalse) {
+		return nil
+	}
+	eventType := corev1.EventTypeNormal
+	if condition.Status == metav1.ConditionTrue {
+		eventType = corev1.EventTypeWarning
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, eventType, condition.Reason, condition.Message)
+	}
+	r.notify(ctx, workspace, condition.Reason, condition.Message)
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+func TestQuotaPressureCondition(t *testing.T) {
+	quota := func(hard, used corev1.ResourceList) *corev1.ResourceQuota {
+		return &corev1.ResourceQuota{Status: corev1.ResourceQuotaStatus{Hard: hard, Used: used}}
+	}
+	cpu := func(hard, used string) (corev1.ResourceList, corev1.ResourceList) {
+		return corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(hard)},
+			corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(used)}
+	}
+
+	tests := []struct {
+		name       string
+		thresholds []int32
+		quota      *corev1.ResourceQuota
+		status     metav1.ConditionStatus
+		reason     string
+		message    string
+	}{
+		{
+			name:    "no usage reported",
+			quota:   quota(nil, nil),
+			status:  metav1.ConditionFalse,
+			reason:  "UsageBelowThreshold",
+			message: "ResourceQuota usage is below 80%",
+		},
+		{
+			name:    "below the lowest threshold",
+			quota:   quota(cpu("1", "500m")),
+			status:  metav1.ConditionFalse,
+			reason:  "UsageBelowThreshold",
+			message: "ResourceQuota usage is below 80%",
+		},
+		{
+			name:    "at the lowest threshold",
+			quota:   quota(cpu("1", "800m")),
+			status:  metav1.ConditionTrue,
+			reason:  "UsageAbove80Percent",
+			message: "requests.cpu at 80% of 1",
+		},
+		{
+			name:    "between the thresholds",
+			quota:   quota(cpu("1", "900m")),
+			status:  metav1.ConditionTrue,
+			reason:  "UsageAbove80Percent",
+			message: "requests.cpu at 90% of 1",
+		},
+		{
+			name:    "above the highest threshold",
+			quota:   quota(cpu("1", "970m")),
+			status:  metav1.ConditionTrue,
+			reason:  "UsageAbove95Percent",
+			message: "requests.cpu at 97% of 1",
+		},
+		{
+			name:    "exhausted",
+			quota:   quota(cpu("2", "2")),
+			status:  metav1.ConditionTrue,
+			reason:  "UsageAbove95Percent",
+			message: "requests.cpu at 100% of 2",
+		},
+		{
+			name:       "unsorted configured thresholds",
+			thresholds: []int32{90, 50, 70},
+			quota:      quota(cpu("1", "750m")),
+			status:     metav1.ConditionTrue,
+			reason:     "UsageAbove70Percent",
+			message:    "requests.cpu at 75% of 1",
+		},
+		{
+			name:       "below the lowest configured threshold",
+			thresholds: []int32{90, 50, 70},
+			quota:      quota(cpu("1", "400m")),
+			status:     metav1.ConditionFalse,
+			reason:     "UsageBelowThreshold",
+			message:    "ResourceQuota usage is below 50%",
+		},
+		{
+			name: "highest threshold crossed by any resource",
+			quota: quota(
+				corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1"), corev1.ResourceRequestsMemory: resource.MustParse("10Gi"), corev1.ResourcePods: resource.MustParse("10")},
+				corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("850m"), corev1.ResourceRequestsMemory: resource.MustParse("10Gi"), corev1.ResourcePods: resource.MustParse("1")},
+			),
+			status:  metav1.ConditionTrue,
+			reason:  "UsageAbove95Percent",
+			message: "requests.cpu at 85% of 1, requests.memory at 100% of 10Gi",
+		},
+		{
+			name: "zero hard limit",
+			quota: quota(
+				corev1.ResourceList{corev1.ResourcePods: resource.MustParse("0")},
+				corev1.ResourceList{corev1.ResourcePods: resource.MustParse("0")},
+			),
+			status:  metav1.ConditionFalse,
+			reason:  "UsageBelowThreshold",
+			message: "ResourceQuota usage is below 80%",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := &environmentv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Generation: 3}}
+			workspace.Spec.QuotaAlerts.Thresholds = tt.thresholds
+			condition := quotaPressureCondition(workspace, tt.quota)
+			if condition.Type != ConditionQuotaPressure || condition.ObservedGeneration != 3 {
+				t.Errorf("condition type %s of generation %d, want %s of generation 3", condition.Type, condition.ObservedGeneration, ConditionQuotaPressure)
+			}
+			if condition.Status != tt.status || condition.Reason != tt.reason || condition.Message != tt.message {
+				t.Errorf("condition = %s %s %q, want %s %s %q", condition.Status, condition.Reason, condition.Message, tt.status, tt.reason, tt.message)
+			}
+		})
+	}
+}
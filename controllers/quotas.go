@@ -0,0 +1,112 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// QuotaNameLabel marks the ResourceQuotas generated from spec.quotas with the name of their entry,
+// so that the ResourceQuotas of removed entries can be found and deleted
+const QuotaNameLabel = "environment.tf.operator.com/quota"
+
+// reconcileQuotas keeps the additional ResourceQuotas of the workspace namespace in sync with spec.quotas
+func (r *WorkspaceReconciler) reconcileQuotas(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	existing := &corev1.ResourceQuotaList{}
+	if err := r.List(ctx, existing, client.InNamespace(workspace.Spec.Name), client.HasLabels{QuotaNameLabel}); err != nil {
+		return err
+	}
+	current := map[string]*corev1.ResourceQuota{}
+	for i := range existing.Items {
+		current[existing.Items[i].Labels[QuotaNameLabel]] = &existing.Items[i]
+	}
+
+	for _, quota := range workspace.Spec.Quotas {
+		// <namespace>-quota is the ResourceQuota of spec.resources
+		if quota.Name == "quota" {
+			return fmt.Errorf("quota name %q is reserved for the ResourceQuota of spec.resources", quota.Name)
+		}
+		rq, err := r.namedResourceQuotaForWorkspace(workspace, quota)
+		if err != nil {
+			return err
+		}
+		resourceQuota, ok := current[quota.Name]
+		delete(current, quota.Name)
+		if !ok {
+			reconcilerLog.Info(fmt.Sprintf("Creating a new ResourceQuota ResourceQuota.Name %s", rq.Name))
+			if err := r.apply(ctx, rq); err != nil {
+				return err
+			}
+			continue
+		}
+		// check if the hard limits or scopes of the quota changed
+		if !equality.Semantic.DeepEqual(resourceQuota.Spec, rq.Spec) {
+			reconcilerLog.Info(fmt.Sprintf("Spec not same for ResourceQuota.Name %s in Namespace.Name %s", rq.Name, rq.Namespace))
+			resourceQuota.Spec = rq.Spec
+			if err := r.update(ctx, resourceQuota); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Delete the ResourceQuotas of the entries removed from spec.quotas
+	for _, resourceQuota := range current {
+		if !metav1.IsControlledBy(resourceQuota, workspace) {
+			continue
+		}
+		reconcilerLog.Info(fmt.Sprintf("Deleting ResourceQuota ResourceQuota.Name %s", resourceQuota.Name))
+		if err := r.Delete(ctx, resourceQuota); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResourceQuota of an entry of spec.quotas, named <namespace>-<name>
+func (r *WorkspaceReconciler) namedResourceQuotaForWorkspace(workspace *environmentv1alpha1.Workspace, quota environmentv1alpha1.WorkspaceQuota) (*corev1.ResourceQuota, error) {
+	labels := labelsForWorkspace(workspace, map[string]string{QuotaNameLabel: quota.Name})
+	rq := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s", workspace.Spec.Name, quota.Name),
+			Namespace:   workspace.Spec.Name,
+			Labels:      labels,
+			Annotations: annotationsForWorkspace(workspace),
+		},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard:          quota.Hard,
+			Scopes:        quota.Scopes,
+			ScopeSelector: quota.ScopeSelector,
+		},
+	}
+	if err := ctrl.SetControllerReference(workspace, rq, r.Scheme); err != nil {
+		return nil, err
+	}
+	return rq, nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+const (
+	// ConditionAccessReview is raised when the access of the workspace is due for recertification
+	ConditionAccessReview = "AccessReview"
+
+	// RecertifiedAtAnnotation is set by the owner of the workspace to the RFC 3339 time the access of
+	// the workspace users was last re-attested, e.g. with kubectl annotate --overwrite
+	RecertifiedAtAnnotation = "environment.tf.operator.com/recertified-at"
+
+	// DefaultRecertificationGracePeriod is the time a workspace has to be re-attested once its review is due
+	// when RecertificationGracePeriod is not set
+	DefaultRecertificationGracePeriod = 7 * 24 * time.Hour
+)
+
+// Reasons of the AccessReview condition
+const (
+	accessCertified     = "Certified"
+	accessPendingReview = "PendingReview"
+	accessExpired       = "Expired"
+)
+
+// lastRecertification returns the time the access of the workspace was last re-attested,
+// its creation when it never was. Times in the future are capped to now.
+func lastRecertification(workspace *environmentv1alpha1.Workspace, now time.Time) time.Time {
+	last := workspace.CreationTimestamp.Time
+	if value, ok := workspace.Annotations[RecertifiedAtAnnotation]; ok {
+		if attested, err := time.Parse(time.RFC3339, value); err == nil && attested.After(last) {
+			last = attested
+		}
+	}
+	if last.After(now) {
+		return now
+	}
+	return last
+}
+
+// recertificationGracePeriod returns the time a workspace has to be re-attested once its review is due
+func (r *WorkspaceReconciler) recertificationGracePeriod() time.Duration {
+	if r.RecertificationGracePeriod > 0 {
+		return r.RecertificationGracePeriod
+	}
+	return DefaultRecertificationGracePeriod
+}
+
+// recertificationState returns the recertification state of the access of the workspace at now,
+// and the time the review is due
+func (r *WorkspaceReconciler) recertificationState(workspace *environmentv1alpha1.Workspace, now time.Time) (string, time.Time) {
+	dueAt := lastRecertification(workspace, now).Add(r.RecertificationInterval)
+	switch {
+	case now.Before(dueAt):
+		return accessCertified, dueAt
+	case now.Before(dueAt.Add(r.recertificationGracePeriod())):
+		return accessPendingReview, dueAt
+	default:
+		return accessExpired, dueAt
+	}
+}
+
+// accessExpired reports whether the access of the workspace was not re-attested within the grace period
+// of its review, in which case only its admin stays bound
+func (r *WorkspaceReconciler) accessExpired(workspace *environmentv1alpha1.Workspace, now time.Time) bool {
+	if r.RecertificationInterval <= 0 {
+		return false
+	}
+	state, _ := r.recertificationState(workspace, now)
+	return state == accessExpired
+}
+
+// nextRecertificationChange returns the first time after now the recertification state of the workspace changes,
+// zero when recertification is disabled
+func (r *WorkspaceReconciler) nextRecertificationChange(workspace *environmentv1alpha1.Workspace, now time.Time) time.Time {
+	if r.RecertificationInterval <= 0 {
+		return time.Time{}
+	}
+	state, dueAt := r.recertificationState(workspace, now)
+	switch state {
+	case accessCertified:
+		return dueAt
+	case accessPendingReview:
+		return dueAt.Add(r.recertificationGracePeriod())
+	default:
+		return time.Time{}
+	}
+}
+
+// reconcileRecertification updates the AccessReview condition of the workspace every RecertificationInterval.
+// The owner is notified when the review is due and when the access expires, and the condition is removed
+// when recertification is disabled. The review is evaluated at now, the time of the reconciliation.
+func (r *WorkspaceReconciler) reconcileRecertification(ctx context.Context, workspace *environmentv1alpha1.Workspace, now time.Time) error {
+	previous := meta.FindStatusCondition(workspace.Status.Conditions, ConditionAccessReview)
+	if r.RecertificationInterval <= 0 {
+		if previous == nil {
+			return nil
+		}
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionAccessReview)
+		return r.Status().Update(ctx, workspace)
+	}
+
+	state, dueAt := r.recertificationState(workspace, now)
+	condition := metav1.Condition{
+		Type:               ConditionAccessReview,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             state,
+	}
+	switch state {
+	case accessCertified:
+		condition.Status = metav1.ConditionFalse
+		condition.Message = fmt.Sprintf("Access was recertified, the next review is due at %s", dueAt.UTC().Format(time.RFC3339))
+	case accessPendingReview:
+		condition.Message = fmt.Sprintf("Access review is due since %s, annotate the Workspace with %s before %s or the editor and viewer bindings are dropped",
+			dueAt.UTC().Format(time.RFC3339), RecertifiedAtAnnotation, dueAt.Add(r.recertificationGracePeriod()).UTC().Format(time.RFC3339))
+	default:
+		condition.Message = fmt.Sprintf("Access was not recertified since %s, the editor and viewer bindings are dropped until the Workspace is annotated with %s",
+			dueAt.UTC().Format(time.RFC3339), RecertifiedAtAnnotation)
+	}
+	if previous != nil && previous.Reason == condition.Reason && previous.Message == condition.Message &&
+		previous.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	meta.SetStatusCondition(&workspace.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return err
+	}
+	if state == accessCertified || (previous != nil && previous.Reason == condition.Reason) {
+		return nil
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+	r.notify(ctx, workspace, condition.Reason, condition.Message)
+	return nil
+}
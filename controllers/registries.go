@@ -0,0 +1,59 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// registryPolicyValidation returns the CEL validation requiring the images of all the containers
+// to come from one of the allowed registries
+func registryPolicyValidation(registries []string) map[string]interface{} {
+	quoted := make([]string, 0, len(registries))
+	for _, registry := range registries {
+		quoted = append(quoted, fmt.Sprintf("%q", strings.TrimSuffix(registry, "/")))
+	}
+	return celValidation(
+		fmt.Sprintf("variables.containers.all(c, [%s].exists(r, c.image.startsWith(r + '/')))", strings.Join(quoted, ", ")),
+		fmt.Sprintf("Images must come from one of the allowed registries: %s", strings.Join(registries, ", ")))
+}
+
+// reconcileRegistryPolicy generates the admission policy rejecting the pods of the workspace namespace whose
+// images come from other registries than spec.allowedRegistries. The policy uses the engine of the
+// security policy of the workspace class, or a ValidatingAdmissionPolicy when the class has none.
+func (r *WorkspaceReconciler) reconcileRegistryPolicy(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	var policy *admissionPolicy
+	if len(workspace.Spec.AllowedRegistries) > 0 {
+		class, err := r.workspaceClass(ctx, workspace)
+		if err != nil {
+			return err
+		}
+		policy = &admissionPolicy{
+			engine:      "vap",
+			action:      "Enforce",
+			validations: []interface{}{registryPolicyValidation(workspace.Spec.AllowedRegistries)},
+		}
+		if class != nil && class.Spec.SecurityPolicy != nil {
+			policy.engine = class.Spec.SecurityPolicy.Engine
+		}
+	}
+	return r.reconcileAdmissionPolicy(ctx, workspace, fmt.Sprintf("%s-allowed-registries", workspace.Spec.Name), policy)
+}
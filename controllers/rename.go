@@ -0,0 +1,238 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// ConditionRenaming is True while a renamed workspace keeps its previous namespace for the rename grace period
+	ConditionRenaming = "Renaming"
+
+	// DefaultRenameGracePeriod is the time the previous namespace of a renamed workspace is kept
+	// when RenameGracePeriod is not set
+	DefaultRenameGracePeriod = 7 * 24 * time.Hour
+)
+
+// namespacedAdmissionPolicies are the suffixes of the admission policies of a workspace, which are cluster scoped
+// and named after its namespace, so that they are not removed with the namespace
+var namespacedAdmissionPolicies = []string{"security-context", "allowed-registries", "disruption-budgets", "namespace-protection", "object-protection", "token-automount"}
+
+// MigrationHook migrates the workloads of a renamed workspace from its previous namespace to its new one
+type MigrationHook interface {
+	Migrate(ctx context.Context, workspace, from, to string) error
+}
+
+// NewHTTPMigrationHook returns a MigrationHook which POSTs the rename as JSON to the endpoint,
+// e.g. {"workspace": "payments", "from": "payments", "to": "payments-eu"}
+func NewHTTPMigrationHook(endpoint string) MigrationHook {
+	return &httpMigrationHook{endpoint: endpoint, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type httpMigrationHook struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (h *httpMigrationHook) Migrate(ctx context.Context, workspace, from, to string) error {
+	body, err := json.Marshal(map[string]string{"workspace": workspace, "from": from, "to": to})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("migration hook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// validateRename rejects a change of spec.name while the previous rename of the workspace is in progress
+func validateRename(oldWorkspace, workspace *environmentv1alpha1.Workspace) error {
+	if rename := oldWorkspace.Status.Rename; rename != nil && oldWorkspace.Spec.Name != workspace.Spec.Name {
+		return fmt.Errorf("spec.name can not be changed until the rename from %s to %s completes at %s",
+			rename.From, rename.To, rename.RetireAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// renameRetireTime returns the time the previous namespace of a renamed workspace is retired,
+// or the zero time when no rename is in progress
+func renameRetireTime(workspace *environmentv1alpha1.Workspace) time.Time {
+	if workspace.Status.Rename == nil {
+		return time.Time{}
+	}
+	return workspace.Status.Rename.RetireAt.Time
+}
+
+// reconcileRename tracks the namespace of the workspace in status.namespace. Once the workspace is provisioned in
+// the namespace of a renamed spec.name, the migration hook is called and the previous namespace is kept for the
+// rename grace period, after which it is retired with the objects of the workspace named after it.
+func (r *WorkspaceReconciler) reconcileRename(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+	rename := workspace.Status.Rename
+	switch {
+	case rename != nil:
+		if time.Now().Before(rename.RetireAt.Time) {
+			return nil
+		}
+		if err := r.retireNamespace(ctx, workspace, rename.From); err != nil {
+			return err
+		}
+		message := fmt.Sprintf("Workspace was renamed from Namespace %s to %s, Namespace %s is retired", rename.From, rename.To, rename.From)
+		workspace.Status.Rename = nil
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionRenaming,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "Renamed",
+			Message:            message,
+		})
+		if err := r.Status().Update(ctx, workspace); err != nil {
+			return err
+		}
+		if r.Recorder != nil {
+			r.Recorder.Event(workspace, corev1.EventTypeNormal, "Renamed", message)
+		}
+		r.notify(ctx, workspace, "Renamed", message)
+		return nil
+
+	case workspace.Status.Namespace == workspace.Spec.Name:
+		return nil
+
+	case workspace.Status.Namespace == "":
+		workspace.Status.Namespace = workspace.Spec.Name
+		return r.Status().Update(ctx, workspace)
+	}
+
+	from := workspace.Status.Namespace
+	if r.MigrationHook != nil {
+		reconcilerLog.Info(fmt.Sprintf("Calling migration hook for the rename of Workspace %s from Namespace %s to %s", workspace.Name, from, workspace.Spec.Name))
+		if err := r.MigrationHook.Migrate(ctx, workspace.Name, from, workspace.Spec.Name); err != nil {
+			return fmt.Errorf("migration hook failed for the rename from Namespace %s to %s: %w", from, workspace.Spec.Name, err)
+		}
+	}
+	gracePeriod := r.RenameGracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultRenameGracePeriod
+	}
+	now := time.Now()
+	workspace.Status.Rename = &environmentv1alpha1.WorkspaceRename{
+		From:       from,
+		To:         workspace.Spec.Name,
+		MigratedAt: metav1.NewTime(now),
+		RetireAt:   metav1.NewTime(now.Add(gracePeriod)),
+	}
+	workspace.Status.Namespace = workspace.Spec.Name
+	// the new namespace is registered with the observability tenant on the next reconciliation
+	workspace.Status.ObservabilityTenant = ""
+	message := fmt.Sprintf("Workspace was migrated from Namespace %s to %s, Namespace %s is retired at %s",
+		from, workspace.Spec.Name, from, workspace.Status.Rename.RetireAt.UTC().Format(time.RFC3339))
+	meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+		Type:               ConditionRenaming,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             "RetiringNamespace",
+		Message:            message,
+	})
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, corev1.EventTypeNormal, "RetiringNamespace", message)
+	}
+	r.notify(ctx, workspace, "RetiringNamespace", message)
+	return nil
+}
+
+// retireNamespace deletes the previous namespace of a renamed workspace and the objects of the workspace named
+// after it outside of the namespace: its admission policies, its Kueue ClusterQueue and its log pipeline ConfigMap.
+// The observability tenant mapping of the namespace is removed.
+func (r *WorkspaceReconciler) retireNamespace(ctx context.Context, workspace *environmentv1alpha1.Workspace, namespace string) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	for _, suffix := range namespacedAdmissionPolicies {
+		if err := r.reconcileAdmissionPolicy(ctx, workspace, fmt.Sprintf("%s-%s", namespace, suffix), nil); err != nil {
+			return err
+		}
+	}
+
+	clusterQueue := &unstructured.Unstructured{}
+	clusterQueue.SetGroupVersionKind(clusterQueueGVK)
+	type retiredObject struct {
+		kind   string
+		key    types.NamespacedName
+		object client.Object
+	}
+	objects := []retiredObject{{"ClusterQueue", types.NamespacedName{Name: namespace}, clusterQueue}}
+	if r.LogPipelineNamespace != "" {
+		objects = append(objects, retiredObject{"ConfigMap", types.NamespacedName{Namespace: r.LogPipelineNamespace, Name: fmt.Sprintf("%s-log-pipeline", namespace)}, &corev1.ConfigMap{}})
+	}
+	if !r.NamespacedOnly {
+		objects = append(objects, retiredObject{"Namespace", types.NamespacedName{Name: namespace}, &corev1.Namespace{}})
+	}
+	for _, retired := range objects {
+		err := r.Get(ctx, retired.key, retired.object)
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(retired.object, workspace) {
+			continue
+		}
+		reconcilerLog.Info(fmt.Sprintf("Deleting %s %s of renamed Workspace", retired.kind, retired.key.Name))
+		if err := r.Delete(ctx, retired.object); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if r.TenantRegistry != nil && workspace.Spec.ObservabilityTenant != "" {
+		reconcilerLog.Info(fmt.Sprintf("Unregistering Namespace.Name %s from observability tenant %s", namespace, workspace.Spec.ObservabilityTenant))
+		if err := r.TenantRegistry.Unregister(ctx, workspace.Spec.ObservabilityTenant, namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
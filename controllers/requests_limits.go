@@ -0,0 +1,62 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// requestsAndLimits returns the separate requests and limits of resources by the name of their hard limit
+// in the workspace ResourceQuota. The cpu and memory hard limits of CPU and Memory cap the requests as well,
+// the quota controller accounting them as requests.cpu and requests.memory.
+func requestsAndLimits(resources environmentv1alpha1.WorkspaceResource) map[corev1.ResourceName]string {
+	return map[corev1.ResourceName]string{
+		corev1.ResourceRequestsCPU:    resources.RequestsCPU,
+		corev1.ResourceLimitsCPU:      resources.LimitsCPU,
+		corev1.ResourceRequestsMemory: resources.RequestsMemory,
+		corev1.ResourceLimitsMemory:   resources.LimitsMemory,
+	}
+}
+
+// requestsAndLimitsFields returns the fields of spec.resources of the separate requests and limits of resources
+func requestsAndLimitsFields(resources environmentv1alpha1.WorkspaceResource) []struct{ field, value string } {
+	return []struct{ field, value string }{
+		{"spec.resources.requestsCPU", resources.RequestsCPU},
+		{"spec.resources.limitsCPU", resources.LimitsCPU},
+		{"spec.resources.requestsMemory", resources.RequestsMemory},
+		{"spec.resources.limitsMemory", resources.LimitsMemory},
+	}
+}
+
+// mergeRequestsAndLimits sets the separate requests and limits set in resources on merged
+func mergeRequestsAndLimits(merged *environmentv1alpha1.WorkspaceResource, resources environmentv1alpha1.WorkspaceResource) {
+	for _, limit := range []struct {
+		merged *string
+		value  string
+	}{
+		{&merged.RequestsCPU, resources.RequestsCPU},
+		{&merged.LimitsCPU, resources.LimitsCPU},
+		{&merged.RequestsMemory, resources.RequestsMemory},
+		{&merged.LimitsMemory, resources.LimitsMemory},
+	} {
+		if limit.value != "" {
+			*limit.merged = limit.value
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/yaml"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// RequiredLabel is a label every workspace namespace must carry, e.g. its data classification or compliance regime
+type RequiredLabel struct {
+	// Key of the label
+	Key string `json:"key"`
+
+	// Default is the value of the label on the namespaces of the workspaces not setting it in spec.labels.
+	// The Workspaces must set it when it is empty.
+	Default string `json:"default,omitempty"`
+
+	// AllowedValues restricts the values of the label, any value is allowed when empty
+	AllowedValues []string `json:"allowedValues,omitempty"`
+}
+
+// RequiredLabels are the labels every workspace namespace must carry
+type RequiredLabels []RequiredLabel
+
+// LoadRequiredLabels reads the required labels from a YAML file listing their key, default and allowedValues
+func LoadRequiredLabels(path string) (RequiredLabels, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var required RequiredLabels
+	if err := yaml.UnmarshalStrict(data, &required); err != nil {
+		return nil, fmt.Errorf("invalid required labels %s: %w", path, err)
+	}
+	for _, label := range required {
+		if errs := validation.IsQualifiedName(label.Key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid required label key %q: %s", label.Key, strings.Join(errs, ", "))
+		}
+		for _, value := range append([]string{label.Default}, label.AllowedValues...) {
+			if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid value %q of required label %s: %s", value, label.Key, strings.Join(errs, ", "))
+			}
+		}
+		if label.Default != "" && !label.allows(label.Default) {
+			return nil, fmt.Errorf("default %q of required label %s is not one of its allowedValues", label.Default, label.Key)
+		}
+	}
+	return required, nil
+}
+
+// allows reports whether the value is one of the allowed values of the label
+func (l RequiredLabel) allows(value string) bool {
+	if len(l.AllowedValues) == 0 {
+		return true
+	}
+	for _, allowed := range l.AllowedValues {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the default of the required labels missing from the labels
+func (r RequiredLabels) Apply(labels map[string]string) map[string]string {
+	for _, label := range r {
+		if labels[label.Key] == "" && label.Default != "" {
+			labels[label.Key] = label.Default
+		}
+	}
+	return labels
+}
+
+// Check returns why the spec.labels of the workspace do not set the required labels without default to an allowed value.
+// On updates, previous is the Workspace before the update and only the labels whose value changed are checked,
+// so that the Workspaces created before a label was required can still be updated.
+func (r RequiredLabels) Check(previous, workspace *environmentv1alpha1.Workspace) error {
+	var problems []string
+	for _, label := range r {
+		value := workspace.Spec.Labels[label.Key]
+		if previous != nil && previous.Spec.Labels[label.Key] == value {
+			continue
+		}
+		switch {
+		case value == "" && label.Default == "":
+			problems = append(problems, fmt.Sprintf("spec.labels must set the required label %s", label.Key))
+		case value != "" && !label.allows(value):
+			problems = append(problems, fmt.Sprintf("spec.labels.%s must be one of %s", label.Key, strings.Join(label.AllowedValues, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// driftPredicate lets through the updates of a namespace changing one of the required labels,
+// so that they are corrected right away rather than on the next resync
+func (r RequiredLabels) driftPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			for _, label := range r {
+				if e.ObjectOld.GetLabels()[label.Key] != e.ObjectNew.GetLabels()[label.Key] {
+					return true
+				}
+			}
+			return false
+		},
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// The NamespaceReady, QuotaReady and RBACReady conditions report the provisioning state of the resources of the
+// workspace, next to the Ready condition summarizing all of them
+const (
+	// ConditionNamespaceReady is True once the namespace of the workspace exists
+	ConditionNamespaceReady = "NamespaceReady"
+
+	// ConditionQuotaReady is True once the ResourceQuota of the workspace exists
+	ConditionQuotaReady = "QuotaReady"
+
+	// ConditionRBACReady is True once the Roles and RoleBindings of the workspace exist
+	ConditionRBACReady = "RBACReady"
+)
+
+// resourceState returns the provisioning state of a resource of the workspace from the cache
+func (r *WorkspaceReconciler) resourceState(ctx context.Context, kind, namespace, name string, object client.Object) (environmentv1alpha1.WorkspaceResourceState, error) {
+	state := environmentv1alpha1.WorkspaceResourceState{Kind: kind, Name: name, State: environmentv1alpha1.ResourceProvisioned}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, object); apierrors.IsNotFound(err) {
+		state.State = environmentv1alpha1.ResourceMissing
+	} else if err != nil {
+		return state, err
+	} else if !object.GetDeletionTimestamp().IsZero() {
+		state.State = environmentv1alpha1.ResourceTerminating
+	}
+	return state, nil
+}
+
+// reconcileResourceStates sets status.resources and the NamespaceReady, QuotaReady and RBACReady conditions
+// of the workspace from the resources found in the cache
+func (r *WorkspaceReconciler) reconcileResourceStates(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	name := workspace.Spec.Name
+	namespaceState, err := r.resourceState(ctx, "Namespace", "", name, &corev1.Namespace{})
+	if err != nil {
+		return err
+	}
+	states := []environmentv1alpha1.WorkspaceResourceState{namespaceState}
+	setResourceCondition(workspace, ConditionNamespaceReady, "Namespace", states)
+
+	if workspace.Spec.QuotaMode == environmentv1alpha1.QuotaModeMonitor {
+		// No ResourceQuota is created in Monitor quota mode
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionQuotaReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "MonitorMode",
+			Message:            "The usage of the namespace is monitored by the operator",
+		})
+	} else {
+		quotaState, err := r.resourceState(ctx, "ResourceQuota", name, fmt.Sprintf("%s-quota", name), &corev1.ResourceQuota{})
+		if err != nil {
+			return err
+		}
+		setResourceCondition(workspace, ConditionQuotaReady, "ResourceQuota", []environmentv1alpha1.WorkspaceResourceState{quotaState})
+		states = append(states, quotaState)
+	}
+
+	var rbacStates []environmentv1alpha1.WorkspaceResourceState
+	for _, role := range workspaceRoles {
+		roleState, err := r.resourceState(ctx, "Role", name, fmt.Sprintf("%s-%s", name, role), &rbacv1.Role{})
+		if err != nil {
+			return err
+		}
+		roleBindingState, err := r.resourceState(ctx, "RoleBinding", name, fmt.Sprintf("%s-%s-rb", name, role), &rbacv1.RoleBinding{})
+		if err != nil {
+			return err
+		}
+		rbacStates = append(rbacStates, roleState, roleBindingState)
+	}
+	setResourceCondition(workspace, ConditionRBACReady, "Roles and RoleBindings", rbacStates)
+	workspace.Status.Resources = append(states, rbacStates...)
+	return nil
+}
+
+// setResourceCondition sets a condition of the workspace to True when all the resources are provisioned,
+// to False with the resources which are not otherwise
+func setResourceCondition(workspace *environmentv1alpha1.Workspace, conditionType, resources string, states []environmentv1alpha1.WorkspaceResourceState) {
+	condition := metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             "Provisioned",
+	}
+	var pending []string
+	for _, state := range states {
+		if state.State != environmentv1alpha1.ResourceProvisioned {
+			pending = append(pending, fmt.Sprintf("%s %s is %s", state.Kind, state.Name, strings.ToLower(string(state.State))))
+			condition.Reason = string(state.State)
+		}
+	}
+	if len(pending) == 0 {
+		condition.Message = fmt.Sprintf("%s provisioned", resources)
+	} else {
+		condition.Status = metav1.ConditionFalse
+		condition.Message = strings.Join(pending, ", ")
+	}
+	meta.SetStatusCondition(&workspace.Status.Conditions, condition)
+}
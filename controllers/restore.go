@@ -0,0 +1,220 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// RestoreAnnotation set to "true" on a Workspace during its deletion grace period reverses the deletion
+	RestoreAnnotation = "environment.tf.operator.com/restore"
+
+	// RestoreWorkspaceLabel marks a namespace whose Workspace is being restored, its value is the Workspace name
+	RestoreWorkspaceLabel = "environment.tf.operator.com/restore-workspace"
+
+	// TrashedWorkspaceAnnotation holds the Workspace being restored on its namespace until it is recreated
+	TrashedWorkspaceAnnotation = "environment.tf.operator.com/trashed-workspace"
+)
+
+// restoreRequested reports whether the restore of a deleted workspace was requested
+func restoreRequested(workspace *environmentv1alpha1.Workspace) bool {
+	restore, _ := strconv.ParseBool(workspace.Annotations[RestoreAnnotation])
+	return restore
+}
+
+// restoreWorkspace reverses the deletion of a workspace during its deletion grace period.
+// A deleted object can not be undeleted, so the namespace is detached from the deleted Workspace
+// together with a copy of it, the finalizer is released and the Workspace is recreated
+// from the copy by recreateRestoredWorkspace once the deleted Workspace is gone.
+func (r *WorkspaceReconciler) restoreWorkspace(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+	reconcilerLog.Info(fmt.Sprintf("Restoring Workspace %s", workspace.Name))
+
+	if err := r.thawWorkspace(ctx, workspace); err != nil {
+		return err
+	}
+
+	namespace := &corev1.Namespace{}
+	err := r.Get(ctx, types.NamespacedName{Name: workspace.Spec.Name}, namespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		trashed := &environmentv1alpha1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        workspace.Name,
+				Labels:      workspace.Labels,
+				Annotations: map[string]string{},
+			},
+			Spec: workspace.Spec,
+		}
+		for k, v := range workspace.Annotations {
+			if k != RestoreAnnotation {
+				trashed.Annotations[k] = v
+			}
+		}
+		data, err := json.Marshal(trashed)
+		if err != nil {
+			return err
+		}
+		// Orphan the namespace so that the garbage collector keeps it when the deleted Workspace is removed
+		var ownerReferences []metav1.OwnerReference
+		for _, ownerReference := range namespace.OwnerReferences {
+			if ownerReference.UID != workspace.UID {
+				ownerReferences = append(ownerReferences, ownerReference)
+			}
+		}
+		namespace.OwnerReferences = ownerReferences
+		if namespace.Labels == nil {
+			namespace.Labels = map[string]string{}
+		}
+		namespace.Labels[RestoreWorkspaceLabel] = workspace.Name
+		setAnnotation(&namespace.ObjectMeta, TrashedWorkspaceAnnotation, string(data))
+		if err := r.Update(ctx, namespace); err != nil {
+			return err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(workspace, WorkspaceFinalizer)
+	if err := r.Update(ctx, workspace); err != nil {
+		return err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, corev1.EventTypeNormal, "Restored", "Workspace deletion was reversed, the Workspace is being recreated")
+	}
+	r.notify(ctx, workspace, "Restored", fmt.Sprintf("Deletion of Workspace %s was reversed", workspace.Name))
+	return nil
+}
+
+// recreateRestoredWorkspace recreates a restored Workspace from the copy kept on its namespace
+// and adopts the namespace again. It reports whether a Workspace was recreated.
+func (r *WorkspaceReconciler) recreateRestoredWorkspace(ctx context.Context, name string) (bool, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaces, client.MatchingLabels{RestoreWorkspaceLabel: name}); err != nil {
+		return false, err
+	}
+	if len(namespaces.Items) == 0 {
+		return false, nil
+	}
+	namespace := &namespaces.Items[0]
+
+	workspace := &environmentv1alpha1.Workspace{}
+	if err := json.Unmarshal([]byte(namespace.Annotations[TrashedWorkspaceAnnotation]), workspace); err != nil {
+		return false, fmt.Errorf("failed to decode trashed Workspace on Namespace %s: %w", namespace.Name, err)
+	}
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, name).Info(fmt.Sprintf("Recreating restored Workspace %s", workspace.Name))
+	if err := r.Create(ctx, workspace); err != nil && !apierrors.IsAlreadyExists(err) {
+		return false, err
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, workspace); err != nil {
+		return false, err
+	}
+	if !workspace.DeletionTimestamp.IsZero() {
+		return false, fmt.Errorf("deleted Workspace %s is not removed yet", name)
+	}
+
+	delete(namespace.Labels, RestoreWorkspaceLabel)
+	delete(namespace.Annotations, TrashedWorkspaceAnnotation)
+	if err := ctrl.SetControllerReference(workspace, namespace, r.Scheme); err != nil {
+		return false, err
+	}
+	if err := r.Update(ctx, namespace); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// thawWorkspace scales the workloads scaled down by scaleDownWorkloads back up and resumes the suspended cronjobs.
+// The RBAC of the workspace is recreated by the reconciliation of the restored Workspace.
+func (r *WorkspaceReconciler) thawWorkspace(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return err
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		replicas, ok := deployment.Annotations[ReplicasBeforeFreezeAnnotation]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(replicas, 10, 32)
+		if err != nil {
+			return err
+		}
+		count := int32(value)
+		deployment.Spec.Replicas = &count
+		delete(deployment.Annotations, ReplicasBeforeFreezeAnnotation)
+		if err := r.Update(ctx, deployment); err != nil {
+			return err
+		}
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return err
+	}
+	for i := range statefulSets.Items {
+		statefulSet := &statefulSets.Items[i]
+		replicas, ok := statefulSet.Annotations[ReplicasBeforeFreezeAnnotation]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(replicas, 10, 32)
+		if err != nil {
+			return err
+		}
+		count := int32(value)
+		statefulSet.Spec.Replicas = &count
+		delete(statefulSet.Annotations, ReplicasBeforeFreezeAnnotation)
+		if err := r.Update(ctx, statefulSet); err != nil {
+			return err
+		}
+	}
+	cronJobs := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronJobs, client.InNamespace(workspace.Spec.Name)); err != nil {
+		return err
+	}
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		if _, ok := cronJob.Annotations[SuspendedBeforeFreezeAnnotation]; !ok {
+			continue
+		}
+		suspend := false
+		cronJob.Spec.Suspend = &suspend
+		delete(cronJob.Annotations, SuspendedBeforeFreezeAnnotation)
+		if err := r.Update(ctx, cronJob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=create
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=create
+
+// ConditionRestored is True once the objects of the snapshot of spec.restoreFrom are restored in the namespace
+const ConditionRestored = "Restored"
+
+// validateRestoreFrom refuses changes of spec.restoreFrom, a workspace is only restored when it is created
+func validateRestoreFrom(oldWorkspace, workspace *environmentv1alpha1.Workspace) error {
+	if oldWorkspace.Spec.RestoreFrom != workspace.Spec.RestoreFrom {
+		return fmt.Errorf("spec.restoreFrom can not be changed after the Workspace is created")
+	}
+	return nil
+}
+
+// reconcileRestoreFrom creates the objects captured by the snapshot of spec.restoreFrom in the namespace of the
+// workspace, once. The objects already present in the namespace are left untouched, and the objects refused by the
+// API server, e.g. Secrets whose type requires data, are skipped and listed in the Restored condition.
+// It returns false while the snapshot is not completed yet.
+func (r *WorkspaceReconciler) reconcileRestoreFrom(ctx context.Context, workspace *environmentv1alpha1.Workspace) (bool, error) {
+	if workspace.Spec.RestoreFrom == "" || meta.IsStatusConditionTrue(workspace.Status.Conditions, ConditionRestored) {
+		return true, nil
+	}
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	snapshot := &environmentv1alpha1.WorkspaceSnapshot{}
+	if err := r.Get(ctx, types.NamespacedName{Name: workspace.Spec.RestoreFrom}, snapshot); apierrors.IsNotFound(err) {
+		return false, stall(fmt.Errorf("WorkspaceSnapshot %s of spec.restoreFrom not found", workspace.Spec.RestoreFrom))
+	} else if err != nil {
+		return false, err
+	}
+	switch snapshot.Status.Phase {
+	case environmentv1alpha1.SnapshotCompleted:
+	case environmentv1alpha1.SnapshotFailed:
+		return false, stall(fmt.Errorf("WorkspaceSnapshot %s of spec.restoreFrom failed: %s", snapshot.Name, snapshot.Status.Message))
+	default:
+		return false, nil
+	}
+
+	content, err := r.loadSnapshotContent(ctx, snapshot)
+	if err != nil {
+		return false, err
+	}
+	restored := 0
+	var skipped []string
+	for i := range content.Objects {
+		obj := content.Objects[i].DeepCopy()
+		obj.SetNamespace(workspace.Spec.Name)
+		if obj.GetKind() == "Secret" {
+			restoreSecretKeys(obj.Object, obj.GetAnnotations()[SnapshotSecretKeysAnnotation])
+		}
+		reconcilerLog.Info(fmt.Sprintf("Restoring %s %s from WorkspaceSnapshot %s", obj.GetKind(), obj.GetName(), snapshot.Name))
+		if err := r.Create(ctx, obj); apierrors.IsAlreadyExists(err) {
+			continue
+		} else if apierrors.IsInvalid(err) || apierrors.IsForbidden(err) {
+			skipped = append(skipped, fmt.Sprintf("%s/%s", obj.GetKind(), obj.GetName()))
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		restored++
+	}
+
+	message := fmt.Sprintf("Restored %d objects from WorkspaceSnapshot %s", restored, snapshot.Name)
+	if len(skipped) > 0 {
+		message += fmt.Sprintf(", skipped %s", strings.Join(skipped, ", "))
+	}
+	meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+		Type:               ConditionRestored,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             "SnapshotRestored",
+		Message:            message,
+	})
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return false, err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, corev1.EventTypeNormal, "SnapshotRestored", message)
+	}
+	return true, nil
+}
+
+// loadSnapshotContent reads the content of a completed snapshot from its ConfigMap or the object storage
+func (r *WorkspaceReconciler) loadSnapshotContent(ctx context.Context, snapshot *environmentv1alpha1.WorkspaceSnapshot) (*snapshotContent, error) {
+	if snapshot.Spec.Storage == environmentv1alpha1.SnapshotObjectStorage {
+		if r.SnapshotStore == nil {
+			return nil, stall(fmt.Errorf("WorkspaceSnapshot %s is stored in the object storage but none is configured", snapshot.Name))
+		}
+		data, err := r.SnapshotStore.Get(ctx, snapshot.Status.Location)
+		if err != nil {
+			return nil, err
+		}
+		return decodeSnapshotContent(data)
+	}
+	namespace, name, _ := strings.Cut(snapshot.Status.Location, "/")
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configMap); err != nil {
+		return nil, err
+	}
+	return decodeSnapshotContent(configMap.BinaryData[snapshotContentKey])
+}
+
+// restoreSecretKeys sets the captured keys of a Secret with empty values, to be filled in by the tenants or
+// their secret manager
+func restoreSecretKeys(secret map[string]interface{}, keys string) {
+	if keys == "" {
+		return
+	}
+	data := map[string]interface{}{}
+	for _, key := range strings.Split(keys, ",") {
+		data[key] = ""
+	}
+	secret["data"] = data
+}
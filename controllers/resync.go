@@ -0,0 +1,45 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"hash/fnv"
+	"time"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// DefaultResyncPeriod is the time after which a ready workspace is reconciled again when ResyncPeriod is not set.
+// The drift of the owned resources is corrected as soon as it is watched, the resync is a safety net
+// for the changes the watches miss, e.g. of the objects the operator does not own.
+const DefaultResyncPeriod = 10 * time.Minute
+
+// resyncAfter returns the time after which a ready workspace is reconciled again. The workspaces are spread
+// over the jitter window with an offset derived from their name, so that a fleet of workspaces does not
+// reconcile on the same beat and the load on the API server stays even.
+func (r *WorkspaceReconciler) resyncAfter(workspace *environmentv1alpha1.Workspace) time.Duration {
+	period := r.ResyncPeriod
+	if period <= 0 {
+		period = DefaultResyncPeriod
+	}
+	if r.ResyncJitter <= 0 {
+		return period
+	}
+	h := fnv.New64a()
+	h.Write([]byte(workspace.Name))
+	return period + time.Duration(h.Sum64()%uint64(r.ResyncJitter))
+}
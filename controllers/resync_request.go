@@ -0,0 +1,56 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// ResyncAnnotation requests a full reconciliation of the workspace when set to a new value, e.g. the current time.
+// The value is copied to status.lastResync once the reconciliation succeeded.
+const ResyncAnnotation = "environment.tf.operator.com/resync"
+
+// ActivityResynced is the action of the forced resyncs recorded in status.recentEvents
+const ActivityResynced = "Resynced"
+
+// resyncRequested reports whether the workspace carries a resync request that was not handled yet
+func resyncRequested(workspace *environmentv1alpha1.Workspace) bool {
+	value := workspace.Annotations[ResyncAnnotation]
+	return value != "" && value != workspace.Status.LastResync
+}
+
+// forceResync drops what the operator keeps between two reconciliations of the workspace, so that the
+// requested resync starts from the live state: its circuit is closed, all its resources are compared to their
+// desired state and the members of its teams are fetched again
+func (r *WorkspaceReconciler) forceResync(workspace *environmentv1alpha1.Workspace) {
+	r.breaker.forget(workspace.Name)
+	r.applied.forget(workspace.Name)
+	if r.TeamSync != nil {
+		for _, team := range workspace.Spec.Teams {
+			r.TeamSync.Expire(team, time.Now())
+		}
+	}
+}
+
+// completeResync records the resync request of the workspace as handled
+func (r *WorkspaceReconciler) completeResync(workspace *environmentv1alpha1.Workspace) {
+	workspace.Status.LastResync = workspace.Annotations[ResyncAnnotation]
+	r.recordActivity(workspace, ActivityResynced, fmt.Sprintf("Resynced all the resources as requested at %s", workspace.Status.LastResync))
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// AcknowledgeRBACChangesAnnotation acknowledges the pending changes of the Roles of a workspace
+// when it is set to their status.pendingChanges.hash
+const AcknowledgeRBACChangesAnnotation = "environment.tf.operator.com/acknowledge-rbac-changes"
+
+// Event reasons of the changes of the Roles of a workspace
+const (
+	rbacChangePending = "RBACChangePending"
+	rbacChangeApplied = "RBACChangeApplied"
+)
+
+// workspaceRoles are the Roles created in the namespace of every workspace
+var workspaceRoles = []string{"admin", "editor", "viewer"}
+
+// DefaultRoleAPIGroups are the API groups covered by the built-in rules of the Roles: the core group and the
+// groups of the Deployments, Jobs, Ingresses and HorizontalPodAutoscalers of the workspaces
+var DefaultRoleAPIGroups = []string{"", "apps", "batch", "networking.k8s.io", "autoscaling"}
+
+// restrictedRoleResources are the only resources the built-in rules of the Roles cover in some API groups,
+// e.g. the Ingresses of networking.k8s.io, so that the users can not rewrite the NetworkPolicies isolating the workspace
+var restrictedRoleResources = map[string][]string{
+	"networking.k8s.io": {"ingresses"},
+}
+
+// BuiltInRoleTemplates returns the built-in rules of the admin, editor and viewer Roles over the API groups.
+// They cover all the resources of the API groups, but the ones of restrictedRoleResources.
+func BuiltInRoleTemplates(apiGroups []string) *environmentv1alpha1.WorkspaceRoleTemplates {
+	rule := func(verbs ...string) []rbacv1.PolicyRule {
+		var groups []string
+		var restricted []rbacv1.PolicyRule
+		for _, group := range apiGroups {
+			if resources, ok := restrictedRoleResources[group]; ok {
+				restricted = append(restricted, rbacv1.PolicyRule{
+					Verbs:     verbs,
+					APIGroups: []string{group},
+					Resources: append([]string(nil), resources...),
+				})
+				continue
+			}
+			groups = append(groups, group)
+		}
+		var rules []rbacv1.PolicyRule
+		if len(groups) > 0 {
+			rules = append(rules, rbacv1.PolicyRule{
+				Verbs:     verbs,
+				APIGroups: groups,
+				Resources: []string{"*"},
+			})
+		}
+		return append(rules, restricted...)
+	}
+	return &environmentv1alpha1.WorkspaceRoleTemplates{
+		Admin:  rule("get", "list", "watch", "create", "update", "patch", "delete"),
+		Editor: rule("get", "list", "watch", "create", "update", "patch"),
+		Viewer: rule("get", "list", "watch"),
+	}
+}
+
+// DefaultRoleTemplates returns the built-in rules of the admin, editor and viewer Roles over DefaultRoleAPIGroups
+func DefaultRoleTemplates() *environmentv1alpha1.WorkspaceRoleTemplates {
+	return BuiltInRoleTemplates(DefaultRoleAPIGroups)
+}
+
+// LoadRoleTemplates reads the role templates of the operator from a YAML file with admin, editor and viewer
+// lists of rules. The roles missing from the file are left empty, so that they keep their built-in rules.
+func LoadRoleTemplates(path string) (*environmentv1alpha1.WorkspaceRoleTemplates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	templates := &environmentv1alpha1.WorkspaceRoleTemplates{}
+	if err := yaml.UnmarshalStrict(data, templates); err != nil {
+		return nil, fmt.Errorf("invalid role templates %s: %w", path, err)
+	}
+	return templates, nil
+}
+
+// mergeRoleTemplates returns the rules of base overridden by the roles of override with rules
+func mergeRoleTemplates(base, override *environmentv1alpha1.WorkspaceRoleTemplates) *environmentv1alpha1.WorkspaceRoleTemplates {
+	merged := base.DeepCopy()
+	if override == nil {
+		return merged
+	}
+	if len(override.Admin) > 0 {
+		merged.Admin = override.Admin
+	}
+	if len(override.Editor) > 0 {
+		merged.Editor = override.Editor
+	}
+	if len(override.Viewer) > 0 {
+		merged.Viewer = override.Viewer
+	}
+	return merged
+}
+
+// roleAPIGroups returns the API groups covered by the built-in rules of the Roles of the workspace,
+// spec.rbac.apiGroups or the ones of the operator. The API groups of spec.rbac.apiGroups the operator does not
+// allow are skipped, in case the webhook was bypassed.
+func (r *WorkspaceReconciler) roleAPIGroups(workspace *environmentv1alpha1.Workspace) []string {
+	if workspace.Spec.RBAC != nil && len(workspace.Spec.RBAC.APIGroups) > 0 {
+		allowed := allowedRoleAPIGroups(r.RoleAllowedAPIGroups, r.RoleAPIGroups)
+		var groups []string
+		for _, group := range workspace.Spec.RBAC.APIGroups {
+			if containsString(allowed, group) {
+				groups = append(groups, group)
+			}
+		}
+		return groups
+	}
+	if len(r.RoleAPIGroups) > 0 {
+		return r.RoleAPIGroups
+	}
+	return DefaultRoleAPIGroups
+}
+
+// allowedRoleAPIGroups returns the API groups spec.rbac.apiGroups can list, the allowed ones of the operator
+// or else the API groups of its built-in rules
+func allowedRoleAPIGroups(allowed, roleAPIGroups []string) []string {
+	if len(allowed) > 0 {
+		return allowed
+	}
+	if len(roleAPIGroups) > 0 {
+		return roleAPIGroups
+	}
+	return DefaultRoleAPIGroups
+}
+
+// roleTemplates returns the rules of the Roles of the workspace per role, the roles of its WorkspaceTemplate
+// take precedence over the templates of its class, which take precedence over the ones of the operator
+// and then over the built-in rules
+func (r *WorkspaceReconciler) roleTemplates(ctx context.Context, workspace *environmentv1alpha1.Workspace) (map[string][]rbacv1.PolicyRule, error) {
+	templates := mergeRoleTemplates(BuiltInRoleTemplates(r.roleAPIGroups(workspace)), r.RoleTemplates)
+	class, err := r.workspaceClass(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	if class != nil {
+		templates = mergeRoleTemplates(templates, class.Spec.Roles)
+	}
+	template, err := r.workspaceTemplate(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	if template != nil {
+		templates = mergeRoleTemplates(templates, template.Spec.Roles)
+	}
+	return map[string][]rbacv1.PolicyRule{
+		"admin":  templates.Admin,
+		"editor": templates.Editor,
+		"viewer": templates.Viewer,
+	}, nil
+}
+
+// missingRules returns the rules of rules that are not in other
+func missingRules(rules, other []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	var missing []rbacv1.PolicyRule
+	for _, rule := range rules {
+		found := false
+		for _, o := range other {
+			if equality.Semantic.DeepEqual(rule, o) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, rule)
+		}
+	}
+	return missing
+}
+
+// reconcileRoleRules brings the rules of the Roles of the workspace to their templates.
+// The changes are first published in status.pendingChanges with an event and a notification, and only applied
+// once RBACChangeDelay has passed and, when RBACChangeAcknowledgment is set, the owner acknowledged them.
+// They are applied right away when there is neither a delay nor an acknowledgment.
+func (r *WorkspaceReconciler) reconcileRoleRules(ctx context.Context, workspace *environmentv1alpha1.Workspace, roles map[string]*rbacv1.Role, templates map[string][]rbacv1.PolicyRule) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	var changes []environmentv1alpha1.WorkspaceRoleChange
+	for _, role := range workspaceRoles {
+		added := missingRules(templates[role], roles[role].Rules)
+		removed := missingRules(roles[role].Rules, templates[role])
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, environmentv1alpha1.WorkspaceRoleChange{Role: role, Added: added, Removed: removed})
+		}
+	}
+	if len(changes) == 0 {
+		// The Roles were brought back to their templates by someone else
+		if workspace.Status.PendingChanges == nil {
+			return nil
+		}
+		workspace.Status.PendingChanges = nil
+		return r.Status().Update(ctx, workspace)
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	pending := workspace.Status.PendingChanges
+	if pending == nil || pending.Hash != specHash(data) {
+		pending = &environmentv1alpha1.WorkspacePendingChanges{
+			Hash:                   specHash(data),
+			Since:                  metav1.NewTime(now),
+			ApplyAfter:             metav1.NewTime(now.Add(r.RBACChangeDelay)),
+			AcknowledgmentRequired: r.RBACChangeAcknowledgment,
+			Roles:                  changes,
+		}
+		if r.RBACChangeDelay > 0 || r.RBACChangeAcknowledgment {
+			reconcilerLog.Info(fmt.Sprintf("Publishing pending changes %s of the Roles of Workspace", pending.Hash))
+			workspace.Status.PendingChanges = pending
+			if err := r.Status().Update(ctx, workspace); err != nil {
+				return err
+			}
+			message := fmt.Sprintf("The rules of the %s Roles change on %s", changedRoles(changes), pending.ApplyAfter.UTC().Format(time.RFC3339))
+			if pending.AcknowledgmentRequired {
+				message = fmt.Sprintf("The rules of the %s Roles change once acknowledged with the %s=%s annotation, not before %s",
+					changedRoles(changes), AcknowledgeRBACChangesAnnotation, pending.Hash, pending.ApplyAfter.UTC().Format(time.RFC3339))
+			}
+			if r.Recorder != nil {
+				r.Recorder.Event(workspace, corev1.EventTypeWarning, rbacChangePending, message)
+			}
+			r.notify(ctx, workspace, rbacChangePending, message)
+		}
+	}
+	if now.Before(pending.ApplyAfter.Time) ||
+		(pending.AcknowledgmentRequired && workspace.Annotations[AcknowledgeRBACChangesAnnotation] != pending.Hash) {
+		return nil
+	}
+
+	for _, change := range changes {
+		role := roles[change.Role]
+		original := role.DeepCopy()
+		role.Rules = templates[change.Role]
+		reconcilerLog.Info(fmt.Sprintf("Updating the rules of Role.Name %s", role.Name))
+		if err := r.Patch(ctx, role, client.MergeFrom(original)); err != nil {
+			return err
+		}
+		r.audit(ctx, workspace, AuditActionRoleUpdated, "Role", role.Name, nil, role.Rules)
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, corev1.EventTypeNormal, rbacChangeApplied,
+			fmt.Sprintf("The rules of the %s Roles were updated to their templates", changedRoles(changes)))
+	}
+	if workspace.Status.PendingChanges == nil {
+		return nil
+	}
+	workspace.Status.PendingChanges = nil
+	return r.Status().Update(ctx, workspace)
+}
+
+// changedRoles lists the roles of the changes
+func changedRoles(changes []environmentv1alpha1.WorkspaceRoleChange) string {
+	roles := make([]string, 0, len(changes))
+	for _, change := range changes {
+		roles = append(roles, change.Role)
+	}
+	return strings.Join(roles, ", ")
+}
+
+// nextRoleChange returns the time the pending changes of the Roles of the workspace are due, zero when there are none
+func nextRoleChange(workspace *environmentv1alpha1.Workspace, now time.Time) time.Time {
+	pending := workspace.Status.PendingChanges
+	if pending == nil || !now.Before(pending.ApplyAfter.Time) {
+		return time.Time{}
+	}
+	return pending.ApplyAfter.Time
+}
+
+// validateRBAC checks that spec.rbac.apiGroups lists API groups allowed by the operator,
+// the wildcard being left to the role templates
+func validateRBAC(workspace *environmentv1alpha1.Workspace, allowed []string) error {
+	if workspace.Spec.RBAC == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, group := range workspace.Spec.RBAC.APIGroups {
+		switch {
+		case group == rbacv1.APIGroupAll:
+			return fmt.Errorf("spec.rbac.apiGroups: %q is not allowed, use the role templates of the operator instead", group)
+		case seen[group]:
+			return fmt.Errorf("spec.rbac.apiGroups: API group %q is listed twice", group)
+		case !containsString(allowed, group):
+			return fmt.Errorf("spec.rbac.apiGroups: API group %q is not allowed by the operator, allowed are %q", group, allowed)
+		}
+		seen[group] = true
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+func TestBuiltInRoleTemplates(t *testing.T) {
+	viewer := []string{"get", "list", "watch"}
+	tests := []struct {
+		name      string
+		apiGroups []string
+		want      []rbacv1.PolicyRule
+	}{
+		{
+			name:      "default API groups",
+			apiGroups: DefaultRoleAPIGroups,
+			want: []rbacv1.PolicyRule{
+				{Verbs: viewer, APIGroups: []string{"", "apps", "batch", "autoscaling"}, Resources: []string{"*"}},
+				{Verbs: viewer, APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingresses"}},
+			},
+		},
+		{
+			name:      "only restricted API groups",
+			apiGroups: []string{"networking.k8s.io"},
+			want: []rbacv1.PolicyRule{
+				{Verbs: viewer, APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingresses"}},
+			},
+		},
+		{
+			name: "no API group",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuiltInRoleTemplates(tt.apiGroups).Viewer; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuiltInRoleTemplates(%q).Viewer = %v, want %v", tt.apiGroups, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRBAC(t *testing.T) {
+	allowed := allowedRoleAPIGroups(nil, nil)
+	tests := []struct {
+		name      string
+		apiGroups []string
+		valid     bool
+	}{
+		{name: "allowed API groups", apiGroups: []string{"", "apps"}, valid: true},
+		{name: "wildcard", apiGroups: []string{"*"}},
+		{name: "API group listed twice", apiGroups: []string{"apps", "apps"}},
+		{name: "API group not allowed", apiGroups: []string{"kyverno.io"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := &environmentv1alpha1.Workspace{Spec: environmentv1alpha1.WorkspaceSpec{
+				RBAC: &environmentv1alpha1.WorkspaceRBAC{APIGroups: tt.apiGroups},
+			}}
+			if err := validateRBAC(workspace, allowed); (err == nil) != tt.valid {
+				t.Errorf("validateRBAC(%q) = %v, want valid %t", tt.apiGroups, err, tt.valid)
+			}
+		})
+	}
+}
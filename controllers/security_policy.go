@@ -0,0 +1,71 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// securityPolicyValidations returns the CEL validations of the pods of a workspace required by the security policy
+func securityPolicyValidations(policy *environmentv1alpha1.WorkspaceSecurityPolicy) []interface{} {
+	var validations []interface{}
+	if policy.RuntimeDefaultSeccomp {
+		validations = append(validations, celValidation(
+			"(has(object.spec.securityContext) && has(object.spec.securityContext.seccompProfile) && "+
+				"object.spec.securityContext.seccompProfile.type in ['RuntimeDefault', 'Localhost'] && "+
+				"variables.containers.all(c, !has(c.securityContext) || !has(c.securityContext.seccompProfile) || "+
+				"c.securityContext.seccompProfile.type in ['RuntimeDefault', 'Localhost'])) || "+
+				"variables.containers.all(c, has(c.securityContext) && has(c.securityContext.seccompProfile) && "+
+				"c.securityContext.seccompProfile.type in ['RuntimeDefault', 'Localhost'])",
+			"The seccomp profile of the pod or of all its containers must be RuntimeDefault or Localhost"))
+	}
+	if policy.RunAsNonRoot {
+		validations = append(validations, celValidation(
+			"(has(object.spec.securityContext) && has(object.spec.securityContext.runAsNonRoot) && "+
+				"object.spec.securityContext.runAsNonRoot && "+
+				"variables.containers.all(c, !has(c.securityContext) || !has(c.securityContext.runAsNonRoot) || c.securityContext.runAsNonRoot)) || "+
+				"variables.containers.all(c, has(c.securityContext) && has(c.securityContext.runAsNonRoot) && c.securityContext.runAsNonRoot)",
+			"The pod or all its containers must set runAsNonRoot to true"))
+	}
+	if policy.DropAllCapabilities {
+		validations = append(validations, celValidation(
+			"variables.containers.all(c, has(c.securityContext) && has(c.securityContext.capabilities) && "+
+				"has(c.securityContext.capabilities.drop) && 'ALL' in c.securityContext.capabilities.drop)",
+			"All the containers must drop ALL capabilities"))
+	}
+	return validations
+}
+
+// reconcileSecurityPolicy generates the security policy of the class of the workspace with the engine of the class
+func (r *WorkspaceReconciler) reconcileSecurityPolicy(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	class, err := r.workspaceClass(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	var policy *admissionPolicy
+	if class != nil && class.Spec.SecurityPolicy != nil {
+		policy = &admissionPolicy{
+			engine:      class.Spec.SecurityPolicy.Engine,
+			action:      class.Spec.SecurityPolicy.Action,
+			validations: securityPolicyValidations(class.Spec.SecurityPolicy),
+		}
+	}
+	return r.reconcileAdmissionPolicy(ctx, workspace, fmt.Sprintf("%s-security-context", workspace.Spec.Name), policy)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// CostProvider reports the spend of a namespace over a window such as 7d or 30d
+type CostProvider interface {
+	NamespaceCost(ctx context.Context, namespace, window string) (float64, error)
+}
+
+// NewOpenCostProvider returns a CostProvider which queries the allocation API of OpenCost or Kubecost.
+// endpoint is the base URL of the allocation API, e.g. http://opencost.opencost:9003 for OpenCost
+// or http://kubecost-cost-analyzer.kubecost:9090/model for Kubecost.
+func NewOpenCostProvider(endpoint string) CostProvider {
+	return &openCostProvider{endpoint: strings.TrimSuffix(endpoint, "/"), client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+type openCostProvider struct {
+	endpoint string
+	client   *http.Client
+}
+
+// allocationResponse is the subset of the allocation API response used by the operator
+type allocationResponse struct {
+	Code    int                                `json:"code"`
+	Message string                             `json:"message"`
+	Data    []map[string]allocationAggregation `json:"data"`
+}
+
+type allocationAggregation struct {
+	TotalCost float64 `json:"totalCost"`
+}
+
+func (p *openCostProvider) NamespaceCost(ctx context.Context, namespace, window string) (float64, error) {
+	query := url.Values{}
+	query.Set("window", window)
+	query.Set("aggregate", "namespace")
+	query.Set("accumulate", "true")
+	query.Set("filterNamespaces", namespace)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/allocation/compute?%s", p.endpoint, query.Encode()), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("allocation API returned %s", resp.Status)
+	}
+	allocations := allocationResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&allocations); err != nil {
+		return 0, err
+	}
+	if allocations.Code != 0 && allocations.Code != http.StatusOK {
+		return 0, fmt.Errorf("allocation API returned code %d: %s", allocations.Code, allocations.Message)
+	}
+	cost := 0.0
+	for _, set := range allocations.Data {
+		if allocation, ok := set[namespace]; ok {
+			cost += allocation.TotalCost
+		}
+	}
+	return cost, nil
+}
+
+// reconcileSpend refreshes status.spend of the workspace, exported by the WorkspaceCollector,
+// once the previous report is older than SpendRefreshInterval
+func (r *WorkspaceReconciler) reconcileSpend(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	if r.CostProvider == nil {
+		return nil
+	}
+	spend := workspace.Status.Spend
+	if spend != nil && time.Since(spend.LastUpdated.Time) < r.SpendRefreshInterval {
+		return nil
+	}
+
+	last7Days, err := r.CostProvider.NamespaceCost(ctx, workspace.Spec.Name, "7d")
+	if err != nil {
+		return err
+	}
+	last30Days, err := r.CostProvider.NamespaceCost(ctx, workspace.Spec.Name, "30d")
+	if err != nil {
+		return err
+	}
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Updating spend for Workspace %s", workspace.Name))
+	workspace.Status.Spend = &environmentv1alpha1.WorkspaceSpend{
+		Last7Days:   strconv.FormatFloat(last7Days, 'f', 2, 64),
+		Last30Days:  strconv.FormatFloat(last30Days, 'f', 2, 64),
+		LastUpdated: metav1.Now(),
+	}
+	return r.Status().Update(ctx, workspace)
+}
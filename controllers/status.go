@@ -0,0 +1,188 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// The Ready, Reconciling and Stalled conditions follow the kstatus conventions
+// (https://github.com/kubernetes-sigs/cli-utils/tree/master/pkg/kstatus) so that
+// tools such as Flux, ArgoCD and kubectl wait compute the health of a Workspace.
+// Reconciling and Stalled are "abnormal-true" conditions and are removed when they do not apply.
+const (
+	// ConditionReady is True once all the resources of the workspace are in the desired state
+	ConditionReady = "Ready"
+
+	// ConditionReconciling is True while the resources of the workspace are being created or updated
+	ConditionReconciling = "Reconciling"
+
+	// ConditionStalled is True when the reconciliation of the workspace failed and retrying does not fix it
+	ConditionStalled = "Stalled"
+)
+
+// stalledError is the error of a reconciliation that retrying does not fix, e.g. a workspace rejected by a policy
+// of the operator or failing the validation of its spec. It stays until the workspace or the cluster is changed.
+type stalledError struct {
+	err error
+}
+
+func (e *stalledError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stalledError) Unwrap() error {
+	return e.err
+}
+
+// stall marks err as an error that retrying does not fix
+func stall(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &stalledError{err: err}
+}
+
+// stalled reports whether the reconciliation failed with an error that retrying does not fix: the workspace
+// is rejected by a policy or the validation of the API server, or its circuit is open. The other errors,
+// such as conflicts and timeouts, are retried.
+func stalled(err error) bool {
+	var stalledErr *stalledError
+	var circuitOpen *circuitOpenError
+	return errors.As(err, &stalledErr) || errors.As(err, &circuitOpen) || apierrors.IsInvalid(err) || apierrors.IsBadRequest(err)
+}
+
+// reconcileStatus sets the kstatus conditions, the conditions of the resources and status.observedGeneration
+// of the workspace from the outcome of the reconciliation. The status is only written when it changed since previous.
+func (r *WorkspaceReconciler) reconcileStatus(ctx context.Context, workspace *environmentv1alpha1.Workspace, previous *environmentv1alpha1.WorkspaceStatus, ready bool, reconcileErr error) error {
+	if err := r.reconcileResourceStates(ctx, workspace); err != nil {
+		return err
+	}
+	switch {
+	case stalled(reconcileErr):
+		// The workspaces failing repeatedly are reported with the CircuitOpen reason while they are backed off
+		reason := "ReconcileFailed"
+		var circuitOpen *circuitOpenError
+		if errors.As(reconcileErr, &circuitOpen) {
+			reason = "CircuitOpen"
+		}
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionStalled,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: workspace.Generation,
+			Reason:             reason,
+			Message:            reconcileErr.Error(),
+		})
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: workspace.Generation,
+			Reason:             reason,
+			Message:            reconcileErr.Error(),
+		})
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionReconciling)
+	case reconcileErr != nil:
+		// The workspace is still being reconciled, the error is retried
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionReconciling,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "RetryingAfterError",
+			Message:            reconcileErr.Error(),
+		})
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "ReconcileFailed",
+			Message:            reconcileErr.Error(),
+		})
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionStalled)
+	case ready:
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "Reconciled",
+			Message:            "All the resources of the Workspace are in the desired state",
+		})
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionReconciling)
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionStalled)
+	default:
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionReconciling,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "Progressing",
+			Message:            "The resources of the Workspace are being created or updated",
+		})
+		meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+			Type:               ConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: workspace.Generation,
+			Reason:             "Progressing",
+			Message:            "The resources of the Workspace are being created or updated",
+		})
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionStalled)
+	}
+	workspace.Status.ObservedGeneration = workspace.Generation
+	phase := nextPhase(workspace.Status.Phase, ready, reconcileErr)
+	if phase == environmentv1alpha1.WorkspaceReady && workspace.Status.SuspendedAt != nil {
+		phase = environmentv1alpha1.WorkspaceSuspended
+	}
+	setPhase(workspace, phase)
+
+	if equality.Semantic.DeepEqual(previous, &workspace.Status) {
+		return nil
+	}
+	return r.Status().Update(ctx, workspace)
+}
+
+// nextPhase moves the lifecycle phase of the workspace forward from the outcome of the reconciliation.
+// Only the errors that retrying does not fix move the workspace to the Failed phase.
+func nextPhase(phase environmentv1alpha1.WorkspacePhase, ready bool, reconcileErr error) environmentv1alpha1.WorkspacePhase {
+	switch {
+	case stalled(reconcileErr):
+		return environmentv1alpha1.WorkspaceFailed
+	case ready && reconcileErr == nil:
+		return environmentv1alpha1.WorkspaceReady
+	case phase == "":
+		return environmentv1alpha1.WorkspacePending
+	case provisioned(phase):
+		return environmentv1alpha1.WorkspaceUpdating
+	default:
+		return environmentv1alpha1.WorkspaceProvisioning
+	}
+}
+
+// setPhase sets status.phase of the workspace, exported by the WorkspaceCollector
+func setPhase(workspace *environmentv1alpha1.Workspace, phase environmentv1alpha1.WorkspacePhase) {
+	workspace.Status.Phase = phase
+}
+
+// provisioned reports whether the resources of a workspace in the phase were all in the desired state once
+func provisioned(phase environmentv1alpha1.WorkspacePhase) bool {
+	return phase == environmentv1alpha1.WorkspaceReady || phase == environmentv1alpha1.WorkspaceUpdating || phase == environmentv1alpha1.WorkspaceSuspended
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+func TestNextPhase(t *testing.T) {
+	tests := []struct {
+		name         string
+		phase        environmentv1alpha1.WorkspacePhase
+		ready        bool
+		reconcileErr error
+		want         environmentv1alpha1.WorkspacePhase
+	}{
+		{name: "new workspace", want: environmentv1alpha1.WorkspacePending},
+		{name: "new workspace ready in one pass", ready: true, want: environmentv1alpha1.WorkspaceReady},
+		{name: "new workspace failing", reconcileErr: stall(errors.New("boom")), want: environmentv1alpha1.WorkspaceFailed},
+		{name: "new workspace retrying", reconcileErr: errors.New("boom"), want: environmentv1alpha1.WorkspacePending},
+		{name: "pending workspace provisioned", phase: environmentv1alpha1.WorkspacePending, want: environmentv1alpha1.WorkspaceProvisioning},
+		{name: "provisioning workspace ready", phase: environmentv1alpha1.WorkspaceProvisioning, ready: true, want: environmentv1alpha1.WorkspaceReady},
+		{name: "provisioning workspace not ready yet", phase: environmentv1alpha1.WorkspaceProvisioning, want: environmentv1alpha1.WorkspaceProvisioning},
+		{name: "ready workspace changed", phase: environmentv1alpha1.WorkspaceReady, want: environmentv1alpha1.WorkspaceUpdating},
+		{name: "ready workspace failing", phase: environmentv1alpha1.WorkspaceReady, reconcileErr: stall(errors.New("boom")), want: environmentv1alpha1.WorkspaceFailed},
+		{name: "ready workspace retrying", phase: environmentv1alpha1.WorkspaceReady, reconcileErr: errors.New("boom"), want: environmentv1alpha1.WorkspaceUpdating},
+		{name: "updating workspace not ready yet", phase: environmentv1alpha1.WorkspaceUpdating, want: environmentv1alpha1.WorkspaceUpdating},
+		{name: "updating workspace ready", phase: environmentv1alpha1.WorkspaceUpdating, ready: true, want: environmentv1alpha1.WorkspaceReady},
+		{name: "suspended workspace changed", phase: environmentv1alpha1.WorkspaceSuspended, want: environmentv1alpha1.WorkspaceUpdating},
+		{name: "failed workspace recovering", phase: environmentv1alpha1.WorkspaceFailed, want: environmentv1alpha1.WorkspaceProvisioning},
+		{name: "failed workspace recovered", phase: environmentv1alpha1.WorkspaceFailed, ready: true, want: environmentv1alpha1.WorkspaceReady},
+		{name: "failed workspace retrying", phase: environmentv1alpha1.WorkspaceFailed, reconcileErr: errors.New("boom"), want: environmentv1alpha1.WorkspaceProvisioning},
+		{name: "error takes precedence over readiness", phase: environmentv1alpha1.WorkspaceUpdating, ready: true, reconcileErr: stall(errors.New("boom")), want: environmentv1alpha1.WorkspaceFailed},
+		{name: "retried error takes precedence over readiness", phase: environmentv1alpha1.WorkspaceUpdating, ready: true, reconcileErr: errors.New("boom"), want: environmentv1alpha1.WorkspaceUpdating},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextPhase(tt.phase, tt.ready, tt.reconcileErr); got != tt.want {
+				t.Errorf("nextPhase(%q, %t, %v) = %q, want %q", tt.phase, tt.ready, tt.reconcileErr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStalled(t *testing.T) {
+	workspaces := schema.GroupResource{Group: environmentv1alpha1.GroupVersion.Group, Resource: "workspaces"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "no error"},
+		{name: "retried error", err: errors.New("boom")},
+		{name: "conflict", err: apierrors.NewConflict(workspaces, "team-a", errors.New("the object has been modified"))},
+		{name: "timeout", err: apierrors.NewTimeoutError("request timed out", 1)},
+		{name: "wrapped conflict", err: fmt.Errorf("failed to update: %w", apierrors.NewConflict(workspaces, "team-a", errors.New("the object has been modified")))},
+		{name: "policy", err: stall(errors.New("namespace kube-system is denied")), want: true},
+		{name: "wrapped policy", err: fmt.Errorf("failed to reconcile: %w", stall(errors.New("namespace kube-system is denied"))), want: true},
+		{name: "invalid", err: apierrors.NewInvalid(schema.GroupKind{Kind: "ResourceQuota"}, "team-a", field.ErrorList{field.Invalid(field.NewPath("spec"), "", "invalid")}), want: true},
+		{name: "bad request", err: apierrors.NewBadRequest("bad request"), want: true},
+		{name: "circuit open", err: &circuitOpenError{failures: 5, backoff: time.Minute, err: errors.New("boom")}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stalled(tt.err); got != tt.want {
+				t.Errorf("stalled(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// ConditionUnknownSubjects is True while some subjects of the workspace were never seen authenticating
+// by the identity provider, e.g. a typo in a username. It is an "abnormal-true" condition removed once they were.
+const ConditionUnknownSubjects = "UnknownSubjects"
+
+// unseenSubjectTTL is the time after which a subject never seen authenticating is looked up again
+const unseenSubjectTTL = 5 * time.Minute
+
+// SubjectVerifier looks the subjects of the workspaces up in the identity provider of the cluster
+type SubjectVerifier interface {
+	// Seen reports whether the User or Group subject is known to the identity provider,
+	// i.e. the user authenticated to the cluster at least once or the group has members
+	Seen(ctx context.Context, kind, name string) (bool, error)
+}
+
+// NewHTTPSubjectVerifier returns a SubjectVerifier which looks the subjects up through GET <endpoint>/users/<user>
+// and GET <endpoint>/groups/<group>, answering 200 for a known subject and 404 for an unknown one.
+// The known subjects are remembered, the unknown ones are looked up again after unseenSubjectTTL.
+func NewHTTPSubjectVerifier(endpoint string) SubjectVerifier {
+	return &httpSubjectVerifier{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: 5 * time.Second},
+		seen:     map[string]bool{},
+		unseen:   map[string]time.Time{},
+	}
+}
+
+type httpSubjectVerifier struct {
+	endpoint string
+	client   *http.Client
+
+	mu     sync.Mutex
+	seen   map[string]bool
+	unseen map[string]time.Time
+}
+
+func (v *httpSubjectVerifier) Seen(ctx context.Context, kind, name string) (bool, error) {
+	path := "users"
+	if kind == rbacv1.GroupKind {
+		path = "groups"
+	}
+	key := path + "/" + name
+	v.mu.Lock()
+	seen, checkedAt := v.seen[key], v.unseen[key]
+	v.mu.Unlock()
+	if seen {
+		return true, nil
+	}
+	if time.Since(checkedAt) < unseenSubjectTTL {
+		return false, nil
+	}
+
+	u := fmt.Sprintf("%s/%s/%s", v.endpoint, path, url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		v.mu.Lock()
+		v.seen[key] = true
+		delete(v.unseen, key)
+		v.mu.Unlock()
+		return true, nil
+	case http.StatusNotFound:
+		v.mu.Lock()
+		v.unseen[key] = time.Now()
+		v.mu.Unlock()
+		return false, nil
+	default:
+		return false, fmt.Errorf("subject verifier returned %s for %s", resp.Status, u)
+	}
+}
+
+// reconcileSubjects reports the users and groups of spec.users and spec.subjects never seen authenticating
+// in the UnknownSubjects condition, with a Warning event and a notification, so that a typo does not silently
+// produce a useless RoleBinding
+func (r *WorkspaceReconciler) reconcileSubjects(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	if r.SubjectVerifier == nil {
+		return nil
+	}
+	var unknown []string
+	for _, role := range workspaceRoles {
+		for _, user := range roleUsers(workspace, role) {
+			seen, err := r.SubjectVerifier.Seen(ctx, rbacv1.UserKind, user)
+			if err != nil {
+				return fmt.Errorf("failed to verify user %s: %w", user, err)
+			}
+			if !seen {
+				unknown = append(unknown, fmt.Sprintf("User %s (%s)", user, role))
+			}
+		}
+	}
+	for _, subject := range workspace.Spec.Subjects {
+		if subject.Kind != rbacv1.GroupKind {
+			continue
+		}
+		seen, err := r.SubjectVerifier.Seen(ctx, rbacv1.GroupKind, subject.Name)
+		if err != nil {
+			return fmt.Errorf("failed to verify group %s: %w", subject.Name, err)
+		}
+		if !seen {
+			unknown = append(unknown, fmt.Sprintf("Group %s (%s)", subject.Name, subject.Role))
+		}
+	}
+
+	previous := meta.FindStatusCondition(workspace.Status.Conditions, ConditionUnknownSubjects)
+	if len(unknown) == 0 {
+		if previous == nil {
+			return nil
+		}
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionUnknownSubjects)
+		return r.Status().Update(ctx, workspace)
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionUnknownSubjects,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             "SubjectNeverSeen",
+		Message:            fmt.Sprintf("Subjects never seen authenticating: %s", strings.Join(unknown, ", ")),
+	}
+	if previous != nil && previous.Message == condition.Message && previous.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	meta.SetStatusCondition(&workspace.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, workspace); err != nil {
+		return err
+	}
+	if previous == nil || previous.Message != condition.Message {
+		if r.Recorder != nil {
+			r.Recorder.Event(workspace, corev1.EventTypeWarning, condition.Reason, condition.Message)
+		}
+		r.notify(ctx, workspace, condition.Reason, condition.Message)
+	}
+	return nil
+}
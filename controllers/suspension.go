@@ -0,0 +1,94 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+// Actions of the suspensions recorded in status.recentEvents
+const (
+	ActivitySuspended = "Suspended"
+	ActivityResumed   = "Resumed"
+)
+
+// suspended reports whether the workspace is suspended, with spec.suspended, by a window of its hibernation schedule
+// or once it expired with the Suspend expiry action, at now
+func suspended(workspace *environmentv1alpha1.Workspace, now time.Time) bool {
+	return workspace.Spec.Suspended || hibernating(workspace, now) || expiredSuspended(workspace, now)
+}
+
+// setSuspendedQuota sets the pods hard limit of the ResourceQuota of a suspended workspace to 0,
+// so that no pod is started in its namespace until it is resumed
+func setSuspendedQuota(hard corev1.ResourceList, workspace *environmentv1alpha1.Workspace, now time.Time) {
+	if suspended(workspace, now) {
+		hard[corev1.ResourcePods] = *quotaResource.NewQuantity(0, quotaResource.DecimalSI)
+	}
+}
+
+// reconcileSuspension scales the workloads of a suspended workspace down with spec.suspension.scaleDown,
+// and back up once it is resumed. The time the workspace was suspended at is recorded in status.suspendedAt.
+// The workloads scaled back up out-of-band while the workspace is suspended are scaled down again.
+func (r *WorkspaceReconciler) reconcileSuspension(ctx context.Context, workspace *environmentv1alpha1.Workspace, now time.Time) error {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	if suspended(workspace, now) {
+		if workspace.Spec.Suspension != nil && workspace.Spec.Suspension.ScaleDown {
+			if err := r.scaleDownWorkloads(ctx, workspace); err != nil {
+				return err
+			}
+		}
+		if workspace.Status.SuspendedAt != nil {
+			return nil
+		}
+		suspendedAt := metav1.NewTime(now.UTC().Truncate(time.Second))
+		workspace.Status.SuspendedAt = &suspendedAt
+		message := fmt.Sprintf("Suspended Workspace, no pod is started in Namespace %s until it is resumed", workspace.Spec.Name)
+		if !workspace.Spec.Suspended && expiredSuspended(workspace, now) {
+			message = fmt.Sprintf("Suspended expired Workspace, no pod is started in Namespace %s until %s is moved later", workspace.Spec.Name, expiryField(workspace))
+		} else if !workspace.Spec.Suspended {
+			message = fmt.Sprintf("Hibernated Workspace, no pod is started in Namespace %s until its hibernation window stops", workspace.Spec.Name)
+		}
+		reconcilerLog.Info(message)
+		r.recordActivity(workspace, ActivitySuspended, message)
+		r.event(workspace, corev1.EventTypeNormal, EventSuspended, message)
+		return nil
+	}
+
+	if workspace.Status.SuspendedAt == nil {
+		return nil
+	}
+	if err := r.thawWorkspace(ctx, workspace); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Resumed Workspace suspended since %s", workspace.Status.SuspendedAt.UTC().Format(time.RFC3339))
+	workspace.Status.SuspendedAt = nil
+	reconcilerLog.Info(message)
+	r.recordActivity(workspace, ActivityResumed, message)
+	r.event(workspace, corev1.EventTypeNormal, EventResumed, message)
+	return nil
+}
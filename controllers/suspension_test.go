@@ -0,0 +1,59 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+func TestSuspended(t *testing.T) {
+	// 2023-06-07 is a Wednesday
+	now := time.Date(2023, time.June, 7, 20, 0, 0, 0, time.UTC)
+	nightly := &environmentv1alpha1.WorkspaceHibernation{
+		TimeZone: "UTC",
+		Schedule: []environmentv1alpha1.WorkspaceHibernationWindow{{Start: "0 20 * * *", Stop: "0 8 * * *"}},
+	}
+	expired := &metav1.Time{Time: now}
+
+	tests := []struct {
+		name string
+		spec environmentv1alpha1.WorkspaceSpec
+		now  time.Time
+		want bool
+	}{
+		{name: "running", now: now},
+		{name: "suspended", spec: environmentv1alpha1.WorkspaceSpec{Suspended: true}, now: now, want: true},
+		{name: "before a hibernation window", spec: environmentv1alpha1.WorkspaceSpec{Hibernation: nightly}, now: now.Add(-time.Second)},
+		{name: "at the start of a hibernation window", spec: environmentv1alpha1.WorkspaceSpec{Hibernation: nightly}, now: now, want: true},
+		{name: "expired with the Delete expiry action", spec: environmentv1alpha1.WorkspaceSpec{DeleteAt: expired, ExpiryAction: environmentv1alpha1.ExpiryActionDelete}, now: now},
+		{name: "before it expires with the Suspend expiry action", spec: environmentv1alpha1.WorkspaceSpec{DeleteAt: expired, ExpiryAction: environmentv1alpha1.ExpiryActionSuspend}, now: now.Add(-time.Second)},
+		{name: "expired with the Suspend expiry action", spec: environmentv1alpha1.WorkspaceSpec{DeleteAt: expired, ExpiryAction: environmentv1alpha1.ExpiryActionSuspend}, now: now, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := &environmentv1alpha1.Workspace{Spec: tt.spec}
+			if got := suspended(workspace, tt.now); got != tt.want {
+				t.Errorf("suspended(%s) = %t, want %t", tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+const (
+	// TeamSyncGitHubPath is the path the GitHub membership webhooks are served on
+	TeamSyncGitHubPath = "/team-sync/github"
+	// TeamSyncGitLabPath is the path the GitLab member webhooks are served on
+	TeamSyncGitLabPath = "/team-sync/gitlab"
+
+	// DefaultTeamSyncInterval is the time after which the members of a team are fetched again when Interval is not set
+	DefaultTeamSyncInterval = 15 * time.Minute
+
+	// teamPageSize is the number of members fetched per page from the APIs of the providers
+	teamPageSize = 100
+)
+
+// TeamProvider lists the members of the teams of a source control platform
+type TeamProvider interface {
+	// Members returns the usernames of the members of the team
+	Members(ctx context.Context, team string) ([]string, error)
+}
+
+// NewGitHubTeamProvider returns a TeamProvider reading the members of the <org>/<team-slug> teams
+// from the REST API of GitHub, e.g. https://api.github.com, with a token allowed to read the organization
+func NewGitHubTeamProvider(apiURL, token string) TeamProvider {
+	return &githubTeamProvider{apiURL: strings.TrimSuffix(apiURL, "/"), token: token, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type githubTeamProvider struct {
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+func (g *githubTeamProvider) Members(ctx context.Context, team string) ([]string, error) {
+	org, slug, ok := strings.Cut(team, "/")
+	if !ok || strings.Contains(slug, "/") {
+		return nil, fmt.Errorf("GitHub team %q is not of the form <org>/<team-slug>", team)
+	}
+	var members []string
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/orgs/%s/teams/%s/members?per_page=%d&page=%d", g.apiURL, url.PathEscape(org), url.PathEscape(slug), teamPageSize, page)
+		var result []struct {
+			Login string `json:"login"`
+		}
+		if err := getTeamPage(ctx, g.client, u, "Authorization", "Bearer "+g.token, &result); err != nil {
+			return nil, err
+		}
+		for _, member := range result {
+			members = append(members, member.Login)
+		}
+		if len(result) < teamPageSize {
+			break
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// NewGitLabTeamProvider returns a TeamProvider reading the active members of the groups, including the members
+// inherited from their parent groups, from the REST API of a GitLab instance, e.g. https://gitlab.com
+func NewGitLabTeamProvider(baseURL, token string) TeamProvider {
+	return &gitlabTeamProvider{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type gitlabTeamProvider struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func (g *gitlabTeamProvider) Members(ctx context.Context, group string) ([]string, error) {
+	var members []string
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/api/v4/groups/%s/members/all?per_page=%d&page=%d", g.baseURL, url.PathEscape(group), teamPageSize, page)
+		var result []struct {
+			Username string `json:"username"`
+			State    string `json:"state"`
+		}
+		if err := getTeamPage(ctx, g.client, u, "PRIVATE-TOKEN", g.token, &result); err != nil {
+			return nil, err
+		}
+		for _, member := range result {
+			if member.State == "" || member.State == "active" {
+				members = append(members, member.Username)
+			}
+		}
+		if len(result) < teamPageSize {
+			break
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// getTeamPage decodes the JSON page of members returned by GET u, authenticated with the header
+func getTeamPage(ctx context.Context, c *http.Client, u, header, value string, into interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(header, value)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("team provider returned %s for %s", resp.Status, u)
+	}
+	return json.NewDecoder(resp.Body).Decode(into)
+}
+
+// cachedTeam are the members of a team as last fetched from its provider
+type cachedTeam struct {
+	members   []string
+	fetchedAt time.Time
+}
+
+// TeamSync keeps the members of the teams of the workspaces. The members of a team are fetched from its provider
+// at most once per Interval, or as soon as a membership webhook of the provider reports a change of the team.
+type TeamSync struct {
+	client.Client
+
+	// Providers are the providers of the teams, a workspace with a team of another provider is not reconciled
+	Providers map[environmentv1alpha1.WorkspaceTeamProvider]TeamProvider
+
+	// Interval is the time after which the members of a team are fetched again, DefaultTeamSyncInterval when 0
+	Interval time.Duration
+
+	// UserPrefix is prepended to the usernames of the members, e.g. github: when the OIDC provider of the cluster
+	// prefixes the usernames of its users
+	UserPrefix string
+
+	// WebhookSecret is the secret the GitHub webhooks are signed with and the token of the GitLab webhooks.
+	// The webhooks are rejected when it is empty.
+	WebhookSecret string
+
+	mu     sync.Mutex
+	cache  map[string]*cachedTeam
+	events chan event.GenericEvent
+}
+
+// NewTeamSync returns a TeamSync fetching the members of the teams with the providers
+func NewTeamSync(c client.Client, providers map[environmentv1alpha1.WorkspaceTeamProvider]TeamProvider) *TeamSync {
+	return &TeamSync{
+		Client:    c,
+		Providers: providers,
+		cache:     map[string]*cachedTeam{},
+		events:    make(chan event.GenericEvent, 100),
+	}
+}
+
+// Events returns the channel of the workspaces to reconcile after a change of the members of one of their teams
+func (t *TeamSync) Events() <-chan event.GenericEvent {
+	return t.events
+}
+
+// teamKey returns the key of a team in the cache
+func teamKey(provider environmentv1alpha1.WorkspaceTeamProvider, team string) string {
+	return string(provider) + ":" + strings.ToLower(team)
+}
+
+// Members returns the usernames of the members of the team, prefixed with UserPrefix. The members last fetched
+// are returned while the provider is unreachable.
+func (t *TeamSync) Members(ctx context.Context, team environmentv1alpha1.WorkspaceTeam) ([]string, error) {
+	provider, ok := t.Providers[team.Provider]
+	if !ok {
+		return nil, fmt.Errorf("team sync with %s is not configured on the operator", team.Provider)
+	}
+	interval := t.Interval
+	if interval <= 0 {
+		interval = DefaultTeamSyncInterval
+	}
+	key := teamKey(team.Provider, team.Name)
+	t.mu.Lock()
+	cached, ok := t.cache[key]
+	t.mu.Unlock()
+	if !ok || time.Since(cached.fetchedAt) >= interval {
+		members, err := provider.Members(ctx, team.Name)
+		if err != nil {
+			if !ok {
+				return nil, fmt.Errorf("failed to fetch the members of %s team %s: %w", team.Provider, team.Name, err)
+			}
+			ctrl.Log.WithName("team-sync").Error(err, fmt.Sprintf("Failed to fetch the members of %s team %s, keeping the last members fetched", team.Provider, team.Name))
+		} else {
+			cached = &cachedTeam{members: members, fetchedAt: time.Now()}
+			t.mu.Lock()
+			t.cache[key] = cached
+			t.mu.Unlock()
+		}
+	}
+	users := make([]string, 0, len(cached.members))
+	for _, member := range cached.members {
+		users = append(users, t.UserPrefix+member)
+	}
+	return users, nil
+}
+
+// Expire drops the members of the team fetched before changedAt, e.g. after an identity event reported a change of the team
+func (t *TeamSync) Expire(team environmentv1alpha1.WorkspaceTeam, changedAt time.Time) {
+	key := teamKey(team.Provider, team.Name)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cached, ok := t.cache[key]; ok && cached.fetchedAt.Before(changedAt) {
+		delete(t.cache, key)
+	}
+}
+
+// changed drops the members of the team from the cache and reconciles the workspaces the team belongs to
+func (t *TeamSync) changed(ctx context.Context, provider environmentv1alpha1.WorkspaceTeamProvider, matches func(team string) bool) error {
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := t.List(ctx, workspaces); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	for key := range t.cache {
+		if team := strings.TrimPrefix(key, string(provider)+":"); team != key && matches(team) {
+			delete(t.cache, key)
+		}
+	}
+	t.mu.Unlock()
+	for i := range workspaces.Items {
+		for _, team := range workspaces.Items[i].Spec.Teams {
+			if team.Provider != provider || !matches(strings.ToLower(team.Name)) {
+				continue
+			}
+			select {
+			case t.events <- event.GenericEvent{Object: &workspaces.Items[i]}:
+			default:
+				// the workspace picks up the change on its next resync
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// GitHubWebhook returns the handler of the membership events of the GitHub organization webhooks,
+// signed with WebhookSecret
+func (t *TeamSync) GitHubWebhook() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, ok := t.readWebhook(w, req)
+		if !ok {
+			return
+		}
+		mac := hmac.New(sha256.New, []byte(t.WebhookSecret))
+		mac.Write(body)
+		signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if t.WebhookSecret == "" || !hmac.Equal([]byte(signature), []byte(req.Header.Get("X-Hub-Signature-256"))) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if req.Header.Get("X-GitHub-Event") != "membership" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var payload struct {
+			Team struct {
+				Slug string `json:"slug"`
+			} `json:"team"`
+			Organization struct {
+				Login string `json:"login"`
+			} `json:"organization"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		team := strings.ToLower(payload.Organization.Login + "/" + payload.Team.Slug)
+		t.respond(w, req, environmentv1alpha1.TeamProviderGitHub, func(name string) bool { return name == team })
+	})
+}
+
+// GitLabWebhook returns the handler of the member events of the GitLab group webhooks, whose secret token is WebhookSecret.
+// The subgroups of the group of an event are synced too, as they inherit its members.
+func (t *TeamSync) GitLabWebhook() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, ok := t.readWebhook(w, req)
+		if !ok {
+			return
+		}
+		if t.WebhookSecret == "" || subtle.ConstantTimeCompare([]byte(req.Header.Get("X-Gitlab-Token")), []byte(t.WebhookSecret)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		var payload struct {
+			EventName string `json:"event_name"`
+			GroupPath string `json:"group_path"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(payload.EventName, "user_") || payload.GroupPath == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		group := strings.ToLower(payload.GroupPath)
+		t.respond(w, req, environmentv1alpha1.TeamProviderGitLab, func(name string) bool {
+			return name == group || strings.HasPrefix(name, group+"/") || strings.Contains(name, "/"+group+"/") || strings.HasSuffix(name, "/"+group)
+		})
+	})
+}
+
+// readWebhook reads the body of a webhook request
+func (t *TeamSync) readWebhook(w http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+// respond syncs the teams matching a webhook event and writes the response of the webhook
+func (t *TeamSync) respond(w http.ResponseWriter, req *http.Request, provider environmentv1alpha1.WorkspaceTeamProvider, matches func(team string) bool) {
+	if err := t.changed(req.Context(), provider, matches); err != nil {
+		ctrl.Log.WithName("team-sync").Error(err, fmt.Sprintf("Failed to sync the %s teams of a webhook", provider))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// teamMembers returns the users bound to each role of the workspace through its teams
+func (r *WorkspaceReconciler) teamMembers(ctx context.Context, workspace *environmentv1alpha1.Workspace) (map[string][]string, error) {
+	if len(workspace.Spec.Teams) == 0 {
+		return nil, nil
+	}
+	if r.TeamSync == nil {
+		return nil, fmt.Errorf("team sync is not enabled on the operator")
+	}
+	members := map[string][]string{}
+	changedAt := identityChangedAt(workspace)
+	for _, team := range workspace.Spec.Teams {
+		r.TeamSync.Expire(team, changedAt)
+		users, err := r.TeamSync.Members(ctx, team)
+		if err != nil {
+			return nil, err
+		}
+		members[team.Role] = append(members[team.Role], users...)
+	}
+	return members, nil
+}
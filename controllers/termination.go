@@ -0,0 +1,98 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// ConditionTerminationBlocked is raised when the namespace of the workspace is stuck terminating,
+// usually because of the finalizers of resources of the tenant
+const ConditionTerminationBlocked = "TerminationBlocked"
+
+// DefaultTerminationTimeout is the time after which a terminating namespace is reported as blocked
+// when TerminationTimeout is not set
+const DefaultTerminationTimeout = 5 * time.Minute
+
+// namespaceBlockingConditions are the conditions the namespace controller reports on a namespace it can not empty
+var namespaceBlockingConditions = []corev1.NamespaceConditionType{
+	corev1.NamespaceFinalizersRemaining,
+	corev1.NamespaceContentRemaining,
+	corev1.NamespaceDeletionContentFailure,
+	corev1.NamespaceDeletionDiscoveryFailure,
+	corev1.NamespaceDeletionGVParsingFailure,
+}
+
+// namespaceTerminationBlocker returns why the termination of the namespace does not complete,
+// naming the remaining resources and finalizers reported by the namespace controller
+func namespaceTerminationBlocker(namespace *corev1.Namespace) string {
+	var reasons []string
+	for _, conditionType := range namespaceBlockingConditions {
+		for _, condition := range namespace.Status.Conditions {
+			if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+				reasons = append(reasons, condition.Message)
+			}
+		}
+	}
+	message := fmt.Sprintf("Namespace %s is terminating since %s", namespace.Name, namespace.DeletionTimestamp.UTC().Format(time.RFC3339))
+	if len(reasons) > 0 {
+		message += ": " + strings.Join(reasons, ", ")
+	}
+	return message
+}
+
+// checkNamespaceTermination sets the TerminationBlocked condition of the workspace when its namespace is terminating
+// for longer than the termination timeout and removes it otherwise. A Warning event is emitted when the condition
+// is raised or its message changes. It reports whether the condition changed, the condition is not written.
+func (r *WorkspaceReconciler) checkNamespaceTermination(workspace *environmentv1alpha1.Workspace, namespace *corev1.Namespace) bool {
+	timeout := r.TerminationTimeout
+	if timeout <= 0 {
+		timeout = DefaultTerminationTimeout
+	}
+	if namespace == nil || namespace.DeletionTimestamp.IsZero() || time.Since(namespace.DeletionTimestamp.Time) < timeout {
+		if meta.FindStatusCondition(workspace.Status.Conditions, ConditionTerminationBlocked) == nil {
+			return false
+		}
+		meta.RemoveStatusCondition(&workspace.Status.Conditions, ConditionTerminationBlocked)
+		return true
+	}
+
+	message := namespaceTerminationBlocker(namespace)
+	previous := meta.FindStatusCondition(workspace.Status.Conditions, ConditionTerminationBlocked)
+	if previous != nil && previous.Status == metav1.ConditionTrue && previous.Message == message {
+		return false
+	}
+	meta.SetStatusCondition(&workspace.Status.Conditions, metav1.Condition{
+		Type:               ConditionTerminationBlocked,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: workspace.Generation,
+		Reason:             "NamespaceStuckTerminating",
+		Message:            message,
+	})
+	if r.Recorder != nil {
+		r.Recorder.Event(workspace, "Warning", "TerminationBlocked", message)
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// TokenAutomountAnnotation marks the default ServiceAccounts whose token automount was disabled by the operator,
+// so that it is only restored on the ones the operator changed
+const TokenAutomountAnnotation = "environment.tf.operator.com/token-automount"
+
+// tokenAutomountValidation is the CEL validation requiring the pods using another ServiceAccount than default
+// to set automountServiceAccountToken, so that the token of a ServiceAccount is only mounted on purpose
+var tokenAutomountValidation = celValidation(
+	"has(object.spec.automountServiceAccountToken) || !has(object.spec.serviceAccountName) || object.spec.serviceAccountName == 'default'",
+	"Pods using a ServiceAccount other than default must set automountServiceAccountToken, true to mount its token")
+
+// reconcileTokenAutomount disables the token automount of the default ServiceAccount of the workspace namespace
+// and generates the admission policy requiring the other ServiceAccounts to opt in to their token when
+// spec.disableTokenAutomount is set. The policy uses the engine of the security policy of the workspace class,
+// or a ValidatingAdmissionPolicy when the class has none.
+func (r *WorkspaceReconciler) reconcileTokenAutomount(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	// The default ServiceAccount is created by Kubernetes shortly after the namespace, it is handled on the next reconciliation
+	serviceAccount := &corev1.ServiceAccount{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: "default"}, serviceAccount)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		original := serviceAccount.DeepCopy()
+		if workspace.Spec.DisableTokenAutomount {
+			automount := false
+			serviceAccount.AutomountServiceAccountToken = &automount
+			setAnnotation(&serviceAccount.ObjectMeta, TokenAutomountAnnotation, "disabled")
+		} else if _, ok := serviceAccount.Annotations[TokenAutomountAnnotation]; ok {
+			serviceAccount.AutomountServiceAccountToken = nil
+			delete(serviceAccount.Annotations, TokenAutomountAnnotation)
+		}
+		if _, err := r.patchIfChanged(ctx, workspace, "ServiceAccount", original, serviceAccount); err != nil {
+			return err
+		}
+	}
+
+	var policy *admissionPolicy
+	if workspace.Spec.DisableTokenAutomount {
+		class, err := r.workspaceClass(ctx, workspace)
+		if err != nil {
+			return err
+		}
+		policy = &admissionPolicy{
+			engine:      "vap",
+			action:      "Enforce",
+			validations: []interface{}{tokenAutomountValidation},
+		}
+		if class != nil && class.Spec.SecurityPolicy != nil {
+			policy.engine = class.Spec.SecurityPolicy.Engine
+		}
+	}
+	return r.reconcileAdmissionPolicy(ctx, workspace, fmt.Sprintf("%s-token-automount", workspace.Spec.Name), policy)
+}
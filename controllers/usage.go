@@ -0,0 +1,118 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+const (
+	// usageHistoryLength is the number of usage snapshots kept in status.usage, two weeks of daily snapshots
+	usageHistoryLength = 14
+
+	// usageTrendPeriod is the age of the snapshot the most recent one is compared to
+	usageTrendPeriod = 7 * 24 * time.Hour
+)
+
+// usageSnapshot returns the snapshot of the usage reported in the status of the workspace ResourceQuota
+func usageSnapshot(resourceQuota *corev1.ResourceQuota, now time.Time) environmentv1alpha1.WorkspaceUsageSnapshot {
+	quantity := func(name corev1.ResourceName) string {
+		if used, ok := resourceQuota.Status.Used[name]; ok {
+			return used.String()
+		}
+		return ""
+	}
+	return environmentv1alpha1.WorkspaceUsageSnapshot{
+		Time:   metav1.NewTime(now),
+		CPU:    quantity(corev1.ResourceCPU),
+		Memory: quantity(corev1.ResourceMemory),
+		Disk:   quantity(corev1.ResourceRequestsStorage),
+	}
+}
+
+// usageChange returns the change in percent from the previous to the current usage,
+// or nil when nothing was used before or either usage can not be parsed
+func usageChange(previous, current string) *int32 {
+	before, err := quotaResource.ParseQuantity(previous)
+	if err != nil || before.IsZero() {
+		return nil
+	}
+	after, err := quotaResource.ParseQuantity(current)
+	if err != nil {
+		return nil
+	}
+	change := int32(math.Round((after.AsApproximateFloat64() - before.AsApproximateFloat64()) * 100 / before.AsApproximateFloat64()))
+	return &change
+}
+
+// usageTrend returns the change of the usage from the snapshot taken a week before the most recent one,
+// or nil when the snapshots do not cover a week
+func usageTrend(snapshots []environmentv1alpha1.WorkspaceUsageSnapshot) *environmentv1alpha1.WorkspaceUsageTrend {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	latest := snapshots[0]
+	for _, snapshot := range snapshots[1:] {
+		if latest.Time.Sub(snapshot.Time.Time) < usageTrendPeriod {
+			continue
+		}
+		return &environmentv1alpha1.WorkspaceUsageTrend{
+			Since:  snapshot.Time,
+			CPU:    usageChange(snapshot.CPU, latest.CPU),
+			Memory: usageChange(snapshot.Memory, latest.Memory),
+			Disk:   usageChange(snapshot.Disk, latest.Disk),
+		}
+	}
+	return nil
+}
+
+// reconcileUsage records a snapshot of the usage of the workspace ResourceQuota in status.usage once the
+// previous one is older than UsageSnapshotInterval, and updates the week-over-week trend of the usage
+func (r *WorkspaceReconciler) reconcileUsage(ctx context.Context, workspace *environmentv1alpha1.Workspace, resourceQuota *corev1.ResourceQuota) error {
+	if r.UsageSnapshotInterval <= 0 {
+		return nil
+	}
+	usage := workspace.Status.Usage
+	if usage != nil && len(usage.Snapshots) > 0 && time.Since(usage.Snapshots[0].Time.Time) < r.UsageSnapshotInterval {
+		return nil
+	}
+
+	snapshots := []environmentv1alpha1.WorkspaceUsageSnapshot{usageSnapshot(resourceQuota, time.Now())}
+	if usage != nil {
+		snapshots = append(snapshots, usage.Snapshots...)
+	}
+	if len(snapshots) > usageHistoryLength {
+		snapshots = snapshots[:usageHistoryLength]
+	}
+	ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Recording usage snapshot for Workspace %s", workspace.Name))
+	workspace.Status.Usage = &environmentv1alpha1.WorkspaceUsage{
+		Snapshots:    snapshots,
+		WeekOverWeek: usageTrend(snapshots),
+	}
+	return r.Status().Update(ctx, workspace)
+}
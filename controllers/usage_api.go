@@ -0,0 +1,318 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	usagev1alpha1 "github.com/dunefro/workspace-operator/api/usage/v1alpha1"
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+const (
+	// UsageAPIPath is the path the usage API is served on, the API aggregation layer proxies
+	// the requests to the usage.environment.tf.operator.com group to the webhook server
+	UsageAPIPath = "/apis/usage.environment.tf.operator.com/v1alpha1"
+
+	// UsageAPIService is the name of the APIService of the usage API, see config/usageapi
+	UsageAPIService = "v1alpha1.usage.environment.tf.operator.com"
+
+	// requestHeaderConfigMap holds the CA and the names of the client certificates of the aggregation layer
+	requestHeaderConfigMap = "extension-apiserver-authentication"
+
+	// remoteUserHeader, remoteGroupHeader and remoteExtraHeaderPrefix carry the user authenticated by the aggregation layer
+	remoteUserHeader        = "X-Remote-User"
+	remoteGroupHeader       = "X-Remote-Group"
+	remoteExtraHeaderPrefix = "X-Remote-Extra-"
+)
+
+// usageGroupResource is the resource the usage subresource belongs to, used in the API errors
+var usageGroupResource = schema.GroupResource{Group: usagev1alpha1.GroupVersion.Group, Resource: "workspaces"}
+
+// UsageAPI serves the live quota consumption, pod counts and cost estimate of the Workspaces at
+// workspaces/<name>/usage of the usage API group, so that portals and CLIs do not need access to the namespaces.
+// The requests are only accepted from the aggregation layer, and authorized for the user it authenticated.
+type UsageAPI struct {
+	// Client reads the Workspaces and ResourceQuotas from the cache and creates the SubjectAccessReviews
+	Client client.Client
+
+	// Reader lists the pods of a namespace from the API server, so that the pods of the cluster are not cached
+	Reader client.Reader
+
+	// Pricing estimates the monthly cost of the requests of the workspaces. The estimate is omitted when it is nil.
+	Pricing *BudgetPricing
+
+	// Filter scopes the API to the workspaces of the operator instance
+	Filter *WorkspaceFilter
+
+	// ClientCAs verify the client certificates of the aggregation layer
+	ClientCAs *x509.CertPool
+
+	// AllowedNames are the common names of the client certificates of the aggregation layer, any when empty
+	AllowedNames []string
+}
+
+// NewUsageAPI returns a UsageAPI trusting the aggregation layer configured in the
+// extension-apiserver-authentication ConfigMap of kube-system
+func NewUsageAPI(ctx context.Context, c client.Client, reader client.Reader) (*UsageAPI, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceSystem, Name: requestHeaderConfigMap}, configMap); err != nil {
+		return nil, err
+	}
+	api := &UsageAPI{Client: c, Reader: reader, ClientCAs: x509.NewCertPool()}
+	if !api.ClientCAs.AppendCertsFromPEM([]byte(configMap.Data["requestheader-client-ca-file"])) {
+		return nil, fmt.Errorf("no requestheader-client-ca-file in ConfigMap %s/%s, is the aggregation layer enabled?",
+			metav1.NamespaceSystem, requestHeaderConfigMap)
+	}
+	if names := configMap.Data["requestheader-allowed-names"]; names != "" {
+		if err := json.Unmarshal([]byte(names), &api.AllowedNames); err != nil {
+			return nil, fmt.Errorf("invalid requestheader-allowed-names: %w", err)
+		}
+	}
+	return api, nil
+}
+
+// TLSOption makes the webhook server verify the client certificates of the aggregation layer.
+// The certificates stay optional, as the API server does not present one when calling the admission webhooks.
+func (a *UsageAPI) TLSOption(config *tls.Config) {
+	config.ClientCAs = a.ClientCAs
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+}
+
+// ServeHTTP serves the discovery of the usage API and the usage of the Workspaces
+func (a *UsageAPI) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		a.writeError(w, apierrors.NewMethodNotSupported(usageGroupResource, req.Method))
+		return
+	}
+	user, groups, ok := a.authenticate(req)
+	if !ok {
+		a.writeError(w, apierrors.NewUnauthorized("requests are only accepted from the aggregation layer"))
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, UsageAPIPath), "/")
+	if path == "" {
+		a.writeJSON(w, http.StatusOK, &metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: usagev1alpha1.GroupVersion.String(),
+			APIResources: []metav1.APIResource{{Name: "workspaces/usage", Kind: "WorkspaceUsage", Verbs: []string{"get"}}},
+		})
+		return
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 || parts[0] != "workspaces" || parts[2] != "usage" {
+		a.writeError(w, apierrors.NewNotFound(schema.GroupResource{Group: usagev1alpha1.GroupVersion.Group, Resource: parts[0]}, ""))
+		return
+	}
+	name := parts[1]
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user,
+			Groups: groups,
+			Extra:  remoteExtra(req.Header),
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:        "get",
+				Group:       usagev1alpha1.GroupVersion.Group,
+				Resource:    "workspaces",
+				Subresource: "usage",
+				Name:        name,
+			},
+		},
+	}
+	if err := a.Client.Create(req.Context(), review); err != nil {
+		a.writeError(w, err)
+		return
+	}
+	if !review.Status.Allowed {
+		a.writeError(w, apierrors.NewForbidden(usageGroupResource, name, fmt.Errorf("user %q can not get the usage of the workspace", user)))
+		return
+	}
+
+	usage, err := a.usage(req.Context(), name)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+	a.writeJSON(w, http.StatusOK, usage)
+}
+
+// authenticate returns the user and groups authenticated by the aggregation layer,
+// whose client certificate must be signed by ClientCAs and carry one of AllowedNames
+func (a *UsageAPI) authenticate(req *http.Request) (string, []string, bool) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return "", nil, false
+	}
+	if len(a.AllowedNames) > 0 {
+		allowed := false
+		commonName := req.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, name := range a.AllowedNames {
+			allowed = allowed || name == commonName
+		}
+		if !allowed {
+			return "", nil, false
+		}
+	}
+	user := req.Header.Get(remoteUserHeader)
+	return user, req.Header.Values(remoteGroupHeader), user != ""
+}
+
+// remoteExtra returns the extra attributes of the user authenticated by the aggregation layer
+func remoteExtra(header http.Header) map[string]authorizationv1.ExtraValue {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for name, values := range header {
+		if !strings.HasPrefix(name, remoteExtraHeaderPrefix) {
+			continue
+		}
+		key, err := url.PathUnescape(strings.ToLower(strings.TrimPrefix(name, remoteExtraHeaderPrefix)))
+		if err != nil {
+			continue
+		}
+		extra[key] = append(extra[key], values...)
+	}
+	return extra
+}
+
+// usage returns the live usage of the workspace
+func (a *UsageAPI) usage(ctx context.Context, name string) (*usagev1alpha1.WorkspaceUsage, error) {
+	workspace := &environmentv1alpha1.Workspace{}
+	if err := a.Client.Get(ctx, types.NamespacedName{Name: name}, workspace); apierrors.IsNotFound(err) || (err == nil && !a.Filter.Matches(workspace)) {
+		return nil, apierrors.NewNotFound(usageGroupResource, name)
+	} else if err != nil {
+		return nil, err
+	}
+	namespace := workspace.Spec.Name
+
+	resourceQuotas := &corev1.ResourceQuotaList{}
+	if err := a.Client.List(ctx, resourceQuotas, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	pods := &corev1.PodList{}
+	if err := a.Reader.List(ctx, pods, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	usage := &usagev1alpha1.WorkspaceUsage{
+		TypeMeta:   metav1.TypeMeta{Kind: "WorkspaceUsage", APIVersion: usagev1alpha1.GroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: workspace.Name, UID: workspace.UID},
+		Namespace:  namespace,
+		Quotas:     []usagev1alpha1.QuotaUsage{},
+	}
+	var requests corev1.ResourceList
+	for _, resourceQuota := range resourceQuotas.Items {
+		quota := usagev1alpha1.QuotaUsage{
+			Name:        resourceQuota.Name,
+			Hard:        resourceQuota.Status.Hard,
+			Used:        resourceQuota.Status.Used,
+			UsedPercent: map[corev1.ResourceName]int32{},
+		}
+		for resourceName, hard := range resourceQuota.Status.Hard {
+			used, ok := resourceQuota.Status.Used[resourceName]
+			if !ok || hard.IsZero() {
+				continue
+			}
+			quota.UsedPercent[resourceName] = int32(math.Round(used.AsApproximateFloat64() * 100 / hard.AsApproximateFloat64()))
+		}
+		usage.Quotas = append(usage.Quotas, quota)
+		if resourceQuota.Name == fmt.Sprintf("%s-quota", namespace) {
+			requests = quotaRequests(resourceQuota.Status.Used)
+		}
+	}
+
+	for _, pod := range pods.Items {
+		usage.Pods.Total++
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			usage.Pods.Running++
+		case corev1.PodPending:
+			usage.Pods.Pending++
+		case corev1.PodSucceeded:
+			usage.Pods.Succeeded++
+		case corev1.PodFailed:
+			usage.Pods.Failed++
+		}
+	}
+
+	if spend := workspace.Status.Spend; spend != nil {
+		lastUpdated := spend.LastUpdated
+		usage.Cost = &usagev1alpha1.CostEstimate{Last7Days: spend.Last7Days, Last30Days: spend.Last30Days, LastUpdated: &lastUpdated}
+	}
+	if a.Pricing != nil && requests != nil {
+		if usage.Cost == nil {
+			usage.Cost = &usagev1alpha1.CostEstimate{}
+		}
+		usage.Cost.MonthlyEstimate = strconv.FormatFloat(a.Pricing.MonthlyCost(requests), 'f', 2, 64)
+	}
+	return usage, nil
+}
+
+// quotaRequests returns the cpu, memory and storage requested in a namespace from the usage of its ResourceQuota
+func quotaRequests(used corev1.ResourceList) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, resourceName := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if quantity, ok := used[corev1.ResourceName("requests."+string(resourceName))]; ok {
+			requests[resourceName] = quantity
+		} else if quantity, ok := used[resourceName]; ok {
+			requests[resourceName] = quantity
+		}
+	}
+	if quantity, ok := used[corev1.ResourceRequestsStorage]; ok {
+		requests[corev1.ResourceStorage] = quantity
+	}
+	return requests
+}
+
+// writeJSON writes a response of the usage API
+func (a *UsageAPI) writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		ctrl.Log.WithName("usage-api").Error(err, "Failed to write response")
+	}
+}
+
+// writeError writes an error of the usage API as a Status, the way the API server does
+func (a *UsageAPI) writeError(w http.ResponseWriter, err error) {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		status = apierrors.NewInternalError(err)
+	}
+	body := status.Status()
+	body.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	a.writeJSON(w, int(body.Code), &body)
+}
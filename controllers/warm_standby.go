@@ -0,0 +1,89 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// warmedObjects are the objects the reconcilers read from the cache on every reconciliation.
+// The optional APIs such as Kyverno or the Prometheus operator are left out as they may not be installed.
+var warmedObjects = []client.Object{
+	&environmentv1alpha1.Workspace{},
+	&environmentv1alpha1.WorkspaceClass{},
+	&corev1.Namespace{},
+	&corev1.ResourceQuota{},
+	&corev1.LimitRange{},
+	&rbacv1.Role{},
+	&rbacv1.RoleBinding{},
+	&networkingv1.NetworkPolicy{},
+	&policyv1.PodDisruptionBudget{},
+}
+
+// CacheWarmer starts the informers of the objects the reconcilers read on every replica, including the ones
+// waiting for the leadership. The controllers only start their informers once elected, which takes minutes
+// on large fleets, so a standby replica pre-warming them reconciles right after a failover.
+type CacheWarmer struct {
+	// Cache is the cache of the manager the informers are started in
+	Cache cache.Cache
+
+	warmed atomic.Bool
+}
+
+// Start starts the informers and waits for them to sync
+func (w *CacheWarmer) Start(ctx context.Context) error {
+	start := time.Now()
+	for _, obj := range warmedObjects {
+		if _, err := w.Cache.GetInformer(ctx, obj); err != nil {
+			return fmt.Errorf("failed to start informer of %T: %w", obj, err)
+		}
+	}
+	if !w.Cache.WaitForCacheSync(ctx) {
+		return ctx.Err()
+	}
+	w.warmed.Store(true)
+	ctrl.Log.WithName("warm-standby").Info(fmt.Sprintf("Warmed the caches of %d kinds in %s", len(warmedObjects), time.Since(start).Round(time.Millisecond)))
+	return nil
+}
+
+// NeedLeaderElection makes the informers start on every replica
+func (w *CacheWarmer) NeedLeaderElection() bool {
+	return false
+}
+
+// Checker is a readiness check failing until the caches are warm, so that a rolling update
+// only retires the previous replica once the new one can take over
+func (w *CacheWarmer) Checker(_ *http.Request) error {
+	if !w.warmed.Load() {
+		return fmt.Errorf("caches are not synced yet")
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;update
+//+kubebuilder:rbac:groups=apiregistration.k8s.io,resources=apiservices,verbs=get;update
+
+const (
+	// WebhookCertModeCertManager leaves the serving certificate of the webhook server and the injection
+	// of its CA to cert-manager, see config/certmanager
+	WebhookCertModeCertManager = "cert-manager"
+	// WebhookCertModeSelfManaged makes the operator generate and rotate the serving certificate itself
+	WebhookCertModeSelfManaged = "self-managed"
+
+	// DefaultWebhookCertValidity is the validity of the serving certificates generated by the CertRotator
+	DefaultWebhookCertValidity = 90 * 24 * time.Hour
+
+	// webhookCAValidity is the validity of the CA signing the serving certificates
+	webhookCAValidity = 10 * 365 * 24 * time.Hour
+
+	// webhookCertCheckInterval is the time between two checks of the expiry of the certificates
+	webhookCertCheckInterval = time.Hour
+
+	// caCertKey and caKeyKey hold the CA in the Secret of the certificates, next to the tls.crt and tls.key of the serving certificate.
+	// caCertKey holds the bundle of the current CA followed by the previous one until it expires.
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+)
+
+// apiServiceGVK is the group version kind of the APIServices, read unstructured to avoid depending on the aggregator types
+var apiServiceGVK = schema.GroupVersionKind{Group: "apiregistration.k8s.io", Version: "v1", Kind: "APIService"}
+
+// CertRotator generates the serving certificate of the webhook server and its CA in a Secret, renews them once
+// two thirds of their validity are over, and writes the CA bundle into the webhook configurations and APIServices
+// served by the webhook server. It runs on every replica, as each of them serves the certificate from its CertDir.
+type CertRotator struct {
+	// Client reads and writes the Secret, the webhook configurations and the APIServices without a cache
+	Client client.Client
+
+	// Secret is the Secret holding the certificates, shared by the replicas
+	Secret types.NamespacedName
+
+	// Service is the Service of the webhook server, its DNS names are the subject of the serving certificate
+	Service types.NamespacedName
+
+	// CertDir is the directory the webhook server reads tls.crt and tls.key from
+	CertDir string
+
+	// WebhookConfigurations are the names of the ValidatingWebhookConfigurations calling the webhook server
+	WebhookConfigurations []string
+
+	// APIServices are the names of the APIServices served by the webhook server, missing ones are skipped
+	APIServices []string
+
+	// Validity is the validity of the serving certificates, DefaultWebhookCertValidity when 0
+	Validity time.Duration
+}
+
+// Start renews the certificates when they are about to expire until the context is done
+func (r *CertRotator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(webhookCertCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.Ensure(ctx); err != nil {
+				ctrl.Log.WithName("cert-rotator").Error(err, "Failed to rotate the webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection makes every replica refresh the certificate files it serves
+func (r *CertRotator) NeedLeaderElection() bool {
+	return false
+}
+
+// Ensure renews the certificates of the Secret when needed, writes the serving certificate to CertDir and the CA bundle
+// to the webhook configurations. It must succeed once before the webhook server starts, which reads CertDir on start.
+func (r *CertRotator) Ensure(ctx context.Context) error {
+	var secret *corev1.Secret
+	// The replicas starting together race to create the Secret, the losers read the one of the winner
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		var err error
+		secret, err = r.rotate(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.writeCertDir(secret); err != nil {
+		return err
+	}
+	return r.injectCABundle(ctx, secret.Data[caCertKey])
+}
+
+// rotate returns the Secret of the certificates, after renewing the CA or the serving certificate when needed
+func (r *CertRotator) rotate(ctx context.Context) (*corev1.Secret, error) {
+	reconcilerLog := ctrl.Log.WithName("cert-rotator")
+	validity := r.Validity
+	if validity <= 0 {
+		validity = DefaultWebhookCertValidity
+	}
+	now := time.Now()
+
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, r.Secret, secret)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: r.Secret.Name, Namespace: r.Secret.Namespace},
+			Type:       corev1.SecretTypeTLS,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{}
+	for k, v := range secret.Data {
+		data[k] = v
+	}
+
+	caCerts, _ := parseCertificates(data[caCertKey])
+	caKey, _ := parsePrivateKey(data[caKeyKey])
+	// The CA is renewed when it would expire before the serving certificates it signs, the previous one
+	// stays in the bundle so that the clients trust the current serving certificate until it is renewed
+	if len(caCerts) == 0 || caKey == nil || caCerts[0].NotAfter.Before(now.Add(2*validity)) {
+		reconcilerLog.Info(fmt.Sprintf("Generating webhook CA in Secret %s", r.Secret))
+		caCert, caKeyPEM, err := generateCA(now)
+		if err != nil {
+			return nil, err
+		}
+		bundle := caCert
+		if len(caCerts) > 0 && caCerts[0].NotAfter.After(now) {
+			bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCerts[0].Raw})...)
+		}
+		data[caCertKey], data[caKeyKey] = bundle, caKeyPEM
+		caCerts, _ = parseCertificates(bundle)
+		caKey, _ = parsePrivateKey(caKeyPEM)
+	}
+
+	dnsNames := []string{
+		fmt.Sprintf("%s.%s.svc", r.Service.Name, r.Service.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", r.Service.Name, r.Service.Namespace),
+	}
+	certs, _ := parseCertificates(data[corev1.TLSCertKey])
+	trusted := false
+	for _, caCert := range caCerts {
+		trusted = trusted || (len(certs) > 0 && certs[0].CheckSignatureFrom(caCert) == nil)
+	}
+	if !trusted || certs[0].VerifyHostname(dnsNames[0]) != nil || now.After(certs[0].NotAfter.Add(-validity/3)) {
+		reconcilerLog.Info(fmt.Sprintf("Generating webhook serving certificate in Secret %s", r.Secret))
+		certPEM, keyPEM, err := generateServingCert(caCerts[0], caKey, dnsNames, now, validity)
+		if err != nil {
+			return nil, err
+		}
+		data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey] = certPEM, keyPEM
+	}
+
+	if secret.ResourceVersion == "" {
+		secret.Data = data
+		return secret, r.Client.Create(ctx, secret)
+	}
+	changed := len(data) != len(secret.Data)
+	for k, v := range data {
+		changed = changed || !bytes.Equal(v, secret.Data[k])
+	}
+	if !changed {
+		return secret, nil
+	}
+	secret.Data = data
+	return secret, r.Client.Update(ctx, secret)
+}
+
+// writeCertDir writes the serving certificate to CertDir when it changed, the webhook server reloads it on change
+func (r *CertRotator) writeCertDir(secret *corev1.Secret) error {
+	if err := os.MkdirAll(r.CertDir, 0o700); err != nil {
+		return err
+	}
+	// The key is written first, the webhook server keeps the previous certificate until both files match
+	for _, key := range []string{corev1.TLSPrivateKeyKey, corev1.TLSCertKey} {
+		path := filepath.Join(r.CertDir, key)
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, secret.Data[key]) {
+			continue
+		}
+		if err := os.WriteFile(path, secret.Data[key], 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectCABundle writes the CA bundle into the webhook configurations and APIServices served by the webhook server
+func (r *CertRotator) injectCABundle(ctx context.Context, caBundle []byte) error {
+	for _, name := range r.WebhookConfigurations {
+		configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, configuration); err != nil {
+			return err
+		}
+		changed := false
+		for i := range configuration.Webhooks {
+			if !bytes.Equal(configuration.Webhooks[i].ClientConfig.CABundle, caBundle) {
+				configuration.Webhooks[i].ClientConfig.CABundle = caBundle
+				changed = true
+			}
+		}
+		if changed {
+			ctrl.Log.WithName("cert-rotator").Info(fmt.Sprintf("Injecting webhook CA bundle in ValidatingWebhookConfiguration %s", name))
+			if err := r.Client.Update(ctx, configuration); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range r.APIServices {
+		apiService := &unstructured.Unstructured{}
+		apiService.SetGroupVersionKind(apiServiceGVK)
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, apiService); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		// caBundle is base64 encoded in the unstructured APIService
+		encoded := base64.StdEncoding.EncodeToString(caBundle)
+		if current, _, _ := unstructured.NestedString(apiService.Object, "spec", "caBundle"); current == encoded {
+			continue
+		}
+		ctrl.Log.WithName("cert-rotator").Info(fmt.Sprintf("Injecting webhook CA bundle in APIService %s", name))
+		if err := unstructured.SetNestedField(apiService.Object, encoded, "spec", "caBundle"); err != nil {
+			return err
+		}
+		if err := r.Client.Update(ctx, apiService); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateCA returns the PEM encoded certificate and key of a new self-signed CA
+func generateCA(now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("workspace-operator-webhook-ca@%d", now.Unix())},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(webhookCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+// generateServingCert returns the PEM encoded certificate and key of a new serving certificate for the DNS names signed by the CA
+func generateServingCert(ca *x509.Certificate, caKey *ecdsa.PrivateKey, dnsNames []string, now time.Time, validity time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+// encodePrivateKey returns the PEM encoded EC private key
+func encodePrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// parseCertificates returns the certificates of a PEM bundle
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// parsePrivateKey returns the PEM encoded EC private key
+func parsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
@@ -18,31 +18,219 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	quotaResource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
 )
 
 // WorkspaceReconciler reconciles a Workspace object
 type WorkspaceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Audit receives a record for every RBAC change performed by the operator.
+	// Auditing is disabled when it is nil.
+	Audit AuditSink
+
+	// Recorder emits Kubernetes events on the Workspace
+	Recorder record.EventRecorder
+
+	// Notifier sends notifications to the workspace owners.
+	// Notifications are disabled when it is nil.
+	Notifier Notifier
+
+	// LogPipelineNamespace is the namespace the rendered log pipeline ConfigMaps are created in.
+	// They are created in the workspace namespace when it is empty.
+	LogPipelineNamespace string
+
+	// TenantRegistry registers workspace namespaces with their observability tenant.
+	// Only the namespace annotation is managed when it is nil.
+	TenantRegistry TenantRegistry
+
+	// CostProvider reports the spend of the workspace namespaces.
+	// Spend reporting is disabled when it is nil.
+	CostProvider CostProvider
+
+	// SpendRefreshInterval is the minimum time between two spend queries for a workspace
+	SpendRefreshInterval time.Duration
+
+	// UsageSnapshotInterval is the time between two snapshots of the usage of a workspace in status.usage.
+	// The usage history is disabled when it is 0.
+	UsageSnapshotInterval time.Duration
+
+	// HistoryLimit is the number of applied spec revisions kept in status.history.
+	// No history is recorded when it is 0.
+	HistoryLimit int
+
+	// Filter scopes the reconciler to a subset of the Workspaces.
+	// All the Workspaces are managed when it is nil.
+	Filter *WorkspaceFilter
+
+	// NamespacePolicy restricts the target namespaces of the Workspaces.
+	// All the namespace names are allowed when it is nil.
+	NamespacePolicy *NamespacePolicy
+
+	// GrantPolicy restricts the shared namespaces and ClusterRoles of spec.grants, no grant is allowed when empty
+	GrantPolicy GrantPolicy
+
+	// BenchmarkInterval is the minimum time between two multi-tenancy benchmark self-checks of a workspace.
+	// The self-check is disabled when it is 0.
+	BenchmarkInterval time.Duration
+
+	// ResyncPeriod is the time after which a ready workspace is reconciled again, DefaultResyncPeriod when 0.
+	// The changes of the owned resources trigger the reconciliation, the resync only catches the missed ones.
+	ResyncPeriod time.Duration
+
+	// ResyncJitter is the window the resyncs of the workspaces are spread over.
+	// All the workspaces resync on the same period when it is 0.
+	ResyncJitter time.Duration
+
+	// TerminationTimeout is the time after which a terminating workspace namespace is reported
+	// with the TerminationBlocked condition, DefaultTerminationTimeout when 0
+	TerminationTimeout time.Duration
+
+	// ForceCleanupFinalizers are the finalizers the force cleanup of a workspace may strip,
+	// the force cleanup is disabled when empty
+	ForceCleanupFinalizers []string
+
+	// Discovery lists the namespaced resources inspected by the force cleanup
+	Discovery discovery.DiscoveryInterface
+
+	// CreateParallelism is the number of independent child objects created or patched concurrently,
+	// DefaultCreateParallelism when 0
+	CreateParallelism int
+
+	// NamespacedOnly runs the operator without the rights to create, update and delete namespaces.
+	// The namespaces are pre-created and adopted with the AdoptedByAnnotation, and only the resources
+	// inside them are managed.
+	NamespacedOnly bool
+
+	// BudgetPricing translates spec.budget into the hard limits of the workspace ResourceQuota.
+	// The Workspaces with a budget are stalled when it is nil.
+	BudgetPricing *BudgetPricing
+
+	// DevicePluginConfig is the NVIDIA device plugin ConfigMap the time slicing configurations of the workspaces are written to.
+	// The ConfigMap is not managed when it is nil.
+	DevicePluginConfig *types.NamespacedName
+
+	// RecertificationInterval is the time after which the access of a workspace must be re-attested by its owner.
+	// Recertification is disabled when it is 0.
+	RecertificationInterval time.Duration
+
+	// RecertificationGracePeriod is the time the owner has to re-attest the access of a workspace once its review is due,
+	// after which the editor and viewer bindings are dropped, DefaultRecertificationGracePeriod when 0
+	RecertificationGracePeriod time.Duration
+
+	// ExpiryWarningPeriod is the time before the expiry of a workspace its owner is warned with the Expiring condition
+	// and a notification, DefaultExpiryWarningPeriod when 0
+	ExpiryWarningPeriod time.Duration
+
+	// GroupResolver expands the Group subjects of the workspace RoleBindings into their members in status.access.
+	// The groups are listed without their members when it is nil.
+	GroupResolver GroupResolver
+
+	// SubjectVerifier looks the users and groups of spec.users and spec.subjects up in the identity provider, so that
+	// the ones never seen authenticating are reported in the UnknownSubjects condition. The subjects are not verified when it is nil.
+	SubjectVerifier SubjectVerifier
+
+	// DeletionProtection generates the admission policies preventing anyone but the operator, OperatorUsername,
+	// and the cluster administrators from deleting the workspace namespaces and the objects the operator owns in them
+	DeletionProtection bool
+
+	// OperatorUsername is the username the operator authenticates to the API server with
+	OperatorUsername string
+
+	// RenameGracePeriod is the time the previous namespace of a renamed workspace is kept before it is deleted,
+	// DefaultRenameGracePeriod when 0
+	RenameGracePeriod time.Duration
+
+	// MigrationHook migrates the workloads of the renamed workspaces to their new namespace.
+	// Only the objects managed by the operator are provisioned in the new namespace when it is nil.
+	MigrationHook MigrationHook
+
+	// TeamSync syncs the members of the GitHub teams and GitLab groups of spec.teams.
+	// The Workspaces with teams are stalled when it is nil.
+	TeamSync *TeamSync
+
+	// SnapshotStore reads the content of the ObjectStorage snapshots restored with spec.restoreFrom.
+	// Restoring such snapshots fails when it is nil.
+	SnapshotStore SnapshotStore
+
+	// RoleTemplates are the rules of the admin, editor and viewer Roles, overridden per role by the classes.
+	// The roles without rules, or all of them when it is nil, keep their built-in rules.
+	RoleTemplates *environmentv1alpha1.WorkspaceRoleTemplates
+
+	// RoleAPIGroups are the API groups covered by the built-in rules of the Roles, overridden by spec.rbac.apiGroups.
+	// DefaultRoleAPIGroups are covered when it is empty.
+	RoleAPIGroups []string
+
+	// RoleAllowedAPIGroups are the API groups spec.rbac.apiGroups can list, RoleAPIGroups when it is empty
+	RoleAllowedAPIGroups []string
+
+	// RBACChangeDelay is the time the changes of the rules of the Roles are published in status.pendingChanges
+	// before they are applied
+	RBACChangeDelay time.Duration
+
+	// RBACChangeAcknowledgment only applies the changes of the rules of the Roles once the owner of the workspace
+	// acknowledged them with the AcknowledgeRBACChangesAnnotation
+	RBACChangeAcknowledgment bool
+
+	// RequiredLabels are the labels every workspace namespace carries, set to their default
+	// when the workspace does not set them in spec.labels
+	RequiredLabels RequiredLabels
+
+	// ReconcileTimeout bounds a single reconciliation of a workspace, so that a hanging call does not hold
+	// a worker of the work queue. The reconciliations are not bounded when it is 0.
+	ReconcileTimeout time.Duration
+
+	// CircuitBreakerThreshold is the number of consecutive failed reconciliations of a workspace after which
+	// it is backed off and reported with the CircuitOpen reason. The circuit breaker is disabled when it is 0.
+	CircuitBreakerThreshold int
+
+	// CircuitBreakerMaxBackoff caps the time a workspace whose circuit is open is left alone,
+	// DefaultCircuitBreakerMaxBackoff when 0
+	CircuitBreakerMaxBackoff time.Duration
+
+	// Metrics records the duration and the errors of the reconciliations and the changes of the child resources.
+	// The provisioning metrics are disabled when it is nil.
+	Metrics *ProvisioningMetrics
+
+	breaker circuitBreaker
+
+	// applied remembers the child resources brought to their desired state, so that the unchanged ones are skipped
+	applied appliedStates
+
+	// activityMu serializes the changes recorded in status.recentEvents by the concurrent tasks of a reconciliation
+	activityMu sync.Mutex
 }
 
 //+kubebuilder:rbac:groups=environment.tf.operator.com,resources=workspaces,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=environment.tf.operator.com,resources=workspaces/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=environment.tf.operator.com,resources=workspaces/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -56,7 +244,7 @@ type WorkspaceReconciler struct {
 func (r *WorkspaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 
 	// setting up logging with zap from the controller
-	reconcilerLog := ctrl.Log.WithName("reconciler")
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, req.Name)
 
 	// We create a CR of Workspace and then we query the workspaces across req.NamespacedName
 	// The reconciler loop is triggered by a request that is carried out in req
@@ -65,9 +253,21 @@ func (r *WorkspaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	err := r.Get(ctx, req.NamespacedName, workspace)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
+			// A Workspace restored during its deletion grace period is recreated once the deleted one is gone
+			restored, err := r.recreateRestoredWorkspace(ctx, req.Name)
+			if err != nil {
+				reconcilerLog.Error(err, "Failed to recreate restored Workspace")
+				return ctrl.Result{}, err
+			}
+			if restored {
+				return ctrl.Result{}, nil
+			}
 			// If the custom resource is not found then, it usually means that it was deleted or not created
 			// In this way, we will stop the reconciliation
 			reconcilerLog.Info("Workspace resource not found. Ignoring since object must be deleted")
+			r.breaker.forget(req.Name)
+			r.applied.forget(req.Name)
+			r.Metrics.forget(req.Name)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -77,424 +277,646 @@ func (r *WorkspaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// If we come here it means error was nil and there is a workspace created.
 	// From now we will check whether that workspace created all the required resources or not.
 
+	// Check if the workspace is managed by this operator instance
+	if !r.Filter.Matches(workspace) {
+		reconcilerLog.Info(fmt.Sprintf("Workspace %s is not managed by this operator instance. Ignoring", workspace.Name))
+		return ctrl.Result{}, nil
+	}
+
+	// Bound the reconciliation so that a hanging call does not hold a worker of the work queue
+	reconcileCtx := ctx
+	if r.ReconcileTimeout > 0 {
+		var cancel context.CancelFunc
+		reconcileCtx, cancel = context.WithTimeout(ctx, r.ReconcileTimeout)
+		defer cancel()
+	}
+
+	// Check if the workspace is being deleted
+	// Workspaces with a deletion grace period are frozen until the grace period is over
+	if !workspace.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(reconcileCtx, workspace)
+	}
+
+	// A requested resync starts from the live state, even when the circuit of the workspace is open
+	resync := resyncRequested(workspace)
+	if resync {
+		reconcilerLog.Info(fmt.Sprintf("Resync of Workspace requested at %s", workspace.Annotations[ResyncAnnotation]))
+		r.forceResync(workspace)
+	}
+
+	// Leave the workspaces whose circuit is open alone until their backoff is over or their spec changes
+	if wait := r.breaker.open(workspace, time.Now()); wait > 0 {
+		reconcilerLog.Info(fmt.Sprintf("Circuit of Workspace is open, skipping the reconciliation for %s", wait.Round(time.Second)))
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	// The status is compared with the one at the start of the reconciliation, so that the changes recorded
+	// in status.recentEvents along the way are written even when the conditions did not change
+	initialStatus := workspace.Status.DeepCopy()
+	start := time.Now()
+	result, ready, err := r.reconcileWorkspace(reconcileCtx, workspace)
+	r.Metrics.observeReconcile(workspace, time.Since(start), err)
+	if err != nil {
+		r.reconcileFailedEvent(workspace, err)
+	}
+	if resync && ready && err == nil {
+		r.completeResync(workspace)
+	}
+
+	// Open the circuit of the workspace after CircuitBreakerThreshold consecutive failures
+	err = r.breaker.record(workspace, err, r.CircuitBreakerThreshold, r.circuitBreakerMaxBackoff(), time.Now())
+	var circuitOpen *circuitOpenError
+	if errors.As(err, &circuitOpen) {
+		reconcilerLog.Error(err, "Circuit of Workspace opened")
+		result = ctrl.Result{RequeueAfter: circuitOpen.backoff}
+	}
+
+	// Report the outcome of the reconciliation in the status conditions of the workspace
+	// The status is written with the context of the request, in case the reconciliation timed out
+	if statusErr := r.reconcileStatus(ctx, workspace, initialStatus, ready, err); statusErr != nil {
+		reconcilerLog.Error(statusErr, "Failed to update status conditions for Workspace")
+		if err == nil {
+			return ctrl.Result{}, statusErr
+		}
+	}
+	if circuitOpen != nil {
+		// The error is not returned, so that the rate limiter of the work queue does not retry it before the backoff
+		return result, nil
+	}
+	return result, err
+}
+
+// circuitBreakerMaxBackoff returns the time a workspace whose circuit is open is left alone at most
+func (r *WorkspaceReconciler) circuitBreakerMaxBackoff() time.Duration {
+	if r.CircuitBreakerMaxBackoff > 0 {
+		return r.CircuitBreakerMaxBackoff
+	}
+	return DefaultCircuitBreakerMaxBackoff
+}
+
+// reconcileWorkspace creates and updates all the resources of the workspace in a single pass, only stopping
+// early on an error or while the workspace waits for something. It reports whether all of them are in the desired state.
+func (r *WorkspaceReconciler) reconcileWorkspace(ctx context.Context, workspace *environmentv1alpha1.Workspace) (ctrl.Result, bool, error) {
+	reconcilerLog := ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name)
+
+	// The whole pass is reconciled at the same time, so that its steps agree on whether the workspace is suspended,
+	// expired or within an access schedule, even when one of their windows starts or stops during the pass
+	now := time.Now()
+
+	// Check the target namespace against the namespace policy, in case the webhook was bypassed or disabled
+	if err := r.NamespacePolicy.Check(workspace); err != nil {
+		reconcilerLog.Error(err, "Namespace of Workspace is not allowed")
+		return ctrl.Result{}, false, stall(err)
+	}
+
+	// Check if the finalizer is in the desired state for the deletion grace period
+	if err := r.reconcileFinalizer(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to update finalizer for Workspace")
+		return ctrl.Result{}, false, err
+	}
+
+	// Check if the workspace expired, nothing is provisioned for an expired workspace being deleted
+	deleted, err := r.reconcileExpiry(ctx, workspace, now)
+	if err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile expiry of Workspace")
+		return ctrl.Result{}, false, err
+	}
+	if deleted {
+		return ctrl.Result{}, false, nil
+	}
+
+	// Resolve the template of the workspace, the objects of the workspace are generated from its merged values
+	if err := r.reconcileTemplate(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to resolve the template of Workspace")
+		return ctrl.Result{}, false, err
+	}
+
 	// Check if the namespace already exists, if not create a new one
 	// We create a namespace pointer and check if namespace exists with the name in workspace.Spec.Name
 	namespace := &corev1.Namespace{}
 	err = r.Get(ctx, types.NamespacedName{Namespace: "", Name: workspace.Spec.Name}, namespace)
 	if err != nil && apierrors.IsNotFound(err) {
+		r.checkNamespaceTermination(workspace, nil)
+
+		// Refuse to create a namespace claimed by an older Workspace
+		if err := r.checkDuplicateWorkspace(ctx, workspace, nil); err != nil {
+			reconcilerLog.Error(err, "Namespace of Workspace is claimed by another Workspace")
+			return ctrl.Result{}, false, err
+		}
+
+		// Wait for the Workspaces the workspace depends on to be Ready before provisioning it
+		waiting, err := r.reconcileDependencies(ctx, workspace)
+		if err != nil {
+			reconcilerLog.Error(err, "Failed to check the dependencies of Workspace")
+			return ctrl.Result{}, false, err
+		}
+		if waiting {
+			return ctrl.Result{RequeueAfter: dependencyRetryInterval}, false, nil
+		}
+
+		// Namespaces are pre-created by the cluster administrators in namespaced-only mode
+		if r.NamespacedOnly {
+			err := stall(fmt.Errorf("Namespace %s does not exist, it must be created and adopted before the Workspace in namespaced-only mode", workspace.Spec.Name))
+			reconcilerLog.Error(err, "Namespace of Workspace is missing")
+			return ctrl.Result{}, false, err
+		}
+
+		// Report the namespaces deleted without deleting their Workspace
+		r.reportNamespaceDeleted(ctx, workspace)
+
 		// Define a new namespace as the namespace is not found
 		ns, err := r.namespaceForWorkspace(workspace)
 		if err != nil {
 			reconcilerLog.Error(err, "Failed to define new Namespace resource for Workspace")
-			return ctrl.Result{}, err
+			return ctrl.Result{}, false, err
 		}
 
 		// we will now create the namespace.
+		// The applied namespace is the one returned by the API server, the rest of the workspace
+		// is provisioned in it in the same reconciliation
 		reconcilerLog.Info(fmt.Sprintf("Creating a new Namespace Namespace.Name %s", ns.Name))
-		if err = r.Create(ctx, ns); err != nil {
+		if err = r.apply(ctx, ns); err != nil {
 			reconcilerLog.Error(err, fmt.Sprintf("Error creating a new Namespace Namespace.Name %s", ns.Name))
-			return ctrl.Result{}, err
+			return ctrl.Result{}, false, err
 		}
-
-		// Namespace created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+		r.recordActivity(workspace, ActivityCreated, fmt.Sprintf("Created Namespace %s", ns.Name))
+		r.event(workspace, corev1.EventTypeNormal, EventNamespaceCreated, fmt.Sprintf("Created Namespace %s", ns.Name))
+		namespace = ns
 	} else if err != nil {
 		reconcilerLog.Error(err, "Failed to get Namespace")
 		// Let's return the error for the reconciliation be re-trigged again
-		return ctrl.Result{}, err
+		return ctrl.Result{}, false, err
 	}
 
-	// Check if resource quotas for the namespace exists
-	// resource-quota name will be Namespace.Name-quota
-	resourceQuota := corev1.ResourceQuota{}
-	err = r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-quota", workspace.Spec.Name)}, &resourceQuota)
-	if err != nil && apierrors.IsNotFound(err) {
-		// Define a new resourcequota as the resourcequota is not found
-		rq, err := r.resourceQuotaForWorkspace(workspace)
-		if err != nil {
-			reconcilerLog.Error(err, "Failed to define new ResourceQuota resource for Workspace")
-			return ctrl.Result{}, err
-		}
+	// Refuse to co-manage a namespace claimed by an older Workspace
+	if err := r.checkDuplicateWorkspace(ctx, workspace, namespace); err != nil {
+		reconcilerLog.Error(err, "Namespace of Workspace is claimed by another Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// When we create a pointer of namespace object, we will now create the namespace.
-		reconcilerLog.Info(fmt.Sprintf("Creating a new ResourceQuota ResourceQuota.Name %s", rq.Name))
-		if err = r.Create(ctx, rq); err != nil {
-			reconcilerLog.Error(err, fmt.Sprintf("Error creating a new ResourceQuota ResourceQuota.Name %s", rq.Name))
-			return ctrl.Result{}, err
+	// Refuse to co-manage a namespace managed by another controller, e.g. HNC or Capsule
+	if err := checkNamespaceConflict(workspace, namespace); err != nil {
+		reconcilerLog.Error(err, "Namespace of Workspace is managed by another controller")
+		return ctrl.Result{}, false, err
+	}
+	if r.NamespacedOnly {
+		if err := checkNamespaceAdopted(workspace, namespace); err != nil {
+			reconcilerLog.Error(err, "Namespace of Workspace is not adopted")
+			return ctrl.Result{}, false, err
 		}
+	}
 
-		// ResourceQuota created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
-	} else if err != nil {
-		reconcilerLog.Error(err, "Failed to get ResourceQuota")
-		// Let's return the error for the reconciliation be re-trigged again
-		return ctrl.Result{}, err
+	// Wait for a terminating namespace to be gone before recreating it, reporting it when it is stuck
+	r.checkNamespaceTermination(workspace, namespace)
+	if !namespace.DeletionTimestamp.IsZero() {
+		reconcilerLog.Info(fmt.Sprintf("Namespace.Name %s is terminating, waiting for it to be deleted", namespace.Name))
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, false, nil
 	}
 
-	// Check if roles are created or not
-	// 1. Admin role
-	adminRole := rbacv1.Role{}
-	err = r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-admin", workspace.Spec.Name)}, &adminRole)
-	if err != nil && apierrors.IsNotFound(err) {
-		// Define a new role as the admin role is not found
-		ar, err := r.adminRoleForWorkspace(workspace)
-		if err != nil {
-			reconcilerLog.Error(err, "Failed to define new admin Role resource for Workspace")
-			return ctrl.Result{}, err
-		}
+	// Compute the hard limits of the ResourceQuota from spec.budget when the workspace has one
+	resources, err := r.reconcileBudget(ctx, workspace)
+	if err != nil {
+		reconcilerLog.Error(err, "Failed to translate the budget of Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// When we create a pointer of admin Role object, we will now create the admin Role.
-		reconcilerLog.Info(fmt.Sprintf("Creating a new Admin Role Role.Name %s", ar.Name))
-		if err = r.Create(ctx, ar); err != nil {
-			reconcilerLog.Error(err, fmt.Sprintf("Error creating a new Admin Role Role.Name %s", ar.Name))
-			return ctrl.Result{}, err
-		}
+	// Fetch the members of the teams of the workspace, bound to the role of their team next to spec.users
+	teamMembers, err := r.teamMembers(ctx, workspace)
+	if err != nil {
+		reconcilerLog.Error(err, "Failed to sync the teams of Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// Admin Role created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
-	} else if err != nil {
-		reconcilerLog.Error(err, "Failed to get Admin role")
-		// Let's return the error for the reconciliation be re-trigged again
-		return ctrl.Result{}, err
+	// Resolve the rules of the roles from the templates of the operator and of the class of the workspace
+	roleTemplates, err := r.roleTemplates(ctx, workspace)
+	if err != nil {
+		reconcilerLog.Error(err, "Failed to resolve the role templates of Workspace")
+		return ctrl.Result{}, false, err
 	}
-	// 2. Editor role
-	editorRole := rbacv1.Role{}
-	err = r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-editor", workspace.Spec.Name)}, &editorRole)
-	if err != nil && apierrors.IsNotFound(err) {
-		// Define a new role as the editor role is not found
-		er, err := r.editorRoleForWorkspace(workspace)
-		if err != nil {
-			reconcilerLog.Error(err, "Failed to define new editor Role resource for Workspace")
-			return ctrl.Result{}, err
-		}
 
-		// When we create a pointer of editor Role object, we will now create the editor Role.
-		reconcilerLog.Info(fmt.Sprintf("Creating a new Editor Role Role.Name %s", er.Name))
-		if err = r.Create(ctx, er); err != nil {
-			reconcilerLog.Error(err, fmt.Sprintf("Error creating a new Editor Role Role.Name %s", er.Name))
-			return ctrl.Result{}, err
+	// Check if the resourcequota, the roles and the rolebindings of the workspace exist
+	// resource-quota name will be Namespace.Name-quota
+	// The missing ones are independent of each other and are applied concurrently
+	resourceQuota := corev1.ResourceQuota{}
+	adminRole, editorRole, viewerRole := rbacv1.Role{}, rbacv1.Role{}, rbacv1.Role{}
+	adminRoleBinding, editorRoleBinding, viewerRoleBinding := rbacv1.RoleBinding{}, rbacv1.RoleBinding{}, rbacv1.RoleBinding{}
+	auditRole := func(object client.Object) {
+		r.audit(ctx, workspace, AuditActionRoleCreated, "Role", object.GetName(), nil, object.(*rbacv1.Role).Rules)
+	}
+	auditRoleBinding := func(object client.Object) {
+		for i := range object.(*rbacv1.RoleBinding).Subjects {
+			r.audit(ctx, workspace, AuditActionRoleBindingCreated, "RoleBinding", object.GetName(), &object.(*rbacv1.RoleBinding).Subjects[i], nil)
 		}
+	}
+	children := []childObject{
+		{kind: "Admin Role", name: fmt.Sprintf("%s-admin", workspace.Spec.Name), existing: &adminRole, created: auditRole, reason: EventRBACSynced,
+			define: func() (client.Object, error) { return r.adminRoleForWorkspace(workspace, roleTemplates["admin"]) }},
+		{kind: "Editor Role", name: fmt.Sprintf("%s-editor", workspace.Spec.Name), existing: &editorRole, created: auditRole, reason: EventRBACSynced,
+			define: func() (client.Object, error) { return r.editorRoleForWorkspace(workspace, roleTemplates["editor"]) }},
+		{kind: "Viewer Role", name: fmt.Sprintf("%s-viewer", workspace.Spec.Name), existing: &viewerRole, created: auditRole, reason: EventRBACSynced,
+			define: func() (client.Object, error) { return r.viewerRoleForWorkspace(workspace, roleTemplates["viewer"]) }},
+		{kind: "Admin RoleBinding", name: fmt.Sprintf("%s-admin-rb", workspace.Spec.Name), existing: &adminRoleBinding, created: auditRoleBinding, reason: EventRBACSynced,
+			define: func() (client.Object, error) { return r.adminRoleBindingForWorkspace(workspace, teamMembers, now) }},
+		{kind: "Editor RoleBinding", name: fmt.Sprintf("%s-editor-rb", workspace.Spec.Name), existing: &editorRoleBinding, created: auditRoleBinding, reason: EventRBACSynced,
+			define: func() (client.Object, error) { return r.editorRoleBindingForWorkspace(workspace, teamMembers, now) }},
+		{kind: "Viewer RoleBinding", name: fmt.Sprintf("%s-viewer-rb", workspace.Spec.Name), existing: &viewerRoleBinding, created: auditRoleBinding, reason: EventRBACSynced,
+			define: func() (client.Object, error) { return r.viewerRoleBindingForWorkspace(workspace, teamMembers, now) }},
+	}
+	// The ResourceQuota is not created in Monitor quota mode
+	if workspace.Spec.QuotaMode != environmentv1alpha1.QuotaModeMonitor {
+		children = append([]childObject{
+			{kind: "ResourceQuota", name: fmt.Sprintf("%s-quota", workspace.Spec.Name), existing: &resourceQuota, reason: EventQuotaApplied,
+				define: func() (client.Object, error) { return r.resourceQuotaForWorkspace(workspace, resources, now) }},
+		}, children...)
+	}
+	if err := r.createChildren(ctx, workspace, children); err != nil {
+		return ctrl.Result{}, false, err
+	}
 
-		// Editor Role created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
-	} else if err != nil {
-		reconcilerLog.Error(err, "Failed to get Editor role")
-		// Let's return the error for the reconciliation be re-trigged again
-		return ctrl.Result{}, err
+	// Check if the additional ResourceQuotas of spec.quotas are in the desired state
+	if err := r.reconcileQuotas(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile additional ResourceQuotas for Workspace")
+		return ctrl.Result{}, false, err
 	}
-	// 3. Viewer role
-	viewerRole := rbacv1.Role{}
-	err = r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-viewer", workspace.Spec.Name)}, &viewerRole)
-	if err != nil && apierrors.IsNotFound(err) {
-		// Define a new role as the viewer role is not found
-		vr, err := r.viewerRoleForWorkspace(workspace)
-		if err != nil {
-			reconcilerLog.Error(err, "Failed to define new viewer Role resource for Workspace")
-			return ctrl.Result{}, err
-		}
 
-		// When we create a pointer of viewer Role object, we will now create the viewer Role.
-		reconcilerLog.Info(fmt.Sprintf("Creating a new Viewer Role Role.Name %s", vr.Name))
-		if err = r.Create(ctx, vr); err != nil {
-			reconcilerLog.Error(err, fmt.Sprintf("Error creating a new Viewer Role Role.Name %s", vr.Name))
-			return ctrl.Result{}, err
-		}
+	// Check if the LimitRange of spec.limits is in the desired state
+	if err := r.reconcileLimitRange(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile LimitRange for Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// Viewer Role created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
-	} else if err != nil {
-		reconcilerLog.Error(err, "Failed to get Viewer role")
-		// Let's return the error for the reconciliation be re-trigged again
-		return ctrl.Result{}, err
+	// Check if the PrometheusRule with the standard workspace alerts is in the desired state
+	if err := r.reconcilePrometheusRule(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile PrometheusRule for Workspace")
+		return ctrl.Result{}, false, err
 	}
 
-	// Check rolebindings
-	// 1. AdminRoleBinding
-	adminRoleBinding := rbacv1.RoleBinding{}
-	err = r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-admin-rb", workspace.Spec.Name)}, &adminRoleBinding)
-	if err != nil && apierrors.IsNotFound(err) {
-		// Define a new rolebinding
-		arb, err := r.adminRoleBindingForWorkspace(workspace)
-		if err != nil {
-			reconcilerLog.Error(err, "Failed to define new admin RoleBinding resource for Workspace")
-			return ctrl.Result{}, err
-		}
+	// Check if the AlertmanagerConfig routing the workspace alerts is in the desired state
+	if err := r.reconcileAlertmanagerConfig(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile AlertmanagerConfig for Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// When we create a pointer of admin RoleBinding object, we will now create the admin RoleBinding.
-		reconcilerLog.Info(fmt.Sprintf("Creating a new Admin RoleBinding RoleBinding.Name %s", arb.Name))
-		if err = r.Create(ctx, arb); err != nil {
-			reconcilerLog.Error(err, fmt.Sprintf("Error creating a new Admin RoleBinding RoleBinding.Name %s", arb.Name))
-			return ctrl.Result{}, err
-		}
+	// Check if the log pipeline of the workspace namespace is in the desired state
+	if err := r.reconcileLogPipeline(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile log pipeline for Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// Admin Role Binding created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
-	} else if err != nil {
-		reconcilerLog.Error(err, "Failed to get Admin RoleBinding")
-		// Let's return the error for the reconciliation be re-trigged again
-		return ctrl.Result{}, err
+	// Check if the Kueue queues of the batch workloads of the workspace are in the desired state
+	if err := r.reconcileKueue(ctx, workspace, resources); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile Kueue queues for Workspace")
+		return ctrl.Result{}, false, err
 	}
 
-	// EditorRoleBinding
-	editorRoleBinding := rbacv1.RoleBinding{}
-	err = r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-editor-rb", workspace.Spec.Name)}, &editorRoleBinding)
-	if err != nil && apierrors.IsNotFound(err) {
-		// Define a new rolebinding
-		erb, err := r.editorRoleBindingForWorkspace(workspace)
-		if err != nil {
-			reconcilerLog.Error(err, "Failed to define new editor RoleBinding resource for Workspace")
-			return ctrl.Result{}, err
-		}
+	// The admission policies of the workspace are independent of each other and are reconciled concurrently
+	err = r.parallel(
+		// Check if the security policy of the workspace class is in the desired state
+		// Changes to the class are picked up on the next periodic reconciliation
+		func() error {
+			if err := r.reconcileSecurityPolicy(ctx, workspace); err != nil {
+				reconcilerLog.Error(err, "Failed to reconcile security policy for Workspace")
+				return err
+			}
+			return nil
+		},
+		// Check if the registry allowlist of the workspace is in the desired state
+		func() error {
+			if err := r.reconcileRegistryPolicy(ctx, workspace); err != nil {
+				reconcilerLog.Error(err, "Failed to reconcile registry policy for Workspace")
+				return err
+			}
+			return nil
+		},
+		// Check if the PodDisruptionBudget guardrails of the workspace are in the desired state
+		func() error {
+			if err := r.reconcileDisruptionBudgets(ctx, workspace); err != nil {
+				reconcilerLog.Error(err, "Failed to reconcile PodDisruptionBudget guardrails for Workspace")
+				return err
+			}
+			return nil
+		},
+		// Check if the deletion protection of the workspace is in the desired state
+		func() error {
+			if err := r.reconcileDeletionProtection(ctx, workspace); err != nil {
+				reconcilerLog.Error(err, "Failed to reconcile deletion protection for Workspace")
+				return err
+			}
+			return nil
+		},
+		// Check if the ServiceAccount token hygiene of the workspace is in the desired state
+		func() error {
+			if err := r.reconcileTokenAutomount(ctx, workspace); err != nil {
+				reconcilerLog.Error(err, "Failed to reconcile ServiceAccount token automount for Workspace")
+				return err
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return ctrl.Result{}, false, err
+	}
 
-		// When we create a pointer of editor RoleBinding object, we will now create the editor RoleBinding.
-		reconcilerLog.Info(fmt.Sprintf("Creating a new editor RoleBinding RoleBinding.Name %s", erb.Name))
-		if err = r.Create(ctx, erb); err != nil {
-			reconcilerLog.Error(err, fmt.Sprintf("Error creating a new editor RoleBinding RoleBinding.Name %s", erb.Name))
-			return ctrl.Result{}, err
-		}
+	// Check if the device plugin configuration of the GPU time slicing of the workspace is in the desired state
+	if err := r.reconcileGPUSharing(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile GPU sharing for Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// Editor Role Binding created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
-	} else if err != nil {
-		reconcilerLog.Error(err, "Failed to get editor RoleBinding")
-		// Let's return the error for the reconciliation be re-trigged again
-		return ctrl.Result{}, err
+	// Check if the network peering of the workspace is in the desired state
+	if err := r.reconcileNetworking(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile network peering for Workspace")
+		return ctrl.Result{}, false, err
 	}
 
-	// ViewerRoleBinding
-	viewerRoleBinding := rbacv1.RoleBinding{}
-	err = r.Get(ctx, types.NamespacedName{Namespace: workspace.Spec.Name, Name: fmt.Sprintf("%s-viewer-rb", workspace.Spec.Name)}, &viewerRoleBinding)
-	if err != nil && apierrors.IsNotFound(err) {
-		// Define a new rolebinding
-		erb, err := r.viewerRoleBindingForWorkspace(workspace)
-		if err != nil {
-			reconcilerLog.Error(err, "Failed to define new viewer RoleBinding resource for Workspace")
-			return ctrl.Result{}, err
-		}
+	// Check if the default-deny policies of the workspace namespace are in the desired state
+	if err := r.reconcileNetworkIsolation(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile network isolation for Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// When we create a pointer of viewer RoleBinding object, we will now create the viewer RoleBinding.
-		reconcilerLog.Info(fmt.Sprintf("Creating a new viewer RoleBinding RoleBinding.Name %s", erb.Name))
-		if err = r.Create(ctx, erb); err != nil {
-			reconcilerLog.Error(err, fmt.Sprintf("Error creating a new viewer RoleBinding RoleBinding.Name %s", erb.Name))
-			return ctrl.Result{}, err
-		}
+	// Check if the egress policies of the workspace namespace are in the desired state
+	if err := r.reconcileEgress(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile egress policies for Workspace")
+		return ctrl.Result{}, false, err
+	}
 
-		// Viewer Role Binding created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
-	} else if err != nil {
-		reconcilerLog.Error(err, "Failed to get viewer RoleBinding")
-		// Let's return the error for the reconciliation be re-trigged again
-		return ctrl.Result{}, err
+	// Check if the shared-services grants of the workspace are in the desired state
+	if err := r.reconcileGrants(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile grants for Workspace")
+		return ctrl.Result{}, false, err
 	}
 
-	// Check if Workspace labels are updated
-	workspaceLabels := workspace.Spec.Labels
-	namespaceLabels := namespace.ObjectMeta.Labels
-	resourceQuotaLabels := resourceQuota.ObjectMeta.Labels
-	adminRoleLabels := adminRole.ObjectMeta.Labels
-	editorRoleLabels := editorRole.ObjectMeta.Labels
-	viewerRoleLabels := viewerRole.ObjectMeta.Labels
-	// Check for namespace labels
-	for k, v := range workspaceLabels {
-		value, ok := namespaceLabels[k]
-		if !ok || value != v {
-			reconcilerLog.Info(fmt.Sprintf("Labels not same for Namespace.Name %s", workspace.Spec.Name))
-			namespace.ObjectMeta.Labels = workspaceLabels
-			if err := r.Update(ctx, namespace); err != nil {
-				reconcilerLog.Error(err, "Failed to update Namespace.ObjectMeta.Labels for Namespace")
-				return ctrl.Result{}, err
-			}
-		}
+	// Rehydrate the objects of the snapshot of spec.restoreFrom once the RBAC and quotas are provisioned
+	restored, err := r.reconcileRestoreFrom(ctx, workspace)
+	if err != nil {
+		reconcilerLog.Error(err, "Failed to restore snapshot for Workspace")
+		return ctrl.Result{}, false, err
 	}
-	// Check for resourceQuota labels
-	for k, v := range workspaceLabels {
-		value, ok := resourceQuotaLabels[k]
-		if !ok || value != v {
-			reconcilerLog.Info(fmt.Sprintf("Labels not same for ResourceQuota.Name %s in Namespace.Name %s", fmt.Sprintf("%s-quota", workspace.Spec.Name), workspace.Spec.Name))
-			resourceQuota.ObjectMeta.Labels = workspaceLabels
-			if err := r.Update(ctx, &resourceQuota); err != nil {
-				reconcilerLog.Error(err, "Failed to update ResourceQuota.ObjectMeta.Labels for ResourceQuota")
-				return ctrl.Result{}, err
-			}
-		}
+	if !restored {
+		// The snapshot is still being taken
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, false, nil
 	}
-	// Check for adminRole labels
-	for k, v := range workspaceLabels {
-		value, ok := adminRoleLabels[k]
-		if !ok || value != v {
-			reconcilerLog.Info(fmt.Sprintf("Labels not same for admin Role.Name %s in Namespace.Name %s", fmt.Sprintf("%s-admin", workspace.Spec.Name), workspace.Spec.Name))
-			adminRole.ObjectMeta.Labels = workspaceLabels
-			if err := r.Update(ctx, &adminRole); err != nil {
-				reconcilerLog.Error(err, "Failed to update adminRole.ObjectMeta.Labels")
-				return ctrl.Result{}, err
-			}
+
+	// Check if the labels, annotations and subjects of the resources are updated
+	// All the corrections of a resource are sent in a single patch, only when something effectively changed
+	workspaceLabels := labelsForWorkspace(workspace, nil)
+	workspaceAnnotations := annotationsForWorkspace(workspace)
+	namespaceLabels := r.RequiredLabels.Apply(namespaceLabelsForWorkspace(workspace))
+	namespaceAnnotations := namespaceAnnotationsForWorkspace(workspace)
+
+	// The resources carrying the hash of the inputs of their desired state, and not changed since they were
+	// brought to it, are neither rebuilt nor compared
+	namespaceHash, err := desiredStateHash(namespaceLabels, namespaceAnnotations, workspace.Spec.GPU)
+	if err != nil {
+		reconcilerLog.Error(err, "Failed to hash the desired state of Namespace")
+		return ctrl.Result{}, false, err
+	}
+	quotaHash, err := desiredStateHash(workspaceLabels, workspaceAnnotations, resources, objectCounts(workspaceResources(workspace)), requestsAndLimits(workspaceResources(workspace)), suspended(workspace, now), workspace.Spec.GPU)
+	if err != nil {
+		reconcilerLog.Error(err, "Failed to hash the desired state of ResourceQuota")
+		return ctrl.Result{}, false, err
+	}
+	roleHash, err := desiredStateHash(workspaceLabels)
+	if err != nil {
+		reconcilerLog.Error(err, "Failed to hash the desired state of Roles")
+		return ctrl.Result{}, false, err
+	}
+
+	// Check for namespace labels and annotations
+	// The namespace is left as pre-created in namespaced-only mode
+	if !r.NamespacedOnly && !r.applied.upToDate(workspace.Name, namespace, namespaceHash) {
+		originalNamespace := namespace.DeepCopy()
+		pruneOwnerAnnotations(&namespace.ObjectMeta, workspace)
+		pruneGPUAnnotations(&namespace.ObjectMeta, workspace)
+		setMetadata(&namespace.ObjectMeta, namespaceLabels, namespaceAnnotations)
+		setAnnotation(&namespace.ObjectMeta, DesiredStateHashAnnotation, namespaceHash)
+		if _, err := r.patchIfChanged(ctx, workspace, "Namespace", originalNamespace, namespace); err != nil {
+			reconcilerLog.Error(err, "Failed to patch Namespace.ObjectMeta for Namespace")
+			return ctrl.Result{}, false, err
 		}
+		r.applied.applied(workspace.Name, namespace)
 	}
-	// Check for editorRole labels
-	for k, v := range workspaceLabels {
-		value, ok := editorRoleLabels[k]
-		if !ok || value != v {
-			reconcilerLog.Info(fmt.Sprintf("Labels not same for editor Role.Name %s in Namespace.Name %s", fmt.Sprintf("%s-editor", workspace.Spec.Name), workspace.Spec.Name))
-			editorRole.ObjectMeta.Labels = workspaceLabels
-			if err := r.Update(ctx, &editorRole); err != nil {
-				reconcilerLog.Error(err, "Failed to update editorRole.ObjectMeta.Labels")
-				return ctrl.Result{}, err
+
+	if workspace.Spec.QuotaMode == environmentv1alpha1.QuotaModeMonitor {
+		// In Monitor quota mode the usage of the namespace is tracked against the hard limits by the operator
+		monitoredQuota, err := r.reconcileMonitoredQuota(ctx, workspace, resources, now)
+		if err != nil {
+			reconcilerLog.Error(err, "Failed to compute the usage of the Namespace of Workspace")
+			return ctrl.Result{}, false, err
+		}
+		resourceQuota = *monitoredQuota
+	} else if !r.applied.upToDate(workspace.Name, &resourceQuota, quotaHash) {
+		// Check for resourceQuota labels, annotations and right cpu, memory and disk
+		originalResourceQuota := resourceQuota.DeepCopy()
+		setMetadata(&resourceQuota.ObjectMeta, workspaceLabels, workspaceAnnotations)
+		setAnnotation(&resourceQuota.ObjectMeta, DesiredStateHashAnnotation, quotaHash)
+		if resourceQuota.Spec.Hard == nil {
+			resourceQuota.Spec.Hard = corev1.ResourceList{}
+		}
+		for _, hard := range []struct {
+			name  corev1.ResourceName
+			field string
+			value string
+		}{
+			{corev1.ResourceMemory, "workspace.Spec.Resources.Memory", resources.Memory},
+			{corev1.ResourceCPU, "workspace.Spec.Resources.CPU", resources.CPU},
+			{corev1.ResourceRequestsStorage, "workspace.Spec.Resources.Disk", resources.Disk},
+		} {
+			quantity, err := quotaResource.ParseQuantity(hard.value)
+			if err != nil {
+				reconcilerLog.Error(err, fmt.Sprintf("Not able to parse %s", hard.field))
+				return ctrl.Result{}, false, err
 			}
+			// an equal quantity written differently, e.g. 1Gi and 1024Mi, is not a change
+			resourceQuota.Spec.Hard[hard.name] = quantity
+		}
+		// the object counts, requests and limits are not paid by spec.budget, they always come from spec.resources
+		if err := setOptionalQuota(resourceQuota.Spec.Hard, workspaceResources(workspace)); err != nil {
+			reconcilerLog.Error(err, "Not able to parse workspace.Spec.Resources")
+			return ctrl.Result{}, false, err
+		}
+		setSuspendedQuota(resourceQuota.Spec.Hard, workspace, now)
+		setGPUQuota(resourceQuota.Spec.Hard, workspace)
+		if _, err := r.patchIfChanged(ctx, workspace, "ResourceQuota", originalResourceQuota, &resourceQuota); err != nil {
+			reconcilerLog.Error(err, "Failed to patch ResourceQuota")
+			return ctrl.Result{}, false, err
 		}
+		r.applied.applied(workspace.Name, &resourceQuota)
 	}
-	// Check for viewerRole labels
-	for k, v := range workspaceLabels {
-		value, ok := viewerRoleLabels[k]
-		if !ok || value != v {
-			reconcilerLog.Info(fmt.Sprintf("Labels not same for viewer Role.Name %s in Namespace.Name %s", fmt.Sprintf("%s-viewer", workspace.Spec.Name), workspace.Spec.Name))
-			viewerRole.ObjectMeta.Labels = workspaceLabels
-			if err := r.Update(ctx, &viewerRole); err != nil {
-				reconcilerLog.Error(err, "Failed to update viewerRole.ObjectMeta.Labels")
-				return ctrl.Result{}, err
-			}
+
+	// Check if the workloads of the workspace are scaled down while it is suspended, and back up once resumed
+	if err := r.reconcileSuspension(ctx, workspace, now); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile suspension of Workspace")
+		return ctrl.Result{}, false, err
+	}
+
+	// Check for admin, editor and viewer Role labels
+	for _, role := range []*rbacv1.Role{&adminRole, &editorRole, &viewerRole} {
+		if r.applied.upToDate(workspace.Name, role, roleHash) {
+			continue
+		}
+		originalRole := role.DeepCopy()
+		setMetadata(&role.ObjectMeta, workspaceLabels, nil)
+		setAnnotation(&role.ObjectMeta, DesiredStateHashAnnotation, roleHash)
+		if _, err := r.patchIfChanged(ctx, workspace, "Role", originalRole, role); err != nil {
+			reconcilerLog.Error(err, fmt.Sprintf("Failed to patch Role.ObjectMeta.Labels for Role.Name %s", role.Name))
+			return ctrl.Result{}, false, err
 		}
+		r.applied.applied(workspace.Name, role)
+	}
+
+	// Bring the rules of the roles to their templates, once the changes were published for long enough
+	if err := r.reconcileRoleRules(ctx, workspace, map[string]*rbacv1.Role{"admin": &adminRole, "editor": &editorRole, "viewer": &viewerRole}, roleTemplates); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile Role rules for Workspace")
+		return ctrl.Result{}, false, err
 	}
 
 	// leaving label checking for RoleBindings
 
-	// Check if Workspace annotations are updated
-	workspaceAnnotations := workspace.Spec.Annotations
-	namespaceAnnotations := namespace.ObjectMeta.Annotations
-	resourceQuotaAnnotations := resourceQuota.ObjectMeta.Annotations
-	// Check for namespace annotations
-	for k, v := range workspaceAnnotations {
-		value, ok := namespaceAnnotations[k]
-		if !ok || value != v {
-			reconcilerLog.Info(fmt.Sprintf("Annotations not same for Namespace.Name %s", workspace.Spec.Name))
-			namespace.ObjectMeta.Annotations = workspaceAnnotations
-			if err := r.Update(ctx, namespace); err != nil {
-				reconcilerLog.Error(err, "Failed to update Namespace.ObjectMeta.Annotations for Namespace")
-				return ctrl.Result{}, err
-			}
-		}
+	// Check if the access of the workspace is due for recertification
+	if err := r.reconcileRecertification(ctx, workspace, now); err != nil {
+		reconcilerLog.Error(err, "Failed to update AccessReview condition for Workspace")
+		return ctrl.Result{}, false, err
 	}
-	// Check for resourceQuota annotations
-	for k, v := range workspaceAnnotations {
-		value, ok := resourceQuotaAnnotations[k]
-		if !ok || value != v {
-			reconcilerLog.Info(fmt.Sprintf("Annotations not same for ResourceQuota.Name %s in Namespace.Name %s", fmt.Sprintf("%s-quota", workspace.Spec.Name), workspace.Spec.Name))
-			resourceQuota.ObjectMeta.Annotations = workspaceAnnotations
-			if err := r.Update(ctx, &resourceQuota); err != nil {
-				reconcilerLog.Error(err, "Failed to update ResourceQuota.ObjectMeta.Annotations for ResourceQuota")
-				return ctrl.Result{}, err
-			}
+
+	// check if admin, editor and viewer rolebindings have the right users
+	// The users of the roles with an access schedule are only bound during its time window
+	for _, binding := range []struct {
+		roleBinding *rbacv1.RoleBinding
+		role        string
+	}{
+		{&adminRoleBinding, "admin"},
+		{&editorRoleBinding, "editor"},
+		{&viewerRoleBinding, "viewer"},
+	} {
+		roleBinding := binding.roleBinding
+		subjects, err := r.roleBindingSubjects(workspace, binding.role, teamMembers[binding.role], now)
+		if err != nil {
+			reconcilerLog.Error(err, fmt.Sprintf("Failed to compute the subjects of RoleBinding %s", roleBinding.Name))
+			return ctrl.Result{}, false, err
+		}
+		if equality.Semantic.DeepEqual(roleBinding.Subjects, subjects) {
+			continue
+		}
+		reconcilerLog.Info(fmt.Sprintf("Users not same for RoleBinding %s in Namespace.Name %s", roleBinding.Name, workspace.Spec.Name))
+		originalRoleBinding := roleBinding.DeepCopy()
+		wasBound := len(roleBinding.Subjects) > 0
+		// Only the users added to or removed from the role are audited, not the ones merely reordered
+		removedSubjects, addedSubjects := diffSubjects(roleBinding.Subjects, subjects)
+		roleBinding.Subjects = subjects
+		if err := r.Patch(ctx, roleBinding, client.MergeFrom(originalRoleBinding)); err != nil {
+			reconcilerLog.Error(err, fmt.Sprintf("Failed to patch RoleBinding %s", roleBinding.Name))
+			return ctrl.Result{}, false, err
+		}
+		for i := range removedSubjects {
+			r.audit(ctx, workspace, AuditActionSubjectRemoved, "RoleBinding", roleBinding.Name, &removedSubjects[i], nil)
+		}
+		for i := range addedSubjects {
+			r.audit(ctx, workspace, AuditActionSubjectAdded, "RoleBinding", roleBinding.Name, &addedSubjects[i], nil)
 		}
+		r.recordAccessTransition(workspace, binding.role, roleUsers(workspace, binding.role), wasBound, len(subjects) > 0)
 	}
 
-	// check if admin rolebindings has right user
-	adminUserName := workspace.Spec.Users.Admin
-	if adminUserName != adminRoleBinding.Subjects[0].Name {
-		reconcilerLog.Info(fmt.Sprintf("User not same for admin RoleBinding %s in Namespace.Name %s", fmt.Sprintf("%s-admin-rb", workspace.Spec.Name), workspace.Spec.Name))
-		adminRoleBinding.Subjects[0].Name = adminUserName
-		if err := r.Update(ctx, &adminRoleBinding); err != nil {
-			reconcilerLog.Error(err, "Failed to update admin RoleBinding")
-			return ctrl.Result{}, err
-		}
+	// Publish the effective access of the workspace
+	if err := r.reconcileAccess(ctx, workspace, &adminRoleBinding, &editorRoleBinding, &viewerRoleBinding); err != nil {
+		// The summary only reports, failing to resolve the groups should not block the workspace
+		reconcilerLog.Error(err, "Failed to update access for Workspace")
 	}
 
-	// check if editor rolebindings has right user
-	editorUserName := workspace.Spec.Users.Editor
-	if editorUserName != editorRoleBinding.Subjects[0].Name {
-		reconcilerLog.Info(fmt.Sprintf("User not same for editor RoleBinding %s in Namespace.Name %s", fmt.Sprintf("%s-editor-rb", workspace.Spec.Name), workspace.Spec.Name))
-		editorRoleBinding.Subjects[0].Name = editorUserName
-		if err := r.Update(ctx, &editorRoleBinding); err != nil {
-			reconcilerLog.Error(err, "Failed to update editor RoleBinding")
-			return ctrl.Result{}, err
-		}
+	// Check if the users of the workspace are known to the identity provider
+	if err := r.reconcileSubjects(ctx, workspace); err != nil {
+		// The verification only reports, an unreachable identity provider should not block the workspace
+		reconcilerLog.Error(err, "Failed to verify the subjects of Workspace")
 	}
 
-	// check if viewer rolebindings has right user
-	viewerUserName := workspace.Spec.Users.Viewer
-	if viewerUserName != viewerRoleBinding.Subjects[0].Name {
-		reconcilerLog.Info(fmt.Sprintf("User not same for viewer RoleBinding %s in Namespace.Name %s", fmt.Sprintf("%s-viewer-rb", workspace.Spec.Name), workspace.Spec.Name))
-		viewerRoleBinding.Subjects[0].Name = viewerUserName
-		if err := r.Update(ctx, &viewerRoleBinding); err != nil {
-			reconcilerLog.Error(err, "Failed to update viewer RoleBinding")
-			return ctrl.Result{}, err
-		}
+	// Check if the namespace is registered with the right observability tenant
+	if err := r.reconcileObservabilityTenant(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to register observability tenant for Workspace")
+		return ctrl.Result{}, false, err
 	}
 
-	// Check if resourceQuota has right cpu, memory and disk
-	// 1. checking memory
-	workspaceMemory := workspace.Spec.Resources.Memory
-	workspaceMemoryQuantity, err := quotaResource.ParseQuantity(workspaceMemory)
-	if err != nil {
-		reconcilerLog.Error(err, "Not able to parse workspace.Spec.Resources.Memory")
-		return ctrl.Result{}, err
+	// Check if the ResourceQuota usage crossed any of the alerting thresholds
+	if err := r.reconcileQuotaPressure(ctx, workspace, &resourceQuota); err != nil {
+		reconcilerLog.Error(err, "Failed to update QuotaPressure condition for Workspace")
+		return ctrl.Result{}, false, err
 	}
-	// comparing if Memory in workspace matches Memory in resourceQuota
-	if workspaceMemoryQuantity.Cmp(resourceQuota.Spec.Hard[corev1.ResourceMemory]) != 0 {
-		reconcilerLog.Info(fmt.Sprintf("Memory not same for ResourceQuota.Name %s in Namespace.Name %s", fmt.Sprintf("%s-quota", workspace.Spec.Name), workspace.Spec.Name))
-		resourceQuota.Spec.Hard[corev1.ResourceMemory] = workspaceMemoryQuantity
-		if err := r.Update(ctx, &resourceQuota); err != nil {
-			reconcilerLog.Error(err, "Failed to update resourceQuota.Spec.Hard[corev1.ResourceMemory]")
-			return ctrl.Result{}, err
-		}
+
+	// Check if the usage of a workspace in Monitor quota mode exceeds its hard limits
+	if err := r.reconcileQuotaExceeded(ctx, workspace, &resourceQuota); err != nil {
+		reconcilerLog.Error(err, "Failed to update QuotaExceeded condition for Workspace")
+		return ctrl.Result{}, false, err
 	}
-	// 2. checking CPU
-	workspaceCPU := workspace.Spec.Resources.CPU
-	workspaceCPUQuantity, err := quotaResource.ParseQuantity(workspaceCPU)
-	if err != nil {
-		reconcilerLog.Error(err, "Not able to parse workspace.Spec.Resources.Memory")
-		return ctrl.Result{}, err
+
+	// Record the applied spec in the revision history of the workspace
+	if err := r.reconcileHistory(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to update history for Workspace")
+		return ctrl.Result{}, false, err
 	}
-	// comparing if CPU in workspace matches CPU in resourceQuota
-	if workspaceCPUQuantity.Cmp(resourceQuota.Spec.Hard[corev1.ResourceCPU]) != 0 {
-		reconcilerLog.Info(fmt.Sprintf("CPU not same for ResourceQuota.Name %s in Namespace.Name %s", fmt.Sprintf("%s-quota", workspace.Spec.Name), workspace.Spec.Name))
-		resourceQuota.Spec.Hard[corev1.ResourceCPU] = workspaceCPUQuantity
-		if err := r.Update(ctx, &resourceQuota); err != nil {
-			reconcilerLog.Error(err, "Failed to update resourceQuota.Spec.Hard[corev1.ResourceCPU] for ResourceQuota")
-			return ctrl.Result{}, err
-		}
+
+	// Refresh the spend of the workspace namespace
+	if err := r.reconcileSpend(ctx, workspace); err != nil {
+		// Cost reporting is best effort, failing to reach the cost API should not block the workspace
+		reconcilerLog.Error(err, "Failed to update spend for Workspace")
 	}
-	// 3. checking disk size
-	workspaceDisk := workspace.Spec.Resources.Disk
-	workspaceDiskQuantity, err := quotaResource.ParseQuantity(workspaceDisk)
-	if err != nil {
-		reconcilerLog.Error(err, "Not able to parse workspace.Spec.Resources.Disk")
-		return ctrl.Result{}, err
+
+	// Record the usage of the workspace in its usage history
+	if err := r.reconcileUsage(ctx, workspace, &resourceQuota); err != nil {
+		reconcilerLog.Error(err, "Failed to update usage for Workspace")
+		return ctrl.Result{}, false, err
 	}
-	// comparing if Disk in workspace matches Disk in resourceQuota
-	if workspaceDiskQuantity.Cmp(resourceQuota.Spec.Hard[corev1.ResourceRequestsStorage]) != 0 {
-		reconcilerLog.Info(fmt.Sprintf("Disk not same for ResourceQuota.Name %s in Namespace.Name %s", fmt.Sprintf("%s-quota", workspace.Spec.Name), workspace.Spec.Name))
-		resourceQuota.Spec.Hard[corev1.ResourceRequestsStorage] = workspaceDiskQuantity
-		if err := r.Update(ctx, &resourceQuota); err != nil {
-			reconcilerLog.Error(err, "Failed to update resourceQuota.Spec.Hard[corev1.ResourceRequestsStorage] for ResourceQuota")
-			return ctrl.Result{}, err
-		}
+
+	// Check the isolation of the workspace against the multi-tenancy benchmarks
+	if err := r.reconcileBenchmark(ctx, workspace); err != nil {
+		// The self-check only reports, failing to run it should not block the workspace
+		reconcilerLog.Error(err, "Failed to update benchmark for Workspace")
 	}
 
-	// This will force the check for controller after every 5 seconds
+	// Check if the workspace was renamed and its previous namespace is due for retirement
+	if err := r.reconcileRename(ctx, workspace); err != nil {
+		reconcilerLog.Error(err, "Failed to reconcile rename of Workspace")
+		return ctrl.Result{}, false, err
+	}
+
+	// This will force the check for controller after every resync period
 	// This is done to maintain the namespace state, for e.g. if the namespace is deleted
 	// it should be created again to maintain the state of workspace
-	return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+	// The workspace is reconciled earlier when one of its access schedules opens or closes,
+	// or when its access review is due or expires, or when the previous namespace of a rename is retired,
+	// or when the warning period of its expiry starts or it expires, or when the pending changes of its roles are due,
+	// or when one of its hibernation windows starts or stops
+	requeueAfter := r.resyncAfter(workspace)
+	for _, next := range []time.Time{nextAccessChange(workspace, now), r.nextRecertificationChange(workspace, now), renameRetireTime(workspace), r.nextExpiryChange(workspace, now), nextRoleChange(workspace, now), nextHibernationChange(workspace, now)} {
+		if !next.IsZero() && time.Until(next) < requeueAfter {
+			requeueAfter = time.Until(next)
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, true, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// The changes of the resources owned by the workspaces trigger their reconciliation, so that a drifted or deleted
+// resource is corrected right away instead of on the next resync. The namespaces only trigger it on their deletion
+// and on the changes of their labels and annotations, not on the changes of their status.
+// The changes of a Workspace trigger the reconciliation of the Workspaces depending on it.
 func (r *WorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&environmentv1alpha1.Workspace{}).
+		Owns(&corev1.Namespace{}, builder.WithPredicates(predicate.Or(namespaceDeletionPredicate, r.RequiredLabels.driftPredicate(),
+			predicate.LabelChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Owns(&corev1.ResourceQuota{}).
+		Owns(&corev1.LimitRange{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Watches(&source.Kind{Type: &environmentv1alpha1.Workspace{}}, handler.EnqueueRequestsFromMapFunc(r.dependentWorkspaces)).
+		Watches(&source.Kind{Type: &environmentv1alpha1.WorkspaceTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.templatedWorkspaces))
+	// The workspaces are reconciled as soon as a webhook reports a change of the members of one of their teams
+	if r.TeamSync != nil {
+		b = b.Watches(&source.Channel{Source: r.TeamSync.Events()}, &handler.EnqueueRequestForObject{})
+	}
+	return b.WithEventFilter(r.Filter.predicate()).
 		Complete(r)
 }
 
@@ -503,8 +925,8 @@ func (r *WorkspaceReconciler) namespaceForWorkspace(workspace *environmentv1alph
 	ns := &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        workspace.Spec.Name,
-			Labels:      workspace.Spec.Labels,
-			Annotations: workspace.Spec.Annotations,
+			Labels:      r.RequiredLabels.Apply(namespaceLabelsForWorkspace(workspace)),
+			Annotations: namespaceAnnotationsForWorkspace(workspace),
 		},
 		Spec: corev1.NamespaceSpec{
 			Finalizers: []corev1.FinalizerName{corev1.FinalizerKubernetes},
@@ -516,17 +938,17 @@ func (r *WorkspaceReconciler) namespaceForWorkspace(workspace *environmentv1alph
 	return ns, nil
 }
 
-// ResourceQuota for Workspace
-func (r *WorkspaceReconciler) resourceQuotaForWorkspace(workspace *environmentv1alpha1.Workspace) (*corev1.ResourceQuota, error) {
-	cpu, err := r.resourceQuotaCPUForWorkspace(workspace)
+// ResourceQuota for Workspace, with the hard limits of spec.resources or of spec.budget, suspended at now
+func (r *WorkspaceReconciler) resourceQuotaForWorkspace(workspace *environmentv1alpha1.Workspace, resources environmentv1alpha1.WorkspaceResource, now time.Time) (*corev1.ResourceQuota, error) {
+	cpu, err := r.resourceQuotaCPUForWorkspace(resources)
 	if err != nil {
 		return nil, err
 	}
-	memory, err := r.resourceQuotaMemoryForWorkspace(workspace)
+	memory, err := r.resourceQuotaMemoryForWorkspace(resources)
 	if err != nil {
 		return nil, err
 	}
-	disk, err := r.resourceQuotaStorageForWorkspace(workspace)
+	disk, err := r.resourceQuotaStorageForWorkspace(resources)
 	if err != nil {
 		return nil, err
 	}
@@ -535,8 +957,8 @@ func (r *WorkspaceReconciler) resourceQuotaForWorkspace(workspace *environmentv1
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-quota", workspace.Spec.Name),
 			Namespace:   workspace.Spec.Name,
-			Labels:      workspace.Spec.Labels,
-			Annotations: workspace.Spec.Annotations,
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
 		},
 		Spec: corev1.ResourceQuotaSpec{
 			Hard: map[corev1.ResourceName]quotaResource.Quantity{
@@ -546,6 +968,11 @@ func (r *WorkspaceReconciler) resourceQuotaForWorkspace(workspace *environmentv1
 			},
 		},
 	}
+	if err := setOptionalQuota(rq.Spec.Hard, workspaceResources(workspace)); err != nil {
+		return nil, err
+	}
+	setSuspendedQuota(rq.Spec.Hard, workspace, now)
+	setGPUQuota(rq.Spec.Hard, workspace)
 	if err := ctrl.SetControllerReference(workspace, rq, r.Scheme); err != nil {
 		return nil, err
 	}
@@ -553,24 +980,24 @@ func (r *WorkspaceReconciler) resourceQuotaForWorkspace(workspace *environmentv1
 }
 
 // converts the string to Quantity
-func (r *WorkspaceReconciler) resourceQuotaCPUForWorkspace(workspace *environmentv1alpha1.Workspace) (*quotaResource.Quantity, error) {
-	cpu, err := quotaResource.ParseQuantity(workspace.Spec.Resources.CPU)
+func (r *WorkspaceReconciler) resourceQuotaCPUForWorkspace(resources environmentv1alpha1.WorkspaceResource) (*quotaResource.Quantity, error) {
+	cpu, err := quotaResource.ParseQuantity(resources.CPU)
 	if err != nil {
 		return nil, err
 	}
 	return &cpu, nil
 }
 
-func (r *WorkspaceReconciler) resourceQuotaMemoryForWorkspace(workspace *environmentv1alpha1.Workspace) (*quotaResource.Quantity, error) {
-	memory, err := quotaResource.ParseQuantity(workspace.Spec.Resources.Memory)
+func (r *WorkspaceReconciler) resourceQuotaMemoryForWorkspace(resources environmentv1alpha1.WorkspaceResource) (*quotaResource.Quantity, error) {
+	memory, err := quotaResource.ParseQuantity(resources.Memory)
 	if err != nil {
 		return nil, err
 	}
 	return &memory, nil
 }
 
-func (r *WorkspaceReconciler) resourceQuotaStorageForWorkspace(workspace *environmentv1alpha1.Workspace) (*quotaResource.Quantity, error) {
-	disk, err := quotaResource.ParseQuantity(workspace.Spec.Resources.Disk)
+func (r *WorkspaceReconciler) resourceQuotaStorageForWorkspace(resources environmentv1alpha1.WorkspaceResource) (*quotaResource.Quantity, error) {
+	disk, err := quotaResource.ParseQuantity(resources.Disk)
 	if err != nil {
 		return nil, err
 	}
@@ -578,34 +1005,16 @@ func (r *WorkspaceReconciler) resourceQuotaStorageForWorkspace(workspace *enviro
 }
 
 // Admin role for Workspace
-func (r *WorkspaceReconciler) adminRoleForWorkspace(workspace *environmentv1alpha1.Workspace) (*rbacv1.Role, error) {
+func (r *WorkspaceReconciler) adminRoleForWorkspace(workspace *environmentv1alpha1.Workspace, rules []rbacv1.PolicyRule) (*rbacv1.Role, error) {
 
 	adminRole := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-admin", workspace.Spec.Name),
 			Namespace:   workspace.Spec.Name,
-			Labels:      workspace.Spec.Labels,
-			Annotations: workspace.Spec.Annotations,
-		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				Verbs: []string{
-					"get",
-					"list",
-					"watch",
-					"create",
-					"update",
-					"patch",
-					"delete",
-				},
-				APIGroups: []string{
-					"",
-				},
-				Resources: []string{
-					"*",
-				},
-			},
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
 		},
+		Rules: rules,
 	}
 	if err := ctrl.SetControllerReference(workspace, adminRole, r.Scheme); err != nil {
 		return nil, err
@@ -614,33 +1023,16 @@ func (r *WorkspaceReconciler) adminRoleForWorkspace(workspace *environmentv1alph
 }
 
 // Editor role for Workspace
-func (r *WorkspaceReconciler) editorRoleForWorkspace(workspace *environmentv1alpha1.Workspace) (*rbacv1.Role, error) {
+func (r *WorkspaceReconciler) editorRoleForWorkspace(workspace *environmentv1alpha1.Workspace, rules []rbacv1.PolicyRule) (*rbacv1.Role, error) {
 
 	editorRole := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-editor", workspace.Spec.Name),
 			Namespace:   workspace.Spec.Name,
-			Labels:      workspace.Spec.Labels,
-			Annotations: workspace.Spec.Annotations,
-		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				Verbs: []string{
-					"get",
-					"list",
-					"watch",
-					"create",
-					"update",
-					"patch",
-				},
-				APIGroups: []string{
-					"",
-				},
-				Resources: []string{
-					"*",
-				},
-			},
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
 		},
+		Rules: rules,
 	}
 	if err := ctrl.SetControllerReference(workspace, editorRole, r.Scheme); err != nil {
 		return nil, err
@@ -649,30 +1041,16 @@ func (r *WorkspaceReconciler) editorRoleForWorkspace(workspace *environmentv1alp
 }
 
 // Viewer role for Workspace
-func (r *WorkspaceReconciler) viewerRoleForWorkspace(workspace *environmentv1alpha1.Workspace) (*rbacv1.Role, error) {
+func (r *WorkspaceReconciler) viewerRoleForWorkspace(workspace *environmentv1alpha1.Workspace, rules []rbacv1.PolicyRule) (*rbacv1.Role, error) {
 
 	viewerRole := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-viewer", workspace.Spec.Name),
 			Namespace:   workspace.Spec.Name,
-			Labels:      workspace.Spec.Labels,
-			Annotations: workspace.Spec.Annotations,
-		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				Verbs: []string{
-					"get",
-					"list",
-					"watch",
-				},
-				APIGroups: []string{
-					"",
-				},
-				Resources: []string{
-					"*",
-				},
-			},
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
 		},
+		Rules: rules,
 	}
 	if err := ctrl.SetControllerReference(workspace, viewerRole, r.Scheme); err != nil {
 		return nil, err
@@ -681,22 +1059,20 @@ func (r *WorkspaceReconciler) viewerRoleForWorkspace(workspace *environmentv1alp
 }
 
 // Admin role Binding for Workspace
-func (r *WorkspaceReconciler) adminRoleBindingForWorkspace(workspace *environmentv1alpha1.Workspace) (*rbacv1.RoleBinding, error) {
+func (r *WorkspaceReconciler) adminRoleBindingForWorkspace(workspace *environmentv1alpha1.Workspace, teamMembers map[string][]string, now time.Time) (*rbacv1.RoleBinding, error) {
+	subjects, err := r.roleBindingSubjects(workspace, "admin", teamMembers["admin"], now)
+	if err != nil {
+		return nil, err
+	}
 
 	adminRoleBinding := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-admin-rb", workspace.Spec.Name),
 			Namespace:   workspace.Spec.Name,
-			Labels:      workspace.Spec.Labels,
-			Annotations: workspace.Spec.Annotations,
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:     "User",
-				Name:     workspace.Spec.Users.Admin,
-				APIGroup: "rbac.authorization.k8s.io",
-			},
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
 		},
+		Subjects: subjects,
 		RoleRef: rbacv1.RoleRef{
 			Kind:     "Role",
 			APIGroup: "rbac.authorization.k8s.io",
@@ -710,22 +1086,20 @@ func (r *WorkspaceReconciler) adminRoleBindingForWorkspace(workspace *environmen
 }
 
 // Editor role Binding for Workspace
-func (r *WorkspaceReconciler) editorRoleBindingForWorkspace(workspace *environmentv1alpha1.Workspace) (*rbacv1.RoleBinding, error) {
+func (r *WorkspaceReconciler) editorRoleBindingForWorkspace(workspace *environmentv1alpha1.Workspace, teamMembers map[string][]string, now time.Time) (*rbacv1.RoleBinding, error) {
+	subjects, err := r.roleBindingSubjects(workspace, "editor", teamMembers["editor"], now)
+	if err != nil {
+		return nil, err
+	}
 
 	editorRoleBinding := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-editor-rb", workspace.Spec.Name),
 			Namespace:   workspace.Spec.Name,
-			Labels:      workspace.Spec.Labels,
-			Annotations: workspace.Spec.Annotations,
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:     "User",
-				Name:     workspace.Spec.Users.Editor,
-				APIGroup: "rbac.authorization.k8s.io",
-			},
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
 		},
+		Subjects: subjects,
 		RoleRef: rbacv1.RoleRef{
 			Kind:     "Role",
 			APIGroup: "rbac.authorization.k8s.io",
@@ -739,22 +1113,20 @@ func (r *WorkspaceReconciler) editorRoleBindingForWorkspace(workspace *environme
 }
 
 // Viewer role Binding for Workspace
-func (r *WorkspaceReconciler) viewerRoleBindingForWorkspace(workspace *environmentv1alpha1.Workspace) (*rbacv1.RoleBinding, error) {
+func (r *WorkspaceReconciler) viewerRoleBindingForWorkspace(workspace *environmentv1alpha1.Workspace, teamMembers map[string][]string, now time.Time) (*rbacv1.RoleBinding, error) {
+	subjects, err := r.roleBindingSubjects(workspace, "viewer", teamMembers["viewer"], now)
+	if err != nil {
+		return nil, err
+	}
 
 	viewerRoleBinding := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-viewer-rb", workspace.Spec.Name),
 			Namespace:   workspace.Spec.Name,
-			Labels:      workspace.Spec.Labels,
-			Annotations: workspace.Spec.Annotations,
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:     "User",
-				Name:     workspace.Spec.Users.Viewer,
-				APIGroup: "rbac.authorization.k8s.io",
-			},
+			Labels:      labelsForWorkspace(workspace, nil),
+			Annotations: annotationsForWorkspace(workspace),
 		},
+		Subjects: subjects,
 		RoleRef: rbacv1.RoleRef{
 			Kind:     "Role",
 			APIGroup: "rbac.authorization.k8s.io",
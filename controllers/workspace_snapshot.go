@@ -0,0 +1,388 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=environment.tf.operator.com,resources=workspacesnapshots,verbs=get;list;watch
+//+kubebuilder:rbac:groups=environment.tf.operator.com,resources=workspacesnapshots/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list
+
+const (
+	// WorkspaceSnapshotLabel marks the ConfigMaps holding the content of the in-cluster snapshots with the snapshot name
+	WorkspaceSnapshotLabel = "environment.tf.operator.com/snapshot"
+
+	// SnapshotSecretKeysAnnotation records the comma separated keys of a captured Secret, whose data is never captured
+	SnapshotSecretKeysAnnotation = "environment.tf.operator.com/snapshot-secret-keys"
+
+	// snapshotContentKey is the key of the gzipped content in the ConfigMap of an in-cluster snapshot
+	snapshotContentKey = "snapshot.json.gz"
+)
+
+// snapshotKinds are the group version kinds of the tenant objects a snapshot can capture
+var snapshotKinds = map[environmentv1alpha1.WorkspaceSnapshotKind]schema.GroupVersionKind{
+	environmentv1alpha1.SnapshotConfigMap:   corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+	environmentv1alpha1.SnapshotSecret:      corev1.SchemeGroupVersion.WithKind("Secret"),
+	environmentv1alpha1.SnapshotService:     corev1.SchemeGroupVersion.WithKind("Service"),
+	environmentv1alpha1.SnapshotDeployment:  appsv1.SchemeGroupVersion.WithKind("Deployment"),
+	environmentv1alpha1.SnapshotStatefulSet: appsv1.SchemeGroupVersion.WithKind("StatefulSet"),
+	environmentv1alpha1.SnapshotDaemonSet:   appsv1.SchemeGroupVersion.WithKind("DaemonSet"),
+	environmentv1alpha1.SnapshotCronJob:     batchv1.SchemeGroupVersion.WithKind("CronJob"),
+}
+
+// snapshotContent is the document stored for a snapshot
+type snapshotContent struct {
+	// Workspace is the captured Workspace, without its status and server-set metadata.
+	// The objects the operator manages in the namespace are generated from its spec.
+	Workspace *environmentv1alpha1.Workspace `json:"workspace"`
+
+	// Objects are the captured tenant objects, without their status and server-set metadata
+	Objects []unstructured.Unstructured `json:"objects"`
+}
+
+// SnapshotStore stores the content of the snapshots in an object storage
+type SnapshotStore interface {
+	// Put stores the content under the key and returns its location
+	Put(ctx context.Context, key string, content []byte) (string, error)
+	// Get returns the content stored at the location
+	Get(ctx context.Context, location string) ([]byte, error)
+}
+
+// NewHTTPSnapshotStore returns a SnapshotStore putting the contents under the bucket URL, e.g. an S3 compatible
+// or GCS bucket endpoint. The token is sent as a bearer token when it is not empty.
+func NewHTTPSnapshotStore(bucketURL, token string) SnapshotStore {
+	return &httpSnapshotStore{bucketURL: strings.TrimSuffix(bucketURL, "/"), token: token, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+type httpSnapshotStore struct {
+	bucketURL string
+	token     string
+	client    *http.Client
+}
+
+func (s *httpSnapshotStore) Put(ctx context.Context, key string, content []byte) (string, error) {
+	u := fmt.Sprintf("%s/%s", s.bucketURL, (&url.URL{Path: key}).EscapedPath())
+	resp, err := s.do(ctx, http.MethodPut, u, content)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return u, nil
+}
+
+func (s *httpSnapshotStore) Get(ctx context.Context, location string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s *httpSnapshotStore) do(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("snapshot store returned %s for %s", resp.Status, u)
+	}
+	return resp, nil
+}
+
+// WorkspaceSnapshotReconciler takes the WorkspaceSnapshots. A snapshot captures the Workspace and the selected
+// objects of its namespace once, it is never updated afterwards.
+type WorkspaceSnapshotReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// Namespace is the namespace the ConfigMaps of the in-cluster snapshots are created in
+	Namespace string
+
+	// Store stores the content of the ObjectStorage snapshots. Such snapshots fail when it is nil.
+	Store SnapshotStore
+
+	// Filter scopes the snapshots to the workspaces of the operator instance
+	Filter *WorkspaceFilter
+}
+
+// Reconcile captures the Workspace of a pending snapshot and stores the content
+func (r *WorkspaceSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	reconcilerLog := ctrl.Log.WithName("snapshot").WithValues("snapshot", req.Name)
+
+	snapshot := &environmentv1alpha1.WorkspaceSnapshot{}
+	if err := r.Get(ctx, req.NamespacedName, snapshot); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if snapshot.Status.Phase == environmentv1alpha1.SnapshotCompleted || snapshot.Status.Phase == environmentv1alpha1.SnapshotFailed {
+		return ctrl.Result{}, nil
+	}
+
+	workspace := &environmentv1alpha1.Workspace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: snapshot.Spec.Workspace}, workspace); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, r.fail(ctx, snapshot, fmt.Sprintf("Workspace %s not found", snapshot.Spec.Workspace))
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !r.Filter.Matches(workspace) {
+		return ctrl.Result{}, nil
+	}
+
+	content, err := r.capture(ctx, snapshot, workspace)
+	if err != nil {
+		reconcilerLog.Error(err, fmt.Sprintf("Failed to capture Workspace %s", workspace.Name))
+		return ctrl.Result{}, err
+	}
+	data, err := encodeSnapshotContent(content)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	location, err := r.store(ctx, snapshot, workspace, data)
+	if err != nil {
+		if apierrors.IsInvalid(err) || apierrors.IsRequestEntityTooLargeError(err) {
+			return ctrl.Result{}, r.fail(ctx, snapshot, fmt.Sprintf("Snapshot content of %d bytes can not be stored in a ConfigMap, use the ObjectStorage storage", len(data)))
+		}
+		reconcilerLog.Error(err, "Failed to store snapshot content")
+		return ctrl.Result{}, err
+	}
+	if location == "" {
+		return ctrl.Result{}, r.fail(ctx, snapshot, "No object storage is configured on the operator")
+	}
+
+	now := metav1.Now()
+	snapshot.Status = environmentv1alpha1.WorkspaceSnapshotStatus{
+		Phase:      environmentv1alpha1.SnapshotCompleted,
+		CapturedAt: &now,
+		Namespace:  workspace.Spec.Name,
+		Location:   location,
+		Objects:    int32(len(content.Objects)),
+	}
+	if err := r.Status().Update(ctx, snapshot); err != nil {
+		return ctrl.Result{}, err
+	}
+	reconcilerLog.Info(fmt.Sprintf("Captured %d objects of Workspace %s into %s", len(content.Objects), workspace.Name, location))
+	if r.Recorder != nil {
+		r.Recorder.Event(snapshot, "Normal", "SnapshotCompleted", fmt.Sprintf("Captured %d objects of Workspace %s", len(content.Objects), workspace.Name))
+	}
+	return ctrl.Result{}, nil
+}
+
+// fail marks the snapshot as Failed with the message
+func (r *WorkspaceSnapshotReconciler) fail(ctx context.Context, snapshot *environmentv1alpha1.WorkspaceSnapshot, message string) error {
+	snapshot.Status.Phase = environmentv1alpha1.SnapshotFailed
+	snapshot.Status.Message = message
+	if err := r.Status().Update(ctx, snapshot); err != nil {
+		return err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(snapshot, "Warning", "SnapshotFailed", message)
+	}
+	return nil
+}
+
+// capture reads the Workspace and the included kinds of objects of its namespace.
+// The objects owned by another object, including the ones the operator manages, are skipped as they are regenerated.
+func (r *WorkspaceSnapshotReconciler) capture(ctx context.Context, snapshot *environmentv1alpha1.WorkspaceSnapshot, workspace *environmentv1alpha1.Workspace) (*snapshotContent, error) {
+	captured := &environmentv1alpha1.Workspace{
+		TypeMeta: metav1.TypeMeta{APIVersion: environmentv1alpha1.GroupVersion.String(), Kind: "Workspace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        workspace.Name,
+			Labels:      workspace.Labels,
+			Annotations: workspace.Annotations,
+		},
+		Spec: workspace.Spec,
+	}
+	delete(captured.Annotations, LastAppliedSpecAnnotation)
+	content := &snapshotContent{Workspace: captured}
+
+	for _, kind := range snapshot.Spec.Include {
+		gvk, ok := snapshotKinds[kind]
+		if !ok {
+			continue
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.List(ctx, list, client.InNamespace(workspace.Spec.Name)); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if !snapshotIncludes(obj) {
+				continue
+			}
+			obj.SetGroupVersionKind(gvk)
+			sanitizeSnapshotObject(obj)
+			content.Objects = append(content.Objects, *obj)
+		}
+	}
+	return content, nil
+}
+
+// snapshotIncludes reports whether the tenant object is captured
+func snapshotIncludes(obj *unstructured.Unstructured) bool {
+	if len(obj.GetOwnerReferences()) > 0 || obj.GetLabels()[ManagedByLabel] == ManagedByValue {
+		return false
+	}
+	// The CA bundle and the ServiceAccount tokens are created by Kubernetes in every namespace
+	if obj.GetKind() == "ConfigMap" && obj.GetName() == "kube-root-ca.crt" {
+		return false
+	}
+	secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+	return secretType != string(corev1.SecretTypeServiceAccountToken)
+}
+
+// sanitizeSnapshotObject removes the status and the server-set fields of the object, so that it can be created again.
+// Only the key names of the Secrets are kept.
+func sanitizeSnapshotObject(obj *unstructured.Unstructured) {
+	unstructured.RemoveNestedField(obj.Object, "status")
+	for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp", "managedFields", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	switch obj.GetKind() {
+	case "Secret":
+		data, _, _ := unstructured.NestedMap(obj.Object, "data")
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		unstructured.RemoveNestedField(obj.Object, "data")
+		unstructured.RemoveNestedField(obj.Object, "stringData")
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[SnapshotSecretKeysAnnotation] = strings.Join(keys, ",")
+		obj.SetAnnotations(annotations)
+	case "Service":
+		// The cluster IPs and node ports are allocated again
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		ports, _, _ := unstructured.NestedSlice(obj.Object, "spec", "ports")
+		for _, port := range ports {
+			if port, ok := port.(map[string]interface{}); ok {
+				delete(port, "nodePort")
+			}
+		}
+		if ports != nil {
+			_ = unstructured.SetNestedSlice(obj.Object, ports, "spec", "ports")
+		}
+	}
+}
+
+// encodeSnapshotContent returns the gzipped JSON of the content
+func encodeSnapshotContent(content *snapshotContent) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeSnapshotContent decodes the gzipped JSON of a content
+func decodeSnapshotContent(data []byte) (*snapshotContent, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	content := &snapshotContent{}
+	if err := json.NewDecoder(r).Decode(content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// store stores the content of the snapshot and returns its location.
+// It returns an empty location when the snapshot is stored in the object storage and none is configured.
+func (r *WorkspaceSnapshotReconciler) store(ctx context.Context, snapshot *environmentv1alpha1.WorkspaceSnapshot, workspace *environmentv1alpha1.Workspace, data []byte) (string, error) {
+	if snapshot.Spec.Storage == environmentv1alpha1.SnapshotObjectStorage {
+		if r.Store == nil {
+			return "", nil
+		}
+		return r.Store.Put(ctx, fmt.Sprintf("%s/%s.json.gz", workspace.Name, snapshot.Name), data)
+	}
+
+	// The ConfigMap is garbage collected with the snapshot
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("snapshot-%s", snapshot.Name),
+			Namespace: r.Namespace,
+			Labels: map[string]string{
+				WorkspaceSnapshotLabel: snapshot.Name,
+				WorkspaceLabel:         workspace.Name,
+			},
+		},
+		BinaryData: map[string][]byte{snapshotContentKey: data},
+	}
+	if err := ctrl.SetControllerReference(snapshot, configMap, r.Scheme); err != nil {
+		return "", err
+	}
+	if err := r.Create(ctx, configMap); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", configMap.Namespace, configMap.Name), nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WorkspaceSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("workspace-snapshot").
+		For(&environmentv1alpha1.WorkspaceSnapshot{}).
+		Complete(r)
+}
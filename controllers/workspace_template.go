@@ -0,0 +1,188 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/internal/logging"
+)
+
+//+kubebuilder:rbac:groups=environment.tf.operator.com,resources=workspacetemplates,verbs=get;list;watch
+
+// WorkspaceTemplateIndex indexes the Workspaces by the WorkspaceTemplate of their spec.templateRef
+const WorkspaceTemplateIndex = "spec.templateRef"
+
+// WorkspaceTemplateRef returns the WorkspaceTemplate of the spec.templateRef of a Workspace
+func WorkspaceTemplateRef(obj client.Object) []string {
+	workspace, ok := obj.(*environmentv1alpha1.Workspace)
+	if !ok || workspace.Spec.TemplateRef == "" {
+		return nil
+	}
+	return []string{workspace.Spec.TemplateRef}
+}
+
+// IndexWorkspaceTemplates registers WorkspaceTemplateIndex in the cache of the manager
+func IndexWorkspaceTemplates(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &environmentv1alpha1.Workspace{}, WorkspaceTemplateIndex, WorkspaceTemplateRef)
+}
+
+// workspaceTemplate returns the WorkspaceTemplate of the workspace, or nil when the workspace has no template
+func (r *WorkspaceReconciler) workspaceTemplate(ctx context.Context, workspace *environmentv1alpha1.Workspace) (*environmentv1alpha1.WorkspaceTemplate, error) {
+	if workspace.Spec.TemplateRef == "" {
+		return nil, nil
+	}
+	template := &environmentv1alpha1.WorkspaceTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: workspace.Spec.TemplateRef}, template); err != nil {
+		return nil, fmt.Errorf("failed to get WorkspaceTemplate %s: %w", workspace.Spec.TemplateRef, err)
+	}
+	return template, nil
+}
+
+// reconcileTemplate resolves the WorkspaceTemplate of the workspace, merged with the fields of the workspace,
+// into status.template. The objects of the workspace are then generated from the resolved values.
+func (r *WorkspaceReconciler) reconcileTemplate(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	template, err := r.workspaceTemplate(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	var resolved *environmentv1alpha1.WorkspaceTemplateStatus
+	if template != nil {
+		resolved = resolveTemplate(workspace, template)
+	}
+	if equality.Semantic.DeepEqual(workspace.Status.Template, resolved) {
+		return nil
+	}
+	if resolved != nil {
+		ctrl.Log.WithName("reconciler").WithValues(logging.WorkspaceKey, workspace.Name).Info(fmt.Sprintf("Resolved WorkspaceTemplate %s generation %d for Workspace %s",
+			resolved.Name, resolved.Generation, workspace.Name))
+	}
+	workspace.Status.Template = resolved
+	return r.Status().Update(ctx, workspace)
+}
+
+// resolveTemplate merges the template with the fields of the workspace, the fields set on the workspace,
+// and the keys of its labels and annotations, taking precedence over the ones of the template
+func resolveTemplate(workspace *environmentv1alpha1.Workspace, template *environmentv1alpha1.WorkspaceTemplate) *environmentv1alpha1.WorkspaceTemplateStatus {
+	resolved := &environmentv1alpha1.WorkspaceTemplateStatus{
+		Name:        template.Name,
+		Generation:  template.Generation,
+		Resources:   templateResources(workspace.Spec.Resources, template.Spec.Resources),
+		Labels:      mergeStrings(template.Spec.Labels, workspace.Spec.Labels),
+		Annotations: mergeStrings(template.Spec.Annotations, workspace.Spec.Annotations),
+	}
+	switch {
+	case workspace.Spec.NetworkIsolation != nil:
+		resolved.NetworkIsolation = *workspace.Spec.NetworkIsolation
+	case template.Spec.NetworkIsolation != nil:
+		resolved.NetworkIsolation = *template.Spec.NetworkIsolation
+	}
+	return resolved
+}
+
+// templateResources returns the quota preset of a template overridden by the quantities set in resources
+func templateResources(resources environmentv1alpha1.WorkspaceResource, preset *environmentv1alpha1.WorkspaceResource) environmentv1alpha1.WorkspaceResource {
+	if preset == nil {
+		return resources
+	}
+	merged := *preset
+	if resources.CPU != "" {
+		merged.CPU = resources.CPU
+	}
+	if resources.Memory != "" {
+		merged.Memory = resources.Memory
+	}
+	if resources.Disk != "" {
+		merged.Disk = resources.Disk
+	}
+	mergeRequestsAndLimits(&merged, resources)
+	mergeObjectCounts(&merged, resources)
+	return merged
+}
+
+// mergeStrings returns the keys of base overridden by the keys of override, or nil when both are empty
+func mergeStrings(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// resolvedTemplate returns the values of the template of the workspace resolved in status.template,
+// or nil when the workspace has no template or its template was not resolved yet
+func resolvedTemplate(workspace *environmentv1alpha1.Workspace) *environmentv1alpha1.WorkspaceTemplateStatus {
+	resolved := workspace.Status.Template
+	if resolved == nil || workspace.Spec.TemplateRef == "" || resolved.Name != workspace.Spec.TemplateRef {
+		return nil
+	}
+	return resolved
+}
+
+// workspaceResources returns the cpu, memory, disk, requests, limits and object counts of the workspace, spec.resources merged with the quota preset of its template
+func workspaceResources(workspace *environmentv1alpha1.Workspace) environmentv1alpha1.WorkspaceResource {
+	if resolved := resolvedTemplate(workspace); resolved != nil {
+		return resolved.Resources
+	}
+	return workspace.Spec.Resources
+}
+
+// annotationsForWorkspace returns the annotations of the objects generated for the workspace:
+// spec.annotations merged with the annotations of its template
+func annotationsForWorkspace(workspace *environmentv1alpha1.Workspace) map[string]string {
+	if resolved := resolvedTemplate(workspace); resolved != nil {
+		return resolved.Annotations
+	}
+	return workspace.Spec.Annotations
+}
+
+// networkIsolated reports whether the namespace of the workspace is isolated, with spec.networkIsolation
+// or the networkIsolation of its template
+func networkIsolated(workspace *environmentv1alpha1.Workspace) bool {
+	if resolved := resolvedTemplate(workspace); resolved != nil {
+		return resolved.NetworkIsolation
+	}
+	return workspace.Spec.NetworkIsolation != nil && *workspace.Spec.NetworkIsolation
+}
+
+// templatedWorkspaces returns the requests of the Workspaces created from a WorkspaceTemplate,
+// so that the changes of the template are rolled out to them
+func (r *WorkspaceReconciler) templatedWorkspaces(obj client.Object) []ctrl.Request {
+	workspaces := &environmentv1alpha1.WorkspaceList{}
+	if err := r.List(context.Background(), workspaces, client.MatchingFields{WorkspaceTemplateIndex: obj.GetName()}); err != nil {
+		ctrl.Log.WithName("reconciler").Error(err, fmt.Sprintf("Failed to list the Workspaces of WorkspaceTemplate %s", obj.GetName()))
+		return nil
+	}
+	requests := make([]ctrl.Request, 0, len(workspaces.Items))
+	for _, workspace := range workspaces.Items {
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: workspace.Name}})
+	}
+	return requests
+}
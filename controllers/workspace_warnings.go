@@ -0,0 +1,108 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch
+
+// ResourcesWarningFactor is how many times the typical resources of its class a workspace
+// can request before it is warned at admission
+const ResourcesWarningFactor = 4
+
+// warnings returns the non-blocking warnings about the suspicious fields of the workspace, sent back to the user
+// in the Warning headers of the admission response. They are best effort, failing to compute one is not an error.
+func (v *WorkspaceValidator) warnings(ctx context.Context, workspace *environmentv1alpha1.Workspace) []string {
+	if v.Client == nil {
+		return nil
+	}
+	var warnings []string
+
+	// Grants binding ClusterRoles with wildcard permissions in shared namespaces
+	for i, grant := range workspace.Spec.Grants {
+		if grant.ClusterRole == "" {
+			continue
+		}
+		field := fmt.Sprintf("spec.grants[%d].clusterRole", i)
+		clusterRole := &rbacv1.ClusterRole{}
+		err := v.Client.Get(ctx, types.NamespacedName{Name: grant.ClusterRole}, clusterRole)
+		if apierrors.IsNotFound(err) {
+			warnings = append(warnings, fmt.Sprintf("%s: ClusterRole %s does not exist", field, grant.ClusterRole))
+			continue
+		} else if err != nil {
+			continue
+		}
+		if hasWildcardRule(clusterRole.Rules) {
+			warnings = append(warnings, fmt.Sprintf("%s: ClusterRole %s grants wildcard permissions to the workspace users in namespace %s",
+				field, grant.ClusterRole, grant.Namespace))
+		}
+	}
+
+	// Resources far exceeding the typical size of the workspace class
+	if workspace.Spec.ClassName != "" {
+		class := &environmentv1alpha1.WorkspaceClass{}
+		if err := v.Client.Get(ctx, types.NamespacedName{Name: workspace.Spec.ClassName}, class); err == nil && class.Spec.Resources != nil {
+			for _, resource := range []struct {
+				field     string
+				requested string
+				typical   string
+			}{
+				{"spec.resources.cpu", workspace.Spec.Resources.CPU, class.Spec.Resources.CPU},
+				{"spec.resources.memory", workspace.Spec.Resources.Memory, class.Spec.Resources.Memory},
+				{"spec.resources.disk", workspace.Spec.Resources.Disk, class.Spec.Resources.Disk},
+			} {
+				requested, err := quotaResource.ParseQuantity(resource.requested)
+				if err != nil {
+					continue
+				}
+				typical, err := quotaResource.ParseQuantity(resource.typical)
+				if err != nil || typical.IsZero() {
+					continue
+				}
+				if requested.MilliValue() > typical.MilliValue()*ResourcesWarningFactor {
+					warnings = append(warnings, fmt.Sprintf("%s: %s is more than %d times the typical %s of WorkspaceClass %s",
+						resource.field, resource.requested, ResourcesWarningFactor, resource.typical, class.Name))
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// hasWildcardRule reports whether one of the rules grants all the verbs, resources or API groups
+func hasWildcardRule(rules []rbacv1.PolicyRule) bool {
+	for _, rule := range rules {
+		for _, values := range [][]string{rule.Verbs, rule.Resources, rule.APIGroups} {
+			for _, value := range values {
+				if value == "*" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	quotaResource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// WorkspaceValidatorPath is the path the validating webhook of the Workspaces is served on
+const WorkspaceValidatorPath = "/validate-environment-tf-operator-com-v1alpha1-workspace"
+
+//+kubebuilder:webhook:path=/validate-environment-tf-operator-com-v1alpha1-workspace,mutating=false,failurePolicy=fail,sideEffects=None,groups=environment.tf.operator.com,resources=workspaces,verbs=create;update,versions=v1alpha1,name=vworkspace.kb.io,admissionReviewVersions=v1
+
+// WorkspaceValidator rejects Workspaces which do not comply with the operator policies
+type WorkspaceValidator struct {
+	// NamespacePolicy restricts the target namespaces of the Workspaces
+	NamespacePolicy *NamespacePolicy
+
+	// Client creates the SubjectAccessReviews of the users setting the force cleanup annotation or adding grants
+	// and reads the ClusterRoles and WorkspaceClasses the warnings are computed from
+	Client client.Client
+
+	// RequiredLabels are the labels the Workspaces must set in spec.labels when they have no default
+	RequiredLabels RequiredLabels
+
+	// GrantPolicy restricts the shared namespaces and ClusterRoles of spec.grants, no grant is allowed when empty
+	GrantPolicy GrantPolicy
+
+	// RoleAPIGroups are the API groups covered by the built-in rules of the Roles of the operator
+	RoleAPIGroups []string
+
+	// RoleAllowedAPIGroups are the API groups spec.rbac.apiGroups can list, RoleAPIGroups when it is empty
+	RoleAllowedAPIGroups []string
+
+	// OperatorUsername is the username of the operator, the only one allowed to change the deactivated users
+	// of the workspaces, DefaultOperatorUsername when empty
+	OperatorUsername string
+}
+
+// Handle validates the created or updated Workspace
+func (v *WorkspaceValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	workspace := &environmentv1alpha1.Workspace{}
+	if err := json.Unmarshal(req.Object.Raw, workspace); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// A Workspace being deleted is only updated to release its finalizers, which must not be blocked by
+	// a policy changed since its creation
+	if workspace.DeletionTimestamp != nil {
+		return admission.Allowed("")
+	}
+
+	// Only approvers can approve a denied namespace
+	approved := workspace.Annotations[ApprovedNamespaceAnnotation]
+	previouslyApproved := ""
+	previouslyDeactivated := ""
+	previouslyForced := false
+	previousNamespace := ""
+	var previousDeleteAt *metav1.Time
+	var previousTTL *metav1.Duration
+	var previous *environmentv1alpha1.Workspace
+	if req.Operation == admissionv1.Update {
+		oldWorkspace := &environmentv1alpha1.Workspace{}
+		if err := json.Unmarshal(req.OldObject.Raw, oldWorkspace); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		previous = oldWorkspace
+		previouslyApproved = oldWorkspace.Annotations[ApprovedNamespaceAnnotation]
+		previouslyDeactivated = oldWorkspace.Annotations[DeactivatedUsersAnnotation]
+		previouslyForced = forceCleanupRequested(oldWorkspace)
+		previousNamespace = oldWorkspace.Spec.Name
+		previousDeleteAt = oldWorkspace.Spec.DeleteAt
+		previousTTL = oldWorkspace.Spec.TTL
+		if err := validateRename(oldWorkspace, workspace); err != nil {
+			return admission.Denied(err.Error())
+		}
+		if err := validateRestoreFrom(oldWorkspace, workspace); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	if approved != "" && approved != previouslyApproved && !v.NamespacePolicy.IsApprover(req.UserInfo.Username, req.UserInfo.Groups) {
+		return admission.Denied(fmt.Sprintf("%s can only be set by the namespace approvers", ApprovedNamespaceAnnotation))
+	}
+
+	// Only the operator, on the events of the identity provider, can deactivate or reactivate users,
+	// so that a deactivated user can not restore their own access
+	if workspace.Annotations[DeactivatedUsersAnnotation] != previouslyDeactivated && req.UserInfo.Username != v.operatorUsername() {
+		return admission.Denied(fmt.Sprintf("%s can only be changed by the operator", DeactivatedUsersAnnotation))
+	}
+
+	// Only the users allowed the force-cleanup verb on the workspace can request a force cleanup
+	if forceCleanupRequested(workspace) && !previouslyForced {
+		allowed, err := v.forceCleanupAllowed(ctx, req, workspace)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if !allowed {
+			return admission.Denied(fmt.Sprintf("%s can only be set by the users allowed to %s workspaces", ForceCleanupAnnotation, ForceCleanupVerb))
+		}
+	}
+
+	// Only the target namespace of a new Workspace or of a renamed one is checked against the namespace policy,
+	// so that the existing Workspaces can still be updated after the policy was restricted
+	if workspace.Spec.Name != previousNamespace {
+		if err := v.NamespacePolicy.Check(workspace); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+
+	// Only the shared namespaces and ClusterRoles allowed by the operator can be granted, and only by the users
+	// allowed to bind the ClusterRole of a new grant in its shared namespace themselves
+	if err := v.GrantPolicy.Check(workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	for _, grant := range newGrantBindings(previous, workspace) {
+		allowed, err := v.bindAllowed(ctx, req, grant)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if !allowed {
+			return admission.Denied(fmt.Sprintf("grant %s: ClusterRole %s can only be granted in namespace %s by the users allowed to bind it there",
+				grant.Name, grant.ClusterRole, grant.Namespace))
+		}
+	}
+	// Only the target namespace of a new Workspace or of a renamed one is checked, so that existing duplicates can still be fixed
+	if workspace.Spec.Name != previousNamespace {
+		if claimed, err := v.namespaceClaimed(ctx, workspace); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		} else if claimed != "" {
+			return admission.Denied(claimed)
+		}
+	}
+	// The quantities missing from spec.resources may be set by the template of the workspace. A template which
+	// does not exist yet is reported by the reconciliation, so that both can be applied at once.
+	resources := workspace.Spec.Resources
+	checkResources := true
+	if workspace.Spec.TemplateRef != "" {
+		template := &environmentv1alpha1.WorkspaceTemplate{}
+		if v.Client == nil {
+			checkResources = false
+		} else if err := v.Client.Get(ctx, types.NamespacedName{Name: workspace.Spec.TemplateRef}, template); apierrors.IsNotFound(err) {
+			checkResources = false
+		} else if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		resources = templateResources(resources, template.Spec.Resources)
+	}
+	if checkResources {
+		if err := validateResources(workspace, resources); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	if err := validateGPU(workspace.Spec.GPU); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateEgress(workspace.Spec.Networking); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateAccessSchedules(workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateHibernation(workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateLogging(workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateSubjects(workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateRBAC(workspace, allowedRoleAPIGroups(v.RoleAllowedAPIGroups, v.RoleAPIGroups)); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateLimitRange(workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateDependsOn(workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateDeletionPolicy(workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateDeleteAt(previousDeleteAt, workspace.Spec.DeleteAt, time.Now()); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateTTL(previousTTL, workspace.Spec.TTL, workspace.CreationTimestamp, time.Now()); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := v.RequiredLabels.Check(previous, workspace); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("").WithWarnings(v.warnings(ctx, workspace)...)
+}
+
+// operatorUsername returns the username of the operator
+func (v *WorkspaceValidator) operatorUsername() string {
+	if v.OperatorUsername == "" {
+		return DefaultOperatorUsername
+	}
+	return v.OperatorUsername
+}
+
+// forceCleanupAllowed checks with a SubjectAccessReview that the requesting user is allowed the force-cleanup verb on the workspace
+func (v *WorkspaceValidator) forceCleanupAllowed(ctx context.Context, req admission.Request, workspace *environmentv1alpha1.Workspace) (bool, error) {
+	return v.accessAllowed(ctx, req, &authorizationv1.ResourceAttributes{
+		Group:    environmentv1alpha1.GroupVersion.Group,
+		Resource: "workspaces",
+		Verb:     ForceCleanupVerb,
+		Name:     workspace.Name,
+	})
+}
+
+// bindAllowed checks with a SubjectAccessReview that the requesting user is allowed to bind the ClusterRole
+// of the grant in its shared namespace
+func (v *WorkspaceValidator) bindAllowed(ctx context.Context, req admission.Request, grant environmentv1alpha1.WorkspaceGrant) (bool, error) {
+	return v.accessAllowed(ctx, req, &authorizationv1.ResourceAttributes{
+		Namespace: grant.Namespace,
+		Group:     rbacv1.GroupName,
+		Resource:  "clusterroles",
+		Verb:      "bind",
+		Name:      grant.ClusterRole,
+	})
+}
+
+// accessAllowed checks with a SubjectAccessReview that the requesting user is allowed the access to the resource
+func (v *WorkspaceValidator) accessAllowed(ctx context.Context, req admission.Request, attributes *authorizationv1.ResourceAttributes) (bool, error) {
+	if v.Client == nil {
+		return false, nil
+	}
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               req.UserInfo.Username,
+			Groups:             req.UserInfo.Groups,
+			UID:                req.UserInfo.UID,
+			Extra:              extra,
+			ResourceAttributes: attributes,
+		},
+	}
+	if err := v.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// namespaceClaimed returns why the target namespace of the workspace can not be claimed by it,
+// or an empty string when no other Workspace claims it
+func (v *WorkspaceValidator) namespaceClaimed(ctx context.Context, workspace *environmentv1alpha1.Workspace) (string, error) {
+	if v.Client == nil {
+		return "", nil
+	}
+	claiming, err := claimingWorkspaces(ctx, v.Client, workspace)
+	if err != nil || len(claiming) == 0 {
+		return "", err
+	}
+	return fmt.Sprintf("Namespace %s is already claimed by Workspace %s", workspace.Spec.Name, claiming[0].Name), nil
+}
+
+// validateResources checks that the quantities of spec.resources the ResourceQuota of the workspace is made of,
+// merged with the quota preset of its template, are set and not negative, as the reconciliation would otherwise fail.
+// They are computed from spec.budget when it is set. The separate requests and limits are optional.
+func validateResources(workspace *environmentv1alpha1.Workspace, resources environmentv1alpha1.WorkspaceResource) error {
+	for _, resource := range requestsAndLimitsFields(resources) {
+		if resource.value == "" {
+			continue
+		}
+		if err := validateQuantity(resource.field, resource.value); err != nil {
+			return err
+		}
+	}
+	if workspace.Spec.Budget != nil {
+		return nil
+	}
+	for _, resource := range []struct {
+		field, value string
+	}{
+		{"spec.resources.cpu", resources.CPU},
+		{"spec.resources.memory", resources.Memory},
+		{"spec.resources.disk", resources.Disk},
+	} {
+		if resource.value == "" {
+			return fmt.Errorf("%s is required when spec.budget is not set and the template of spec.templateRef has no default", resource.field)
+		}
+		if err := validateQuantity(resource.field, resource.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateQuantity checks that the value of a field of spec.resources is a valid quantity and not negative
+func validateQuantity(field, value string) error {
+	quantity, err := quotaResource.ParseQuantity(value)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a valid quantity: %w", field, value, err)
+	}
+	if quantity.Sign() < 0 {
+		return fmt.Errorf("%s: %s can not be negative", field, value)
+	}
+	return nil
+}
+
+// newGrantBindings returns the grants of the workspace binding a ClusterRole in a shared namespace
+// the previous version of the workspace did not bind it in
+func newGrantBindings(previous, workspace *environmentv1alpha1.Workspace) []environmentv1alpha1.WorkspaceGrant {
+	var grants []environmentv1alpha1.WorkspaceGrant
+	for _, grant := range workspace.Spec.Grants {
+		if grant.ClusterRole == "" {
+			continue
+		}
+		bound := false
+		if previous != nil {
+			for _, previousGrant := range previous.Spec.Grants {
+				if previousGrant.Namespace == grant.Namespace && previousGrant.ClusterRole == grant.ClusterRole {
+					bound = true
+					break
+				}
+			}
+		}
+		if !bound {
+			grants = append(grants, grant)
+		}
+	}
+	return grants
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+func TestWorkspaceValidatorNamespacePolicy(t *testing.T) {
+	policy, err := NewNamespacePolicy("", "prod-.*", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace := func(namespace string, deleting bool) *environmentv1alpha1.Workspace {
+		ws := &environmentv1alpha1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+			Spec: environmentv1alpha1.WorkspaceSpec{
+				Name:      namespace,
+				Resources: environmentv1alpha1.WorkspaceResource{CPU: "2", Memory: "4Gi", Disk: "10Gi"},
+			},
+		}
+		if deleting {
+			ws.DeletionTimestamp = &metav1.Time{}
+		}
+		return ws
+	}
+	raw := func(ws *environmentv1alpha1.Workspace) runtime.RawExtension {
+		data, err := json.Marshal(ws)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		previous  *environmentv1alpha1.Workspace
+		workspace *environmentv1alpha1.Workspace
+		allowed   bool
+	}{
+		{name: "created in an allowed namespace", operation: admissionv1.Create, workspace: workspace("team-a", false), allowed: true},
+		{name: "created in a denied namespace", operation: admissionv1.Create, workspace: workspace("prod-a", false)},
+		{name: "renamed to a denied namespace", operation: admissionv1.Update, previous: workspace("team-a", false), workspace: workspace("prod-a", false)},
+		{name: "updated in a namespace denied since", operation: admissionv1.Update, previous: workspace("prod-a", false), workspace: workspace("prod-a", false), allowed: true},
+		{name: "finalizer released while deleted", operation: admissionv1.Update, previous: workspace("prod-a", true), workspace: workspace("prod-a", true), allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: tt.operation, Object: raw(tt.workspace)}}
+			if tt.previous != nil {
+				req.OldObject = raw(tt.previous)
+			}
+			validator := &WorkspaceValidator{NamespacePolicy: policy}
+			if got := validator.Handle(context.Background(), req); got.Allowed != tt.allowed {
+				t.Errorf("Handle() allowed = %t, want %t: %v", got.Allowed, tt.allowed, got.Result)
+			}
+		})
+	}
+}
+
+func TestWorkspaceValidatorDeactivatedUsers(t *testing.T) {
+	workspace := func(deactivated string) runtime.RawExtension {
+		ws := &environmentv1alpha1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+			Spec: environmentv1alpha1.WorkspaceSpec{
+				Name:      "payments",
+				Resources: environmentv1alpha1.WorkspaceResource{CPU: "2", Memory: "4Gi", Disk: "10Gi"},
+			},
+		}
+		if deactivated != "" {
+			ws.Annotations = map[string]string{DeactivatedUsersAnnotation: deactivated}
+		}
+		data, err := json.Marshal(ws)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+
+	tests := []struct {
+		name                  string
+		username              string
+		previous, deactivated string
+		allowed               bool
+	}{
+		{name: "unchanged", username: "jane", previous: "john", deactivated: "john", allowed: true},
+		{name: "removed by a user", username: "john", previous: "john"},
+		{name: "set by a user", username: "jane", deactivated: "john"},
+		{name: "set by the operator", username: DefaultOperatorUsername, deactivated: "john", allowed: true},
+		{name: "removed by the operator", username: DefaultOperatorUsername, previous: "john", allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+				Object:    workspace(tt.deactivated),
+				OldObject: workspace(tt.previous),
+			}}
+			req.UserInfo.Username = tt.username
+			if got := (&WorkspaceValidator{}).Handle(context.Background(), req); got.Allowed != tt.allowed {
+				t.Errorf("Handle() allowed = %t, want %t: %v", got.Allowed, tt.allowed, got.Result)
+			}
+		})
+	}
+}
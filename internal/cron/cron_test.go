@@ -0,0 +1,152 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, spec string) *Schedule {
+	t.Helper()
+	s, err := Parse(spec)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", spec, err)
+	}
+	return s
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	return location
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec  string
+		valid bool
+	}{
+		{spec: "0 20 * * *", valid: true},
+		{spec: "*/15 8-18 * * 1-5", valid: true},
+		{spec: "0 0 1,15 * 7", valid: true},
+		{spec: "@weekly", valid: true},
+		{spec: "0 20 * *"},
+		{spec: "60 * * * *"},
+		{spec: "0 24 * * *"},
+		{spec: "0 0 0 * *"},
+		{spec: "0 0 * 13 *"},
+		{spec: "0 0 * * 8"},
+		{spec: "*/0 * * * *"},
+		{spec: "5-1 * * * *"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if _, err := Parse(tt.spec); (err == nil) != tt.valid {
+				t.Errorf("Parse(%q) = %v, want valid %t", tt.spec, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2023-06-07 is a Wednesday
+	at := func(location *time.Location, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2023, month, day, hour, minute, 0, 0, location)
+	}
+	utc := time.UTC
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	kathmandu := mustLoadLocation(t, "Asia/Kathmandu")
+	stJohns := mustLoadLocation(t, "America/St_Johns")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name string
+		spec string
+		t    time.Time
+		want time.Time
+	}{
+		{name: "later today", spec: "0 20 * * *", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.June, 7, 20, 0)},
+		{name: "strictly after", spec: "0 20 * * *", t: at(utc, time.June, 7, 20, 0), want: at(utc, time.June, 8, 20, 0)},
+		{name: "seconds truncated", spec: "* * * * *", t: at(utc, time.June, 7, 12, 0).Add(30 * time.Second), want: at(utc, time.June, 7, 12, 1)},
+		{name: "step", spec: "*/15 * * * *", t: at(utc, time.June, 7, 12, 16), want: at(utc, time.June, 7, 12, 30)},
+		{name: "day of week", spec: "0 9 * * 1", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.June, 12, 9, 0)},
+		{name: "sunday as 7", spec: "0 9 * * 7", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.June, 11, 9, 0)},
+		{name: "day of month", spec: "0 0 13 * *", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.June, 13, 0, 0)},
+		{name: "day of month or day of week", spec: "0 0 13 * 5", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.June, 9, 0, 0)},
+		{name: "day of month or day of week after the week day", spec: "0 0 13 * 5", t: at(utc, time.June, 9, 12, 0), want: at(utc, time.June, 13, 0, 0)},
+		{name: "restricted day of week with any day of month", spec: "0 0 * * 5", t: at(utc, time.June, 10, 12, 0), want: at(utc, time.June, 16, 0, 0)},
+		{name: "month", spec: "0 0 1 9 *", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.September, 1, 0, 0)},
+		{name: "never", spec: "0 0 30 2 *", t: at(utc, time.June, 7, 12, 0)},
+		{name: "half hour offset", spec: "0 20 * * *", t: at(kolkata, time.June, 7, 12, 0), want: at(kolkata, time.June, 7, 20, 0)},
+		{name: "quarter hour offset", spec: "0 20 * * *", t: at(kathmandu, time.June, 7, 12, 0), want: at(kathmandu, time.June, 7, 20, 0)},
+		{name: "half hour offset with daylight saving time", spec: "0 20 * * *", t: at(stJohns, time.June, 7, 12, 0), want: at(stJohns, time.June, 7, 20, 0)},
+		{name: "hourly in a half hour offset", spec: "0 * * * *", t: at(kolkata, time.June, 7, 12, 10), want: at(kolkata, time.June, 7, 13, 0)},
+		{name: "skipped by daylight saving time", spec: "30 2 * * *", t: at(berlin, time.March, 26, 0, 0), want: at(berlin, time.March, 27, 2, 30)},
+		{name: "after the skipped hour", spec: "30 3 * * *", t: at(berlin, time.March, 26, 1, 0), want: at(berlin, time.March, 26, 3, 30)},
+		{name: "repeated hour", spec: "0 3 * * *", t: at(berlin, time.October, 29, 1, 30), want: at(berlin, time.October, 29, 3, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustParse(t, tt.spec).Next(tt.t); !got.Equal(tt.want) {
+				t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.spec, tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrev(t *testing.T) {
+	// 2023-06-07 is a Wednesday
+	at := func(location *time.Location, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2023, month, day, hour, minute, 0, 0, location)
+	}
+	utc := time.UTC
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	kathmandu := mustLoadLocation(t, "Asia/Kathmandu")
+	stJohns := mustLoadLocation(t, "America/St_Johns")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name string
+		spec string
+		t    time.Time
+		want time.Time
+	}{
+		{name: "earlier today", spec: "0 8 * * *", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.June, 7, 8, 0)},
+		{name: "at the activation", spec: "0 8 * * *", t: at(utc, time.June, 7, 8, 0), want: at(utc, time.June, 7, 8, 0)},
+		{name: "yesterday", spec: "0 20 * * *", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.June, 6, 20, 0)},
+		{name: "every minute of the hour", spec: "* 11 * * *", t: at(utc, time.June, 7, 12, 0), want: at(utc, time.June, 7, 11, 59)},
+		{name: "day of month or day of week", spec: "0 0 13 * 5", t: at(utc, time.June, 12, 12, 0), want: at(utc, time.June, 9, 0, 0)},
+		{name: "restricted day of month with any day of week", spec: "0 0 13 * *", t: at(utc, time.June, 12, 12, 0), want: at(utc, time.May, 13, 0, 0)},
+		{name: "never", spec: "0 0 30 2 *", t: at(utc, time.June, 7, 12, 0)},
+		{name: "half hour offset", spec: "0 20 * * *", t: at(kolkata, time.June, 8, 3, 0), want: at(kolkata, time.June, 7, 20, 0)},
+		{name: "every minute of the hour in a half hour offset", spec: "* 11 * * *", t: at(kolkata, time.June, 7, 12, 0), want: at(kolkata, time.June, 7, 11, 59)},
+		{name: "quarter hour offset", spec: "0 20 * * *", t: at(kathmandu, time.June, 8, 3, 0), want: at(kathmandu, time.June, 7, 20, 0)},
+		{name: "half hour offset with daylight saving time", spec: "0 20 * * *", t: at(stJohns, time.June, 8, 3, 0), want: at(stJohns, time.June, 7, 20, 0)},
+		{name: "repeated hour", spec: "0 0 * * *", t: time.Date(2023, time.October, 29, 2, 30, 0, 0, berlin).Add(-time.Hour), want: at(berlin, time.October, 29, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustParse(t, tt.spec).Prev(tt.t); !got.Equal(tt.want) {
+				t.Errorf("Parse(%q).Prev(%s) = %s, want %s", tt.spec, tt.t, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging adjusts the level of the operator logs at runtime and keeps the info entries of the
+// reconciler in check on large fleets, with a rate limit per workspace and the sampling of the chatty entries.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/time/rate"
+	ctrl "sigs.k8s.io/controller-runtime"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// WorkspaceKey is the key of the workspace name in the log entries of the reconciler
+const WorkspaceKey = "workspace"
+
+// ReloadInterval is the interval the config file is reloaded at
+const ReloadInterval = 10 * time.Second
+
+// chattyPrefixes are the prefixes of the info entries logged for every resource of every workspace
+var chattyPrefixes = []string{"Creating", "Checking", "Deleting", "Updating"}
+
+// Config is the runtime configuration of the logs, it is reloaded from the config file
+type Config struct {
+	// Level is the minimum level logged, e.g. debug, info or error. The level of the flags is kept when empty.
+	Level string `json:"level,omitempty"`
+	// WorkspaceRate is the maximum number of info entries logged per second for a workspace, unlimited when 0
+	WorkspaceRate float64 `json:"workspaceRate,omitempty"`
+	// WorkspaceBurst is the number of info entries of a workspace logged at once above WorkspaceRate
+	WorkspaceBurst int `json:"workspaceBurst,omitempty"`
+	// SampleEvery logs one of every SampleEvery chatty info entries, e.g. "Creating a new Role", of a workspace.
+	// All of them are logged when 0 or 1.
+	SampleEvery int `json:"sampleEvery,omitempty"`
+}
+
+// Controls applies the configuration of the logs to the zap logger of the operator
+type Controls struct {
+	// Path of the config file, the flags configuration is kept when empty
+	Path string
+
+	level    zap.AtomicLevel
+	defaults Config
+
+	mu       sync.Mutex
+	config   Config
+	limiters map[string]*rate.Limiter
+	counts   map[string]uint64
+}
+
+// NewControls returns the Controls of the given flags configuration
+func NewControls(path string, defaults Config) *Controls {
+	return &Controls{Path: path, defaults: defaults, config: defaults}
+}
+
+// BindOptions makes the level and the info entries of the zap logger built from opts follow the controls.
+// It must be called after the flags are parsed.
+func (c *Controls) BindOptions(opts *crzap.Options) {
+	// --zap-log-level sets an atomic level, the default level of controller-runtime is used otherwise
+	level, ok := opts.Level.(zap.AtomicLevel)
+	if !ok {
+		level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+		if opts.Development {
+			level.SetLevel(zapcore.DebugLevel)
+		}
+	}
+	c.level = level
+	c.defaults.Level = c.level.String()
+	opts.Level = c.level
+	opts.ZapOpts = append(opts.ZapOpts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &controlledCore{Core: core, controls: c}
+	}))
+}
+
+// Load reads the config file and applies it, the flags configuration is applied when it does not exist
+func (c *Controls) Load() error {
+	config := c.defaults
+	if c.Path == "" {
+		return c.Apply(config)
+	}
+	data, err := os.ReadFile(c.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("invalid log config %s: %w", c.Path, err)
+		}
+		if config.Level == "" {
+			config.Level = c.defaults.Level
+		}
+	}
+	return c.Apply(config)
+}
+
+// Apply changes the level, rate limit and sampling of the logs
+func (c *Controls) Apply(config Config) error {
+	if config.Level != "" {
+		if err := c.level.UnmarshalText([]byte(config.Level)); err != nil {
+			return fmt.Errorf("invalid log level %q: %w", config.Level, err)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if config != c.config {
+		c.limiters = nil
+		c.counts = nil
+	}
+	c.config = config
+	return nil
+}
+
+// Start reloads the config file every ReloadInterval until the context is done
+func (c *Controls) Start(ctx context.Context) error {
+	if c.Path == "" {
+		return nil
+	}
+	log := ctrl.Log.WithName("logging")
+	ticker := time.NewTicker(ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.Load(); err != nil {
+				log.Error(err, "Failed to reload log config")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection makes the logs of every replica follow the config file
+func (c *Controls) NeedLeaderElection() bool {
+	return false
+}
+
+// allow reports whether an info entry of a workspace is logged
+func (c *Controls) allow(workspace, message string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.config.SampleEvery > 1 {
+		for _, prefix := range chattyPrefixes {
+			if !strings.HasPrefix(message, prefix) {
+				continue
+			}
+			if c.counts == nil {
+				c.counts = map[string]uint64{}
+			}
+			key := workspace + "/" + prefix
+			c.counts[key]++
+			if (c.counts[key]-1)%uint64(c.config.SampleEvery) != 0 {
+				return false
+			}
+			break
+		}
+	}
+	if c.config.WorkspaceRate > 0 {
+		if c.limiters == nil {
+			c.limiters = map[string]*rate.Limiter{}
+		}
+		limiter, ok := c.limiters[workspace]
+		if !ok {
+			burst := c.config.WorkspaceBurst
+			if burst < 1 {
+				burst = 1
+			}
+			limiter = rate.NewLimiter(rate.Limit(c.config.WorkspaceRate), burst)
+			c.limiters[workspace] = limiter
+		}
+		return limiter.Allow()
+	}
+	return true
+}
+
+// controlledCore drops the info entries of a workspace the controls do not allow.
+// The workspace is taken from the WorkspaceKey field of the logger, entries without it are not limited.
+type controlledCore struct {
+	zapcore.Core
+	controls  *Controls
+	workspace string
+}
+
+func (c *controlledCore) With(fields []zapcore.Field) zapcore.Core {
+	workspace := c.workspace
+	for _, field := range fields {
+		if field.Key == WorkspaceKey && field.Type == zapcore.StringType {
+			workspace = field.String
+		}
+	}
+	return &controlledCore{Core: c.Core.With(fields), controls: c.controls, workspace: workspace}
+}
+
+func (c *controlledCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.workspace != "" && entry.Level < zapcore.WarnLevel && c.Core.Enabled(entry.Level) && !c.controls.allow(c.workspace, entry.Message) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// hncDepthLabelSuffix is the suffix of the labels HNC sets on a namespace for itself and each of its ancestors,
+// e.g. "team-a.tree.hnc.x-k8s.io/depth: 1" on the children of team-a
+const hncDepthLabelSuffix = ".tree.hnc.x-k8s.io/depth"
+
+// fromHNC migrates every namespace of a Hierarchical Namespace Controller hierarchy to a Workspace.
+// The hierarchy itself is flattened, the root of each namespace is recorded in the migrated-from annotation.
+func (m *Migrator) fromHNC(ctx context.Context) ([]environmentv1alpha1.Workspace, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := m.Client.List(ctx, namespaces); err != nil {
+		return nil, err
+	}
+
+	// roots maps the namespaces of a hierarchy to their root, i.e. their deepest ancestor
+	roots := map[string]string{}
+	for _, namespace := range namespaces.Items {
+		root, rootDepth := "", 0
+		for label, value := range namespace.Labels {
+			ancestor := strings.TrimSuffix(label, hncDepthLabelSuffix)
+			if ancestor == label || ancestor == namespace.Name {
+				continue
+			}
+			depth, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			if depth > rootDepth {
+				root, rootDepth = ancestor, depth
+			}
+		}
+		if root != "" {
+			roots[namespace.Name] = root
+			roots[root] = root
+		}
+	}
+
+	var workspaces []environmentv1alpha1.Workspace
+	for i := range namespaces.Items {
+		namespace := &namespaces.Items[i]
+		root, ok := roots[namespace.Name]
+		if !ok {
+			continue
+		}
+		workspace, err := m.workspaceForNamespace(ctx, namespace, fmt.Sprintf("hnc/%s", root), nil)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces, nil
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migrate converts the tenants of other multi-tenancy controllers
+// (Capsule, the Hierarchical Namespace Controller and kiosk) into Workspaces
+// and adopts their namespaces.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// MigratedFromAnnotation records the tenant a Workspace was migrated from, e.g. "capsule/payments"
+const MigratedFromAnnotation = "environment.tf.operator.com/migrated-from"
+
+// Sources are the controllers Workspaces can be migrated from
+var Sources = []string{"capsule", "hnc", "kiosk"}
+
+// foreignMarkers are the label and annotation key fragments the migrated controllers set on their namespaces.
+// They are not copied to the Workspaces and are removed from the namespaces on adoption.
+var foreignMarkers = []string{"capsule.clastix.io/", "hnc.x-k8s.io/", "kiosk.sh/"}
+
+// Migrator builds the Workspaces of the tenants of another controller
+type Migrator struct {
+	Client client.Client
+
+	// Warnings lists what could not be migrated and must be reviewed before applying the Workspaces
+	Warnings []string
+}
+
+// Workspaces returns the Workspaces equivalent to the tenants of the source controller, sorted by name
+func (m *Migrator) Workspaces(ctx context.Context, source string) ([]environmentv1alpha1.Workspace, error) {
+	var workspaces []environmentv1alpha1.Workspace
+	var err error
+	switch source {
+	case "capsule":
+		workspaces, err = m.fromCapsule(ctx)
+	case "hnc":
+		workspaces, err = m.fromHNC(ctx)
+	case "kiosk":
+		workspaces, err = m.fromKiosk(ctx)
+	default:
+		return nil, fmt.Errorf("unknown source %q, must be one of %s", source, strings.Join(Sources, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+	return workspaces, nil
+}
+
+func (m *Migrator) warnf(format string, args ...interface{}) {
+	m.Warnings = append(m.Warnings, fmt.Sprintf(format, args...))
+}
+
+// workspaceForNamespace builds the Workspace of an existing namespace from its ResourceQuotas and RoleBindings.
+// owners are bound to the admin role of the workspace, the first user of the admin RoleBindings is used otherwise.
+func (m *Migrator) workspaceForNamespace(ctx context.Context, namespace *corev1.Namespace, origin string, owners []rbacv1.Subject) (environmentv1alpha1.Workspace, error) {
+	workspace := environmentv1alpha1.Workspace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: environmentv1alpha1.GroupVersion.String(),
+			Kind:       "Workspace",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        namespace.Name,
+			Annotations: map[string]string{MigratedFromAnnotation: origin},
+		},
+		Spec: environmentv1alpha1.WorkspaceSpec{
+			Name:        namespace.Name,
+			Labels:      withoutForeignMarkers(namespace.Labels),
+			Annotations: withoutForeignMarkers(namespace.Annotations),
+		},
+	}
+
+	// Resources from the hard limits of the ResourceQuotas of the namespace
+	quotas := &corev1.ResourceQuotaList{}
+	if err := m.Client.List(ctx, quotas, client.InNamespace(namespace.Name)); err != nil {
+		return workspace, err
+	}
+	resources := &workspace.Spec.Resources
+	for _, quota := range quotas.Items {
+		hard := quota.Spec.Hard
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceRequestsCPU, corev1.ResourceLimitsCPU} {
+			if q, ok := hard[name]; ok && resources.CPU == "" {
+				resources.CPU = q.String()
+			}
+		}
+		for _, name := range []corev1.ResourceName{corev1.ResourceMemory, corev1.ResourceRequestsMemory, corev1.ResourceLimitsMemory} {
+			if q, ok := hard[name]; ok && resources.Memory == "" {
+				resources.Memory = q.String()
+			}
+		}
+		if q, ok := hard[corev1.ResourceRequestsStorage]; ok && resources.Disk == "" {
+			resources.Disk = q.String()
+		}
+		for name, field := range map[corev1.ResourceName]*string{
+			corev1.ResourceLimitsCPU:              &resources.LimitsCPU,
+			corev1.ResourceLimitsMemory:           &resources.LimitsMemory,
+			corev1.ResourcePods:                   &resources.Pods,
+			corev1.ResourceServices:               &resources.Services,
+			corev1.ResourceServicesLoadBalancers:  &resources.LoadBalancers,
+			corev1.ResourceSecrets:                &resources.Secrets,
+			corev1.ResourceConfigMaps:             &resources.ConfigMaps,
+			corev1.ResourcePersistentVolumeClaims: &resources.PersistentVolumeClaims,
+		} {
+			if q, ok := hard[name]; ok && *field == "" {
+				*field = q.String()
+			}
+		}
+	}
+	if resources.CPU == "" || resources.Memory == "" || resources.Disk == "" {
+		m.warnf("Workspace %s: no cpu, memory or requests.storage quota found in namespace %s, set spec.resources", workspace.Name, namespace.Name)
+	}
+
+	// Users from the owners and the RoleBindings to the admin, edit and view ClusterRoles
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := m.Client.List(ctx, roleBindings, client.InNamespace(namespace.Name)); err != nil {
+		return workspace, err
+	}
+	users := map[string]struct {
+		user *string
+		list *[]string
+	}{
+		"admin": {&workspace.Spec.Users.Admin, &workspace.Spec.Users.Admins},
+		"edit":  {&workspace.Spec.Users.Editor, &workspace.Spec.Users.Editors},
+		"view":  {&workspace.Spec.Users.Viewer, &workspace.Spec.Users.Viewers},
+	}
+	// The first user of a role is its single user, the next ones are added to its list
+	setUser := func(role string, subjects []rbacv1.Subject) {
+		for _, subject := range subjects {
+			if subject.Kind != rbacv1.UserKind {
+				m.warnf("Workspace %s: %s %s bound to %s is not migrated, only users are supported", workspace.Name, subject.Kind, subject.Name, role)
+				continue
+			}
+			users := users[role]
+			if *users.user == "" {
+				*users.user = subject.Name
+				continue
+			}
+			known := *users.user == subject.Name
+			for _, user := range *users.list {
+				known = known || user == subject.Name
+			}
+			if !known {
+				*users.list = append(*users.list, subject.Name)
+			}
+		}
+	}
+	setUser("admin", owners)
+	for _, roleBinding := range roleBindings.Items {
+		if _, ok := users[roleBinding.RoleRef.Name]; ok && roleBinding.RoleRef.Kind == "ClusterRole" {
+			setUser(roleBinding.RoleRef.Name, roleBinding.Subjects)
+		}
+	}
+	if workspace.Spec.Users.Admin == "" {
+		m.warnf("Workspace %s: no admin user found, set spec.users.admin", workspace.Name)
+	}
+	return workspace, nil
+}
+
+// withoutForeignMarkers copies the labels or annotations, skipping the ones set by the migrated controllers or Kubernetes
+func withoutForeignMarkers(values map[string]string) map[string]string {
+	var copied map[string]string
+	for key, value := range values {
+		if isForeignMarker(key) || strings.Contains(key, "kubernetes.io/") {
+			continue
+		}
+		if copied == nil {
+			copied = map[string]string{}
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+func isForeignMarker(key string) bool {
+	for _, marker := range foreignMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply creates the Workspaces which do not exist yet. When adopt is set the foreign ownership of their
+// namespaces is removed before and the Workspaces are made the controllers of the namespaces after.
+// The migrated controller must be stopped beforehand, otherwise it restores its ownership.
+func (m *Migrator) Apply(ctx context.Context, workspaces []environmentv1alpha1.Workspace, adopt bool) error {
+	for i := range workspaces {
+		workspace := workspaces[i].DeepCopy()
+		if adopt {
+			if err := m.releaseNamespace(ctx, workspace.Spec.Name); err != nil {
+				return fmt.Errorf("releasing namespace %s: %w", workspace.Spec.Name, err)
+			}
+		}
+		if err := m.Client.Create(ctx, workspace); apierrors.IsAlreadyExists(err) {
+			if err := m.Client.Get(ctx, types.NamespacedName{Name: workspace.Name}, workspace); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return fmt.Errorf("creating Workspace %s: %w", workspace.Name, err)
+		}
+		if adopt {
+			if err := m.adoptNamespace(ctx, workspace); err != nil {
+				return fmt.Errorf("adopting namespace %s: %w", workspace.Spec.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// releaseNamespace removes the owner references, labels and annotations of the migrated controllers from the namespace
+func (m *Migrator) releaseNamespace(ctx context.Context, name string) error {
+	namespace := &corev1.Namespace{}
+	if err := m.Client.Get(ctx, types.NamespacedName{Name: name}, namespace); err != nil {
+		return err
+	}
+	var ownerReferences []metav1.OwnerReference
+	for _, owner := range namespace.OwnerReferences {
+		if owner.Kind == "Workspace" && strings.HasPrefix(owner.APIVersion, environmentv1alpha1.GroupVersion.Group+"/") {
+			ownerReferences = append(ownerReferences, owner)
+		}
+	}
+	namespace.OwnerReferences = ownerReferences
+	for key := range namespace.Labels {
+		if isForeignMarker(key) {
+			delete(namespace.Labels, key)
+		}
+	}
+	for key := range namespace.Annotations {
+		if isForeignMarker(key) {
+			delete(namespace.Annotations, key)
+		}
+	}
+	return m.Client.Update(ctx, namespace)
+}
+
+// adoptNamespace makes the workspace the controller of its namespace
+func (m *Migrator) adoptNamespace(ctx context.Context, workspace *environmentv1alpha1.Workspace) error {
+	namespace := &corev1.Namespace{}
+	if err := m.Client.Get(ctx, types.NamespacedName{Name: workspace.Spec.Name}, namespace); err != nil {
+		return err
+	}
+	if owner := metav1.GetControllerOf(namespace); owner != nil && owner.UID == workspace.UID {
+		return nil
+	}
+	if err := controllerutil.SetControllerReference(workspace, namespace, m.Client.Scheme()); err != nil {
+		return err
+	}
+	return m.Client.Update(ctx, namespace)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+const (
+	// capsuleTenantLabel is set by Capsule on the namespaces of a Tenant
+	capsuleTenantLabel = "capsule.clastix.io/tenant"
+
+	// kioskAccountLabel is set by kiosk on the namespaces of an Account
+	kioskAccountLabel = "kiosk.sh/account"
+)
+
+var (
+	capsuleTenantGVK = schema.GroupVersionKind{Group: "capsule.clastix.io", Version: "v1beta2", Kind: "Tenant"}
+	kioskAccountGVK  = schema.GroupVersionKind{Group: "config.kiosk.sh", Version: "v1alpha1", Kind: "Account"}
+)
+
+// fromCapsule migrates every namespace of a Capsule Tenant to a Workspace administered by the Tenant owners
+func (m *Migrator) fromCapsule(ctx context.Context) ([]environmentv1alpha1.Workspace, error) {
+	return m.fromLabeledNamespaces(ctx, "capsule", capsuleTenantLabel, capsuleTenantGVK, "spec", "owners")
+}
+
+// fromKiosk migrates every namespace of a kiosk Account to a Workspace administered by the Account subjects
+func (m *Migrator) fromKiosk(ctx context.Context) ([]environmentv1alpha1.Workspace, error) {
+	return m.fromLabeledNamespaces(ctx, "kiosk", kioskAccountLabel, kioskAccountGVK, "spec", "subjects")
+}
+
+// fromLabeledNamespaces migrates the namespaces carrying the tenant label of the source controller.
+// The owners of the workspaces are read from the subjects at subjectsPath in the tenant object.
+func (m *Migrator) fromLabeledNamespaces(ctx context.Context, source, label string, tenantGVK schema.GroupVersionKind, subjectsPath ...string) ([]environmentv1alpha1.Workspace, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := m.Client.List(ctx, namespaces, client.HasLabels{label}); err != nil {
+		return nil, err
+	}
+	var workspaces []environmentv1alpha1.Workspace
+	for i := range namespaces.Items {
+		namespace := &namespaces.Items[i]
+		tenantName := namespace.Labels[label]
+		owners, err := m.tenantSubjects(ctx, tenantGVK, tenantName, subjectsPath...)
+		if err != nil {
+			return nil, err
+		}
+		workspace, err := m.workspaceForNamespace(ctx, namespace, fmt.Sprintf("%s/%s", source, tenantName), owners)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces, nil
+}
+
+// tenantSubjects reads the subjects of a tenant object. A missing tenant only raises a warning.
+func (m *Migrator) tenantSubjects(ctx context.Context, gvk schema.GroupVersionKind, name string, path ...string) ([]rbacv1.Subject, error) {
+	tenant := &unstructured.Unstructured{}
+	tenant.SetGroupVersionKind(gvk)
+	if err := m.Client.Get(ctx, types.NamespacedName{Name: name}, tenant); apierrors.IsNotFound(err) {
+		m.warnf("%s %s not found, its owners are not migrated", gvk.Kind, name)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	items, _, err := unstructured.NestedSlice(tenant.Object, path...)
+	if err != nil {
+		return nil, err
+	}
+	var subjects []rbacv1.Subject
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _ := fields["kind"].(string)
+		subjectName, _ := fields["name"].(string)
+		subjects = append(subjects, rbacv1.Subject{Kind: kind, Name: subjectName})
+	}
+	return subjects, nil
+}
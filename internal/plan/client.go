@@ -0,0 +1,398 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// workspaceGVK is the kind of the planned Workspace
+var workspaceGVK = environmentv1alpha1.GroupVersion.WithKind("Workspace")
+
+// entry is an object written by the reconciler during the plan
+type entry struct {
+	// object is the object as written, nil once deleted
+	object client.Object
+	// change is the change of the object against the live cluster
+	change *Change
+}
+
+// overlayClient is the client the reconciler runs with during a plan. Its writes are sent to the API server
+// as server-side dry-runs, so that they are validated and defaulted without being persisted, and are kept
+// in an overlay the next reads are served from. The planned Workspace is served instead of the live one.
+type overlayClient struct {
+	client.Client
+	scheme *runtime.Scheme
+
+	mu        sync.Mutex
+	workspace *environmentv1alpha1.Workspace
+	entries   map[string]*entry
+	// changes are the changes in the order of their first write
+	changes []*Change
+	// writes counts the writes which changed the overlay
+	writes int
+}
+
+// newOverlayClient returns an overlayClient reading the cluster with live and serving workspace as the planned Workspace
+func newOverlayClient(live client.Client, scheme *runtime.Scheme, workspace *environmentv1alpha1.Workspace) *overlayClient {
+	return &overlayClient{Client: live, scheme: scheme, workspace: workspace, entries: map[string]*entry{}}
+}
+
+// overlayKey returns the key of an object in the overlay
+func overlayKey(gvk schema.GroupVersionKind, key types.NamespacedName) string {
+	return fmt.Sprintf("%s/%s", gvk.String(), key.String())
+}
+
+// isWorkspace reports whether the object is the planned Workspace
+func (c *overlayClient) isWorkspace(gvk schema.GroupVersionKind, name string) bool {
+	return gvk == workspaceGVK && name == c.workspace.Name
+}
+
+// Get serves the planned Workspace and the objects of the overlay, and reads the other ones from the cluster
+func (c *overlayClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.isWorkspace(gvk, key.Name) {
+		defer c.mu.Unlock()
+		return copyObject(c.scheme, c.workspace, obj)
+	}
+	stored, ok := c.entries[overlayKey(gvk, key)]
+	c.mu.Unlock()
+	if !ok {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+	if stored.object == nil {
+		return apierrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: strings.ToLower(gvk.Kind)}, key.Name)
+	}
+	return copyObject(c.scheme, stored.object, obj)
+}
+
+// List reads the objects from the cluster and applies the overlay to them. The field selectors of the
+// Workspaces are dropped, as the field indexes of the operator only exist in its cache.
+func (c *overlayClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	gvk, err := apiutil.GVKForObject(list, c.scheme)
+	if err != nil {
+		return err
+	}
+	itemGVK := gvk.GroupVersion().WithKind(strings.TrimSuffix(gvk.Kind, "List"))
+	if itemGVK == workspaceGVK {
+		var kept []client.ListOption
+		for _, opt := range opts {
+			switch opt.(type) {
+			case client.MatchingFields, client.MatchingFieldsSelector:
+				continue
+			}
+			kept = append(kept, opt)
+		}
+		opts = kept
+	}
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := map[string]bool{}
+	var result []runtime.Object
+	for _, item := range items {
+		object, ok := item.(client.Object)
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+		key := overlayKey(itemGVK, client.ObjectKeyFromObject(object))
+		seen[key] = true
+		if c.isWorkspace(itemGVK, object.GetName()) {
+			if err := copyObject(c.scheme, c.workspace, object); err != nil {
+				return err
+			}
+		} else if stored, ok := c.entries[key]; ok {
+			if stored.object == nil {
+				continue
+			}
+			if err := copyObject(c.scheme, stored.object, object); err != nil {
+				return err
+			}
+		}
+		result = append(result, object)
+	}
+
+	// objects created during the plan and the planned Workspace when it does not exist yet
+	var created []client.Object
+	if itemGVK == workspaceGVK && !seen[overlayKey(itemGVK, client.ObjectKeyFromObject(c.workspace))] {
+		created = append(created, c.workspace)
+	}
+	for key, stored := range c.entries {
+		if stored.object != nil && !seen[key] && strings.HasPrefix(key, itemGVK.String()+"/") {
+			created = append(created, stored.object)
+		}
+	}
+	for _, object := range created {
+		if listOptions.Namespace != "" && object.GetNamespace() != listOptions.Namespace {
+			continue
+		}
+		if listOptions.LabelSelector != nil && !listOptions.LabelSelector.Matches(labels.Set(object.GetLabels())) {
+			continue
+		}
+		item, err := newItem(c.scheme, list, itemGVK)
+		if err != nil {
+			return err
+		}
+		if err := copyObject(c.scheme, object, item); err != nil {
+			return err
+		}
+		result = append(result, item)
+	}
+	return meta.SetList(list, result)
+}
+
+// Create creates the object with a dry-run and adds it to the overlay. The objects of a namespace created
+// during the plan can not be dry-run, as the namespace does not exist, and are added as rendered.
+func (c *overlayClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	key := client.ObjectKeyFromObject(obj)
+	err = c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...)
+	if apierrors.IsNotFound(err) && c.namespaceCreated(key.Namespace) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	c.record(ActionCreate, gvk, key, nil, obj)
+	return nil
+}
+
+// Update updates the object with a dry-run and records it in the overlay
+func (c *overlayClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.write(ctx, obj, func(live client.Object) error {
+		// the object may come from the overlay, its resource version is not the live one
+		obj.SetResourceVersion(live.GetResourceVersion())
+		return c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...)
+	})
+}
+
+// Patch patches the object with a dry-run and records it in the overlay
+func (c *overlayClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.write(ctx, obj, func(live client.Object) error {
+		return c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+	})
+}
+
+// Delete deletes the object with a dry-run and records it in the overlay
+func (c *overlayClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	key := client.ObjectKeyFromObject(obj)
+	c.mu.Lock()
+	stored, ok := c.entries[overlayKey(gvk, key)]
+	c.mu.Unlock()
+	if ok && stored.change.Action == ActionCreate {
+		c.forget(gvk, key)
+		return nil
+	}
+	if err := c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	c.record(ActionDelete, gvk, key, obj, nil)
+	return nil
+}
+
+// DeleteAllOf is not used by the reconciler and is not supported during a plan
+func (c *overlayClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	return fmt.Errorf("DeleteAllOf is not supported during a plan")
+}
+
+// Status returns a status writer applying the status of the planned Workspace to the overlay only
+func (c *overlayClient) Status() client.StatusWriter {
+	return &overlayStatusWriter{c}
+}
+
+// write sends an update of the object to the cluster with send, or applies it to the overlay when the object
+// was created during the plan. The updates of the planned Workspace are applied to it.
+func (c *overlayClient) write(ctx context.Context, obj client.Object, send func(live client.Object) error) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	key := client.ObjectKeyFromObject(obj)
+	c.mu.Lock()
+	if c.isWorkspace(gvk, key.Name) {
+		defer c.mu.Unlock()
+		return copyObject(c.scheme, obj, c.workspace)
+	}
+	stored, ok := c.entries[overlayKey(gvk, key)]
+	c.mu.Unlock()
+	if ok && stored.change.Action == ActionCreate {
+		c.record(ActionCreate, gvk, key, nil, obj)
+		return nil
+	}
+
+	live := obj.DeepCopyObject().(client.Object)
+	if err := c.Client.Get(ctx, key, live); err != nil {
+		return err
+	}
+	if err := send(live); err != nil {
+		return err
+	}
+	c.record(ActionUpdate, gvk, key, live, obj)
+	return nil
+}
+
+// record adds a change to the overlay. The first live state of an object is kept as the state it changes from.
+func (c *overlayClient) record(action Action, gvk schema.GroupVersionKind, key types.NamespacedName, before, after client.Object) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var object client.Object
+	if after != nil {
+		object = after.DeepCopyObject().(client.Object)
+		object.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	stored, ok := c.entries[overlayKey(gvk, key)]
+	if !ok {
+		if before != nil {
+			before = before.DeepCopyObject().(client.Object)
+			before.GetObjectKind().SetGroupVersionKind(gvk)
+		}
+		change := &Change{Action: action, Kind: gvk.Kind, Key: key, Before: before}
+		stored = &entry{change: change}
+		c.entries[overlayKey(gvk, key)] = stored
+		c.changes = append(c.changes, change)
+	} else if reflect.DeepEqual(normalize(stored.object), normalize(object)) {
+		return
+	}
+	if stored.change.Action != ActionCreate {
+		stored.change.Action = action
+	}
+	stored.object = object
+	stored.change.After = object
+	c.writes++
+}
+
+// forget removes an object created during the plan from the overlay
+func (c *overlayClient) forget(gvk schema.GroupVersionKind, key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored := c.entries[overlayKey(gvk, key)]
+	delete(c.entries, overlayKey(gvk, key))
+	for i, change := range c.changes {
+		if change == stored.change {
+			c.changes = append(c.changes[:i], c.changes[i+1:]...)
+			break
+		}
+	}
+	c.writes++
+}
+
+// namespaceCreated reports whether the namespace is created during the plan
+func (c *overlayClient) namespaceCreated(namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, ok := c.entries[overlayKey(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, types.NamespacedName{Name: namespace})]
+	return ok && stored.change.Action == ActionCreate
+}
+
+// overlayStatusWriter applies the status updates of the planned Workspace, the status of the other objects
+// is not managed by the operator
+type overlayStatusWriter struct {
+	c *overlayClient
+}
+
+// Update sets the status of the planned Workspace
+func (w *overlayStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return w.apply(obj)
+}
+
+// Patch sets the status of the planned Workspace
+func (w *overlayStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return w.apply(obj)
+}
+
+func (w *overlayStatusWriter) apply(obj client.Object) error {
+	workspace, ok := obj.(*environmentv1alpha1.Workspace)
+	if !ok || workspace.Name != w.c.workspace.Name {
+		return nil
+	}
+	w.c.mu.Lock()
+	defer w.c.mu.Unlock()
+	workspace.Status.DeepCopyInto(&w.c.workspace.Status)
+	return nil
+}
+
+// newItem returns an empty item of the list
+func newItem(scheme *runtime.Scheme, list client.ObjectList, gvk schema.GroupVersionKind) (client.Object, error) {
+	if _, ok := list.(*unstructured.UnstructuredList); ok {
+		item := &unstructured.Unstructured{}
+		item.SetGroupVersionKind(gvk)
+		return item, nil
+	}
+	item, err := scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return item.(client.Object), nil
+}
+
+// copyObject copies src into dst through their JSON form, so that typed and unstructured objects can be mixed
+func copyObject(scheme *runtime.Scheme, src, dst client.Object) error {
+	gvk, err := apiutil.GVKForObject(src, scheme)
+	if err != nil {
+		return err
+	}
+	src = src.DeepCopyObject().(client.Object)
+	src.GetObjectKind().SetGroupVersionKind(gvk)
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(dst).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// volatileFields are the metadata fields set by the API server on every write, left out of the diffs
+var volatileFields = []string{"managedFields", "resourceVersion", "uid", "creationTimestamp", "generation", "selfLink"}
+
+// normalize returns the unstructured form of the object without its status and volatile fields,
+// nil for a nil object
+func normalize(object client.Object) map[string]interface{} {
+	if object == nil || reflect.ValueOf(object).IsNil() {
+		return nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return nil
+	}
+	delete(content, "status")
+	if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, field := range volatileFields {
+			delete(metadata, field)
+		}
+	}
+	return content
+}
+
+// Changed reports whether the change modifies the object
+func (c *Change) Changed() bool {
+	return !reflect.DeepEqual(normalize(c.Before), normalize(c.After))
+}
+
+// Render writes the plan in the style of a Terraform plan, each object followed by the diff of its YAML form
+func Render(out io.Writer, result *Result) error {
+	changes := []*Change{}
+	if result.Workspace != nil {
+		changes = append(changes, result.Workspace)
+	}
+	changes = append(changes, result.Changes...)
+
+	add, change, destroy := 0, 0, 0
+	for _, c := range changes {
+		if !c.Changed() {
+			continue
+		}
+		name := c.Key.Name
+		if c.Key.Namespace != "" {
+			name = c.Key.Namespace + "/" + name
+		}
+		before, err := yamlLines(c.Before)
+		if err != nil {
+			return err
+		}
+		after, err := yamlLines(c.After)
+		if err != nil {
+			return err
+		}
+		switch c.Action {
+		case ActionCreate:
+			add++
+			fmt.Fprintf(out, "  # %s %s will be created\n", c.Kind, name)
+		case ActionUpdate:
+			change++
+			fmt.Fprintf(out, "  # %s %s will be updated in-place\n", c.Kind, name)
+		case ActionDelete:
+			destroy++
+			fmt.Fprintf(out, "  # %s %s will be destroyed\n", c.Kind, name)
+		}
+		for _, line := range diffLines(before, after) {
+			fmt.Fprintf(out, "  %s\n", line)
+		}
+		fmt.Fprintln(out)
+	}
+	if add+change+destroy == 0 {
+		fmt.Fprintln(out, "No changes. The objects of the Workspace match the manifest.")
+		return nil
+	}
+	fmt.Fprintf(out, "Plan: %d to add, %d to change, %d to destroy.\n", add, change, destroy)
+	return nil
+}
+
+// yamlLines returns the lines of the YAML form of the normalized object, none for a nil object
+func yamlLines(object client.Object) ([]string, error) {
+	content := normalize(object)
+	if content == nil {
+		return nil, nil
+	}
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
+
+// diffLines returns the lines of before and after prefixed with - when removed, + when added
+// and a space when kept, from their longest common subsequence
+func diffLines(before, after []string) []string {
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+	var lines []string
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			lines = append(lines, "  "+before[i])
+			i++
+			j++
+		case j < len(after) && (i == len(before) || common[i][j+1] >= common[i+1][j]):
+			lines = append(lines, "+ "+after[j])
+			j++
+		default:
+			lines = append(lines, "- "+before[i])
+			i++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plan previews the objects the operator would create, change or delete for a Workspace manifest.
+// The reconciler of the operator is run against the live cluster with all its writes sent as server-side
+// dry-runs, so that the objects are rendered, validated and defaulted exactly as they would be applied.
+package plan
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/controllers"
+)
+
+// DefaultMaxPasses is the default number of reconciliations a plan runs until the Workspace is in the desired state
+const DefaultMaxPasses = 10
+
+// Action is the kind of write the operator would perform on an object
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change is an object the operator would write for the Workspace
+type Change struct {
+	Action Action
+	Kind   string
+	Key    types.NamespacedName
+	// Before is the live object, nil when it is created
+	Before client.Object
+	// After is the object as the operator would write it, nil when it is deleted
+	After client.Object
+}
+
+// Result is the plan of a Workspace
+type Result struct {
+	// Workspace is the change of the Workspace itself
+	Workspace *Change
+	// Changes are the changes of the objects of the Workspace, in the order the operator would apply them
+	Changes []*Change
+	// Converged is false when the Workspace was not in the desired state after the maximum number of passes
+	Converged bool
+}
+
+// Planner plans the Workspaces against the cluster of Client
+type Planner struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+
+	// DeletionProtection, NamespacedOnly and OperatorUsername are the settings of the operator of the cluster
+	DeletionProtection bool
+	NamespacedOnly     bool
+	OperatorUsername   string
+
+	// MaxPasses is the number of reconciliations run until the Workspace is in the desired state, DefaultMaxPasses when 0
+	MaxPasses int
+}
+
+// Plan returns the changes the operator would apply for the Workspace manifest.
+// The Workspace itself is dry-run too, so that it is rejected by the webhook or the CRD schema as on apply.
+// The error of a reconciliation is returned with the changes planned until then.
+func (p *Planner) Plan(ctx context.Context, workspace *environmentv1alpha1.Workspace) (*Result, error) {
+	result := &Result{}
+	planned := workspace.DeepCopy()
+	live := &environmentv1alpha1.Workspace{}
+	err := p.Client.Get(ctx, types.NamespacedName{Name: workspace.Name}, live)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if apierrors.IsNotFound(err) {
+		if err := p.Client.Create(ctx, planned, client.DryRunAll); err != nil {
+			return nil, err
+		}
+		result.Workspace = &Change{Action: ActionCreate, Kind: "Workspace", Key: client.ObjectKeyFromObject(planned), After: planned}
+	} else {
+		planned.SetResourceVersion(live.GetResourceVersion())
+		if err := p.Client.Update(ctx, planned, client.DryRunAll); err != nil {
+			return nil, err
+		}
+		result.Workspace = &Change{Action: ActionUpdate, Kind: "Workspace", Key: client.ObjectKeyFromObject(planned), Before: live, After: planned}
+	}
+	planned = planned.DeepCopy()
+
+	overlay := newOverlayClient(p.Client, p.Scheme, planned)
+	reconciler := &controllers.WorkspaceReconciler{
+		Client:             overlay,
+		Scheme:             p.Scheme,
+		DeletionProtection: p.DeletionProtection,
+		NamespacedOnly:     p.NamespacedOnly,
+		OperatorUsername:   p.OperatorUsername,
+	}
+	maxPasses := p.MaxPasses
+	if maxPasses == 0 {
+		maxPasses = DefaultMaxPasses
+	}
+	for pass := 0; pass < maxPasses; pass++ {
+		writes := overlay.writes
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: planned.Name}})
+		result.Changes = overlay.changes
+		if err != nil {
+			return result, err
+		}
+		if pass > 0 && overlay.writes == writes {
+			result.Converged = true
+			break
+		}
+	}
+	return result, nil
+}
+
+// ReadWorkspaces reads the Workspaces of a YAML or JSON manifest, the other objects are skipped
+func ReadWorkspaces(in io.Reader) ([]environmentv1alpha1.Workspace, error) {
+	var workspaces []environmentv1alpha1.Workspace
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(in))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			return workspaces, nil
+		} else if err != nil {
+			return nil, err
+		}
+		data, err := yaml.YAMLToJSON(document)
+		if err != nil {
+			return nil, err
+		}
+		meta := struct {
+			Kind string `json:"kind"`
+		}{}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, err
+		}
+		if meta.Kind != "Workspace" {
+			continue
+		}
+		workspace := environmentv1alpha1.Workspace{}
+		if err := json.Unmarshal(data, &workspace); err != nil {
+			return nil, fmt.Errorf("invalid Workspace: %w", err)
+		}
+		workspaces = append(workspaces, workspace)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/controllers"
+)
+
+// DefaultCRDPath is the Workspace CRD manifest of the repository
+const DefaultCRDPath = "config/crd/bases/environment.tf.operator.com_workspaces.yaml"
+
+// Run runs the validate command with its arguments and returns its exit code:
+// 0 when all the Workspaces are valid, 1 when one is rejected and 2 on usage or read errors
+func Run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var file, crd, clusterObjects, allowPatterns, denyPatterns string
+	flags.StringVar(&file, "f", "", "YAML file with the Workspaces to validate, - for the standard input.")
+	flags.StringVar(&crd, "crd", DefaultCRDPath, "Workspace CustomResourceDefinition manifest the Workspaces are validated against.")
+	flags.StringVar(&clusterObjects, "cluster-objects", "",
+		"YAML file with the WorkspaceClasses, WorkspaceTemplates and ClusterRoles of the cluster. The class references are checked and "+
+			"the quotas and warnings of the webhook computed against them. The class references are not checked when unset.")
+	flags.StringVar(&allowPatterns, "namespace-allow-patterns", "", "--namespace-allow-patterns of the operator.")
+	flags.StringVar(&denyPatterns, "namespace-deny-patterns", "", "--namespace-deny-patterns of the operator.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if file == "" {
+		fmt.Fprintln(stderr, "error: -f is required")
+		return 2
+	}
+
+	results, err := run(file, crd, clusterObjects, allowPatterns, denyPatterns)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	code := 0
+	for _, result := range results {
+		for _, warning := range result.Warnings {
+			fmt.Fprintf(stdout, "workspace/%s: warning: %s\n", result.Name, warning)
+		}
+		for _, e := range result.Errors {
+			fmt.Fprintf(stdout, "workspace/%s: error: %s\n", result.Name, e)
+		}
+		if len(result.Errors) > 0 {
+			code = 1
+			continue
+		}
+		fmt.Fprintf(stdout, "workspace/%s: valid\n", result.Name)
+	}
+	return code
+}
+
+func run(file, crd, clusterObjects, allowPatterns, denyPatterns string) ([]Result, error) {
+	manifest, err := os.ReadFile(crd)
+	if err != nil {
+		return nil, err
+	}
+	var classes []environmentv1alpha1.WorkspaceClass
+	var templates []environmentv1alpha1.WorkspaceTemplate
+	var clusterRoles []rbacv1.ClusterRole
+	if clusterObjects != "" {
+		classes = []environmentv1alpha1.WorkspaceClass{}
+		if err := readObjects(clusterObjects, "WorkspaceClass", func(data []byte) error {
+			class := environmentv1alpha1.WorkspaceClass{}
+			if err := json.Unmarshal(data, &class); err != nil {
+				return err
+			}
+			classes = append(classes, class)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if err := readObjects(clusterObjects, "WorkspaceTemplate", func(data []byte) error {
+			template := environmentv1alpha1.WorkspaceTemplate{}
+			if err := json.Unmarshal(data, &template); err != nil {
+				return err
+			}
+			templates = append(templates, template)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if err := readObjects(clusterObjects, "ClusterRole", func(data []byte) error {
+			clusterRole := rbacv1.ClusterRole{}
+			if err := json.Unmarshal(data, &clusterRole); err != nil {
+				return err
+			}
+			clusterRoles = append(clusterRoles, clusterRole)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	var namespacePolicy *controllers.NamespacePolicy
+	if allowPatterns != "" || denyPatterns != "" {
+		namespacePolicy, err = controllers.NewNamespacePolicy(allowPatterns, denyPatterns, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	validator, err := New(manifest, classes, templates, clusterRoles, namespacePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	in := os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	return validator.Validate(context.Background(), in)
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validate checks Workspace manifests offline, before they are applied, with the schema
+// of the Workspace CRD and the validating webhook of the operator, so that CI pipelines can
+// catch bad manifests without a cluster.
+package validate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/controllers"
+)
+
+// Result is the outcome of the validation of a Workspace manifest
+type Result struct {
+	// Name of the Workspace
+	Name string
+	// Errors make the API server or the webhook reject the Workspace
+	Errors []string
+	// Warnings are returned by the webhook without rejecting the Workspace
+	Warnings []string
+}
+
+// Validator validates Workspace manifests with the schema of the Workspace CRD and the validating webhook
+type Validator struct {
+	schema  *validate.SchemaValidator
+	webhook *controllers.WorkspaceValidator
+
+	// classes are the names of the known WorkspaceClasses, class references are not checked when nil
+	classes map[string]bool
+}
+
+// New returns a Validator for the schema of the Workspace CRD manifest. The WorkspaceClasses, WorkspaceTemplates
+// and ClusterRoles stand for the ones of the cluster, the namespace policy for the one of the operator.
+func New(crd []byte, classes []environmentv1alpha1.WorkspaceClass, templates []environmentv1alpha1.WorkspaceTemplate, clusterRoles []rbacv1.ClusterRole, namespacePolicy *controllers.NamespacePolicy) (*Validator, error) {
+	schema, err := workspaceSchema(crd)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(environmentv1alpha1.AddToScheme(scheme))
+	objects := make([]client.Object, 0, len(classes)+len(templates)+len(clusterRoles))
+	for i := range classes {
+		objects = append(objects, &classes[i])
+	}
+	for i := range templates {
+		objects = append(objects, &templates[i])
+	}
+	for i := range clusterRoles {
+		objects = append(objects, &clusterRoles[i])
+	}
+	v := &Validator{
+		schema: validate.NewSchemaValidator(schema, nil, "", strfmt.Default),
+		webhook: &controllers.WorkspaceValidator{
+			NamespacePolicy: namespacePolicy,
+			Client:          fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		},
+	}
+	if classes != nil {
+		v.classes = map[string]bool{}
+		for _, class := range classes {
+			v.classes[class.Name] = true
+		}
+	}
+	return v, nil
+}
+
+// workspaceSchema returns the OpenAPI schema of the served version of the Workspace CRD manifest
+func workspaceSchema(manifest []byte) (*spec.Schema, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(manifest, crd); err != nil {
+		return nil, err
+	}
+	for _, version := range crd.Spec.Versions {
+		if version.Name != environmentv1alpha1.GroupVersion.Version || version.Schema == nil {
+			continue
+		}
+		// The structural schema of a CRD is an OpenAPI v3 schema, it is read as is by the OpenAPI validator
+		data, err := json.Marshal(version.Schema.OpenAPIV3Schema)
+		if err != nil {
+			return nil, err
+		}
+		schema := &spec.Schema{}
+		if err := json.Unmarshal(data, schema); err != nil {
+			return nil, err
+		}
+		return schema, nil
+	}
+	return nil, fmt.Errorf("CustomResourceDefinition %s has no schema for version %s", crd.Name, environmentv1alpha1.GroupVersion.Version)
+}
+
+// Validate validates the Workspaces of a multi-document YAML stream, the other kinds are ignored
+func (v *Validator) Validate(ctx context.Context, r io.Reader) ([]Result, error) {
+	var results []Result
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			return results, nil
+		} else if err != nil {
+			return nil, err
+		}
+		data, err := yaml.YAMLToJSON(document)
+		if err != nil {
+			return nil, err
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, err
+		}
+		if object == nil || object["kind"] != "Workspace" {
+			continue
+		}
+		results = append(results, v.validateWorkspace(ctx, object, data))
+	}
+}
+
+// validateWorkspace runs the checks of the API server and then the ones of the webhook on a Workspace
+func (v *Validator) validateWorkspace(ctx context.Context, object map[string]interface{}, data []byte) Result {
+	result := Result{}
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		result.Name, _ = metadata["name"].(string)
+	}
+
+	// API server: object name, unknown fields and CRD schema
+	for _, msg := range utilvalidation.IsDNS1123Subdomain(result.Name) {
+		result.Errors = append(result.Errors, fmt.Sprintf("metadata.name: %s", msg))
+	}
+	workspace := &environmentv1alpha1.Workspace{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(workspace); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	for _, err := range v.schema.Validate(object).Errors {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	if workspace.Spec.ClassName != "" && v.classes != nil && !v.classes[workspace.Spec.ClassName] {
+		result.Errors = append(result.Errors, fmt.Sprintf("spec.className: WorkspaceClass %s does not exist", workspace.Spec.ClassName))
+	}
+	if len(result.Errors) > 0 {
+		return result
+	}
+
+	// Webhook: namespace policy and warnings
+	response := v.webhook.Handle(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Name:      workspace.Name,
+		Object:    runtime.RawExtension{Raw: data},
+	}})
+	if !response.Allowed {
+		message := "denied by the webhook"
+		if response.Result != nil && response.Result.Message != "" {
+			message = response.Result.Message
+		}
+		result.Errors = append(result.Errors, message)
+	}
+	result.Warnings = response.Warnings
+	return result
+}
+
+// readObjects calls decode with the JSON of each object of the given kind of a multi-document YAML file
+func readObjects(path, kind string, decode func(data []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(file))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		data, err := yaml.YAMLToJSON(document)
+		if err != nil {
+			return err
+		}
+		meta := struct {
+			Kind string `json:"kind"`
+		}{}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return err
+		}
+		if meta.Kind != kind {
+			continue
+		}
+		if err := decode(data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+}
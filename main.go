@@ -17,22 +17,34 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
 	"github.com/dunefro/workspace-operator/controllers"
+	"github.com/dunefro/workspace-operator/internal/cron"
+	"github.com/dunefro/workspace-operator/internal/logging"
+	"github.com/dunefro/workspace-operator/internal/validate"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -49,55 +61,845 @@ func init() {
 }
 
 func main() {
+	// workspace-operator validate -f file.yaml checks Workspace manifests offline
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validate.Run(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
+	var leaseDuration time.Duration
+	var renewDeadline time.Duration
+	var retryPeriod time.Duration
 	var probeAddr string
+	var auditEndpoint string
+	var auditBufferSize int
+	var notificationWebhook string
+	var logPipelineNamespace string
+	var tenantRegistryEndpoint string
+	var openCostEndpoint string
+	var spendRefreshInterval time.Duration
+	var usageSnapshotInterval time.Duration
+	var historyLimit int
+	var watchNamespaces string
+	var workspaceSelector string
+	var chargebackSchedule string
+	var chargebackNamespace string
+	var chargebackSMTPServer string
+	var chargebackEmailFrom string
+	var chargebackEmailTo string
+	var chargebackRetention int
+	var enableWebhook bool
+	var enableUsageAPI bool
+	var webhookCertMode string
+	var webhookCertDir string
+	var webhookCertSecret string
+	var webhookService string
+	var webhookConfiguration string
+	var webhookCertValidity time.Duration
+	var benchmarkInterval time.Duration
+	var namespaceAllowPatterns string
+	var namespaceDenyPatterns string
+	var namespaceApprovers string
+	var grantNamespaces string
+	var grantClusterRoles string
+	var resyncPeriod time.Duration
+	var resyncJitter time.Duration
+	var terminationTimeout time.Duration
+	var forceCleanupFinalizers string
+	var createParallelism int
+	var metricsLevel string
+	var namespacedOnly bool
+	var autoWorkspaces bool
+	var budgetUnitPrices string
+	var budgetSplit string
+	var devicePluginConfig string
+	var recertificationInterval time.Duration
+	var recertificationGracePeriod time.Duration
+	var expiryWarningPeriod time.Duration
+	var groupResolverEndpoint string
+	var subjectVerifierEndpoint string
+	var deletionProtection bool
+	var operatorUsername string
+	var renameGracePeriod time.Duration
+	var renameHookEndpoint string
+	var metricsDetailedWorkspaces string
+	var logConfig string
+	var logWorkspaceRate float64
+	var logWorkspaceBurst int
+	var logSampleEvery int
+	var githubAPIURL string
+	var githubTokenFile string
+	var gitlabURL string
+	var gitlabTokenFile string
+	var teamSyncInterval time.Duration
+	var teamSyncUserPrefix string
+	var teamSyncWebhookSecretFile string
+	var datadogAPIKeyFile string
+	var datadogSite string
+	var datadogInterval time.Duration
+	var datadogTags string
+	var datadogTagLabels string
+	var snapshotNamespace string
+	var snapshotStoreURL string
+	var snapshotStoreTokenFile string
+	var migrationTargetGroup string
+	var migrationWindowEnd string
+	var roleTemplatesFile string
+	var roleAPIGroups string
+	var roleAllowedAPIGroups string
+	var rbacChangeDelay time.Duration
+	var rbacChangeAcknowledgment bool
+	var identityEventsSecretFile string
+	var requiredLabelsFile string
+	var reconcileTimeout time.Duration
+	var circuitBreakerThreshold int
+	var circuitBreakerMaxBackoff time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&leaseDuration, "leader-elect-lease-duration", 15*time.Second,
+		"Time the standby replicas wait before taking over a leadership that is not renewed, e.g. after the eviction of the leader.")
+	flag.DurationVar(&renewDeadline, "leader-elect-renew-deadline", 10*time.Second,
+		"Time the leader retries to renew its leadership before giving it up. Must be lower than --leader-elect-lease-duration.")
+	flag.DurationVar(&retryPeriod, "leader-elect-retry-period", 2*time.Second,
+		"Time between two attempts of the replicas to acquire or renew the leadership.")
+	flag.StringVar(&auditEndpoint, "audit-endpoint", "",
+		"Endpoint that receives structured audit records for RBAC changes made by the operator. "+
+			"Supported schemes are http, https, syslog+udp and syslog+tcp. Auditing is disabled when empty.")
+	flag.IntVar(&auditBufferSize, "audit-buffer-size", controllers.DefaultAuditBufferSize,
+		"Number of audit records buffered while they are shipped to --audit-endpoint in the background. "+
+			"The records are dropped while the buffer is full.")
+	flag.StringVar(&notificationWebhook, "notification-webhook", "",
+		"URL that receives JSON notifications about workspaces, e.g. when quota usage crosses a threshold. "+
+			"Notifications are disabled when empty.")
+	flag.StringVar(&logPipelineNamespace, "log-pipeline-namespace", "",
+		"Namespace the rendered log pipeline ConfigMaps are created in. "+
+			"Defaults to the workspace namespace when empty.")
+	flag.StringVar(&tenantRegistryEndpoint, "observability-tenant-endpoint", "",
+		"Endpoint of the service that maps workspace namespaces to Loki/Mimir tenants. "+
+			"Only the namespace annotation is managed when empty.")
+	flag.StringVar(&openCostEndpoint, "opencost-endpoint", "",
+		"Base URL of the OpenCost/Kubecost allocation API used to report the spend of workspaces. "+
+			"Spend reporting is disabled when empty.")
+	flag.DurationVar(&spendRefreshInterval, "spend-refresh-interval", time.Hour,
+		"Minimum time between two spend queries for a workspace.")
+	flag.DurationVar(&usageSnapshotInterval, "usage-snapshot-interval", 24*time.Hour,
+		"Time between two snapshots of the usage of a workspace recorded in its status, "+
+			"from which the week-over-week trend of the usage is computed. The usage history is disabled when 0.")
+	flag.IntVar(&historyLimit, "status-history-limit", 10,
+		"Number of applied spec revisions kept in the status of a workspace. History is disabled when 0.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma separated target namespaces of the workspaces managed by this operator instance. "+
+			"All the workspaces are managed when empty.")
+	flag.StringVar(&workspaceSelector, "workspace-selector", "",
+		"Label selector of the workspaces managed by this operator instance, e.g. \"business-unit=payments\".")
+	flag.StringVar(&chargebackSchedule, "chargeback-schedule", "",
+		"Cron schedule on which chargeback reports of all workspaces are generated, e.g. \"0 0 1 * *\". "+
+			"Chargeback reports are disabled when empty.")
+	flag.StringVar(&chargebackNamespace, "chargeback-namespace", "workspace-operator-system",
+		"Namespace the chargeback report ConfigMaps are created in.")
+	flag.StringVar(&chargebackSMTPServer, "chargeback-smtp-server", "",
+		"host:port of the SMTP server used to email chargeback reports. Reports are not emailed when empty.")
+	flag.StringVar(&chargebackEmailFrom, "chargeback-email-from", "", "Sender address of the chargeback report emails.")
+	flag.StringVar(&chargebackEmailTo, "chargeback-email-to", "", "Comma separated recipients of the chargeback report emails.")
+	flag.IntVar(&chargebackRetention, "chargeback-retention", controllers.DefaultChargebackRetention,
+		"Number of most recent chargeback report ConfigMaps kept, the older ones are deleted. All the reports are kept when 0.")
+	flag.DurationVar(&benchmarkInterval, "benchmark-interval", time.Hour,
+		"Minimum time between two multi-tenancy benchmark self-checks of a workspace. The self-check is disabled when 0.")
+	flag.BoolVar(&enableWebhook, "enable-webhook", false,
+		"Serve the validating webhook of the workspaces. Requires the webhook certificates, see config/default.")
+	flag.StringVar(&webhookCertMode, "webhook-cert-mode", controllers.WebhookCertModeCertManager,
+		"Who manages the serving certificate of the webhook server: cert-manager, see config/certmanager, "+
+			"or self-managed, where the operator generates and rotates it and injects its CA into the webhook configuration.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"Directory the webhook server reads tls.crt and tls.key from.")
+	flag.StringVar(&webhookCertSecret, "webhook-cert-secret", "webhook-server-cert",
+		"Secret of the namespace of the webhook Service holding the self-managed certificates.")
+	flag.StringVar(&webhookService, "webhook-service", "workspace-operator-system/workspace-operator-webhook-service",
+		"Namespace/name of the Service of the webhook server, the subject of the self-managed serving certificate.")
+	flag.StringVar(&webhookConfiguration, "webhook-configuration", "workspace-operator-validating-webhook-configuration",
+		"ValidatingWebhookConfiguration the CA of the self-managed certificates is injected into.")
+	flag.DurationVar(&webhookCertValidity, "webhook-cert-validity", controllers.DefaultWebhookCertValidity,
+		"Validity of the self-managed serving certificates, they are renewed once two thirds of it are over.")
+	flag.BoolVar(&enableUsageAPI, "enable-usage-api", false,
+		"Serve the usage of the workspaces on "+controllers.UsageAPIPath+"/workspaces/<name>/usage through the "+
+			"API aggregation layer. Requires --enable-webhook and the APIService of config/usageapi.")
+	flag.StringVar(&namespaceAllowPatterns, "namespace-allow-patterns", "",
+		"Comma separated regular expressions of which the target namespace of a workspace must match one, "+
+			"e.g. \"team-.*\". All the namespaces are allowed when empty.")
+	flag.StringVar(&namespaceDenyPatterns, "namespace-deny-patterns", "",
+		"Comma separated regular expressions the target namespace of a workspace must not match, e.g. \"prod-.*,kube-.*\".")
+	flag.StringVar(&namespaceApprovers, "namespace-approvers", "",
+		"Comma separated users and groups allowed to approve a denied namespace with the "+
+			controllers.ApprovedNamespaceAnnotation+" annotation. Denied namespaces can not be approved when empty.")
+	flag.StringVar(&grantNamespaces, "grant-namespaces", "",
+		"Comma separated shared namespaces the spec.grants of the workspaces can target, e.g. \"kafka,ingress-nginx\". "+
+			"No grant is allowed when empty.")
+	flag.StringVar(&grantClusterRoles, "grant-cluster-roles", "",
+		"Comma separated ClusterRoles the spec.grants of the workspaces can bind in the shared namespaces, e.g. \"view\". "+
+			"No ClusterRole can be granted when empty.")
+	flag.DurationVar(&resyncPeriod, "resync-period", controllers.DefaultResyncPeriod,
+		"Time after which a ready workspace is reconciled again to restore the drifted resources the watches missed.")
+	flag.DurationVar(&resyncJitter, "resync-jitter", time.Minute,
+		"Window the resyncs of the workspaces are spread over, so that they do not all reconcile at once. "+
+			"Each workspace gets a stable offset in the window derived from its name. Disabled when 0.")
+	flag.DurationVar(&terminationTimeout, "namespace-termination-timeout", controllers.DefaultTerminationTimeout,
+		"Time after which a workspace namespace stuck terminating is reported with the TerminationBlocked condition.")
+	flag.StringVar(&forceCleanupFinalizers, "force-cleanup-finalizers", "",
+		"Comma separated known-safe finalizers stripped from the resources blocking the termination of the namespace "+
+			"of a deleted workspace annotated with environment.tf.operator.com/force-cleanup=true. "+
+			"Requires --enable-webhook, which only lets the users allowed the force-cleanup verb on the workspace set the annotation.")
+	flag.IntVar(&createParallelism, "create-parallelism", controllers.DefaultCreateParallelism,
+		"Number of independent child objects of a workspace, e.g. its quota, roles and rolebindings, created or patched concurrently.")
+	flag.StringVar(&metricsLevel, "metrics-level", string(controllers.MetricsLevelWorkspace),
+		"Aggregation level of the workspace metrics: global, class or workspace. "+
+			"The series labeled per workspace are only exported at the workspace level, lower it on large fleets.")
+	flag.StringVar(&metricsDetailedWorkspaces, "metrics-detailed-workspaces", "",
+		"Comma separated workspaces whose per-workspace series are exported whatever the metrics level.")
+	flag.BoolVar(&namespacedOnly, "namespaced-only", false,
+		"Run without the rights to create, update and delete namespaces. The namespaces of the workspaces must be pre-created "+
+			"and adopted with the environment.tf.operator.com/adopted-by=<workspace> annotation, only the resources inside them are managed.")
+	flag.BoolVar(&autoWorkspaces, "auto-workspaces", false,
+		"Create the Workspaces of the namespaces annotated with environment.tf.operator.com/auto-workspace=true.")
+	flag.StringVar(&budgetUnitPrices, "budget-unit-prices", "",
+		"Comma separated monthly unit prices of a cpu core, a GiB of memory and a GiB of storage, e.g. cpu=20,memory=2.5,storage=0.1, "+
+			"the spec.budget of the workspaces is translated into ResourceQuota hard limits with. Workspaces with a budget are stalled when empty.")
+	flag.StringVar(&budgetSplit, "budget-split", controllers.DefaultBudgetSplit,
+		"Comma separated percentages of the budget of a workspace spent on cpu, memory and storage. They must add up to 100.")
+	flag.StringVar(&devicePluginConfig, "gpu-device-plugin-config", "",
+		"Namespace/name of the NVIDIA device plugin ConfigMap, e.g. gpu-operator/device-plugin-config, the time slicing "+
+			"configurations of spec.gpu.timeSlicingReplicas are written to. The nodes select one with the nvidia.com/device-plugin.config label.")
+	flag.DurationVar(&recertificationInterval, "recertification-interval", 0,
+		"Time after which the owner of a workspace must re-attest its access, e.g. 2160h for 90 days, by annotating it with "+
+			controllers.RecertifiedAtAnnotation+"=<RFC 3339 time>. Recertification is disabled when 0.")
+	flag.DurationVar(&recertificationGracePeriod, "recertification-grace-period", controllers.DefaultRecertificationGracePeriod,
+		"Time the owner of a workspace has to re-attest its access once the review is due, after which its editor and viewer bindings are dropped.")
+	flag.DurationVar(&expiryWarningPeriod, "expiry-warning-period", controllers.DefaultExpiryWarningPeriod,
+		"Time before the spec.deleteAt of a workspace its owner is warned with the Expiring condition and a notification.")
+	flag.StringVar(&groupResolverEndpoint, "group-resolver-endpoint", "",
+		"Endpoint of the identity provider API resolving the members of the groups shown in status.access, "+
+			"through GET <endpoint>/groups/<group>. The groups are listed without their members when empty.")
+	flag.StringVar(&subjectVerifierEndpoint, "subject-verifier-endpoint", "",
+		"Endpoint of the identity provider API the users and groups of spec.users and spec.subjects are looked up in, through "+
+			"GET <endpoint>/users/<user> and GET <endpoint>/groups/<group>, so that the ones never seen authenticating are reported "+
+			"in the UnknownSubjects condition. The subjects are not verified when empty.")
+	flag.BoolVar(&deletionProtection, "deletion-protection", false,
+		"Generate ValidatingAdmissionPolicies preventing the users of a workspace from deleting its namespace "+
+			"and the objects the operator owns in it, which are then only deleted through the Workspace.")
+	flag.StringVar(&operatorUsername, "operator-username", controllers.DefaultOperatorUsername,
+		"Username the operator authenticates to the API server with, exempted from the deletion protection.")
+	flag.DurationVar(&renameGracePeriod, "rename-grace-period", controllers.DefaultRenameGracePeriod,
+		"Time the previous namespace of a renamed workspace is kept before it is deleted.")
+	flag.StringVar(&renameHookEndpoint, "rename-hook-endpoint", "",
+		"URL the renames of workspaces are POSTed to, e.g. to migrate their workloads and data to the new namespace. "+
+			"Only the objects managed by the operator are provisioned in the new namespace when empty.")
+	flag.StringVar(&logConfig, "log-config", "",
+		"Path of a YAML file, e.g. a mounted ConfigMap, with the level, workspaceRate, workspaceBurst and sampleEvery "+
+			"settings of the logs. It is reloaded every 10s and overrides the log flags.")
+	flag.Float64Var(&logWorkspaceRate, "log-workspace-rate", 0,
+		"Maximum number of info log entries per second for a single workspace. Unlimited when 0.")
+	flag.IntVar(&logWorkspaceBurst, "log-workspace-burst", 10,
+		"Number of info log entries of a workspace logged at once above --log-workspace-rate.")
+	flag.IntVar(&logSampleEvery, "log-sample-every", 1,
+		"Log one of every N of the chatty \"Creating\", \"Checking\", \"Deleting\" and \"Updating\" info entries of a workspace.")
+	flag.StringVar(&githubAPIURL, "github-api-url", "https://api.github.com",
+		"Base URL of the REST API of GitHub the members of the GitHub teams of spec.teams are read from.")
+	flag.StringVar(&githubTokenFile, "github-token-file", "",
+		"Path of a file holding a GitHub token allowed to read the teams of the organizations. The GitHub teams are not synced when empty.")
+	flag.StringVar(&gitlabURL, "gitlab-url", "https://gitlab.com",
+		"Base URL of the GitLab instance the members of the GitLab groups of spec.teams are read from.")
+	flag.StringVar(&gitlabTokenFile, "gitlab-token-file", "",
+		"Path of a file holding a GitLab token with the read_api scope. The GitLab groups are not synced when empty.")
+	flag.DurationVar(&teamSyncInterval, "team-sync-interval", controllers.DefaultTeamSyncInterval,
+		"Time after which the members of the teams of spec.teams are fetched again.")
+	flag.StringVar(&teamSyncUserPrefix, "team-sync-user-prefix", "",
+		"Prefix of the Kubernetes usernames of the members of the teams, e.g. github: when the OIDC provider of the cluster prefixes the usernames.")
+	flag.StringVar(&teamSyncWebhookSecretFile, "team-sync-webhook-secret-file", "",
+		"Path of a file holding the secret of the GitHub and GitLab webhooks, served on "+controllers.TeamSyncGitHubPath+" and "+
+			controllers.TeamSyncGitLabPath+" so that membership changes are synced immediately. Requires --enable-webhook.")
+	flag.StringVar(&datadogAPIKeyFile, "datadog-api-key-file", "",
+		"Path of a file holding a Datadog API key. The quota utilization of the workspaces and their events are sent to Datadog "+
+			"when set, tagged with the workspace, its namespace, its class and the team of spec.owner.")
+	flag.StringVar(&datadogSite, "datadog-site", controllers.DefaultDatadogSite,
+		"Datadog site the metrics and events are sent to, e.g. datadoghq.eu or us5.datadoghq.com.")
+	flag.DurationVar(&datadogInterval, "datadog-interval", controllers.DefaultDatadogInterval,
+		"Time between two submissions of the quota utilization of the workspaces to Datadog.")
+	flag.StringVar(&datadogTags, "datadog-tags", "",
+		"Comma separated tags added to all the Datadog metrics and events, e.g. env:prod,cluster:eu-1.")
+	flag.StringVar(&datadogTagLabels, "datadog-tag-labels", "",
+		"Comma separated labels of the workspaces added as tags to their Datadog metrics and events, e.g. cost-center.")
+	flag.StringVar(&snapshotNamespace, "snapshot-namespace", "workspace-operator-system",
+		"Namespace the ConfigMaps of the InCluster WorkspaceSnapshots are created in.")
+	flag.StringVar(&snapshotStoreURL, "snapshot-store-url", "",
+		"URL of the object storage bucket the contents of the ObjectStorage WorkspaceSnapshots are PUT under, "+
+			"e.g. https://storage.googleapis.com/snapshots. ObjectStorage snapshots can not be taken nor restored when empty.")
+	flag.StringVar(&snapshotStoreTokenFile, "snapshot-store-token-file", "",
+		"Path of a file holding the bearer token sent to the object storage of the snapshots.")
+	flag.StringVar(&migrationTargetGroup, "migration-target-group", "",
+		"API group the Workspaces are mirrored to during a group migration, e.g. workspaces.example.com. "+
+			"The Workspace CRD of the group must be installed. The migration is disabled when empty.")
+	flag.StringVar(&migrationWindowEnd, "migration-window-end", "",
+		"End of the transition window of the group migration in RFC3339, after which the mirrors are no longer synced. "+
+			"The mirrors are synced until the migration is disabled when empty.")
+	flag.StringVar(&roleTemplatesFile, "role-templates", "",
+		"Path of a YAML file, e.g. a mounted ConfigMap, with the admin, editor and viewer lists of rules of the Roles of the workspaces. "+
+			"The roles missing from the file, or all of them when empty, keep their built-in rules.")
+	flag.StringVar(&roleAPIGroups, "role-api-groups", strings.Join(controllers.DefaultRoleAPIGroups, ","),
+		"Comma separated API groups covered by the built-in rules of the Roles of the workspaces, with an empty item for the core group. "+
+			"A workspace overrides them with spec.rbac.apiGroups.")
+	flag.StringVar(&roleAllowedAPIGroups, "role-allowed-api-groups", "",
+		"Comma separated API groups the spec.rbac.apiGroups of the workspaces can list, with an empty item for the core group. "+
+			"Only the ones of --role-api-groups are allowed when empty.")
+	flag.DurationVar(&rbacChangeDelay, "rbac-change-delay", 0,
+		"Time the changes of the rules of the Roles of a workspace are published in its status.pendingChanges before they are applied.")
+	flag.BoolVar(&rbacChangeAcknowledgment, "rbac-change-acknowledgment", false,
+		"Only apply the changes of the rules of the Roles of a workspace once acknowledged by annotating it with "+
+			controllers.AcknowledgeRBACChangesAnnotation+"=<status.pendingChanges.hash>.")
+	flag.StringVar(&identityEventsSecretFile, "identity-events-secret-file", "",
+		"Path of a file holding the secret the user deactivation and group change events of the identity provider or HR system "+
+			"are signed with, served on "+controllers.IdentityEventsPath+" so that the RoleBindings are updated immediately. Requires --enable-webhook.")
+	flag.StringVar(&requiredLabelsFile, "required-labels", "",
+		"Path of a YAML file listing the key, default and allowedValues of the labels every workspace namespace must carry, "+
+			"e.g. its data classification. The Workspaces must set the ones without default in spec.labels.")
+	flag.DurationVar(&reconcileTimeout, "reconcile-timeout", controllers.DefaultReconcileTimeout,
+		"Time a single reconciliation of a workspace may take before it is cancelled and reported as failed. Not bounded when 0.")
+	flag.IntVar(&circuitBreakerThreshold, "circuit-breaker-threshold", controllers.DefaultCircuitBreakerThreshold,
+		"Number of consecutive failed reconciliations of a workspace after which it is backed off, starting at 1m and doubling "+
+			"up to --circuit-breaker-max-backoff, and reported as Stalled with the CircuitOpen reason. Disabled when 0.")
+	flag.DurationVar(&circuitBreakerMaxBackoff, "circuit-breaker-max-backoff", controllers.DefaultCircuitBreakerMaxBackoff,
+		"Maximum time a workspace whose circuit is open is left alone.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	logControls := logging.NewControls(logConfig, logging.Config{
+		WorkspaceRate:  logWorkspaceRate,
+		WorkspaceBurst: logWorkspaceBurst,
+		SampleEvery:    logSampleEvery,
+	})
+	logControls.BindOptions(&opts)
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	if err := logControls.Load(); err != nil {
+		setupLog.Error(err, "unable to load log config")
+		os.Exit(1)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
+		CertDir:                webhookCertDir,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "66f57e72.tf.operator.com",
+		LeaseDuration:          &leaseDuration,
+		RenewDeadline:          &renewDeadline,
+		RetryPeriod:            &retryPeriod,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
 		// speeds up voluntary leader transitions as the new leader don't have to wait
 		// LeaseDuration time first.
 		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
+		// The program ends immediately after the manager stops, so that an evicted or
+		// rolled leader hands over to the warm standby replica right away.
+		LeaderElectionReleaseOnCancel: true,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	// The duplicate target namespaces of the Workspaces are looked up through an index of the cache
+	if err := controllers.IndexWorkspaceNamespaces(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to index Workspaces by namespace")
+		os.Exit(1)
+	}
+	// The Workspaces depending on a Workspace are looked up through an index of the cache
+	if err := controllers.IndexWorkspaceDependencies(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to index Workspaces by dependency")
+		os.Exit(1)
+	}
+	// The Workspaces created from a WorkspaceTemplate are looked up through an index of the cache
+	if err := controllers.IndexWorkspaceTemplates(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to index Workspaces by template")
+		os.Exit(1)
+	}
+
+	var filter *controllers.WorkspaceFilter
+	if watchNamespaces != "" || workspaceSelector != "" {
+		filter = &controllers.WorkspaceFilter{}
+		if watchNamespaces != "" {
+			filter.Namespaces = strings.Split(watchNamespaces, ",")
+		}
+		if workspaceSelector != "" {
+			filter.Selector, err = labels.Parse(workspaceSelector)
+			if err != nil {
+				setupLog.Error(err, "unable to parse workspace selector")
+				os.Exit(1)
+			}
+		}
+	}
+
+	var namespacePolicy *controllers.NamespacePolicy
+	if namespaceAllowPatterns != "" || namespaceDenyPatterns != "" {
+		namespacePolicy, err = controllers.NewNamespacePolicy(namespaceAllowPatterns, namespaceDenyPatterns, namespaceApprovers)
+		if err != nil {
+			setupLog.Error(err, "unable to parse namespace patterns")
+			os.Exit(1)
+		}
+	}
+
+	grantPolicy := controllers.NewGrantPolicy(grantNamespaces, grantClusterRoles)
+
+	var auditSink controllers.AuditSink
+	if auditEndpoint != "" {
+		sink, err := controllers.NewAuditSink(auditEndpoint)
+		if err != nil {
+			setupLog.Error(err, "unable to set up audit sink")
+			os.Exit(1)
+		}
+		auditQueue := controllers.NewAuditQueue(sink, auditBufferSize)
+		if err := mgr.Add(auditQueue); err != nil {
+			setupLog.Error(err, "unable to set up audit queue")
+			os.Exit(1)
+		}
+		auditSink = auditQueue
+	}
+
+	var notifier controllers.Notifier
+	if notificationWebhook != "" {
+		notifier = controllers.NewWebhookNotifier(notificationWebhook)
+	}
+
+	var migrationHook controllers.MigrationHook
+	if renameHookEndpoint != "" {
+		migrationHook = controllers.NewHTTPMigrationHook(renameHookEndpoint)
+	}
+
+	var groupResolver controllers.GroupResolver
+	if groupResolverEndpoint != "" {
+		groupResolver = controllers.NewHTTPGroupResolver(groupResolverEndpoint)
+	}
+
+	var subjectVerifier controllers.SubjectVerifier
+	if subjectVerifierEndpoint != "" {
+		subjectVerifier = controllers.NewHTTPSubjectVerifier(subjectVerifierEndpoint)
+	}
+
+	var tenantRegistry controllers.TenantRegistry
+	if tenantRegistryEndpoint != "" {
+		tenantRegistry = controllers.NewHTTPTenantRegistry(tenantRegistryEndpoint)
+	}
+
+	var costProvider controllers.CostProvider
+	if openCostEndpoint != "" {
+		costProvider = controllers.NewOpenCostProvider(openCostEndpoint)
+	}
+
+	var forceCleanup []string
+	var discoveryClient discovery.DiscoveryInterface
+	if forceCleanupFinalizers != "" {
+		if !enableWebhook {
+			setupLog.Error(nil, "--force-cleanup-finalizers requires --enable-webhook")
+			os.Exit(1)
+		}
+		forceCleanup = strings.Split(forceCleanupFinalizers, ",")
+		discoveryClient, err = discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+		if err != nil {
+			setupLog.Error(err, "unable to set up discovery client")
+			os.Exit(1)
+		}
+	}
+
+	var teamSync *controllers.TeamSync
+	if githubTokenFile != "" || gitlabTokenFile != "" {
+		providers := map[environmentv1alpha1.WorkspaceTeamProvider]controllers.TeamProvider{}
+		if githubTokenFile != "" {
+			token, err := readSecretFile(githubTokenFile)
+			if err != nil {
+				setupLog.Error(err, "unable to read GitHub token")
+				os.Exit(1)
+			}
+			providers[environmentv1alpha1.TeamProviderGitHub] = controllers.NewGitHubTeamProvider(githubAPIURL, token)
+		}
+		if gitlabTokenFile != "" {
+			token, err := readSecretFile(gitlabTokenFile)
+			if err != nil {
+				setupLog.Error(err, "unable to read GitLab token")
+				os.Exit(1)
+			}
+			providers[environmentv1alpha1.TeamProviderGitLab] = controllers.NewGitLabTeamProvider(gitlabURL, token)
+		}
+		teamSync = controllers.NewTeamSync(mgr.GetClient(), providers)
+		teamSync.Interval = teamSyncInterval
+		teamSync.UserPrefix = teamSyncUserPrefix
+		if teamSyncWebhookSecretFile != "" {
+			if !enableWebhook {
+				setupLog.Error(nil, "--team-sync-webhook-secret-file requires --enable-webhook")
+				os.Exit(1)
+			}
+			teamSync.WebhookSecret, err = readSecretFile(teamSyncWebhookSecretFile)
+			if err != nil {
+				setupLog.Error(err, "unable to read team sync webhook secret")
+				os.Exit(1)
+			}
+		}
+	}
+
+	var budgetPricing *controllers.BudgetPricing
+	if budgetUnitPrices != "" {
+		budgetPricing, err = controllers.NewBudgetPricing(budgetUnitPrices, budgetSplit)
+		if err != nil {
+			setupLog.Error(err, "unable to parse budget pricing")
+			os.Exit(1)
+		}
+	}
+
+	var devicePluginConfigMap *types.NamespacedName
+	if devicePluginConfig != "" {
+		devicePluginConfigMap, err = controllers.ParseDevicePluginConfig(devicePluginConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to parse device plugin ConfigMap")
+			os.Exit(1)
+		}
+	}
+
+	recorder := mgr.GetEventRecorderFor("workspace-controller")
+	if datadogAPIKeyFile != "" {
+		apiKey, err := readSecretFile(datadogAPIKeyFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read Datadog API key")
+			os.Exit(1)
+		}
+		publisher := &controllers.DatadogPublisher{
+			Client:   mgr.GetClient(),
+			APIKey:   apiKey,
+			Site:     datadogSite,
+			Interval: datadogInterval,
+			Filter:   filter,
+		}
+		if datadogTags != "" {
+			publisher.Tags = strings.Split(datadogTags, ",")
+		}
+		if datadogTagLabels != "" {
+			publisher.TagLabels = strings.Split(datadogTagLabels, ",")
+		}
+		if err := mgr.Add(publisher); err != nil {
+			setupLog.Error(err, "unable to set up Datadog publisher")
+			os.Exit(1)
+		}
+		recorder = controllers.NewDatadogRecorder(recorder, publisher)
+	}
+
+	var snapshotStore controllers.SnapshotStore
+	if snapshotStoreURL != "" {
+		var token string
+		if snapshotStoreTokenFile != "" {
+			token, err = readSecretFile(snapshotStoreTokenFile)
+			if err != nil {
+				setupLog.Error(err, "unable to read snapshot store token")
+				os.Exit(1)
+			}
+		}
+		snapshotStore = controllers.NewHTTPSnapshotStore(snapshotStoreURL, token)
+	}
+
+	var requiredLabels controllers.RequiredLabels
+	if requiredLabelsFile != "" {
+		requiredLabels, err = controllers.LoadRequiredLabels(requiredLabelsFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load required labels")
+			os.Exit(1)
+		}
+	}
+
+	var roleTemplates *environmentv1alpha1.WorkspaceRoleTemplates
+	if roleTemplatesFile != "" {
+		roleTemplates, err = controllers.LoadRoleTemplates(roleTemplatesFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load role templates")
+			os.Exit(1)
+		}
+	}
+
+	var allowedAPIGroups []string
+	if roleAllowedAPIGroups != "" {
+		allowedAPIGroups = strings.Split(roleAllowedAPIGroups, ",")
+	}
+
+	metricsAggregation, err := controllers.ParseMetricsLevel(metricsLevel)
+	if err != nil {
+		setupLog.Error(err, "unable to parse metrics level")
+		os.Exit(1)
+	}
+	var metricsDetailed []string
+	if metricsDetailedWorkspaces != "" {
+		metricsDetailed = strings.Split(metricsDetailedWorkspaces, ",")
+	}
+	provisioningMetrics := controllers.NewProvisioningMetrics(metricsAggregation, metricsDetailed)
+
 	if err = (&controllers.WorkspaceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Audit:    auditSink,
+		Recorder: recorder,
+		Notifier: notifier,
+
+		LogPipelineNamespace:   logPipelineNamespace,
+		TenantRegistry:         tenantRegistry,
+		CostProvider:           costProvider,
+		SpendRefreshInterval:   spendRefreshInterval,
+		UsageSnapshotInterval:  usageSnapshotInterval,
+		HistoryLimit:           historyLimit,
+		Filter:                 filter,
+		NamespacePolicy:        namespacePolicy,
+		GrantPolicy:            grantPolicy,
+		BenchmarkInterval:      benchmarkInterval,
+		ResyncPeriod:           resyncPeriod,
+		ResyncJitter:           resyncJitter,
+		TerminationTimeout:     terminationTimeout,
+		ForceCleanupFinalizers: forceCleanup,
+		Discovery:              discoveryClient,
+		CreateParallelism:      createParallelism,
+		NamespacedOnly:         namespacedOnly,
+		BudgetPricing:          budgetPricing,
+		DevicePluginConfig:     devicePluginConfigMap,
+
+		RecertificationInterval:    recertificationInterval,
+		RecertificationGracePeriod: recertificationGracePeriod,
+		ExpiryWarningPeriod:        expiryWarningPeriod,
+
+		GroupResolver:   groupResolver,
+		SubjectVerifier: subjectVerifier,
+
+		DeletionProtection: deletionProtection,
+		OperatorUsername:   operatorUsername,
+
+		RenameGracePeriod: renameGracePeriod,
+		MigrationHook:     migrationHook,
+
+		TeamSync: teamSync,
+
+		SnapshotStore: snapshotStore,
+
+		RoleTemplates:            roleTemplates,
+		RoleAPIGroups:            strings.Split(roleAPIGroups, ","),
+		RoleAllowedAPIGroups:     allowedAPIGroups,
+		RBACChangeDelay:          rbacChangeDelay,
+		RBACChangeAcknowledgment: rbacChangeAcknowledgment,
+
+		RequiredLabels: requiredLabels,
+
+		ReconcileTimeout:         reconcileTimeout,
+		CircuitBreakerThreshold:  circuitBreakerThreshold,
+		CircuitBreakerMaxBackoff: circuitBreakerMaxBackoff,
+
+		Metrics: provisioningMetrics,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Workspace")
 		os.Exit(1)
 	}
+	if err = (&controllers.WorkspaceSnapshotReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("workspace-snapshot-controller"),
+		Namespace: snapshotNamespace,
+		Store:     snapshotStore,
+		Filter:    filter,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WorkspaceSnapshot")
+		os.Exit(1)
+	}
+	var groupMigration *controllers.GroupMigrationReconciler
+	if migrationTargetGroup != "" {
+		groupMigration = &controllers.GroupMigrationReconciler{
+			Client:      mgr.GetClient(),
+			TargetGroup: migrationTargetGroup,
+			Filter:      filter,
+		}
+		if migrationWindowEnd != "" {
+			groupMigration.WindowEnd, err = time.Parse(time.RFC3339, migrationWindowEnd)
+			if err != nil {
+				setupLog.Error(err, "unable to parse migration window end")
+				os.Exit(1)
+			}
+		}
+		if err = groupMigration.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "GroupMigration")
+			os.Exit(1)
+		}
+	}
+	if autoWorkspaces {
+		if err = (&controllers.NamespaceReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("auto-workspace-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Namespace")
+			os.Exit(1)
+		}
+	}
+	if enableWebhook {
+		mgr.GetWebhookServer().Register(controllers.WorkspaceValidatorPath, &webhook.Admission{
+			Handler: &controllers.WorkspaceValidator{
+				NamespacePolicy:      namespacePolicy,
+				Client:               mgr.GetClient(),
+				RequiredLabels:       requiredLabels,
+				GrantPolicy:          grantPolicy,
+				RoleAPIGroups:        strings.Split(roleAPIGroups, ","),
+				RoleAllowedAPIGroups: allowedAPIGroups,
+				OperatorUsername:     operatorUsername,
+			},
+		})
+		if teamSync != nil && teamSync.WebhookSecret != "" {
+			mgr.GetWebhookServer().Register(controllers.TeamSyncGitHubPath, teamSync.GitHubWebhook())
+			mgr.GetWebhookServer().Register(controllers.TeamSyncGitLabPath, teamSync.GitLabWebhook())
+		}
+	}
+	if identityEventsSecretFile != "" {
+		if !enableWebhook {
+			setupLog.Error(nil, "--identity-events-secret-file requires --enable-webhook")
+			os.Exit(1)
+		}
+		secret, err := readSecretFile(identityEventsSecretFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read identity events secret")
+			os.Exit(1)
+		}
+		mgr.GetWebhookServer().Register(controllers.IdentityEventsPath, &controllers.IdentityEvents{
+			Client: mgr.GetClient(),
+			Secret: secret,
+			Filter: filter,
+		})
+	}
+	if enableUsageAPI {
+		if !enableWebhook {
+			setupLog.Error(nil, "--enable-usage-api requires --enable-webhook")
+			os.Exit(1)
+		}
+		// The cache is not started yet, the aggregation layer configuration is read from the API server
+		usageAPI, err := controllers.NewUsageAPI(context.Background(), mgr.GetClient(), mgr.GetAPIReader())
+		if err != nil {
+			setupLog.Error(err, "unable to set up usage API")
+			os.Exit(1)
+		}
+		usageAPI.Pricing = budgetPricing
+		usageAPI.Filter = filter
+		webhookServer := mgr.GetWebhookServer()
+		webhookServer.TLSOpts = append(webhookServer.TLSOpts, usageAPI.TLSOption)
+		webhookServer.Register(controllers.UsageAPIPath, usageAPI)
+		webhookServer.Register(controllers.UsageAPIPath+"/", usageAPI)
+	}
+	switch webhookCertMode {
+	case controllers.WebhookCertModeCertManager:
+		// The serving certificate is mounted from the Secret issued by cert-manager
+	case controllers.WebhookCertModeSelfManaged:
+		if !enableWebhook {
+			setupLog.Error(nil, "--webhook-cert-mode=self-managed requires --enable-webhook")
+			os.Exit(1)
+		}
+		namespace, name, _ := strings.Cut(webhookService, "/")
+		// The cache is not started yet, the certificates are written before the webhook server starts
+		directClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create client")
+			os.Exit(1)
+		}
+		certRotator := &controllers.CertRotator{
+			Client:                directClient,
+			Secret:                types.NamespacedName{Namespace: namespace, Name: webhookCertSecret},
+			Service:               types.NamespacedName{Namespace: namespace, Name: name},
+			CertDir:               webhookCertDir,
+			WebhookConfigurations: []string{webhookConfiguration},
+			Validity:              webhookCertValidity,
+		}
+		if enableUsageAPI {
+			certRotator.APIServices = []string{controllers.UsageAPIService}
+		}
+		if err := certRotator.Ensure(context.Background()); err != nil {
+			setupLog.Error(err, "unable to set up webhook certificates")
+			os.Exit(1)
+		}
+		if err := mgr.Add(certRotator); err != nil {
+			setupLog.Error(err, "unable to set up webhook certificate rotation")
+			os.Exit(1)
+		}
+	default:
+		setupLog.Error(nil, "unknown --webhook-cert-mode "+webhookCertMode)
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
+	if chargebackSchedule != "" {
+		schedule, err := cron.Parse(chargebackSchedule)
+		if err != nil {
+			setupLog.Error(err, "unable to parse chargeback schedule")
+			os.Exit(1)
+		}
+		if err := controllers.ValidateChargebackSchedule(schedule, time.Now()); err != nil {
+			setupLog.Error(err, "invalid chargeback schedule")
+			os.Exit(1)
+		}
+		reporter := &controllers.ChargebackReporter{
+			Client:       mgr.GetClient(),
+			Schedule:     schedule,
+			Namespace:    chargebackNamespace,
+			CostProvider: costProvider,
+			Filter:       filter,
+			Retention:    chargebackRetention,
+		}
+		if chargebackSMTPServer != "" {
+			reporter.Mailer = &controllers.ReportMailer{
+				Server: chargebackSMTPServer,
+				From:   chargebackEmailFrom,
+				To:     strings.Split(chargebackEmailTo, ","),
+			}
+		}
+		if err := mgr.Add(reporter); err != nil {
+			setupLog.Error(err, "unable to set up chargeback reporter")
+			os.Exit(1)
+		}
+	}
+
+	collector := &controllers.WorkspaceCollector{
+		Client:   mgr.GetClient(),
+		Level:    metricsAggregation,
+		Detailed: metricsDetailed,
+		Filter:   filter,
+	}
+	if err := metrics.Registry.Register(collector); err != nil {
+		setupLog.Error(err, "unable to register workspace metrics")
+		os.Exit(1)
+	}
+	if err := metrics.Registry.Register(provisioningMetrics); err != nil {
+		setupLog.Error(err, "unable to register provisioning metrics")
+		os.Exit(1)
+	}
+	if groupMigration != nil {
+		if err := metrics.Registry.Register(groupMigration); err != nil {
+			setupLog.Error(err, "unable to register group migration metrics")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.Add(logControls); err != nil {
+		setupLog.Error(err, "unable to set up log config reloading")
+		os.Exit(1)
+	}
+
+	// The informers are started on the standby replicas too, so that they take over with warm caches
+	cacheWarmer := &controllers.CacheWarmer{Cache: mgr.GetCache()}
+	if err := mgr.Add(cacheWarmer); err != nil {
+		setupLog.Error(err, "unable to set up cache warmer")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -106,6 +908,10 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("cache", cacheWarmer.Checker); err != nil {
+		setupLog.Error(err, "unable to set up cache ready check")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -113,3 +919,12 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// readSecretFile returns the content of a mounted secret file without its trailing newline
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
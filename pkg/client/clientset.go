@@ -0,0 +1,96 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is a typed client-go style client for the environment v1alpha1 API.
+// It lets Go services manage and watch Workspaces without depending on controller-runtime clients:
+//
+//	clientset, err := client.NewForConfig(config)
+//	workspace, err := clientset.Workspaces().Get(ctx, "team-a", metav1.GetOptions{})
+//
+//	informer := client.NewWorkspaceInformer(clientset, 10*time.Minute)
+//	lister := client.NewWorkspaceLister(informer.GetIndexer())
+package client
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+var (
+	scheme         = runtime.NewScheme()
+	codecs         = serializer.NewCodecFactory(scheme)
+	parameterCodec = runtime.NewParameterCodec(scheme)
+)
+
+func init() {
+	utilruntime.Must(environmentv1alpha1.AddToScheme(scheme))
+	metav1.AddToGroupVersion(scheme, environmentv1alpha1.GroupVersion)
+}
+
+// Interface is the typed client of the environment v1alpha1 API
+type Interface interface {
+	Workspaces() WorkspaceInterface
+}
+
+// Clientset is the typed client of the environment v1alpha1 API
+type Clientset struct {
+	restClient rest.Interface
+}
+
+// NewForConfig returns a Clientset for the given config
+func NewForConfig(c *rest.Config) (*Clientset, error) {
+	config := *c
+	config.GroupVersion = &environmentv1alpha1.GroupVersion
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	restClient, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &Clientset{restClient: restClient}, nil
+}
+
+// NewForConfigOrDie returns a Clientset for the given config and panics on errors
+func NewForConfigOrDie(c *rest.Config) *Clientset {
+	clientset, err := NewForConfig(c)
+	if err != nil {
+		panic(err)
+	}
+	return clientset
+}
+
+// New returns a Clientset using the given RESTClient
+func New(restClient rest.Interface) *Clientset {
+	return &Clientset{restClient: restClient}
+}
+
+// Workspaces returns the client of the Workspaces
+func (c *Clientset) Workspaces() WorkspaceInterface {
+	return &workspaces{client: c.restClient}
+}
+
+// RESTClient returns the RESTClient used by the Clientset
+func (c *Clientset) RESTClient() rest.Interface {
+	return c.restClient
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// NewWorkspaceInformer returns a shared informer of the Workspaces, keyed by name
+func NewWorkspaceInformer(client Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredWorkspaceInformer(client, resyncPeriod, nil)
+}
+
+// NewFilteredWorkspaceInformer returns a shared informer of the Workspaces, keyed by name.
+// tweakListOptions can restrict the watched Workspaces, e.g. with a label selector.
+func NewFilteredWorkspaceInformer(client Interface, resyncPeriod time.Duration, tweakListOptions func(*metav1.ListOptions)) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.Workspaces().List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.Workspaces().Watch(context.TODO(), options)
+			},
+		},
+		&environmentv1alpha1.Workspace{},
+		resyncPeriod,
+		cache.Indexers{},
+	)
+}
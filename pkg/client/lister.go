@@ -0,0 +1,59 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// WorkspaceLister lists the Workspaces from the cache of an informer
+type WorkspaceLister interface {
+	List(selector labels.Selector) ([]*environmentv1alpha1.Workspace, error)
+	Get(name string) (*environmentv1alpha1.Workspace, error)
+}
+
+// NewWorkspaceLister returns a WorkspaceLister reading from the indexer of a Workspace informer
+func NewWorkspaceLister(indexer cache.Indexer) WorkspaceLister {
+	return &workspaceLister{indexer: indexer}
+}
+
+type workspaceLister struct {
+	indexer cache.Indexer
+}
+
+func (l *workspaceLister) List(selector labels.Selector) ([]*environmentv1alpha1.Workspace, error) {
+	var workspaces []*environmentv1alpha1.Workspace
+	err := cache.ListAll(l.indexer, selector, func(obj interface{}) {
+		workspaces = append(workspaces, obj.(*environmentv1alpha1.Workspace))
+	})
+	return workspaces, err
+}
+
+func (l *workspaceLister) Get(name string) (*environmentv1alpha1.Workspace, error) {
+	obj, exists, err := l.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(environmentv1alpha1.GroupVersion.WithResource("workspaces").GroupResource(), name)
+	}
+	return obj.(*environmentv1alpha1.Workspace), nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// WorkspaceInterface manages the cluster scoped Workspaces
+type WorkspaceInterface interface {
+	Create(ctx context.Context, workspace *environmentv1alpha1.Workspace, opts metav1.CreateOptions) (*environmentv1alpha1.Workspace, error)
+	Update(ctx context.Context, workspace *environmentv1alpha1.Workspace, opts metav1.UpdateOptions) (*environmentv1alpha1.Workspace, error)
+	UpdateStatus(ctx context.Context, workspace *environmentv1alpha1.Workspace, opts metav1.UpdateOptions) (*environmentv1alpha1.Workspace, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*environmentv1alpha1.Workspace, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*environmentv1alpha1.WorkspaceList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*environmentv1alpha1.Workspace, error)
+}
+
+// workspaces implements WorkspaceInterface
+type workspaces struct {
+	client rest.Interface
+}
+
+func (c *workspaces) Create(ctx context.Context, workspace *environmentv1alpha1.Workspace, opts metav1.CreateOptions) (*environmentv1alpha1.Workspace, error) {
+	result := &environmentv1alpha1.Workspace{}
+	err := c.client.Post().
+		Resource("workspaces").
+		VersionedParams(&opts, parameterCodec).
+		Body(workspace).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *workspaces) Update(ctx context.Context, workspace *environmentv1alpha1.Workspace, opts metav1.UpdateOptions) (*environmentv1alpha1.Workspace, error) {
+	result := &environmentv1alpha1.Workspace{}
+	err := c.client.Put().
+		Resource("workspaces").
+		Name(workspace.Name).
+		VersionedParams(&opts, parameterCodec).
+		Body(workspace).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *workspaces) UpdateStatus(ctx context.Context, workspace *environmentv1alpha1.Workspace, opts metav1.UpdateOptions) (*environmentv1alpha1.Workspace, error) {
+	result := &environmentv1alpha1.Workspace{}
+	err := c.client.Put().
+		Resource("workspaces").
+		Name(workspace.Name).
+		SubResource("status").
+		VersionedParams(&opts, parameterCodec).
+		Body(workspace).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *workspaces) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Resource("workspaces").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+func (c *workspaces) Get(ctx context.Context, name string, opts metav1.GetOptions) (*environmentv1alpha1.Workspace, error) {
+	result := &environmentv1alpha1.Workspace{}
+	err := c.client.Get().
+		Resource("workspaces").
+		Name(name).
+		VersionedParams(&opts, parameterCodec).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *workspaces) List(ctx context.Context, opts metav1.ListOptions) (*environmentv1alpha1.WorkspaceList, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result := &environmentv1alpha1.WorkspaceList{}
+	err := c.client.Get().
+		Resource("workspaces").
+		VersionedParams(&opts, parameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *workspaces) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource("workspaces").
+		VersionedParams(&opts, parameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+func (c *workspaces) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*environmentv1alpha1.Workspace, error) {
+	result := &environmentv1alpha1.Workspace{}
+	err := c.client.Patch(pt).
+		Resource("workspaces").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, parameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// Defaults of the Workspaces built by NewWorkspace
+const (
+	DefaultCPU    = "1"
+	DefaultMemory = "1Gi"
+	DefaultDisk   = "10Gi"
+	DefaultAdmin  = "admin@example.com"
+)
+
+// WorkspaceBuilder builds Workspace fixtures
+type WorkspaceBuilder struct {
+	workspace *environmentv1alpha1.Workspace
+}
+
+// NewWorkspace returns a builder of a valid Workspace with the name, provisioned in the namespace of the same name
+// with the default resources and admin
+func NewWorkspace(name string) *WorkspaceBuilder {
+	return &WorkspaceBuilder{workspace: &environmentv1alpha1.Workspace{
+		TypeMeta: metav1.TypeMeta{APIVersion: environmentv1alpha1.GroupVersion.String(), Kind: "Workspace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: environmentv1alpha1.WorkspaceSpec{
+			Name: name,
+			Resources: environmentv1alpha1.WorkspaceResource{
+				CPU:    DefaultCPU,
+				Memory: DefaultMemory,
+				Disk:   DefaultDisk,
+			},
+			Users: environmentv1alpha1.WorkspaceUser{
+				Admin: DefaultAdmin,
+			},
+		},
+	}}
+}
+
+// WithNamespace sets spec.name, the namespace of the workspace
+func (b *WorkspaceBuilder) WithNamespace(namespace string) *WorkspaceBuilder {
+	b.workspace.Spec.Name = namespace
+	return b
+}
+
+// WithResources sets the cpu, memory and disk of spec.resources
+func (b *WorkspaceBuilder) WithResources(cpu, memory, disk string) *WorkspaceBuilder {
+	b.workspace.Spec.Resources = environmentv1alpha1.WorkspaceResource{CPU: cpu, Memory: memory, Disk: disk}
+	return b
+}
+
+// WithUsers sets the admin, editor and viewer of spec.users, the empty ones are unset
+func (b *WorkspaceBuilder) WithUsers(admin, editor, viewer string) *WorkspaceBuilder {
+	b.workspace.Spec.Users = environmentv1alpha1.WorkspaceUser{Admin: admin, Editor: editor, Viewer: viewer}
+	return b
+}
+
+// WithClass sets spec.className
+func (b *WorkspaceBuilder) WithClass(className string) *WorkspaceBuilder {
+	b.workspace.Spec.ClassName = className
+	return b
+}
+
+// WithLabels adds labels to the Workspace
+func (b *WorkspaceBuilder) WithLabels(labels map[string]string) *WorkspaceBuilder {
+	if b.workspace.Labels == nil {
+		b.workspace.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		b.workspace.Labels[k] = v
+	}
+	return b
+}
+
+// WithAnnotations adds annotations to the Workspace
+func (b *WorkspaceBuilder) WithAnnotations(annotations map[string]string) *WorkspaceBuilder {
+	if b.workspace.Annotations == nil {
+		b.workspace.Annotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		b.workspace.Annotations[k] = v
+	}
+	return b
+}
+
+// WithSpec applies a change to the spec, for the fields without a dedicated method
+func (b *WorkspaceBuilder) WithSpec(change func(spec *environmentv1alpha1.WorkspaceSpec)) *WorkspaceBuilder {
+	change(&b.workspace.Spec)
+	return b
+}
+
+// Build returns a copy of the built Workspace, so that the builder can be reused for variants
+func (b *WorkspaceBuilder) Build() *environmentv1alpha1.Workspace {
+	return b.workspace.DeepCopy()
+}
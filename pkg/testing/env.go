@@ -0,0 +1,101 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testing helps the teams extending the operator, e.g. with custom hooks or forked templates,
+// to test their code against the Workspace API without copying its scaffolding. It bootstraps envtest
+// with the CRDs of the operator, builds Workspace fixtures and drives the reconciler over a fake client:
+//
+//	env, err := testing.StartEnvironment()
+//	defer env.Stop()
+//
+//	workspace := testing.NewWorkspace("team-a").WithUsers("alice", "bob", "").Build()
+//	c := testing.NewFakeClient(workspace)
+//	result, err := testing.ReconcileUntilReady(ctx, testing.NewReconciler(c), workspace.Name, 20)
+//
+// The envtest binaries are located with the KUBEBUILDER_ASSETS environment variable,
+// e.g. as set by make test or setup-envtest use -p path.
+package testing
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+)
+
+// NewScheme returns a scheme with the Kubernetes and the environment v1alpha1 types
+func NewScheme() *k8sruntime.Scheme {
+	scheme := k8sruntime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(environmentv1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+// CRDDirectory returns the directory of the CRDs of the operator, resolved from the source of this package
+// so that it is found from the module cache of downstream modules as well
+func CRDDirectory() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "config", "crd", "bases")
+}
+
+// Environment is a running API server with the CRDs of the operator installed
+type Environment struct {
+	*envtest.Environment
+
+	// Config is the config of the API server
+	Config *rest.Config
+
+	// Scheme has the Kubernetes and the environment v1alpha1 types
+	Scheme *k8sruntime.Scheme
+
+	// Client is a client of the API server with Scheme
+	Client client.Client
+}
+
+// StartEnvironment starts an API server with the CRDs of the operator and of the extra CRD directories installed
+func StartEnvironment(extraCRDDirectories ...string) (*Environment, error) {
+	env := &Environment{
+		Environment: &envtest.Environment{
+			CRDDirectoryPaths:     append([]string{CRDDirectory()}, extraCRDDirectories...),
+			ErrorIfCRDPathMissing: true,
+		},
+		Scheme: NewScheme(),
+	}
+	config, err := env.Environment.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start envtest: %w", err)
+	}
+	env.Config = config
+	env.Client, err = client.New(config, client.Options{Scheme: env.Scheme})
+	if err != nil {
+		_ = env.Environment.Stop()
+		return nil, err
+	}
+	return env, nil
+}
+
+// Stop stops the API server
+func (e *Environment) Stop() error {
+	return e.Environment.Stop()
+}
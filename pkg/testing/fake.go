@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/controllers"
+)
+
+// NewFakeClient returns an in-memory client with the Kubernetes and the environment v1alpha1 types,
+// holding the objects
+func NewFakeClient(objects ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(NewScheme()).WithObjects(objects...).Build()
+}
+
+// NewReconciler returns a WorkspaceReconciler on the client with all the optional integrations disabled.
+// Its Recorder is a record.FakeRecorder buffering the last 100 events.
+func NewReconciler(c client.Client) *controllers.WorkspaceReconciler {
+	return &controllers.WorkspaceReconciler{
+		Client:   c,
+		Scheme:   c.Scheme(),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
+
+// Reconcile runs a single reconciliation of the Workspace with the name
+func Reconcile(ctx context.Context, r *controllers.WorkspaceReconciler, name string) (ctrl.Result, error) {
+	return r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+}
+
+// ReconcileUntilReady runs the reconciliations of the Workspace with the name, as requeued by the reconciler,
+// until it is Ready. It fails when the Workspace is not Ready after maxSteps reconciliations, or when the
+// reconciler stops requeueing before.
+func ReconcileUntilReady(ctx context.Context, r *controllers.WorkspaceReconciler, name string, maxSteps int) (ctrl.Result, error) {
+	for step := 0; step < maxSteps; step++ {
+		result, err := Reconcile(ctx, r, name)
+		if err != nil {
+			return result, err
+		}
+		workspace := &environmentv1alpha1.Workspace{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name}, workspace); err != nil {
+			return result, err
+		}
+		if meta.IsStatusConditionTrue(workspace.Status.Conditions, controllers.ConditionReady) {
+			return result, nil
+		}
+		if !result.Requeue && result.RequeueAfter == 0 {
+			return result, fmt.Errorf("Workspace %s is not Ready and is not requeued after %d reconciliations", name, step+1)
+		}
+	}
+	return ctrl.Result{}, fmt.Errorf("Workspace %s is not Ready after %d reconciliations", name, maxSteps)
+}
+
+// Events returns the events recorded by a reconciler of NewReconciler so far, e.g. "Normal Created ..."
+func Events(r *controllers.WorkspaceReconciler) []string {
+	recorder, ok := r.Recorder.(*record.FakeRecorder)
+	if !ok {
+		return nil
+	}
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
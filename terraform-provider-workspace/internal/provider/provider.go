@@ -0,0 +1,70 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provider is the Terraform provider managing Workspaces of the workspace-operator
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/dunefro/workspace-operator/pkg/client"
+)
+
+// New returns the workspace provider
+func New() *schema.Provider {
+	return &schema.Provider{
+		Schema: map[string]*schema.Schema{
+			"config_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("KUBE_CONFIG_PATH", ""),
+				Description: "Path to the kubeconfig file. The in-cluster config or the default loading rules are used when empty.",
+			},
+			"config_context": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("KUBE_CTX", ""),
+				Description: "Context of the kubeconfig file to use. The current context is used when empty.",
+			},
+		},
+		ResourcesMap: map[string]*schema.Resource{
+			"workspace": resourceWorkspace(),
+		},
+		ConfigureContextFunc: configure,
+	}
+}
+
+// configure builds the Workspace client from the kubeconfig
+func configure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path := d.Get("config_path").(string); path != "" {
+		loadingRules.ExplicitPath = path
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: d.Get("config_context").(string)}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	clientset, err := client.NewForConfig(config)
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	return clientset, nil
+}
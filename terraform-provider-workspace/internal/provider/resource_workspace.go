@@ -0,0 +1,370 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	environmentv1alpha1 "github.com/dunefro/workspace-operator/api/v1alpha1"
+	"github.com/dunefro/workspace-operator/pkg/client"
+)
+
+// resourceWorkspace maps the workspace resource to the Workspace custom resource.
+// Only the fields below are managed, the other fields of the spec are left untouched on update.
+func resourceWorkspace() *schema.Resource {
+	return &schema.Resource{
+		Description:   "A Workspace managed by the workspace-operator: a namespace with its quota and RBAC.",
+		CreateContext: resourceWorkspaceCreate,
+		ReadContext:   resourceWorkspaceRead,
+		UpdateContext: resourceWorkspaceUpdate,
+		DeleteContext: resourceWorkspaceDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+		},
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Name of the Workspace object.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Name of the namespace managed by the Workspace (spec.name).",
+			},
+			"labels": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Labels set on the namespace and the resources of the workspace.",
+			},
+			"annotations": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Annotations set on the namespace and the resources of the workspace.",
+			},
+			"resources": {
+				Type:     schema.TypeList,
+				Required: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"cpu":    {Type: schema.TypeString, Required: true},
+						"memory": {Type: schema.TypeString, Required: true},
+						"disk":   {Type: schema.TypeString, Required: true},
+
+						"requests_cpu":    {Type: schema.TypeString, Optional: true},
+						"limits_cpu":      {Type: schema.TypeString, Optional: true},
+						"requests_memory": {Type: schema.TypeString, Optional: true},
+						"limits_memory":   {Type: schema.TypeString, Optional: true},
+
+						"pods":                     {Type: schema.TypeString, Optional: true},
+						"services":                 {Type: schema.TypeString, Optional: true},
+						"load_balancers":           {Type: schema.TypeString, Optional: true},
+						"secrets":                  {Type: schema.TypeString, Optional: true},
+						"config_maps":              {Type: schema.TypeString, Optional: true},
+						"persistent_volume_claims": {Type: schema.TypeString, Optional: true},
+					},
+				},
+				Description: "ResourceQuota hard limits of the namespace, its cpu, memory, disk, requests, limits and object counts.",
+			},
+			"users": {
+				Type:     schema.TypeList,
+				Required: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"admin":   {Type: schema.TypeString, Optional: true},
+						"editor":  {Type: schema.TypeString, Optional: true},
+						"viewer":  {Type: schema.TypeString, Optional: true},
+						"admins":  {Type: schema.TypeList, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+						"editors": {Type: schema.TypeList, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+						"viewers": {Type: schema.TypeList, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+					},
+				},
+				Description: "Users bound to the admin, editor and viewer roles of the namespace.",
+			},
+			"quota_alert_thresholds": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Usage percentages of the quota at which the QuotaPressure condition is raised.",
+			},
+			"observability_tenant": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Loki/Mimir tenant ID the telemetry of the namespace is tagged with.",
+			},
+			"deletion_grace_period": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Duration (e.g. 168h) a deleted workspace is kept frozen before its namespace is deleted.",
+			},
+			"wait_for_ready": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Wait for the Workspace to become Ready on create and update.",
+			},
+			"phase": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Lifecycle phase of the Workspace.",
+			},
+		},
+	}
+}
+
+// expandWorkspaceSpec sets the managed fields of the spec from the resource data
+func expandWorkspaceSpec(d *schema.ResourceData, spec *environmentv1alpha1.WorkspaceSpec) error {
+	spec.Name = d.Get("namespace").(string)
+	spec.Labels = expandStringMap(d.Get("labels").(map[string]interface{}))
+	spec.Annotations = expandStringMap(d.Get("annotations").(map[string]interface{}))
+
+	if resources := d.Get("resources").([]interface{}); len(resources) > 0 && resources[0] != nil {
+		r := resources[0].(map[string]interface{})
+		spec.Resources = environmentv1alpha1.WorkspaceResource{
+			CPU:    r["cpu"].(string),
+			Memory: r["memory"].(string),
+			Disk:   r["disk"].(string),
+
+			RequestsCPU:    r["requests_cpu"].(string),
+			LimitsCPU:      r["limits_cpu"].(string),
+			RequestsMemory: r["requests_memory"].(string),
+			LimitsMemory:   r["limits_memory"].(string),
+
+			Pods:                   r["pods"].(string),
+			Services:               r["services"].(string),
+			LoadBalancers:          r["load_balancers"].(string),
+			Secrets:                r["secrets"].(string),
+			ConfigMaps:             r["config_maps"].(string),
+			PersistentVolumeClaims: r["persistent_volume_claims"].(string),
+		}
+	}
+	if users := d.Get("users").([]interface{}); len(users) > 0 && users[0] != nil {
+		u := users[0].(map[string]interface{})
+		spec.Users = environmentv1alpha1.WorkspaceUser{
+			Admin:   u["admin"].(string),
+			Editor:  u["editor"].(string),
+			Viewer:  u["viewer"].(string),
+			Admins:  stringList(u["admins"]),
+			Editors: stringList(u["editors"]),
+			Viewers: stringList(u["viewers"]),
+		}
+	}
+
+	spec.QuotaAlerts.Thresholds = nil
+	for _, threshold := range d.Get("quota_alert_thresholds").([]interface{}) {
+		spec.QuotaAlerts.Thresholds = append(spec.QuotaAlerts.Thresholds, int32(threshold.(int)))
+	}
+	spec.ObservabilityTenant = d.Get("observability_tenant").(string)
+
+	spec.DeletionGracePeriod = nil
+	if gracePeriod := d.Get("deletion_grace_period").(string); gracePeriod != "" {
+		duration, err := time.ParseDuration(gracePeriod)
+		if err != nil {
+			return fmt.Errorf("invalid deletion_grace_period: %w", err)
+		}
+		spec.DeletionGracePeriod = &metav1.Duration{Duration: duration}
+	}
+	return nil
+}
+
+// flattenWorkspace sets the resource data from the Workspace
+func flattenWorkspace(d *schema.ResourceData, workspace *environmentv1alpha1.Workspace) error {
+	spec := workspace.Spec
+	var thresholds []int
+	for _, threshold := range spec.QuotaAlerts.Thresholds {
+		thresholds = append(thresholds, int(threshold))
+	}
+	gracePeriod := ""
+	if spec.DeletionGracePeriod != nil {
+		gracePeriod = spec.DeletionGracePeriod.Duration.String()
+	}
+	values := map[string]interface{}{
+		"name":        workspace.Name,
+		"namespace":   spec.Name,
+		"labels":      spec.Labels,
+		"annotations": spec.Annotations,
+		"resources": []interface{}{map[string]interface{}{
+			"cpu":    spec.Resources.CPU,
+			"memory": spec.Resources.Memory,
+			"disk":   spec.Resources.Disk,
+
+			"requests_cpu":    spec.Resources.RequestsCPU,
+			"limits_cpu":      spec.Resources.LimitsCPU,
+			"requests_memory": spec.Resources.RequestsMemory,
+			"limits_memory":   spec.Resources.LimitsMemory,
+
+			"pods":                     spec.Resources.Pods,
+			"services":                 spec.Resources.Services,
+			"load_balancers":           spec.Resources.LoadBalancers,
+			"secrets":                  spec.Resources.Secrets,
+			"config_maps":              spec.Resources.ConfigMaps,
+			"persistent_volume_claims": spec.Resources.PersistentVolumeClaims,
+		}},
+		"users": []interface{}{map[string]interface{}{
+			"admin":   spec.Users.Admin,
+			"editor":  spec.Users.Editor,
+			"viewer":  spec.Users.Viewer,
+			"admins":  spec.Users.Admins,
+			"editors": spec.Users.Editors,
+			"viewers": spec.Users.Viewers,
+		}},
+		"quota_alert_thresholds": thresholds,
+		"observability_tenant":   spec.ObservabilityTenant,
+		"deletion_grace_period":  gracePeriod,
+		"phase":                  string(workspace.Status.Phase),
+	}
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expandStringMap(m map[string]interface{}) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		result[k] = v.(string)
+	}
+	return result
+}
+
+func stringList(v interface{}) []string {
+	values, _ := v.([]interface{})
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, value.(string))
+	}
+	return result
+}
+
+// waitForReady waits until the Workspace reports the Ready phase for its current generation
+func waitForReady(ctx context.Context, clientset client.Interface, name string, timeout time.Duration) error {
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		workspace, err := clientset.Workspaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		if workspace.Status.Phase == environmentv1alpha1.WorkspaceFailed {
+			return retry.NonRetryableError(fmt.Errorf("workspace %s failed, see its Stalled condition", name))
+		}
+		if workspace.Status.Phase != environmentv1alpha1.WorkspaceReady || workspace.Status.ObservedGeneration != workspace.Generation {
+			return retry.RetryableError(fmt.Errorf("workspace %s is %s", name, workspace.Status.Phase))
+		}
+		return nil
+	})
+}
+
+func resourceWorkspaceCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	clientset := meta.(client.Interface)
+	workspace := &environmentv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: d.Get("name").(string)},
+	}
+	if err := expandWorkspaceSpec(d, &workspace.Spec); err != nil {
+		return diag.FromErr(err)
+	}
+	if _, err := clientset.Workspaces().Create(ctx, workspace, metav1.CreateOptions{}); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(workspace.Name)
+	if d.Get("wait_for_ready").(bool) {
+		if err := waitForReady(ctx, clientset, workspace.Name, d.Timeout(schema.TimeoutCreate)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return resourceWorkspaceRead(ctx, d, meta)
+}
+
+func resourceWorkspaceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	clientset := meta.(client.Interface)
+	workspace, err := clientset.Workspaces().Get(ctx, d.Id(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := flattenWorkspace(d, workspace); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceWorkspaceUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	clientset := meta.(client.Interface)
+	err := retry.RetryContext(ctx, d.Timeout(schema.TimeoutUpdate), func() *retry.RetryError {
+		workspace, err := clientset.Workspaces().Get(ctx, d.Id(), metav1.GetOptions{})
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		if err := expandWorkspaceSpec(d, &workspace.Spec); err != nil {
+			return retry.NonRetryableError(err)
+		}
+		_, err = clientset.Workspaces().Update(ctx, workspace, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			return retry.RetryableError(err)
+		}
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if d.Get("wait_for_ready").(bool) {
+		if err := waitForReady(ctx, clientset, d.Id(), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return resourceWorkspaceRead(ctx, d, meta)
+}
+
+func resourceWorkspaceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	clientset := meta.(client.Interface)
+	err := clientset.Workspaces().Delete(ctx, d.Id(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return diag.FromErr(err)
+	}
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+
+	"github.com/dunefro/workspace-operator/terraform-provider-workspace/internal/provider"
+)
+
+func main() {
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "Start the provider with support for debuggers like delve.")
+	flag.Parse()
+
+	plugin.Serve(&plugin.ServeOpts{
+		ProviderFunc: provider.New,
+		ProviderAddr: "registry.terraform.io/dunefro/workspace",
+		Debug:        debug,
+	})
+}